@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
 	"log/slog"
 
+	"github.com/pv/uniset-panel/internal/alarm"
 	"github.com/pv/uniset-panel/internal/api"
 	"github.com/pv/uniset-panel/internal/config"
 	"github.com/pv/uniset-panel/internal/dashboard"
@@ -18,6 +22,7 @@ import (
 	"github.com/pv/uniset-panel/internal/logger"
 	"github.com/pv/uniset-panel/internal/logserver"
 	"github.com/pv/uniset-panel/internal/modbus"
+	"github.com/pv/uniset-panel/internal/notify"
 	"github.com/pv/uniset-panel/internal/opcua"
 	"github.com/pv/uniset-panel/internal/poller"
 	"github.com/pv/uniset-panel/internal/recording"
@@ -27,6 +32,8 @@ import (
 	"github.com/pv/uniset-panel/internal/storage"
 	"github.com/pv/uniset-panel/internal/uniset"
 	"github.com/pv/uniset-panel/internal/uwsgate"
+	"github.com/pv/uniset-panel/internal/virtual"
+	"github.com/pv/uniset-panel/internal/watchdog"
 	"github.com/pv/uniset-panel/ui"
 )
 
@@ -51,9 +58,28 @@ func main() {
 			os.Exit(1)
 		}
 		logger.Info("Using SQLite storage", "path", cfg.SQLitePath)
+	case config.StorageTiered:
+		cold, err := storage.NewSQLiteStorage(cfg.SQLitePath)
+		if err != nil {
+			logger.Error("Failed to create SQLite storage", "error", err)
+			os.Exit(1)
+		}
+		store = storage.NewTieredStorage(cold, cfg.HotWindow,
+			storage.WithMemoryBudget(cfg.MemoryLimitMB*1024*1024),
+			storage.WithMaxPointsPerSeries(cfg.MemoryMaxPoints),
+		)
+		logger.Info("Using tiered storage",
+			"path", cfg.SQLitePath,
+			"hot_window", cfg.HotWindow,
+			"memory_limit_mb", cfg.MemoryLimitMB)
 	default:
-		store = storage.NewMemoryStorage()
-		logger.Info("Using in-memory storage")
+		store = storage.NewMemoryStorage(
+			storage.WithMemoryBudget(cfg.MemoryLimitMB*1024*1024),
+			storage.WithMaxPointsPerSeries(cfg.MemoryMaxPoints),
+		)
+		logger.Info("Using in-memory storage",
+			"memory_limit_mb", cfg.MemoryLimitMB,
+			"max_points_per_series", cfg.MemoryMaxPoints)
 	}
 	defer store.Close()
 
@@ -120,15 +146,208 @@ func main() {
 		}
 	}
 
+	// Уведомления во внешние каналы (из YAML конфига)
+	var notifier *notify.Dispatcher
+	if len(cfg.Notifications) > 0 {
+		routes := make([]notify.Route, 0, len(cfg.Notifications))
+		for i, nc := range cfg.Notifications {
+			var n notify.Notifier
+			switch nc.Type {
+			case "webhook":
+				n, err = notify.NewWebhookNotifier(nc.URL, nc.Headers, nc.Retries)
+			case "smtp":
+				n, err = notify.NewSMTPNotifier(notify.SMTPOptions{
+					Addr:     nc.Addr,
+					From:     nc.From,
+					To:       nc.To,
+					Username: nc.Username,
+					Password: nc.Password,
+				})
+			case "syslog":
+				n, err = notify.NewSyslogNotifier(nc.Network, nc.Addr, nc.AppName)
+			default:
+				err = fmt.Errorf("unknown notification type %q", nc.Type)
+			}
+			if err != nil {
+				logger.Error("Invalid notification config", "index", i, "error", err)
+				os.Exit(1)
+			}
+			routes = append(routes, notify.Route{
+				Notifier:   n,
+				Events:     nc.Events,
+				RateLimit:  nc.RateLimit,
+				RatePeriod: nc.RatePeriod,
+			})
+		}
+		notifier, err = notify.NewDispatcher(routes)
+		if err != nil {
+			logger.Error("Invalid notification config", "error", err)
+			os.Exit(1)
+		}
+		notifier.Start()
+		defer notifier.Stop()
+	}
+
+	// Движок пороговых тревог (из YAML конфига)
+	var alarmEngine *alarm.Engine
+	var alarmHistory *alarm.History
+	if len(cfg.Alarms) > 0 {
+		rules := make([]alarm.Rule, 0, len(cfg.Alarms))
+		for _, rule := range cfg.Alarms {
+			rules = append(rules, alarm.Rule{
+				Name:       rule.Name,
+				Source:     rule.Source,
+				Server:     rule.Server,
+				Object:     rule.Object,
+				Sensor:     rule.Sensor,
+				Message:    rule.Message,
+				HiHi:       rule.HiHi,
+				Hi:         rule.Hi,
+				Lo:         rule.Lo,
+				LoLo:       rule.LoLo,
+				Hysteresis: rule.Hysteresis,
+				OnDelay:    rule.OnDelay,
+				OffDelay:   rule.OffDelay,
+			})
+		}
+		// История тревог: события сохраняются до рассылки клиентам
+		alarmHistoryPath := cfg.GetAlarmHistoryPath()
+		alarmHistory, err = alarm.OpenHistory(alarmHistoryPath, 0)
+		if err != nil {
+			logger.Error("Failed to open alarm history", "path", alarmHistoryPath, "error", err)
+		} else {
+			defer alarmHistory.Close()
+			logger.Info("Alarm history opened", "path", alarmHistoryPath)
+		}
+		onAlarm := func(event alarm.Event) {
+			if alarmHistory != nil {
+				if err := alarmHistory.Save(event); err != nil {
+					logger.Warn("Failed to save alarm event", "error", err)
+				}
+			}
+			sseHub.BroadcastAlarmEvent(event)
+			if notifier != nil {
+				notifyAlarm(notifier, event)
+			}
+		}
+
+		alarmEngine, err = alarm.NewEngine(rules, onAlarm)
+		if err != nil {
+			logger.Error("Invalid alarm rules", "error", err)
+			os.Exit(1)
+		}
+		alarmEngine.Start()
+		defer alarmEngine.Stop()
+	}
+
+	// Виртуальные датчики (из YAML конфига): публикуются как датчики сервера virtual.ServerID,
+	// сохраняются в историю и проверяются правилами тревог с source: virtual
+	var virtualEngine *virtual.Engine
+	if len(cfg.VirtualSensors) > 0 {
+		defs := make([]virtual.Definition, 0, len(cfg.VirtualSensors))
+		for _, vs := range cfg.VirtualSensors {
+			inputs := make(map[string]virtual.Input, len(vs.Inputs))
+			for name, in := range vs.Inputs {
+				inputs[name] = virtual.Input{
+					Source: in.Source,
+					Server: in.Server,
+					Object: in.Object,
+					Sensor: in.Sensor,
+					ID:     in.ID,
+				}
+			}
+			defs = append(defs, virtual.Definition{
+				Name:       vs.Name,
+				Object:     vs.Object,
+				Expression: vs.Expression,
+				Inputs:     inputs,
+			})
+		}
+		virtualEngine, err = virtual.New(defs, func(v virtual.Value) {
+			sseHub.BroadcastVirtualValue(v)
+			if v.Error != "" {
+				return
+			}
+			if err := store.Save(virtual.ServerID, v.Object, v.Name, v.Value, v.Timestamp); err != nil {
+				logger.Warn("Failed to save virtual sensor value", "sensor", v.Name, "error", err)
+			}
+			if recordingMgr != nil && recordingMgr.IsRecording() {
+				recordingMgr.Save(virtual.ServerID, v.Object, v.Name, v.Value, v.Timestamp)
+			}
+			if alarmEngine != nil {
+				alarmEngine.Update(alarm.SourceVirtual, virtual.ServerID, v.Object, v.Name, v.Value, v.Timestamp)
+			}
+		})
+		if err != nil {
+			logger.Error("Invalid virtual sensors", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Virtual sensors configured", "count", virtualEngine.Count())
+	}
+
+	// Watchdog устаревших значений (из YAML конфига)
+	var staleWatchdog *watchdog.Watchdog
+	if len(cfg.Staleness) > 0 {
+		rules := make([]watchdog.Rule, 0, len(cfg.Staleness))
+		for _, rule := range cfg.Staleness {
+			rules = append(rules, watchdog.Rule{
+				Source: rule.Source,
+				Server: rule.Server,
+				Object: rule.Object,
+				Sensor: rule.Sensor,
+				Window: rule.Window,
+			})
+		}
+		staleWatchdog, err = watchdog.New(rules, sseHub.BroadcastStaleEvent)
+		if err != nil {
+			logger.Error("Invalid staleness rules", "error", err)
+			os.Exit(1)
+		}
+		// Отслеживаются только датчики, на которые кто-то подписан
+		staleWatchdog.SetSubscribedFunc(func(key watchdog.Key, id int64) bool {
+			switch key.Source {
+			case watchdog.SourceIONC:
+				p, ok := serverMgr.GetIONCPoller(key.ServerID)
+				return ok && p != nil && slices.Contains(p.GetSubscriptions(key.Object), id)
+			case watchdog.SourceUWSGate:
+				instance, ok := serverMgr.GetServer(key.ServerID)
+				return ok && instance.UWSGatePoller != nil &&
+					slices.Contains(instance.UWSGatePoller.GetSubscriptions(key.Object), key.Sensor)
+			}
+			return false
+		})
+		staleWatchdog.Start()
+		defer staleWatchdog.Stop()
+	}
+
+	// Новые значения источников проверяются правилами тревог, пересчитывают виртуальные датчики
+	// и отмечаются в watchdog
+	values := &valueDispatcher{alarms: alarmEngine, virtual: virtualEngine, watchdog: staleWatchdog}
+
 	// Set callbacks for SSE broadcasting (with Recording integration)
-	serverMgr.SetObjectCallback(sseHub.BroadcastObjectDataWithServer)
+	serverMgr.SetObjectCallback(func(serverID, serverName, objectName string, data *uniset.ObjectData) {
+		sseHub.BroadcastObjectDataWithServer(serverID, serverName, objectName, data)
+		if data != nil {
+			now := time.Now()
+			for name, value := range data.Variables {
+				values.dispatch(alarm.SourceObject, serverID, objectName, name, value, now)
+			}
+		}
+	})
 
 	// IONC callback with recording
 	serverMgr.SetIONCCallback(func(serverID, serverName string, updates []ionc.SensorUpdate) {
 		sseHub.BroadcastIONCSensorBatchWithServer(serverID, serverName, updates)
+		now := time.Now()
+		for _, u := range updates {
+			values.dispatch(alarm.SourceIONC, serverID, u.ObjectName, u.Sensor.Name, u.Sensor.Value, now)
+			if u.Sensor.TVSec != 0 {
+				values.observe(watchdog.SourceIONC, serverID, u.ObjectName, u.Sensor.Name, u.Sensor.ID,
+					time.Unix(u.Sensor.TVSec, u.Sensor.TVNsec), now)
+			}
+		}
 		// Record IONC sensor values
 		if recordingMgr != nil && recordingMgr.IsRecording() {
-			now := time.Now()
 			for _, u := range updates {
 				varName := "ionc:" + u.Sensor.Name
 				recordingMgr.Save(serverID, u.ObjectName, varName, u.Sensor.Value, now)
@@ -139,9 +358,12 @@ func main() {
 	// Modbus callback with recording
 	serverMgr.SetModbusCallback(func(serverID, serverName string, updates []modbus.RegisterUpdate) {
 		sseHub.BroadcastModbusRegisterBatchWithServer(serverID, serverName, updates)
+		now := time.Now()
+		for _, u := range updates {
+			values.dispatch(alarm.SourceModbus, serverID, u.ObjectName, u.Register.Name, u.Register.Value, now)
+		}
 		// Record Modbus register values
 		if recordingMgr != nil && recordingMgr.IsRecording() {
-			now := time.Now()
 			for _, u := range updates {
 				varName := "mb:" + u.Register.Name
 				recordingMgr.Save(serverID, u.ObjectName, varName, u.Register.Value, now)
@@ -152,9 +374,12 @@ func main() {
 	// OPCUA callback with recording
 	serverMgr.SetOPCUACallback(func(serverID, serverName string, updates []opcua.SensorUpdate) {
 		sseHub.BroadcastOPCUASensorBatchWithServer(serverID, serverName, updates)
+		now := time.Now()
+		for _, u := range updates {
+			values.dispatch(alarm.SourceOPCUA, serverID, u.ObjectName, u.Sensor.Name, u.Sensor.Value, now)
+		}
 		// Record OPCUA sensor values
 		if recordingMgr != nil && recordingMgr.IsRecording() {
-			now := time.Now()
 			for _, u := range updates {
 				varName := "opcua:" + u.Sensor.Name
 				recordingMgr.Save(serverID, u.ObjectName, varName, u.Sensor.Value, now)
@@ -165,9 +390,16 @@ func main() {
 	// UWebSocketGate callback with recording
 	serverMgr.SetUWSGateCallback(func(serverID, serverName string, updates []uwsgate.SensorUpdate) {
 		sseHub.BroadcastUWSGateSensorBatchWithServer(serverID, serverName, updates)
+		now := time.Now()
+		for _, u := range updates {
+			values.dispatch(alarm.SourceUWSGate, serverID, u.ObjectName, u.Sensor.Name, u.Sensor.Value, now)
+			if u.Sensor.SMTVSec != 0 {
+				values.observe(watchdog.SourceUWSGate, serverID, u.ObjectName, u.Sensor.Name, u.Sensor.ID,
+					time.Unix(u.Sensor.SMTVSec, u.Sensor.SMTVNsec), now)
+			}
+		}
 		// Record UWebSocketGate sensor values
 		if recordingMgr != nil && recordingMgr.IsRecording() {
-			now := time.Now()
 			for _, u := range updates {
 				varName := "ws:" + u.Sensor.Name
 				recordingMgr.Save(serverID, u.ObjectName, varName, u.Sensor.Value, now)
@@ -175,7 +407,40 @@ func main() {
 		}
 	})
 
-	serverMgr.SetStatusCallback(sseHub.BroadcastServerStatus)
+	if notifier != nil {
+		// server_connected отправляется только после ранее отправленного server_disconnected,
+		// чтобы не уведомлять о каждом первом подключении при старте
+		var disconnectedMu sync.Mutex
+		disconnected := make(map[string]bool)
+		serverMgr.SetStatusCallback(func(serverID, serverName string, connected bool, lastError string) {
+			sseHub.BroadcastServerStatus(serverID, serverName, connected, lastError)
+
+			disconnectedMu.Lock()
+			wasDisconnected := disconnected[serverID]
+			disconnected[serverID] = !connected
+			disconnectedMu.Unlock()
+
+			switch {
+			case !connected:
+				notifier.Notify(notify.Event{
+					Type:     notify.EventServerDisconnected,
+					Severity: notify.SeverityCritical,
+					Title:    "Server " + serverName + " disconnected",
+					Message:  lastError,
+					ServerID: serverID,
+				})
+			case wasDisconnected:
+				notifier.Notify(notify.Event{
+					Type:     notify.EventServerConnected,
+					Severity: notify.SeverityInfo,
+					Title:    "Server " + serverName + " connected",
+					ServerID: serverID,
+				})
+			}
+		})
+	} else {
+		serverMgr.SetStatusCallback(sseHub.BroadcastServerStatus)
+	}
 	serverMgr.SetObjectsCallback(sseHub.BroadcastObjectsList)
 
 	// Set recording manager on server manager (for all pollers)
@@ -183,6 +448,36 @@ func main() {
 		serverMgr.SetRecordingManager(recordingMgr)
 	}
 
+	// Change-only сохранение истории (из YAML конфига)
+	serverMgr.SetHistoryConfig(cfg.History)
+	serverMgr.SetPollConcurrency(cfg.GetPollConcurrency())
+
+	// Правила хранения истории (из YAML конфига, по умолчанию --history-ttl)
+	retentionRules := make([]storage.RetentionRule, 0, len(cfg.Retention))
+	for _, rule := range cfg.Retention {
+		retentionRules = append(retentionRules, storage.RetentionRule{
+			Server:   rule.Server,
+			Object:   rule.Object,
+			Variable: rule.Variable,
+			TTL:      rule.TTL,
+		})
+	}
+	retention, err := storage.NewRetentionPolicy(cfg.HistoryTTL, retentionRules)
+	if err != nil {
+		logger.Error("Invalid retention rules", "error", err)
+		os.Exit(1)
+	}
+	serverMgr.SetRetentionPolicy(retention)
+
+	// Входы виртуальных датчиков и датчики правил тревог опрашиваются независимо
+	// от подписок клиентов, в том числе на серверах, добавленных через API
+	pinned := pinnedInputs(virtualEngine, alarmEngine)
+	if len(pinned) > 0 {
+		serverMgr.SetServerAddedCallback(func(instance *server.Instance) {
+			subscribePinnedInputs(pinned, serverMgr, instance, sensorCfg)
+		})
+	}
+
 	// Add servers from configuration
 	for _, srvCfg := range cfg.Servers {
 		if err := serverMgr.AddServer(srvCfg); err != nil {
@@ -210,15 +505,32 @@ func main() {
 	handlers.SetLogServerManager(logServerMgr)
 	handlers.SetServerManager(serverMgr)
 	handlers.SetSSEHub(sseHub)
+	sseHub.SetSubscriptionReleaser(handlers.ReleaseClientSubscriptions)
 	handlers.SetControlsEnabled(cfg.ConFile != "") // Controls visible only if uniset-config specified
 	handlers.SetUIConfig(cfg.UI)
 	handlers.SetLogStreamConfig(cfg.LogStream)
+	handlers.SetRetentionPolicy(retention)
 	if controlMgr != nil {
 		handlers.SetControlManager(controlMgr)
 	}
 	if recordingMgr != nil {
 		handlers.SetRecordingManager(recordingMgr)
 	}
+	if alarmEngine != nil {
+		handlers.SetAlarmEngine(alarmEngine)
+	}
+	if notifier != nil {
+		handlers.SetNotifier(notifier)
+	}
+	if alarmHistory != nil {
+		handlers.SetAlarmHistory(alarmHistory)
+	}
+	if staleWatchdog != nil {
+		handlers.SetStaleWatchdog(staleWatchdog)
+	}
+	if virtualEngine != nil {
+		handlers.SetVirtualEngine(virtualEngine)
+	}
 
 	// Create dashboard manager if directory specified
 	if cfg.DashboardsDir != "" {
@@ -269,13 +581,29 @@ func main() {
 	var smPoller *sm.Poller
 	if cfg.SMURL != "" {
 		smClient := sm.NewClient(cfg.SMURL)
+		// SM обычно доступен через тот же хост, что и сервер UniSet2 — используем его настройки подключения
+		if srv, ok := cfg.ServerForURL(cfg.SMURL); ok {
+			httpClient, err := srv.HTTPClient()
+			if err != nil {
+				logger.Error("Invalid SM connection settings", "server", srv.ID, "error", err)
+				os.Exit(1)
+			}
+			smClient.SetHTTPClient(httpClient)
+		}
 		smInterval := cfg.SMPollInterval
 		if smInterval == 0 {
 			smInterval = cfg.PollInterval
 		}
 		smPoller = sm.NewPoller(smClient, store, smInterval, func(update sm.SensorUpdate) {
 			sseHub.BroadcastSensorUpdate(update)
+			values.dispatch(alarm.SourceSM, api.SharedMemoryServerID, update.ObjectName, update.Sensor.Name, update.Sensor.Value, time.Now())
 		})
+		// Входы виртуальных датчиков и датчики тревог из SM опрашиваются постоянно
+		for _, in := range pinned {
+			if in.Source == virtual.SourceSM {
+				smPoller.SubscribeFor(in.Owner, in.Object, in.Sensor)
+			}
+		}
 		handlers.SetSMPoller(smPoller)
 		logger.Info("SM integration enabled", "url", cfg.SMURL, "poll_interval", smInterval)
 	}
@@ -370,3 +698,139 @@ func main() {
 
 	logger.Info("Server stopped")
 }
+
+// notifyAlarm пересылает поднятие и снятие тревоги во внешние каналы
+func notifyAlarm(notifier *notify.Dispatcher, event alarm.Event) {
+	a := event.Alarm
+	n := notify.Event{
+		ServerID:  a.ServerID,
+		Object:    a.Object,
+		Data:      event,
+		Timestamp: event.Timestamp,
+	}
+	switch event.Type {
+	case alarm.EventRaised:
+		n.Type = notify.EventAlarmRaised
+		n.Severity = notify.SeverityWarning
+		if a.Level == alarm.LevelHiHi || a.Level == alarm.LevelLoLo {
+			n.Severity = notify.SeverityCritical
+		}
+		n.Title = fmt.Sprintf("%s: %s %s", a.Rule, a.Sensor, a.Level)
+		n.Message = fmt.Sprintf("value %g", a.Value)
+		if a.Limit != nil {
+			n.Message += fmt.Sprintf(", limit %g", *a.Limit)
+		}
+	case alarm.EventCleared:
+		n.Type = notify.EventAlarmCleared
+		n.Severity = notify.SeverityInfo
+		n.Title = fmt.Sprintf("%s: %s cleared", a.Rule, a.Sensor)
+		n.Message = fmt.Sprintf("value %g", a.Value)
+	default:
+		return
+	}
+	if a.Message != "" {
+		n.Message = a.Message + " (" + n.Message + ")"
+	}
+	notifier.Notify(n)
+}
+
+// valueDispatcher передаёт новые значения источников движкам тревог, виртуальных датчиков
+// и watchdog устаревания (выключенные движки - nil)
+type valueDispatcher struct {
+	alarms   *alarm.Engine
+	virtual  *virtual.Engine
+	watchdog *watchdog.Watchdog
+}
+
+// dispatch проверяет значение правилами тревог и пересчитывает зависящие от него виртуальные датчики
+// (источники тревог и виртуальных датчиков совпадают)
+func (d *valueDispatcher) dispatch(source, serverID, objectName, name string, value interface{}, now time.Time) {
+	if d.alarms != nil {
+		d.alarms.Update(source, serverID, objectName, name, value, now)
+	}
+	if d.virtual != nil {
+		d.virtual.Update(source, serverID, objectName, name, value, now)
+	}
+}
+
+// observe отмечает в watchdog время последнего изменения значения в источнике
+func (d *valueDispatcher) observe(source, serverID, objectName, name string, id int64, sourceTime, now time.Time) {
+	if d.watchdog != nil {
+		d.watchdog.Observe(source, serverID, objectName, name, id, sourceTime, now)
+	}
+}
+
+// pinnedInput значение, которое опрашивается независимо от подписок клиентов
+type pinnedInput struct {
+	Owner  string // владелец подписки в опросчиках
+	Source string // источники виртуальных датчиков и тревог совпадают
+	Server string // пусто = любой сервер
+	Object string
+	Sensor string
+	ID     int64 // 0 = по имени из --uniset-config
+}
+
+// pinnedInputs собирает входы виртуальных датчиков и датчики правил тревог без шаблонов
+func pinnedInputs(virtualEngine *virtual.Engine, alarmEngine *alarm.Engine) []pinnedInput {
+	var inputs []pinnedInput
+	if virtualEngine != nil {
+		for _, in := range virtualEngine.Inputs() {
+			inputs = append(inputs, pinnedInput{Owner: virtual.Owner, Source: in.Source, Server: in.Server,
+				Object: in.Object, Sensor: in.Sensor, ID: in.ID})
+		}
+	}
+	if alarmEngine != nil {
+		for _, in := range alarmEngine.Inputs() {
+			inputs = append(inputs, pinnedInput{Owner: alarm.Owner, Source: in.Source, Server: in.Server,
+				Object: in.Object, Sensor: in.Sensor})
+		}
+	}
+	return inputs
+}
+
+// subscribePinnedInputs подписывает опросчики сервера на постоянно опрашиваемые значения.
+// ID датчиков IONC/Modbus/OPCUA берутся из конфигурации входа или по имени из --uniset-config.
+// Значения SM подписываются при создании SM poller.
+func subscribePinnedInputs(inputs []pinnedInput, serverMgr *server.Manager, instance *server.Instance, sensorCfg *sensorconfig.SensorConfig) {
+	serverID := instance.Config.ID
+	for _, in := range inputs {
+		if in.Source == virtual.SourceSM || (in.Server != "" && in.Server != serverID) {
+			continue
+		}
+
+		id := in.ID
+		if id == 0 && sensorCfg != nil {
+			if sensor := sensorCfg.GetByName(in.Sensor); sensor != nil {
+				id = sensor.ID
+			}
+		}
+		needsID := in.Source == virtual.SourceIONC || in.Source == virtual.SourceModbus || in.Source == virtual.SourceOPCUA
+		if needsID && id == 0 {
+			logger.Warn("Pinned input has no ID, it is evaluated only while subscribed by clients",
+				"owner", in.Owner, "server", serverID, "source", in.Source, "object", in.Object, "sensor", in.Sensor)
+			continue
+		}
+
+		switch in.Source {
+		case virtual.SourceObject:
+			instance.Poller.Pin(in.Object)
+		case virtual.SourceIONC:
+			instance.IONCPoller.SubscribeFor(in.Owner, in.Object, []int64{id})
+		case virtual.SourceModbus:
+			instance.ModbusPoller.SubscribeFor(in.Owner, in.Object, []int64{id})
+		case virtual.SourceOPCUA:
+			instance.OPCUAPoller.SubscribeWithTypeFor(in.Owner, in.Object, []int64{id}, "")
+		case virtual.SourceUWSGate:
+			// UWebSocketGate poller создаётся лениво
+			uwsPoller := serverMgr.GetUWSGatePoller(serverID)
+			if uwsPoller == nil {
+				logger.Warn("UWebSocketGate not available for pinned input", "owner", in.Owner, "server", serverID, "sensor", in.Sensor)
+				continue
+			}
+			if err := uwsPoller.SubscribeFor(in.Owner, in.Object, []string{in.Sensor}); err != nil {
+				logger.Warn("Failed to subscribe pinned input", "owner", in.Owner, "server", serverID,
+					"sensor", in.Sensor, "error", err)
+			}
+		}
+	}
+}
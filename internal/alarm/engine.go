@@ -0,0 +1,455 @@
+package alarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/logger"
+	"github.com/pv/uniset-panel/internal/storage"
+)
+
+// Типы событий тревог
+const (
+	EventRaised       = "alarm_raised"       // тревога поднята или сменила уровень
+	EventCleared      = "alarm_cleared"      // значение вернулось в норму
+	EventAcknowledged = "alarm_acknowledged" // оператор квитировал тревогу
+	EventShelved      = "alarm_shelved"      // тревога отложена на время
+	EventUnshelved    = "alarm_unshelved"    // откладывание снято или истекло
+)
+
+// IsEventType проверяет, является ли тип SSE события событием тревоги
+func IsEventType(eventType string) bool {
+	switch eventType {
+	case EventRaised, EventCleared, EventAcknowledged, EventShelved, EventUnshelved:
+		return true
+	}
+	return false
+}
+
+// defaultTickInterval период проверки задержек, когда значения не меняются
+const defaultTickInterval = 500 * time.Millisecond
+
+// MaxShelveDuration максимальный срок откладывания тревоги
+const MaxShelveDuration = 24 * time.Hour
+
+// Ошибки квитирования и откладывания
+var (
+	ErrNotFound    = errors.New("alarm not found")
+	ErrNotShelved  = errors.New("alarm is not shelved")
+	ErrBadDuration = errors.New("shelve duration must be positive and not exceed 24h")
+)
+
+// Alarm состояние тревоги по одному значению, подошедшему под правило
+type Alarm struct {
+	ID        string    `json:"id"` // правило/источник/сервер/объект/датчик
+	Rule      string    `json:"rule"`
+	Source    string    `json:"source"`
+	ServerID  string    `json:"serverId"`
+	Object    string    `json:"object"`
+	Sensor    string    `json:"sensor"`
+	Level     Level     `json:"level"`
+	Limit     *float64  `json:"limit,omitempty"` // порог текущего уровня
+	Value     float64   `json:"value"`           // последнее значение
+	Message   string    `json:"message,omitempty"`
+	RaisedAt  time.Time `json:"raisedAt"`  // когда поднята (первый уровень)
+	ChangedAt time.Time `json:"changedAt"` // когда сменился уровень
+
+	// Квитирование сбрасывается при смене уровня
+	Acked   bool       `json:"acked"`
+	AckedBy string     `json:"ackedBy,omitempty"`
+	AckedAt *time.Time `json:"ackedAt,omitempty"`
+
+	// ShelvedUntil до какого момента тревога отложена (события не рассылаются)
+	ShelvedUntil *time.Time `json:"shelvedUntil,omitempty"`
+}
+
+// Event событие изменения состояния тревоги
+type Event struct {
+	Type      string    `json:"type"` // EventRaised или EventCleared
+	Alarm     Alarm     `json:"alarm"`
+	PrevLevel Level     `json:"prevLevel"`
+	User      string    `json:"user,omitempty"`    // для квитирования и откладывания
+	Comment   string    `json:"comment,omitempty"` // для квитирования и откладывания
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventCallback вызывается при поднятии и снятии тревог (вне блокировки состояния движка,
+// по одному событию за раз в порядке изменений; из callback нельзя менять тревоги движка)
+type EventCallback func(event Event)
+
+// state состояние правила для одного значения
+type state struct {
+	rule       *Rule
+	alarm      Alarm
+	level      Level // действующий уровень
+	pending    Level // уровень, ожидающий задержки
+	since      time.Time
+	hasPending bool
+}
+
+// stateKey значение, по которому ведётся состояние правила
+type stateKey struct {
+	rule     string
+	source   string
+	serverID string
+	object   string
+	sensor   string
+}
+
+// id идентификатор тревоги для API
+func (k stateKey) id() string {
+	return strings.Join([]string{k.rule, k.source, k.serverID, k.object, k.sensor}, "/")
+}
+
+// shelf отложенная тревога. Хранится по ID тревоги, поэтому переживает
+// снятие и повторное поднятие тревоги до истечения срока.
+type shelf struct {
+	until time.Time
+	alarm Alarm // состояние на момент откладывания (для события снятия)
+}
+
+// Engine вычисляет пороговые правила над потоком значений и хранит активные тревоги
+type Engine struct {
+	rules    []*Rule
+	callback EventCallback
+
+	mu      sync.Mutex
+	states  map[stateKey]*state
+	shelved map[string]*shelf
+
+	// emitMu упорядочивает рассылку: берётся до снятия mu, поэтому события
+	// разных опросчиков доходят до callback в порядке изменения состояния
+	emitMu sync.Mutex
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewEngine проверяет правила и создаёт движок тревог
+func NewEngine(rules []Rule, callback EventCallback) (*Engine, error) {
+	names := make(map[string]bool, len(rules))
+	validated := make([]*Rule, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("alarm rule %d: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("alarm rule %d: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		validated = append(validated, &rule)
+	}
+
+	return &Engine{
+		rules:    validated,
+		callback: callback,
+		states:   make(map[stateKey]*state),
+		shelved:  make(map[string]*shelf),
+	}, nil
+}
+
+// Rules возвращает правила движка
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// Inputs возвращает значения правил, которые нужно опрашивать постоянно. Правила
+// с шаблонами объекта или датчика, без источника и с source: virtual сюда не входят:
+// они проверяются по значениям, которые опрашиваются по подпискам клиентов
+// (виртуальные датчики вычисляются всегда).
+func (e *Engine) Inputs() []Input {
+	seen := make(map[Input]bool)
+	var result []Input
+	for _, rule := range e.rules {
+		in, ok := rule.input()
+		if ok && !seen[in] {
+			seen[in] = true
+			result = append(result, in)
+		}
+	}
+	return result
+}
+
+// Start запускает периодическую проверку задержек (on-delay/off-delay срабатывают и без новых значений)
+func (e *Engine) Start() {
+	e.stop = make(chan struct{})
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(defaultTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case now := <-ticker.C:
+				e.Tick(now)
+			}
+		}
+	}()
+	logger.Info("Alarm engine started", "rules", len(e.rules))
+}
+
+// Stop останавливает периодическую проверку
+func (e *Engine) Stop() {
+	if e.stop == nil {
+		return
+	}
+	close(e.stop)
+	e.wg.Wait()
+	e.stop = nil
+}
+
+// Update передаёт движку новое значение. Нечисловые значения игнорируются.
+func (e *Engine) Update(source, serverID, objectName, sensor string, value interface{}, now time.Time) {
+	v, ok := storage.ToFloat64(value)
+	if !ok {
+		return
+	}
+
+	var events []Event
+	e.mu.Lock()
+	for _, rule := range e.rules {
+		if !rule.Match(source, serverID, objectName, sensor) {
+			continue
+		}
+		key := stateKey{rule: rule.Name, source: source, serverID: serverID, object: objectName, sensor: sensor}
+		st := e.states[key]
+		if st == nil {
+			st = &state{
+				rule: rule,
+				alarm: Alarm{
+					ID:       key.id(),
+					Rule:     rule.Name,
+					Source:   source,
+					ServerID: serverID,
+					Object:   objectName,
+					Sensor:   sensor,
+					Message:  rule.Message,
+				},
+			}
+			if sh := e.shelved[st.alarm.ID]; sh != nil {
+				until := sh.until
+				st.alarm.ShelvedUntil = &until
+			}
+			e.states[key] = st
+		}
+		st.alarm.Value = v
+
+		target := rule.level(v, st.level)
+		switch {
+		case target == st.level:
+			st.hasPending = false
+		case !st.hasPending || st.pending != target:
+			st.pending = target
+			st.since = now
+			st.hasPending = true
+		}
+		if ev, ok := st.advance(now); ok && st.alarm.ShelvedUntil == nil {
+			events = append(events, ev)
+		}
+		if st.level == LevelNormal && !st.hasPending {
+			delete(e.states, key)
+		}
+	}
+	e.unlockAndEmit(events)
+}
+
+// Tick применяет переходы, задержка которых истекла к моменту now
+func (e *Engine) Tick(now time.Time) {
+	var events []Event
+	e.mu.Lock()
+	for key, st := range e.states {
+		// Отложенные тревоги меняют уровень без рассылки событий
+		if ev, ok := st.advance(now); ok && st.alarm.ShelvedUntil == nil {
+			events = append(events, ev)
+		}
+		if st.level == LevelNormal && !st.hasPending {
+			delete(e.states, key)
+		}
+	}
+	for id, sh := range e.shelved {
+		if now.Before(sh.until) {
+			continue
+		}
+		events = append(events, e.unshelve(id, sh, "", "", now))
+	}
+	e.unlockAndEmit(events)
+}
+
+// Acknowledge квитирует активную тревогу. Повторное квитирование ничего не делает.
+func (e *Engine) Acknowledge(id, user, comment string, now time.Time) error {
+	e.mu.Lock()
+	st := e.find(id)
+	if st == nil || st.level == LevelNormal {
+		e.mu.Unlock()
+		return ErrNotFound
+	}
+	if st.alarm.Acked {
+		e.mu.Unlock()
+		return nil
+	}
+	at := now
+	st.alarm.Acked = true
+	st.alarm.AckedBy = user
+	st.alarm.AckedAt = &at
+	ev := Event{Type: EventAcknowledged, Alarm: st.alarm, PrevLevel: st.level, User: user, Comment: comment, Timestamp: now}
+	e.unlockAndEmit([]Event{ev})
+	return nil
+}
+
+// Shelve откладывает активную тревогу на duration: до истечения срока смена её
+// уровня не рассылается, в том числе после снятия и повторного поднятия
+func (e *Engine) Shelve(id string, duration time.Duration, user, comment string, now time.Time) error {
+	if duration <= 0 || duration > MaxShelveDuration {
+		return ErrBadDuration
+	}
+
+	e.mu.Lock()
+	st := e.find(id)
+	if st == nil || st.level == LevelNormal {
+		e.mu.Unlock()
+		return ErrNotFound
+	}
+	until := now.Add(duration)
+	st.alarm.ShelvedUntil = &until
+	e.shelved[id] = &shelf{until: until, alarm: st.alarm}
+	ev := Event{Type: EventShelved, Alarm: st.alarm, PrevLevel: st.level, User: user, Comment: comment, Timestamp: now}
+	e.unlockAndEmit([]Event{ev})
+	return nil
+}
+
+// Unshelve досрочно снимает откладывание тревоги
+func (e *Engine) Unshelve(id, user, comment string, now time.Time) error {
+	e.mu.Lock()
+	sh := e.shelved[id]
+	if sh == nil {
+		e.mu.Unlock()
+		return ErrNotShelved
+	}
+	ev := e.unshelve(id, sh, user, comment, now)
+	e.unlockAndEmit([]Event{ev})
+	return nil
+}
+
+// unshelve снимает откладывание и возвращает событие с текущим состоянием тревоги. Вызывается под e.mu.
+func (e *Engine) unshelve(id string, sh *shelf, user, comment string, now time.Time) Event {
+	delete(e.shelved, id)
+
+	alarm := sh.alarm
+	alarm.ShelvedUntil = nil
+	if st := e.find(id); st != nil {
+		st.alarm.ShelvedUntil = nil
+		alarm = st.alarm
+	} else {
+		// Тревога снялась, пока была отложена
+		alarm.Level = LevelNormal
+		alarm.Limit = nil
+	}
+	return Event{Type: EventUnshelved, Alarm: alarm, PrevLevel: alarm.Level, User: user, Comment: comment, Timestamp: now}
+}
+
+// find ищет состояние по ID тревоги. Вызывается под e.mu.
+func (e *Engine) find(id string) *state {
+	for key, st := range e.states {
+		if key.id() == id {
+			return st
+		}
+	}
+	return nil
+}
+
+// advance применяет ожидающий уровень, если его задержка истекла
+func (st *state) advance(now time.Time) (Event, bool) {
+	if !st.hasPending {
+		return Event{}, false
+	}
+	delay := st.rule.OnDelay
+	if st.pending == LevelNormal {
+		delay = st.rule.OffDelay
+	}
+	if now.Sub(st.since) < delay {
+		return Event{}, false
+	}
+
+	prev := st.level
+	st.level = st.pending
+	st.hasPending = false
+
+	st.alarm.Level = st.level
+	st.alarm.Limit = st.rule.limit(st.level)
+	st.alarm.ChangedAt = now
+	st.alarm.Acked = false
+	st.alarm.AckedBy = ""
+	st.alarm.AckedAt = nil
+	if prev == LevelNormal {
+		st.alarm.RaisedAt = now
+	}
+
+	eventType := EventRaised
+	if st.level == LevelNormal {
+		eventType = EventCleared
+	}
+	return Event{Type: eventType, Alarm: st.alarm, PrevLevel: prev, Timestamp: now}, true
+}
+
+// unlockAndEmit снимает e.mu и рассылает события. Следующий вызов не начнёт
+// рассылку, пока не закончится текущая.
+func (e *Engine) unlockAndEmit(events []Event) {
+	if len(events) == 0 {
+		e.mu.Unlock()
+		return
+	}
+	e.emitMu.Lock()
+	defer e.emitMu.Unlock()
+	e.mu.Unlock()
+
+	e.emit(events)
+}
+
+func (e *Engine) emit(events []Event) {
+	for _, ev := range events {
+		logger.Info("Alarm state changed", "type", ev.Type, "rule", ev.Alarm.Rule,
+			"server", ev.Alarm.ServerID, "object", ev.Alarm.Object, "sensor", ev.Alarm.Sensor,
+			"level", ev.Alarm.Level, "prev", ev.PrevLevel, "value", ev.Alarm.Value, "user", ev.User)
+		if e.callback != nil {
+			e.callback(ev)
+		}
+	}
+}
+
+// Active возвращает активные тревоги, включая отложенные (по серверу, объекту, датчику, правилу)
+func (e *Engine) Active() []Alarm {
+	e.mu.Lock()
+	alarms := make([]Alarm, 0, len(e.states))
+	for _, st := range e.states {
+		if st.level != LevelNormal {
+			alarms = append(alarms, st.alarm)
+		}
+	}
+	e.mu.Unlock()
+
+	sort.Slice(alarms, func(i, j int) bool {
+		a, b := alarms[i], alarms[j]
+		if a.ServerID != b.ServerID {
+			return a.ServerID < b.ServerID
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		if a.Sensor != b.Sensor {
+			return a.Sensor < b.Sensor
+		}
+		return a.Rule < b.Rule
+	})
+	return alarms
+}
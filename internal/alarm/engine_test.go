@@ -0,0 +1,352 @@
+package alarm
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func limit(v float64) *float64 { return &v }
+
+// recorder собирает события движка
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) add(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *recorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func newTestEngine(t *testing.T, rules ...Rule) (*Engine, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	engine, err := NewEngine(rules, rec.add)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	return engine, rec
+}
+
+func TestNewEngineValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"no name", Rule{Sensor: "x", Hi: limit(1)}, "name is required"},
+		{"no sensor", Rule{Name: "r", Hi: limit(1)}, "sensor is required"},
+		{"no limits", Rule{Name: "r", Sensor: "x"}, "at least one"},
+		{"bad order", Rule{Name: "r", Sensor: "x", Hi: limit(5), HiHi: limit(3)}, "lolo < lo < hi < hihi"},
+		{"bad source", Rule{Name: "r", Sensor: "x", Hi: limit(1), Source: "foo"}, "unknown source"},
+		{"bad pattern", Rule{Name: "r", Sensor: "[", Hi: limit(1)}, "invalid pattern"},
+		{"negative delay", Rule{Name: "r", Sensor: "x", Hi: limit(1), OnDelay: -time.Second}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine([]Rule{tt.rule}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	_, err := NewEngine([]Rule{
+		{Name: "dup", Sensor: "a", Hi: limit(1)},
+		{Name: "dup", Sensor: "b", Hi: limit(1)},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestEngineLevelsAndHysteresis(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{
+		Name: "pressure", Sensor: "AI_P*",
+		LoLo: limit(1), Lo: limit(2), Hi: limit(8), HiHi: limit(10),
+		Hysteresis: 0.5,
+	})
+	now := time.Now()
+	update := func(v float64) []Event {
+		engine.Update(SourceIONC, "s1", "SM", "AI_P1", v, now)
+		return rec.take()
+	}
+
+	if events := update(5); len(events) != 0 {
+		t.Fatalf("normal value should not raise: %+v", events)
+	}
+
+	events := update(8)
+	if len(events) != 1 || events[0].Type != EventRaised || events[0].Alarm.Level != LevelHi || *events[0].Alarm.Limit != 8 {
+		t.Fatalf("expected hi raised, got %+v", events)
+	}
+
+	// В зоне гистерезиса уровень держится
+	if events := update(7.8); len(events) != 0 {
+		t.Fatalf("value within hysteresis should keep hi: %+v", events)
+	}
+
+	events = update(10.2)
+	if len(events) != 1 || events[0].Alarm.Level != LevelHiHi || events[0].PrevLevel != LevelHi {
+		t.Fatalf("expected escalation to hihi, got %+v", events)
+	}
+
+	// 9.7 > 10-0.5: всё ещё hihi
+	if events := update(9.7); len(events) != 0 {
+		t.Fatalf("value within hihi hysteresis should keep hihi: %+v", events)
+	}
+
+	events = update(7)
+	if len(events) != 1 || events[0].Type != EventCleared || events[0].PrevLevel != LevelHiHi {
+		t.Fatalf("expected cleared, got %+v", events)
+	}
+
+	events = update(0.5)
+	if len(events) != 1 || events[0].Alarm.Level != LevelLoLo {
+		t.Fatalf("expected lolo raised, got %+v", events)
+	}
+	events = update(1.2)
+	if len(events) != 0 {
+		t.Fatalf("value within lolo hysteresis should keep lolo: %+v", events)
+	}
+	events = update(1.6)
+	if len(events) != 1 || events[0].Alarm.Level != LevelLo {
+		t.Fatalf("expected lo after leaving lolo hysteresis, got %+v", events)
+	}
+
+	// Значения других датчиков не подходят под правило
+	engine.Update(SourceIONC, "s1", "SM", "AI_T1", 100, now)
+	if events := rec.take(); len(events) != 0 {
+		t.Errorf("non-matching sensor raised alarm: %+v", events)
+	}
+}
+
+func TestEngineDelays(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{
+		Name: "level", Source: SourceModbus, Sensor: "Level",
+		Hi: limit(80), OnDelay: 5 * time.Second, OffDelay: 2 * time.Second,
+	})
+	start := time.Now()
+
+	engine.Update(SourceModbus, "s1", "MB", "Level", 90, start)
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("alarm raised before on-delay: %+v", events)
+	}
+	if len(engine.Active()) != 0 {
+		t.Fatal("pending alarm must not be active")
+	}
+
+	// Кратковременный выброс: значение вернулось до истечения задержки
+	engine.Update(SourceModbus, "s1", "MB", "Level", 50, start.Add(2*time.Second))
+	engine.Tick(start.Add(6 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("short spike raised alarm: %+v", events)
+	}
+
+	// Устойчивое превышение: тревога поднимается по тику без новых значений
+	engine.Update(SourceModbus, "s1", "MB", "Level", 90, start.Add(10*time.Second))
+	engine.Tick(start.Add(14 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("alarm raised before on-delay: %+v", events)
+	}
+	engine.Tick(start.Add(15 * time.Second))
+	events := rec.take()
+	if len(events) != 1 || events[0].Type != EventRaised {
+		t.Fatalf("expected raise after on-delay, got %+v", events)
+	}
+
+	active := engine.Active()
+	if len(active) != 1 || active[0].Sensor != "Level" || active[0].Level != LevelHi || active[0].Value != 90 {
+		t.Fatalf("unexpected active alarms: %+v", active)
+	}
+
+	// Снятие с задержкой
+	engine.Update(SourceModbus, "s1", "MB", "Level", 10, start.Add(20*time.Second))
+	engine.Tick(start.Add(21 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("alarm cleared before off-delay: %+v", events)
+	}
+	engine.Tick(start.Add(22 * time.Second))
+	events = rec.take()
+	if len(events) != 1 || events[0].Type != EventCleared {
+		t.Fatalf("expected clear after off-delay, got %+v", events)
+	}
+	if len(engine.Active()) != 0 {
+		t.Error("cleared alarm should not be active")
+	}
+
+	// Другой источник не подходит под правило
+	engine.Update(SourceIONC, "s1", "MB", "Level", 90, start.Add(30*time.Second))
+	engine.Tick(start.Add(40 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Errorf("rule with source=modbus matched ionc value: %+v", events)
+	}
+}
+
+func TestEngineValueConversion(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Name: "flag", Source: SourceObject, Sensor: "fault", Hi: limit(1)})
+	now := time.Now()
+
+	engine.Update(SourceObject, "s1", "Proc", "fault", "not a number", now)
+	engine.Update(SourceObject, "s1", "Proc", "fault", true, now)
+	events := rec.take()
+	if len(events) != 1 || events[0].Alarm.Value != 1 {
+		t.Fatalf("expected bool true to raise alarm, got %+v", events)
+	}
+
+	engine.Update(SourceObject, "s1", "Proc", "fault", "0", now)
+	if events := rec.take(); len(events) != 1 || events[0].Type != EventCleared {
+		t.Fatalf("expected string \"0\" to clear alarm, got %+v", events)
+	}
+}
+
+func TestEngineAcknowledge(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Name: "temp", Sensor: "T1", Hi: limit(80), HiHi: limit(90)})
+	now := time.Now()
+
+	engine.Update(SourceIONC, "s1", "SM", "T1", 85, now)
+	id := rec.take()[0].Alarm.ID
+
+	if err := engine.Acknowledge("unknown", "op", "", now); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := engine.Acknowledge(id, "op", "checked", now); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	events := rec.take()
+	if len(events) != 1 || events[0].Type != EventAcknowledged || events[0].User != "op" || events[0].Comment != "checked" {
+		t.Fatalf("expected acknowledged event, got %+v", events)
+	}
+	if a := engine.Active()[0]; !a.Acked || a.AckedBy != "op" || a.AckedAt == nil {
+		t.Fatalf("active alarm not acknowledged: %+v", a)
+	}
+
+	// Повторное квитирование не порождает событие
+	engine.Acknowledge(id, "op", "", now)
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("repeated ack emitted events: %+v", events)
+	}
+
+	// Смена уровня требует нового квитирования
+	engine.Update(SourceIONC, "s1", "SM", "T1", 95, now)
+	if events := rec.take(); len(events) != 1 || events[0].Alarm.Acked {
+		t.Fatalf("escalation must reset acknowledgement: %+v", events)
+	}
+}
+
+func TestEngineShelve(t *testing.T) {
+	engine, rec := newTestEngine(t, Rule{Name: "temp", Sensor: "T1", Hi: limit(80)})
+	start := time.Now()
+
+	engine.Update(SourceIONC, "s1", "SM", "T1", 85, start)
+	id := rec.take()[0].Alarm.ID
+
+	if err := engine.Shelve(id, 25*time.Hour, "op", "", start); err != ErrBadDuration {
+		t.Fatalf("expected ErrBadDuration, got %v", err)
+	}
+	if err := engine.Shelve(id, time.Minute, "op", "nuisance", start); err != nil {
+		t.Fatalf("Shelve: %v", err)
+	}
+	if events := rec.take(); len(events) != 1 || events[0].Type != EventShelved || events[0].Alarm.ShelvedUntil == nil {
+		t.Fatalf("expected shelved event, got %+v", events)
+	}
+
+	// Пока тревога отложена, снятие и повторное поднятие не рассылаются
+	engine.Update(SourceIONC, "s1", "SM", "T1", 50, start.Add(10*time.Second))
+	engine.Update(SourceIONC, "s1", "SM", "T1", 85, start.Add(20*time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("shelved alarm emitted events: %+v", events)
+	}
+	if a := engine.Active(); len(a) != 1 || a[0].ShelvedUntil == nil {
+		t.Fatalf("expected shelved active alarm, got %+v", a)
+	}
+
+	// По истечении срока приходит alarm_unshelved с текущим состоянием
+	engine.Tick(start.Add(time.Minute))
+	events := rec.take()
+	if len(events) != 1 || events[0].Type != EventUnshelved || events[0].Alarm.Level != LevelHi || events[0].Alarm.ShelvedUntil != nil {
+		t.Fatalf("expected unshelved event, got %+v", events)
+	}
+	if err := engine.Unshelve(id, "op", "", start.Add(time.Minute)); err != ErrNotShelved {
+		t.Fatalf("expected ErrNotShelved, got %v", err)
+	}
+
+	engine.Update(SourceIONC, "s1", "SM", "T1", 50, start.Add(2*time.Minute))
+	if events := rec.take(); len(events) != 1 || events[0].Type != EventCleared {
+		t.Fatalf("expected clear after unshelve, got %+v", events)
+	}
+}
+
+func TestEngineEmitsEventsInOrderFromConcurrentUpdates(t *testing.T) {
+	rec := &recorder{}
+	// Медленный callback расширяет окно между снятием блокировки и рассылкой
+	engine, err := NewEngine([]Rule{{Name: "high", Sensor: "Temp", Hi: limit(50)}}, func(ev Event) {
+		time.Sleep(10 * time.Microsecond)
+		rec.add(ev)
+	})
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				v := float64(0)
+				if (i+g)%2 == 0 {
+					v = 100
+				}
+				engine.Update(SourceIONC, "s1", "Obj", "Temp", v, time.Now())
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// Каждое событие продолжает предыдущее: уровень до события равен уровню после предыдущего
+	level := LevelNormal
+	for i, ev := range rec.take() {
+		if ev.PrevLevel != level {
+			t.Fatalf("event %d (%s): prev level %q, expected %q", i, ev.Type, ev.PrevLevel, level)
+		}
+		level = ev.Alarm.Level
+	}
+}
+
+func TestEngineInputs(t *testing.T) {
+	engine, _ := newTestEngine(t,
+		Rule{Name: "exact", Source: SourceIONC, Object: "SharedMemory", Sensor: "Temp_AS", Hi: limit(1)},
+		Rule{Name: "exact-dup", Source: SourceIONC, Object: "SharedMemory", Sensor: "Temp_AS", Lo: limit(0)},
+		Rule{Name: "server", Source: SourceModbus, Server: "s1", Object: "MB1", Sensor: "Reg1", Hi: limit(1)},
+		Rule{Name: "glob", Source: SourceIONC, Object: "SharedMemory", Sensor: "Temp*", Hi: limit(1)},
+		Rule{Name: "any-object", Source: SourceIONC, Sensor: "Temp_AS", Hi: limit(1)},
+		Rule{Name: "any-source", Object: "SharedMemory", Sensor: "Temp_AS", Hi: limit(1)},
+		Rule{Name: "virtual", Source: SourceVirtual, Object: "Virtual", Sensor: "Sum", Hi: limit(1)},
+	)
+
+	inputs := engine.Inputs()
+	want := []Input{
+		{Source: SourceIONC, Object: "SharedMemory", Sensor: "Temp_AS"},
+		{Source: SourceModbus, Server: "s1", Object: "MB1", Sensor: "Reg1"},
+	}
+	if len(inputs) != len(want) {
+		t.Fatalf("expected %d inputs, got %+v", len(want), inputs)
+	}
+	for i := range want {
+		if inputs[i] != want[i] {
+			t.Errorf("input %d: got %+v, want %+v", i, inputs[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package alarm
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// DefaultHistoryLimit сколько событий хранится в истории тревог (старые удаляются)
+const DefaultHistoryLimit = 100000
+
+// cleanupEvery через сколько записей проверяется лимит истории
+const cleanupEvery = 1000
+
+// HistoryRecord событие тревоги в истории
+type HistoryRecord struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	AlarmID   string    `json:"alarmId"`
+	Rule      string    `json:"rule"`
+	Source    string    `json:"source"`
+	ServerID  string    `json:"serverId"`
+	Object    string    `json:"object"`
+	Sensor    string    `json:"sensor"`
+	Level     Level     `json:"level"`
+	PrevLevel Level     `json:"prevLevel"`
+	Value     float64   `json:"value"`
+	Limit     *float64  `json:"limit,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// QueryParams параметры запроса истории (как journal.QueryParams)
+type QueryParams struct {
+	From   time.Time // начало периода
+	To     time.Time // конец периода
+	Types  []string  // фильтр по типам событий (alarm_raised, alarm_cleared, ...)
+	Levels []string  // фильтр по уровням
+	Search string    // текстовый поиск (правило, объект, датчик, сообщение, пользователь, комментарий)
+	Limit  int       // лимит записей
+	Offset int       // смещение для пагинации
+}
+
+// HistoryResponse ответ API со страницей истории
+type HistoryResponse struct {
+	Events []HistoryRecord `json:"events"`
+	Total  int             `json:"total"`  // общее количество (для пагинации)
+	Offset int             `json:"offset"` // текущее смещение
+	Limit  int             `json:"limit"`  // лимит
+}
+
+// History хранит события тревог (поднятие, снятие, квитирование, откладывание) в SQLite
+type History struct {
+	mu      sync.Mutex
+	db      *sql.DB
+	limit   int64
+	inserts int
+}
+
+// OpenHistory открывает (создаёт) базу истории тревог. limit <= 0 - DefaultHistoryLimit.
+func OpenHistory(dbPath string, limit int64) (*History, error) {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	db, err := sql.Open("sqlite", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alarm_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			alarm_id TEXT NOT NULL,
+			rule TEXT NOT NULL,
+			source TEXT NOT NULL,
+			server_id TEXT NOT NULL,
+			object_name TEXT NOT NULL,
+			sensor TEXT NOT NULL,
+			level TEXT NOT NULL,
+			prev_level TEXT NOT NULL,
+			value REAL NOT NULL,
+			alarm_limit REAL,
+			message TEXT NOT NULL,
+			user TEXT NOT NULL,
+			comment TEXT NOT NULL,
+			timestamp INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_alarm_history_timestamp
+			ON alarm_history(timestamp);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create tables: %w", err)
+	}
+
+	return &History{db: db, limit: limit}, nil
+}
+
+// Close закрывает базу
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+// Save сохраняет событие тревоги
+func (h *History) Save(event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	a := event.Alarm
+	_, err := h.db.Exec(`
+		INSERT INTO alarm_history (type, alarm_id, rule, source, server_id, object_name, sensor,
+			level, prev_level, value, alarm_limit, message, user, comment, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, event.Type, a.ID, a.Rule, a.Source, a.ServerID, a.Object, a.Sensor,
+		string(a.Level), string(event.PrevLevel), a.Value, a.Limit, a.Message,
+		event.User, event.Comment, event.Timestamp.UnixNano())
+	if err != nil {
+		return fmt.Errorf("save alarm event: %w", err)
+	}
+
+	h.inserts++
+	if h.inserts >= cleanupEvery {
+		h.inserts = 0
+		if _, err := h.db.Exec(`DELETE FROM alarm_history WHERE id <= (SELECT MAX(id) FROM alarm_history) - ?`, h.limit); err != nil {
+			return fmt.Errorf("cleanup alarm history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Query возвращает страницу истории, новые события первыми
+func (h *History) Query(params QueryParams) (*HistoryResponse, error) {
+	if params.Limit <= 0 {
+		params.Limit = 100
+	}
+
+	var conds []string
+	var args []interface{}
+
+	if !params.From.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, params.From.UnixNano())
+	}
+	if !params.To.IsZero() {
+		conds = append(conds, "timestamp <= ?")
+		args = append(args, params.To.UnixNano())
+	}
+	if cond, condArgs := inCondition("type", params.Types); cond != "" {
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	if cond, condArgs := inCondition("level", params.Levels); cond != "" {
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	if params.Search != "" {
+		conds = append(conds, "(rule LIKE ? OR object_name LIKE ? OR sensor LIKE ? OR message LIKE ? OR user LIKE ? OR comment LIKE ?)")
+		pattern := "%" + params.Search + "%"
+		for i := 0; i < 6; i++ {
+			args = append(args, pattern)
+		}
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	resp := &HistoryResponse{Events: []HistoryRecord{}, Offset: params.Offset, Limit: params.Limit}
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM alarm_history"+where, args...).Scan(&resp.Total); err != nil {
+		return nil, fmt.Errorf("count alarm history: %w", err)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, type, alarm_id, rule, source, server_id, object_name, sensor,
+			level, prev_level, value, alarm_limit, message, user, comment, timestamp
+		FROM alarm_history`+where+` ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?`,
+		append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("query alarm history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec HistoryRecord
+		var level, prevLevel string
+		var limit sql.NullFloat64
+		var ts int64
+		if err := rows.Scan(&rec.ID, &rec.Type, &rec.AlarmID, &rec.Rule, &rec.Source, &rec.ServerID,
+			&rec.Object, &rec.Sensor, &level, &prevLevel, &rec.Value, &limit, &rec.Message,
+			&rec.User, &rec.Comment, &ts); err != nil {
+			return nil, fmt.Errorf("scan alarm event: %w", err)
+		}
+		rec.Level = Level(level)
+		rec.PrevLevel = Level(prevLevel)
+		if limit.Valid {
+			rec.Limit = &limit.Float64
+		}
+		rec.Timestamp = time.Unix(0, ts)
+		resp.Events = append(resp.Events, rec)
+	}
+	return resp, rows.Err()
+}
+
+// inCondition строит условие "column IN (?, ...)" для непустого списка значений
+func inCondition(column string, values []string) (string, []interface{}) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
@@ -0,0 +1,58 @@
+package alarm
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistorySaveAndQuery(t *testing.T) {
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "alarms.db"), 0)
+	if err != nil {
+		t.Fatalf("OpenHistory: %v", err)
+	}
+	defer history.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alarm := Alarm{ID: "temp/ionc/s1/SM/T1", Rule: "temp", Source: SourceIONC, ServerID: "s1", Object: "SM", Sensor: "T1",
+		Level: LevelHi, Limit: limit(80), Value: 85, Message: "Перегрев"}
+	events := []Event{
+		{Type: EventRaised, Alarm: alarm, Timestamp: start},
+		{Type: EventAcknowledged, Alarm: alarm, PrevLevel: LevelHi, User: "op", Comment: "checked", Timestamp: start.Add(time.Minute)},
+		{Type: EventCleared, Alarm: Alarm{ID: alarm.ID, Rule: "temp", Sensor: "T1", Value: 50}, PrevLevel: LevelHi, Timestamp: start.Add(2 * time.Minute)},
+	}
+	for _, ev := range events {
+		if err := history.Save(ev); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	resp, err := history.Query(QueryParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if resp.Total != 3 || len(resp.Events) != 2 || resp.Events[0].Type != EventCleared {
+		t.Fatalf("expected newest-first page of 2 out of 3, got %+v", resp)
+	}
+
+	resp, err = history.Query(QueryParams{Types: []string{EventAcknowledged}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if resp.Total != 1 {
+		t.Fatalf("expected one ack event, got %+v", resp)
+	}
+	rec := resp.Events[0]
+	if rec.User != "op" || rec.Comment != "checked" || rec.Level != LevelHi || rec.Limit == nil || *rec.Limit != 80 ||
+		!rec.Timestamp.Equal(start.Add(time.Minute)) {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+
+	resp, err = history.Query(QueryParams{From: start.Add(30 * time.Second), Search: "Перегрев"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if resp.Total != 1 || resp.Events[0].Type != EventAcknowledged {
+		t.Errorf("expected search+from to match ack only, got %+v", resp)
+	}
+}
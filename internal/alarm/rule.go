@@ -0,0 +1,162 @@
+package alarm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/glob"
+)
+
+// Источники значений, по которым вычисляются правила
+const (
+	SourceObject  = "object"  // переменные объекта (Variables)
+	SourceIONC    = "ionc"    // датчики IONotifyController
+	SourceModbus  = "modbus"  // регистры ModbusMaster/ModbusSlave
+	SourceOPCUA   = "opcua"   // датчики OPCUAExchange/OPCUAServer
+	SourceUWSGate = "uwsgate" // датчики UWebSocketGate
+	SourceSM      = "sm"      // внешние датчики SharedMemory
+	SourceVirtual = "virtual" // виртуальные (вычисляемые) датчики
+)
+
+// Owner владелец подписок на датчики правил в опросчиках (не освобождается при отключении SSE клиентов)
+const Owner = "alarm"
+
+// Level уровень тревоги. Пустой уровень - норма.
+type Level string
+
+const (
+	LevelNormal Level = ""
+	LevelLoLo   Level = "lolo"
+	LevelLo     Level = "lo"
+	LevelHi     Level = "hi"
+	LevelHiHi   Level = "hihi"
+)
+
+// Rule пороговое правило для значений, подходящих под glob-шаблоны.
+// Шаблоны поддерживают *, ? и [...]; пустой шаблон равен "*".
+type Rule struct {
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"` // источник значения (пусто = любой)
+	Server  string `json:"server"`
+	Object  string `json:"object"`
+	Sensor  string `json:"sensor"` // имя датчика/регистра/переменной
+	Message string `json:"message,omitempty"`
+
+	// Пороги (nil = не задан). Должно выполняться lolo < lo < hi < hihi.
+	HiHi *float64 `json:"hihi,omitempty"`
+	Hi   *float64 `json:"hi,omitempty"`
+	Lo   *float64 `json:"lo,omitempty"`
+	LoLo *float64 `json:"lolo,omitempty"`
+
+	// Hysteresis зона возврата: уровень снимается, когда значение отойдёт от порога на эту величину
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	// OnDelay сколько уровень должен держаться, прежде чем тревога будет поднята
+	OnDelay time.Duration `json:"-"`
+	// OffDelay сколько значение должно быть в норме, прежде чем тревога будет снята
+	OffDelay time.Duration `json:"-"`
+}
+
+// validate проверяет правило и подставляет шаблоны по умолчанию
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Sensor == "" {
+		return fmt.Errorf("sensor is required")
+	}
+	if r.HiHi == nil && r.Hi == nil && r.Lo == nil && r.LoLo == nil {
+		return fmt.Errorf("at least one of hihi, hi, lo, lolo is required")
+	}
+	if r.Hysteresis < 0 || r.OnDelay < 0 || r.OffDelay < 0 {
+		return fmt.Errorf("hysteresis and delays must not be negative")
+	}
+	switch r.Source {
+	case "", SourceObject, SourceIONC, SourceModbus, SourceOPCUA, SourceUWSGate, SourceSM, SourceVirtual:
+	default:
+		return fmt.Errorf("unknown source %q", r.Source)
+	}
+
+	// Пороги по возрастанию: lolo < lo < hi < hihi
+	var prev *float64
+	for _, limit := range []*float64{r.LoLo, r.Lo, r.Hi, r.HiHi} {
+		if limit == nil {
+			continue
+		}
+		if prev != nil && *limit <= *prev {
+			return fmt.Errorf("limits must satisfy lolo < lo < hi < hihi")
+		}
+		prev = limit
+	}
+
+	if r.Server == "" {
+		r.Server = "*"
+	}
+	if r.Object == "" {
+		r.Object = "*"
+	}
+	return glob.Validate(r.Server, r.Object, r.Sensor)
+}
+
+// Match проверяет, подходит ли значение под правило
+func (r *Rule) Match(source, serverID, objectName, sensor string) bool {
+	return (r.Source == "" || r.Source == source) &&
+		glob.Match(r.Server, serverID) &&
+		glob.Match(r.Object, objectName) &&
+		glob.Match(r.Sensor, sensor)
+}
+
+// Input значение, которое правило проверяет независимо от подписок клиентов
+type Input struct {
+	Source string
+	Server string // пусто = любой сервер
+	Object string
+	Sensor string
+}
+
+// input возвращает значение правила, если источник, объект и датчик заданы точно
+// (без шаблонов), а сервер - точно или "*"
+func (r *Rule) input() (Input, bool) {
+	if r.Source == "" || r.Source == SourceVirtual ||
+		!glob.Literal(r.Object) || !glob.Literal(r.Sensor) {
+		return Input{}, false
+	}
+	in := Input{Source: r.Source, Object: r.Object, Sensor: r.Sensor}
+	if r.Server != "*" {
+		if !glob.Literal(r.Server) {
+			return Input{}, false
+		}
+		in.Server = r.Server
+	}
+	return in, true
+}
+
+// level вычисляет уровень для значения с учётом гистерезиса относительно текущего уровня
+func (r *Rule) level(value float64, current Level) Level {
+	h := r.Hysteresis
+	switch {
+	case r.HiHi != nil && (value >= *r.HiHi || current == LevelHiHi && value > *r.HiHi-h):
+		return LevelHiHi
+	case r.Hi != nil && (value >= *r.Hi || (current == LevelHi || current == LevelHiHi) && value > *r.Hi-h):
+		return LevelHi
+	case r.LoLo != nil && (value <= *r.LoLo || current == LevelLoLo && value < *r.LoLo+h):
+		return LevelLoLo
+	case r.Lo != nil && (value <= *r.Lo || (current == LevelLo || current == LevelLoLo) && value < *r.Lo+h):
+		return LevelLo
+	}
+	return LevelNormal
+}
+
+// limit возвращает порог уровня
+func (r *Rule) limit(level Level) *float64 {
+	switch level {
+	case LevelHiHi:
+		return r.HiHi
+	case LevelHi:
+		return r.Hi
+	case LevelLo:
+		return r.Lo
+	case LevelLoLo:
+		return r.LoLo
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/pv/uniset-panel/internal/notify"
 )
 
 func TestControlManager_IsEnabled(t *testing.T) {
@@ -341,6 +344,49 @@ func TestHandler_TakeControl(t *testing.T) {
 	}
 }
 
+// controlNotifier запоминает уведомления о захвате управления
+type controlNotifier struct {
+	events chan notify.Event
+}
+
+func (n *controlNotifier) Name() string { return "test" }
+
+func (n *controlNotifier) Notify(ctx context.Context, event notify.Event) error {
+	n.events <- event
+	return nil
+}
+
+func TestHandler_TakeControlNotifies(t *testing.T) {
+	handlers, controlMgr := setupControlTestHandlers([]string{"admin123"})
+	defer controlMgr.Stop()
+
+	capture := &controlNotifier{events: make(chan notify.Event, 10)}
+	dispatcher, err := notify.NewDispatcher([]notify.Route{{Notifier: capture}})
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	dispatcher.Start()
+	handlers.SetNotifier(dispatcher)
+
+	// Повторный захват той же сессией не уведомляется
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/control/take", bytes.NewBufferString(`{"token": "admin123"}`))
+		w := httptest.NewRecorder()
+		handlers.TakeControl(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	dispatcher.Stop()
+
+	if len(capture.events) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(capture.events))
+	}
+	if ev := <-capture.events; ev.Type != notify.EventControlTaken {
+		t.Errorf("expected %s, got %s", notify.EventControlTaken, ev.Type)
+	}
+}
+
 func TestHandler_ReleaseControl(t *testing.T) {
 	handlers, controlMgr := setupControlTestHandlers([]string{"admin123"})
 	defer controlMgr.Stop()
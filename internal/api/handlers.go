@@ -6,12 +6,14 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/pv/uniset-panel/internal/alarm"
 	"github.com/pv/uniset-panel/internal/config"
 	"github.com/pv/uniset-panel/internal/dashboard"
 	"github.com/pv/uniset-panel/internal/ionc"
 	"github.com/pv/uniset-panel/internal/journal"
 	"github.com/pv/uniset-panel/internal/logserver"
 	"github.com/pv/uniset-panel/internal/modbus"
+	"github.com/pv/uniset-panel/internal/notify"
 	"github.com/pv/uniset-panel/internal/opcua"
 	"github.com/pv/uniset-panel/internal/poller"
 	"github.com/pv/uniset-panel/internal/recording"
@@ -21,6 +23,8 @@ import (
 	"github.com/pv/uniset-panel/internal/storage"
 	"github.com/pv/uniset-panel/internal/uniset"
 	"github.com/pv/uniset-panel/internal/uwsgate"
+	"github.com/pv/uniset-panel/internal/virtual"
+	"github.com/pv/uniset-panel/internal/watchdog"
 )
 
 type Handlers struct {
@@ -45,6 +49,12 @@ type Handlers struct {
 	uwsgatePoller   *uwsgate.Poller      // поллер UWebSocketGate
 	dashboardMgr    *dashboard.Manager   // менеджер серверных dashboard'ов
 	journalMgr      *journal.Manager     // менеджер журналов сообщений
+	retention       *storage.RetentionPolicy // правила хранения истории
+	alarmEngine     *alarm.Engine            // движок пороговых тревог
+	alarmHistory    *alarm.History           // история тревог
+	staleWatchdog   *watchdog.Watchdog       // watchdog устаревших значений
+	notifier        *notify.Dispatcher       // уведомления во внешние каналы
+	virtualEngine   *virtual.Engine          // виртуальные датчики
 }
 
 func NewHandlers(client *uniset.Client, store storage.Storage, p *poller.Poller, sensorCfg *sensorconfig.SensorConfig, pollInterval time.Duration) *Handlers {
@@ -98,6 +108,36 @@ func (h *Handlers) SetJournalManager(mgr *journal.Manager) {
 	h.journalMgr = mgr
 }
 
+// SetRetentionPolicy устанавливает правила хранения истории (для отображения в API)
+func (h *Handlers) SetRetentionPolicy(policy *storage.RetentionPolicy) {
+	h.retention = policy
+}
+
+// SetAlarmEngine устанавливает движок пороговых тревог
+func (h *Handlers) SetAlarmEngine(engine *alarm.Engine) {
+	h.alarmEngine = engine
+}
+
+// SetAlarmHistory устанавливает историю тревог
+func (h *Handlers) SetAlarmHistory(history *alarm.History) {
+	h.alarmHistory = history
+}
+
+// SetStaleWatchdog устанавливает watchdog устаревших значений
+func (h *Handlers) SetStaleWatchdog(w *watchdog.Watchdog) {
+	h.staleWatchdog = w
+}
+
+// SetNotifier устанавливает диспетчер уведомлений
+func (h *Handlers) SetNotifier(d *notify.Dispatcher) {
+	h.notifier = d
+}
+
+// SetVirtualEngine устанавливает движок виртуальных датчиков
+func (h *Handlers) SetVirtualEngine(e *virtual.Engine) {
+	h.virtualEngine = e
+}
+
 // SetServerManager устанавливает менеджер серверов
 func (h *Handlers) SetServerManager(mgr *server.Manager) {
 	h.serverManager = mgr
@@ -210,7 +250,7 @@ func (h *Handlers) GetConfig(w http.ResponseWriter, r *http.Request) {
 // GetObjects возвращает список доступных объектов
 // GET /api/objects
 func (h *Handlers) GetObjects(w http.ResponseWriter, r *http.Request) {
-	list, err := h.client.GetObjectList()
+	list, err := h.client.GetObjectListContext(r.Context())
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -240,10 +280,10 @@ func (h *Handlers) GetObjectData(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, http.StatusBadRequest, "server parameter is required")
 			return
 		}
-		data, err = h.serverManager.GetObjectData(serverID, name)
+		data, err = h.serverManager.GetObjectDataContext(r.Context(), serverID, name)
 	} else if h.client != nil {
 		// Fallback на старый клиент (для совместимости)
-		data, err = h.client.GetObjectData(name)
+		data, err = h.client.GetObjectDataContext(r.Context(), name)
 	} else {
 		h.writeError(w, http.StatusServiceUnavailable, "no client configured")
 		return
@@ -355,7 +395,8 @@ func (h *Handlers) GetVariableHistory(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetVariableHistoryRange возвращает историю переменной за период
-// GET /api/objects/{name}/variables/{variable}/history/range?from=...&to=...
+// GET /api/objects/{name}/variables/{variable}/history/range?from=...&to=...&step=10s&maxPoints=500
+// Если указан step или maxPoints, возвращаются агрегированные точки (min/max/avg/first/last)
 func (h *Handlers) GetVariableHistoryRange(w http.ResponseWriter, r *http.Request) {
 	objectName := r.PathValue("name")
 	variableName := r.PathValue("variable")
@@ -383,15 +424,67 @@ func (h *Handlers) GetVariableHistoryRange(w http.ResponseWriter, r *http.Reques
 	// serverID из query параметра, пустая строка = DefaultServerID
 	serverID := r.URL.Query().Get("server")
 
+	if bucket := historyBucket(r, from, to); bucket > 0 {
+		aggregated, err := h.storage.GetAggregated(serverID, objectName, variableName, from, to, bucket)
+		if err != nil {
+			h.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.writeJSON(w, aggregated)
+		return
+	}
+
 	history, err := h.storage.GetHistory(serverID, objectName, variableName, from, to)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if err := storage.DetectGaps(h.storage, history, from, to); err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	h.writeJSON(w, history)
 }
 
+// historyBucket определяет длину интервала агрегации из параметров step/maxPoints.
+// step имеет приоритет; 0 означает, что агрегация не запрошена.
+func historyBucket(r *http.Request, from, to time.Time) time.Duration {
+	var step time.Duration
+	if stepStr := r.URL.Query().Get("step"); stepStr != "" {
+		if d, err := time.ParseDuration(stepStr); err == nil {
+			step = d
+		}
+	}
+
+	var maxPoints int
+	if maxStr := r.URL.Query().Get("maxPoints"); maxStr != "" {
+		if n, err := strconv.Atoi(maxStr); err == nil {
+			maxPoints = n
+		}
+	}
+
+	return aggregationBucket(step, maxPoints, from, to)
+}
+
+// aggregationBucket вычисляет длину интервала: step, либо диапазон, делённый на maxPoints
+func aggregationBucket(step time.Duration, maxPoints int, from, to time.Time) time.Duration {
+	if step > 0 {
+		return step
+	}
+
+	if maxPoints > 0 && to.After(from) {
+		span := to.Sub(from)
+		bucket := span / time.Duration(maxPoints)
+		if span%time.Duration(maxPoints) != 0 {
+			bucket++
+		}
+		return bucket
+	}
+
+	return 0
+}
+
 // GetSensors возвращает список всех датчиков из конфигурации
 // GET /api/sensors
 func (h *Handlers) GetSensors(w http.ResponseWriter, r *http.Request) {
@@ -435,7 +528,7 @@ func (h *Handlers) GetSensorByName(w http.ResponseWriter, r *http.Request) {
 // GetSMSensors возвращает список датчиков из SharedMemory
 // GET /api/sm/sensors
 func (h *Handlers) GetSMSensors(w http.ResponseWriter, r *http.Request) {
-	result, err := h.client.GetSMSensors()
+	result, err := h.client.GetSMSensorsContext(r.Context())
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -485,7 +578,7 @@ func (h *Handlers) SubscribeExternalSensors(w http.ResponseWriter, r *http.Reque
 	}
 
 	for _, sensor := range req.Sensors {
-		h.smPoller.Subscribe(name, sensor)
+		h.smPoller.SubscribeFor(sseClientID(r), name, sensor)
 	}
 
 	h.writeJSON(w, map[string]interface{}{
@@ -511,7 +604,7 @@ func (h *Handlers) UnsubscribeExternalSensor(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.smPoller.Unsubscribe(name, sensor)
+	h.smPoller.UnsubscribeFor(sseClientID(r), name, sensor)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status": "unsubscribed",
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/alarm"
+	"github.com/pv/uniset-panel/internal/logger"
+)
+
+// ============================================================================
+// Alarm API Handlers
+// ============================================================================
+
+// GetActiveAlarms возвращает активные тревоги
+// GET /api/alarms/active
+func (h *Handlers) GetActiveAlarms(w http.ResponseWriter, r *http.Request) {
+	if h.alarmEngine == nil {
+		h.writeJSON(w, map[string]interface{}{
+			"enabled": false,
+			"alarms":  []alarm.Alarm{},
+		})
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"enabled": true,
+		"alarms":  h.alarmEngine.Active(),
+	})
+}
+
+// alarmActionRequest тело запросов квитирования и откладывания
+type alarmActionRequest struct {
+	ID       string `json:"id"`
+	User     string `json:"user"`
+	Comment  string `json:"comment"`
+	Duration string `json:"duration"` // для shelve: "30m", "2h"
+}
+
+// decodeAlarmAction проверяет доступ и разбирает тело запроса действия над тревогой
+func (h *Handlers) decodeAlarmAction(w http.ResponseWriter, r *http.Request) (alarmActionRequest, bool) {
+	var req alarmActionRequest
+	if !h.checkControlAccess(w, r) {
+		return req, false
+	}
+	if h.alarmEngine == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "alarms not configured")
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	if req.ID == "" {
+		h.writeError(w, http.StatusBadRequest, "id is required")
+		return req, false
+	}
+	return req, true
+}
+
+// writeAlarmActionResult отправляет результат действия над тревогой
+func (h *Handlers) writeAlarmActionResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		h.writeJSON(w, map[string]string{"status": "ok"})
+	case errors.Is(err, alarm.ErrNotFound), errors.Is(err, alarm.ErrNotShelved):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	default:
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// AckAlarm квитирует активную тревогу
+// POST /api/alarms/ack {"id": "...", "user": "...", "comment": "..."}
+func (h *Handlers) AckAlarm(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeAlarmAction(w, r)
+	if !ok {
+		return
+	}
+	h.writeAlarmActionResult(w, h.alarmEngine.Acknowledge(req.ID, req.User, req.Comment, time.Now()))
+}
+
+// ShelveAlarm откладывает активную тревогу на время
+// POST /api/alarms/shelve {"id": "...", "duration": "2h", "user": "...", "comment": "..."}
+func (h *Handlers) ShelveAlarm(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeAlarmAction(w, r)
+	if !ok {
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid duration")
+		return
+	}
+	h.writeAlarmActionResult(w, h.alarmEngine.Shelve(req.ID, duration, req.User, req.Comment, time.Now()))
+}
+
+// UnshelveAlarm досрочно снимает откладывание тревоги
+// POST /api/alarms/unshelve {"id": "...", "user": "...", "comment": "..."}
+func (h *Handlers) UnshelveAlarm(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeAlarmAction(w, r)
+	if !ok {
+		return
+	}
+	h.writeAlarmActionResult(w, h.alarmEngine.Unshelve(req.ID, req.User, req.Comment, time.Now()))
+}
+
+// GetAlarmHistory возвращает страницу истории тревог
+// GET /api/alarms/history?from=...&to=...&type=...&level=...&search=...&limit=100&offset=0
+func (h *Handlers) GetAlarmHistory(w http.ResponseWriter, r *http.Request) {
+	if h.alarmHistory == nil {
+		http.Error(w, "alarm history not configured", http.StatusNotFound)
+		return
+	}
+
+	// Параметры как у GET /api/journals/{id}/messages
+	params := alarm.QueryParams{}
+	query := r.URL.Query()
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		if t, err := parseTime(fromStr); err == nil {
+			params.From = t
+		}
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		if t, err := parseTime(toStr); err == nil {
+			params.To = t
+		}
+	}
+
+	// type/level - фильтры (comma-separated)
+	if typeStr := query.Get("type"); typeStr != "" {
+		params.Types = strings.Split(typeStr, ",")
+	}
+	if levelStr := query.Get("level"); levelStr != "" {
+		params.Levels = strings.Split(levelStr, ",")
+	}
+
+	params.Search = query.Get("search")
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			params.Limit = l
+		}
+	}
+	if params.Limit == 0 {
+		params.Limit = 100 // default
+	}
+	if params.Limit > 1000 {
+		params.Limit = 1000 // max
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			params.Offset = o
+		}
+	}
+
+	resp, err := h.alarmHistory.Query(params)
+	if err != nil {
+		logger.Error("failed to query alarm history", "error", err)
+		http.Error(w, "query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, resp)
+}
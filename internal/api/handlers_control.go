@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/pv/uniset-panel/internal/notify"
 )
 
 // === Control Types ===
@@ -52,6 +54,7 @@ func (h *Handlers) TakeControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wasController := h.controlMgr.IsController(req.Token)
 	err := h.controlMgr.TakeControl(req.Token)
 	if err != nil {
 		switch err {
@@ -65,6 +68,16 @@ func (h *Handlers) TakeControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Повторный захват той же сессией (переподключение) не уведомляется
+	if h.notifier != nil && !wasController {
+		h.notifier.Notify(notify.Event{
+			Type:     notify.EventControlTaken,
+			Severity: notify.SeverityInfo,
+			Title:    "Control taken",
+			Message:  "Control session taken from " + r.RemoteAddr,
+		})
+	}
+
 	status := h.controlMgr.GetStatus(req.Token)
 	h.writeJSON(w, status)
 }
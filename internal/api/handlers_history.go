@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/storage"
+)
+
+// GetHistorySeries возвращает список рядов, сохранённых в хранилище истории.
+// Параметры server, object, variable - glob-шаблоны (пусто = любые).
+// GET /api/history/series?server=X&object=Y&variable=Z
+func (h *Handlers) GetHistorySeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.SeriesFilter{
+		ServerID:     query.Get("server"),
+		ObjectName:   query.Get("object"),
+		VariableName: query.Get("variable"),
+	}
+
+	series, err := h.storage.ListSeries(filter)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"series": series,
+		"count":  len(series),
+	})
+}
+
+// maxHistoryQuerySeries ограничивает количество рядов в одном запросе
+const maxHistoryQuerySeries = 200
+
+// HistoryQueryRequest запрос истории нескольких рядов
+type HistoryQueryRequest struct {
+	Series    []storage.SeriesKey `json:"series"`
+	From      string              `json:"from,omitempty"`      // RFC3339 (default: час назад)
+	To        string              `json:"to,omitempty"`        // RFC3339 (default: сейчас)
+	Step      string              `json:"step,omitempty"`      // длина интервала агрегации ("10s")
+	MaxPoints int                 `json:"maxPoints,omitempty"` // либо желаемое число интервалов
+}
+
+// QueryHistory возвращает историю нескольких рядов за общий период одним ответом.
+// С step/maxPoints ряды агрегируются по общим интервалам, отсчитываемым от from.
+// POST /api/history/query
+func (h *Handlers) QueryHistory(w http.ResponseWriter, r *http.Request) {
+	var req HistoryQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if len(req.Series) == 0 {
+		h.writeError(w, http.StatusBadRequest, "series list is required")
+		return
+	}
+	if len(req.Series) > maxHistoryQuerySeries {
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("too many series (max %d)", maxHistoryQuerySeries))
+		return
+	}
+	for i, key := range req.Series {
+		if key.ObjectName == "" || key.VariableName == "" {
+			h.writeError(w, http.StatusBadRequest, fmt.Sprintf("series %d: object and variable required", i))
+			return
+		}
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Hour)
+	if req.From != "" {
+		t, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+			return
+		}
+		from = t
+	}
+	if req.To != "" {
+		t, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+			return
+		}
+		to = t
+	}
+
+	var step time.Duration
+	if req.Step != "" {
+		d, err := time.ParseDuration(req.Step)
+		if err != nil || d <= 0 {
+			h.writeError(w, http.StatusBadRequest, "invalid step")
+			return
+		}
+		step = d
+	}
+
+	if bucket := aggregationBucket(step, req.MaxPoints, from, to); bucket > 0 {
+		series, err := h.storage.GetAggregatedMulti(req.Series, from, to, bucket)
+		if err != nil {
+			h.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.writeJSON(w, map[string]interface{}{
+			"from":     from,
+			"to":       to,
+			"bucketMs": bucket.Milliseconds(),
+			"series":   series,
+		})
+		return
+	}
+
+	series, err := h.storage.GetHistoryMulti(req.Series, from, to)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, history := range series {
+		if err := storage.DetectGaps(h.storage, history, from, to); err != nil {
+			h.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"series": series,
+	})
+}
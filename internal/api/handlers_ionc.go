@@ -28,6 +28,7 @@ type IONCUnfreezeRequest struct {
 // IONCSubscribeRequest запрос на подписку датчиков для SSE обновлений
 type IONCSubscribeRequest struct {
 	SensorIDs []int64 `json:"sensor_ids"`
+	Rate      string  `json:"rate,omitempty"` // fast, normal, slow или интервал ("250ms")
 }
 
 // === IONC Handlers ===
@@ -49,7 +50,7 @@ func (h *Handlers) GetIONCSensors(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetIONCSensors(name, offset, limit, search, iotype)
+	result, err := client.GetIONCSensorsContext(r.Context(), name, offset, limit, search, iotype)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -77,7 +78,7 @@ func (h *Handlers) GetIONCSensorValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetIONCSensorValues(name, filter)
+	result, err := client.GetIONCSensorValuesContext(r.Context(), name, filter)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -108,7 +109,7 @@ func (h *Handlers) SetIONCSensorValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := client.SetIONCSensorValue(name, req.SensorID, req.Value); err != nil {
+	if err := client.SetIONCSensorValueContext(r.Context(), name, req.SensorID, req.Value); err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
 	}
@@ -142,7 +143,7 @@ func (h *Handlers) FreezeIONCSensor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := client.FreezeIONCSensor(name, req.SensorID, req.Value); err != nil {
+	if err := client.FreezeIONCSensorContext(r.Context(), name, req.SensorID, req.Value); err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
 	}
@@ -176,7 +177,7 @@ func (h *Handlers) UnfreezeIONCSensor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := client.UnfreezeIONCSensor(name, req.SensorID); err != nil {
+	if err := client.UnfreezeIONCSensorContext(r.Context(), name, req.SensorID); err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
 	}
@@ -206,7 +207,7 @@ func (h *Handlers) GetIONCConsumers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetIONCConsumers(name, sensors)
+	result, err := client.GetIONCConsumersContext(r.Context(), name, sensors)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -227,7 +228,7 @@ func (h *Handlers) GetIONCLostConsumers(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result, err := client.GetIONCLostConsumers(name)
+	result, err := client.GetIONCLostConsumersContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -256,17 +257,24 @@ func (h *Handlers) SubscribeIONCSensors(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	rate, ok := h.parseRate(w, req.Rate)
+	if !ok {
+		return
+	}
+
 	ioncPoller, ok := h.requireIONCPoller(w, r)
 	if !ok {
 		return
 	}
 
-	ioncPoller.Subscribe(name, req.SensorIDs)
+	ioncPoller.SubscribeFor(sseClientID(r), name, req.SensorIDs, rate)
+	h.trackSubscription(r, EventIONCSensorBatch, name, req.SensorIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status":     "subscribed",
 		"object":     name,
 		"sensor_ids": req.SensorIDs,
+		"rate":       rate.String(),
 	})
 }
 
@@ -289,11 +297,12 @@ func (h *Handlers) UnsubscribeIONCSensors(w http.ResponseWriter, r *http.Request
 	}
 
 	if len(req.SensorIDs) == 0 {
-		// Если не указаны конкретные датчики — отписываем все
-		ioncPoller.UnsubscribeAll(name)
+		// Если не указаны конкретные датчики — снимаем все подписки клиента на объект
+		ioncPoller.UnsubscribeAllFor(sseClientID(r), name)
 	} else {
-		ioncPoller.Unsubscribe(name, req.SensorIDs)
+		ioncPoller.UnsubscribeFor(sseClientID(r), name, req.SensorIDs)
 	}
+	h.untrackSubscription(r, EventIONCSensorBatch, name, req.SensorIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status": "unsubscribed",
@@ -330,7 +339,7 @@ func (h *Handlers) GetIONCSubscriptions(w http.ResponseWriter, r *http.Request)
 }
 
 // SubscribeIONCSensorsQuery подписывает на SSE обновления из query string
-// GET /api/objects/{name}/ionc/subscribe?sensors=id1,id2,id3
+// GET /api/objects/{name}/ionc/subscribe?sensors=id1,id2,id3&rate=fast&client=id
 func (h *Handlers) SubscribeIONCSensorsQuery(w http.ResponseWriter, r *http.Request) {
 	name, ok := h.requireObjectName(w, r)
 	if !ok {
@@ -362,11 +371,18 @@ func (h *Handlers) SubscribeIONCSensorsQuery(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	ioncPoller.Subscribe(name, sensorIDs)
+	rate, ok := h.parseRate(w, r.URL.Query().Get("rate"))
+	if !ok {
+		return
+	}
+
+	ioncPoller.SubscribeFor(sseClientID(r), name, sensorIDs, rate)
+	h.trackSubscription(r, EventIONCSensorBatch, name, sensorIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status":     "subscribed",
 		"object":     name,
 		"sensor_ids": sensorIDs,
+		"rate":       rate.String(),
 	})
 }
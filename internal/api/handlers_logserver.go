@@ -72,7 +72,7 @@ func (h *Handlers) HandleLogServerStream(w http.ResponseWriter, r *http.Request)
 			h.writeError(w, http.StatusBadRequest, "server parameter is required")
 			return
 		}
-		objData, err := h.serverManager.GetObjectData(serverID, name)
+		objData, err := h.serverManager.GetObjectDataContext(r.Context(), serverID, name)
 		if err != nil {
 			h.writeError(w, http.StatusBadGateway, err.Error())
 			return
@@ -84,7 +84,7 @@ func (h *Handlers) HandleLogServerStream(w http.ResponseWriter, r *http.Request)
 		host = objData.LogServer.Host
 		port = objData.LogServer.Port
 	} else if h.client != nil {
-		objData, err := h.client.GetObjectData(name)
+		objData, err := h.client.GetObjectDataContext(r.Context(), name)
 		if err != nil {
 			h.writeError(w, http.StatusBadGateway, err.Error())
 			return
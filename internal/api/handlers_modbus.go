@@ -9,6 +9,7 @@ import (
 // ModbusSubscribeRequest структура запроса на подписку Modbus регистров
 type ModbusSubscribeRequest struct {
 	RegisterIDs []int64 `json:"register_ids"`
+	Rate        string  `json:"rate,omitempty"` // fast, normal, slow или интервал ("250ms")
 }
 
 // MBModeSetRequest запрос на установку режима
@@ -36,17 +37,24 @@ func (h *Handlers) SubscribeModbusRegisters(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	rate, ok := h.parseRate(w, req.Rate)
+	if !ok {
+		return
+	}
+
 	mbPoller, ok := h.requireModbusPoller(w, r)
 	if !ok {
 		return
 	}
 
-	mbPoller.Subscribe(name, req.RegisterIDs)
+	mbPoller.SubscribeFor(sseClientID(r), name, req.RegisterIDs, rate)
+	h.trackSubscription(r, EventModbusRegisterBatch, name, req.RegisterIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status":       "subscribed",
 		"object":       name,
 		"register_ids": req.RegisterIDs,
+		"rate":         rate.String(),
 	})
 }
 
@@ -69,11 +77,12 @@ func (h *Handlers) UnsubscribeModbusRegisters(w http.ResponseWriter, r *http.Req
 	}
 
 	if len(req.RegisterIDs) == 0 {
-		// Если не указаны конкретные регистры — отписываем все
-		mbPoller.UnsubscribeAll(name)
+		// Если не указаны конкретные регистры — снимаем все подписки клиента на объект
+		mbPoller.UnsubscribeAllFor(sseClientID(r), name)
 	} else {
-		mbPoller.Unsubscribe(name, req.RegisterIDs)
+		mbPoller.UnsubscribeFor(sseClientID(r), name, req.RegisterIDs)
 	}
+	h.untrackSubscription(r, EventModbusRegisterBatch, name, req.RegisterIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status": "unsubscribed",
@@ -123,7 +132,7 @@ func (h *Handlers) GetMBStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBStatus(name)
+	result, err := client.GetMBStatusContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -151,7 +160,7 @@ func (h *Handlers) GetMBParams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBParams(name, params)
+	result, err := client.GetMBParamsContext(r.Context(), name, params)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -194,7 +203,7 @@ func (h *Handlers) SetMBParams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.SetMBParams(name, params)
+	result, err := client.SetMBParamsContext(r.Context(), name, params)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -220,7 +229,7 @@ func (h *Handlers) GetMBRegisters(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBRegisters(name, search, iotype, limit, offset)
+	result, err := client.GetMBRegistersContext(r.Context(), name, search, iotype, limit, offset)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -248,7 +257,7 @@ func (h *Handlers) GetMBRegisterValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBRegisterValues(name, filter)
+	result, err := client.GetMBRegisterValuesContext(r.Context(), name, filter)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -269,7 +278,7 @@ func (h *Handlers) GetMBDevices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBDevices(name)
+	result, err := client.GetMBDevicesContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -290,7 +299,7 @@ func (h *Handlers) GetMBMode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBMode(name)
+	result, err := client.GetMBModeContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -311,7 +320,7 @@ func (h *Handlers) GetMBModeSupported(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetMBModeSupported(name)
+	result, err := client.GetMBModeSupportedContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -347,7 +356,7 @@ func (h *Handlers) SetMBMode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.SetMBMode(name, req.Mode)
+	result, err := client.SetMBModeContext(r.Context(), name, req.Mode)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -372,7 +381,7 @@ func (h *Handlers) TakeMBControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.TakeMBControl(name)
+	result, err := client.TakeMBControlContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -397,7 +406,7 @@ func (h *Handlers) ReleaseMBControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.ReleaseMBControl(name)
+	result, err := client.ReleaseMBControlContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -11,6 +11,7 @@ import (
 type OPCUASubscribeRequest struct {
 	SensorIDs     []int64 `json:"sensor_ids"`
 	ExtensionType string  `json:"extension_type,omitempty"` // "OPCUAExchange" или "OPCUAServer"
+	Rate          string  `json:"rate,omitempty"`           // fast, normal, slow или интервал ("250ms")
 }
 
 // === OPCUA SSE Subscriptions ===
@@ -33,18 +34,25 @@ func (h *Handlers) SubscribeOPCUASensors(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	rate, ok := h.parseRate(w, req.Rate)
+	if !ok {
+		return
+	}
+
 	opPoller, ok := h.requireOPCUAPoller(w, r)
 	if !ok {
 		return
 	}
 
-	opPoller.SubscribeWithType(name, req.SensorIDs, req.ExtensionType)
+	opPoller.SubscribeWithTypeFor(sseClientID(r), name, req.SensorIDs, req.ExtensionType, rate)
+	h.trackSubscription(r, EventOPCUASensorBatch, name, req.SensorIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status":         "subscribed",
 		"object":         name,
 		"sensor_ids":     req.SensorIDs,
 		"extension_type": req.ExtensionType,
+		"rate":           rate.String(),
 	})
 }
 
@@ -67,11 +75,12 @@ func (h *Handlers) UnsubscribeOPCUASensors(w http.ResponseWriter, r *http.Reques
 	}
 
 	if len(req.SensorIDs) == 0 {
-		// Если не указаны конкретные датчики — отписываем все
-		opPoller.UnsubscribeAll(name)
+		// Если не указаны конкретные датчики — снимаем все подписки клиента на объект
+		opPoller.UnsubscribeAllFor(sseClientID(r), name)
 	} else {
-		opPoller.Unsubscribe(name, req.SensorIDs)
+		opPoller.UnsubscribeFor(sseClientID(r), name, req.SensorIDs)
 	}
+	h.untrackSubscription(r, EventOPCUASensorBatch, name, req.SensorIDs)
 
 	h.writeJSON(w, map[string]interface{}{
 		"status": "unsubscribed",
@@ -121,7 +130,7 @@ func (h *Handlers) GetOPCUAStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetOPCUAStatus(name)
+	result, err := client.GetOPCUAStatusContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -149,7 +158,7 @@ func (h *Handlers) GetOPCUAParams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetOPCUAParams(name, params)
+	result, err := client.GetOPCUAParamsContext(r.Context(), name, params)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -192,7 +201,7 @@ func (h *Handlers) SetOPCUAParams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.SetOPCUAParams(name, params)
+	result, err := client.SetOPCUAParamsContext(r.Context(), name, params)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -218,7 +227,7 @@ func (h *Handlers) GetOPCUASensors(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetOPCUASensors(name, search, iotype, limit, offset)
+	result, err := client.GetOPCUASensorsContext(r.Context(), name, search, iotype, limit, offset)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -252,7 +261,7 @@ func (h *Handlers) GetOPCUASensor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetOPCUASensor(name, id)
+	result, err := client.GetOPCUASensorContext(r.Context(), name, id)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -280,7 +289,7 @@ func (h *Handlers) GetOPCUASensorValues(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result, err := client.GetOPCUASensorValues(name, filter)
+	result, err := client.GetOPCUASensorValuesContext(r.Context(), name, filter)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -301,7 +310,7 @@ func (h *Handlers) GetOPCUADiagnostics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.GetOPCUADiagnostics(name)
+	result, err := client.GetOPCUADiagnosticsContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -326,7 +335,7 @@ func (h *Handlers) TakeOPCUAControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.TakeOPCUAControl(name)
+	result, err := client.TakeOPCUAControlContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -351,7 +360,7 @@ func (h *Handlers) ReleaseOPCUAControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := client.ReleaseOPCUAControl(name)
+	result, err := client.ReleaseOPCUAControlContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
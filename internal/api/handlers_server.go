@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/pv/uniset-panel/internal/config"
+	"github.com/pv/uniset-panel/internal/poller"
+	"github.com/pv/uniset-panel/internal/server"
 )
 
 // ================== Server Management API ==================
@@ -133,6 +135,29 @@ func (h *Handlers) GetServerStatus(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, instance.GetStatus())
 }
 
+// GetServerPollStats возвращает статистику опроса объектов сервера (задержки, ошибки, таймауты)
+// GET /api/servers/{id}/poll-stats
+func (h *Handlers) GetServerPollStats(w http.ResponseWriter, r *http.Request) {
+	if h.serverManager == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "server manager not initialized")
+		return
+	}
+
+	instance, exists := h.serverManager.GetServer(r.PathValue("id"))
+	if !exists {
+		h.writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+
+	stats := instance.Poller.PollStats()
+	stats.RateGroups = map[string][]poller.RateGroup{
+		"ionc":   instance.IONCPoller.RateGroups(),
+		"modbus": instance.ModbusPoller.RateGroups(),
+		"opcua":  instance.OPCUAPoller.RateGroups(),
+	}
+	h.writeJSON(w, stats)
+}
+
 // GetAllObjectsWithServers возвращает объекты со всех серверов, сгруппированные по серверам
 // GET /api/all-objects
 func (h *Handlers) GetAllObjectsWithServers(w http.ResponseWriter, r *http.Request) {
@@ -141,7 +166,7 @@ func (h *Handlers) GetAllObjectsWithServers(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	grouped, err := h.serverManager.GetAllObjectsGrouped()
+	grouped, err := h.serverManager.GetAllObjectsGroupedContext(r.Context())
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -181,33 +206,128 @@ func (h *Handlers) SetPollInterval(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	interval, ok := h.decodePollInterval(w, r)
+	if !ok {
+		return
+	}
+
+	if h.serverManager != nil {
+		h.serverManager.SetPollInterval(interval)
+	}
+
+	h.pollInterval = interval
+
+	h.writeJSON(w, map[string]interface{}{
+		"interval": interval.Milliseconds(),
+		"status":   "ok",
+	})
+}
+
+// decodePollInterval читает интервал опроса из тела запроса {"interval": ms} и проверяет диапазон
+func (h *Handlers) decodePollInterval(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
 	var req struct {
 		Interval int64 `json:"interval"` // миллисекунды
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+		return 0, false
 	}
 
 	// Валидация: минимум 1 секунда, максимум 5 минут
 	if req.Interval < 1000 || req.Interval > 300000 {
 		h.writeError(w, http.StatusBadRequest, "interval must be between 1000ms and 300000ms")
+		return 0, false
+	}
+
+	return time.Duration(req.Interval) * time.Millisecond, true
+}
+
+// writeServerPollSettings отправляет действующие настройки опроса сервера
+func (h *Handlers) writeServerPollSettings(w http.ResponseWriter, instance *server.Instance) {
+	settings := instance.GetPollSettings()
+	h.writeJSON(w, map[string]interface{}{
+		"serverId":        instance.Config.ID,
+		"interval":        settings.Interval.Milliseconds(),
+		"override":        settings.OwnInterval,
+		"healthInterval":  settings.HealthInterval.Milliseconds(),
+		"sensorBatchSize": settings.SensorBatchSize,
+		"supplier":        settings.Supplier,
+	})
+}
+
+// GetServerPollInterval возвращает настройки опроса сервера
+// GET /api/servers/{id}/poll-interval
+func (h *Handlers) GetServerPollInterval(w http.ResponseWriter, r *http.Request) {
+	if h.serverManager == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "server manager not initialized")
 		return
 	}
 
-	interval := time.Duration(req.Interval) * time.Millisecond
+	instance, exists := h.serverManager.GetServer(r.PathValue("id"))
+	if !exists {
+		h.writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
 
-	if h.serverManager != nil {
-		h.serverManager.SetPollInterval(interval)
+	h.writeServerPollSettings(w, instance)
+}
+
+// SetServerPollInterval задаёт интервал опроса одного сервера
+// POST /api/servers/{id}/poll-interval
+func (h *Handlers) SetServerPollInterval(w http.ResponseWriter, r *http.Request) {
+	if !h.checkControlAccess(w, r) {
+		return
 	}
 
-	h.pollInterval = interval
+	if h.serverManager == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "server manager not initialized")
+		return
+	}
 
-	h.writeJSON(w, map[string]interface{}{
-		"interval": interval.Milliseconds(),
-		"status":   "ok",
-	})
+	instance, exists := h.serverManager.GetServer(r.PathValue("id"))
+	if !exists {
+		h.writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+
+	interval, ok := h.decodePollInterval(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serverManager.SetServerPollInterval(instance.Config.ID, interval); err != nil {
+		h.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	h.writeServerPollSettings(w, instance)
+}
+
+// ResetServerPollInterval возвращает серверу глобальный интервал опроса
+// DELETE /api/servers/{id}/poll-interval
+func (h *Handlers) ResetServerPollInterval(w http.ResponseWriter, r *http.Request) {
+	if !h.checkControlAccess(w, r) {
+		return
+	}
+
+	if h.serverManager == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "server manager not initialized")
+		return
+	}
+
+	instance, exists := h.serverManager.GetServer(r.PathValue("id"))
+	if !exists {
+		h.writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+
+	if err := h.serverManager.ResetServerPollInterval(instance.Config.ID); err != nil {
+		h.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	h.writeServerPollSettings(w, instance)
 }
 
 // generateServerID генерирует ID из URL
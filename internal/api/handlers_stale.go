@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pv/uniset-panel/internal/watchdog"
+)
+
+// GetStaleSensors возвращает подписанные датчики, время источника которых не менялось дольше окна
+// GET /api/sensors/stale
+func (h *Handlers) GetStaleSensors(w http.ResponseWriter, r *http.Request) {
+	if h.staleWatchdog == nil {
+		h.writeJSON(w, map[string]interface{}{
+			"enabled": false,
+			"watched": 0,
+			"sensors": []watchdog.SensorState{},
+		})
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"enabled": true,
+		"watched": h.staleWatchdog.Watched(),
+		"sensors": h.staleWatchdog.Stale(),
+	})
+}
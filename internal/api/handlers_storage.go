@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pv/uniset-panel/internal/storage"
+)
+
+// GetStorageStats возвращает статистику хранилища истории
+// GET /api/storage/stats
+func (h *Handlers) GetStorageStats(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.storage.(storage.StatsProvider)
+	if !ok {
+		h.writeError(w, http.StatusNotImplemented, "storage does not provide statistics")
+		return
+	}
+
+	h.writeJSON(w, provider.Stats())
+}
+
+// retentionRuleResponse правило хранения в ответе API
+type retentionRuleResponse struct {
+	Server   string `json:"server"`
+	Object   string `json:"object"`
+	Variable string `json:"variable"`
+	TTLMs    int64  `json:"ttlMs"`
+	TTL      string `json:"ttl"`
+}
+
+// GetStorageRetention возвращает действующие правила хранения истории в порядке применения.
+// С параметрами server/object/variable дополнительно возвращает время хранения этого ряда.
+// GET /api/storage/retention?server=X&object=Y&variable=Z
+func (h *Handlers) GetStorageRetention(w http.ResponseWriter, r *http.Request) {
+	if h.retention == nil {
+		h.writeError(w, http.StatusNotFound, "retention policy not configured")
+		return
+	}
+
+	rules := make([]retentionRuleResponse, 0, len(h.retention.Rules))
+	for _, rule := range h.retention.Rules {
+		rules = append(rules, retentionRuleResponse{
+			Server:   rule.Server,
+			Object:   rule.Object,
+			Variable: rule.Variable,
+			TTLMs:    rule.TTL.Milliseconds(),
+			TTL:      rule.TTL.String(),
+		})
+	}
+
+	// enforced = false, если хранилище умеет удалять только по общему TTL
+	_, enforced := h.storage.(storage.RetentionCleaner)
+
+	response := map[string]interface{}{
+		"defaultTtlMs": h.retention.DefaultTTL.Milliseconds(),
+		"defaultTtl":   h.retention.DefaultTTL.String(),
+		"rules":        rules,
+		"enforced":     enforced,
+	}
+
+	query := r.URL.Query()
+	if query.Get("object") != "" || query.Get("variable") != "" {
+		ttl := h.retention.TTLFor(query.Get("server"), query.Get("object"), query.Get("variable"))
+		response["resolvedTtlMs"] = ttl.Milliseconds()
+		response["resolvedTtl"] = ttl.String()
+	}
+
+	h.writeJSON(w, response)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pv/uniset-panel/internal/logger"
+	"github.com/pv/uniset-panel/internal/poller"
+	"github.com/pv/uniset-panel/internal/sm"
+)
+
+// ServerSubscribers подписки pollers одного сервера с владельцами
+type ServerSubscribers struct {
+	ServerID string              `json:"serverId,omitempty"`
+	IONC     []poller.Subscriber `json:"ionc"`
+	Modbus   []poller.Subscriber `json:"modbus"`
+	OPCUA    []poller.Subscriber `json:"opcua"`
+}
+
+// SubscriptionsResponse диагностика подписок: подключённые SSE клиенты и владельцы каждого элемента
+type SubscriptionsResponse struct {
+	Clients []string            `json:"clients"`
+	Servers []ServerSubscribers `json:"servers"`
+	SM      []sm.Subscriber     `json:"sm,omitempty"`
+}
+
+// ReleaseClientSubscriptions снимает подписки SSE клиента во всех pollers.
+// Вызывается SSEHub после отключения клиента.
+func (h *Handlers) ReleaseClientSubscriptions(clientID string) {
+	released := 0
+
+	if h.serverManager != nil {
+		for _, instance := range h.serverManager.GetAllInstances() {
+			released += instance.IONCPoller.ReleaseOwner(clientID)
+			released += instance.ModbusPoller.ReleaseOwner(clientID)
+			released += instance.OPCUAPoller.ReleaseOwner(clientID)
+			if instance.UWSGatePoller != nil {
+				released += instance.UWSGatePoller.ReleaseOwner(clientID)
+			}
+		}
+	}
+	if h.ioncPoller != nil {
+		released += h.ioncPoller.ReleaseOwner(clientID)
+	}
+	if h.modbusPoller != nil {
+		released += h.modbusPoller.ReleaseOwner(clientID)
+	}
+	if h.opcuaPoller != nil {
+		released += h.opcuaPoller.ReleaseOwner(clientID)
+	}
+	if h.smPoller != nil {
+		released += h.smPoller.ReleaseOwner(clientID)
+	}
+
+	if released > 0 {
+		logger.Info("Released subscriptions of disconnected SSE client", "client", clientID, "count", released)
+	}
+}
+
+// GetSubscriptions возвращает подписчиков каждого элемента
+// GET /api/subscriptions
+func (h *Handlers) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	resp := SubscriptionsResponse{
+		Clients: []string{},
+		Servers: []ServerSubscribers{},
+	}
+
+	if h.sseHub != nil {
+		resp.Clients = h.sseHub.ClientIDs()
+	}
+
+	if h.serverManager != nil {
+		for _, instance := range h.serverManager.GetAllInstances() {
+			resp.Servers = append(resp.Servers, ServerSubscribers{
+				ServerID: instance.Config.ID,
+				IONC:     instance.IONCPoller.Subscribers(),
+				Modbus:   instance.ModbusPoller.Subscribers(),
+				OPCUA:    instance.OPCUAPoller.Subscribers(),
+			})
+		}
+	}
+	if h.ioncPoller != nil || h.modbusPoller != nil || h.opcuaPoller != nil {
+		legacy := ServerSubscribers{
+			IONC:   []poller.Subscriber{},
+			Modbus: []poller.Subscriber{},
+			OPCUA:  []poller.Subscriber{},
+		}
+		if h.ioncPoller != nil {
+			legacy.IONC = h.ioncPoller.Subscribers()
+		}
+		if h.modbusPoller != nil {
+			legacy.Modbus = h.modbusPoller.Subscribers()
+		}
+		if h.opcuaPoller != nil {
+			legacy.OPCUA = h.opcuaPoller.Subscribers()
+		}
+		resp.Servers = append(resp.Servers, legacy)
+	}
+
+	if h.smPoller != nil {
+		resp.SM = h.smPoller.Subscribers()
+	}
+
+	h.writeJSON(w, resp)
+}
+
+// trackSubscription запоминает подписку клиента в SSEHub, чтобы батчи обрезались по его элементам
+func (h *Handlers) trackSubscription(r *http.Request, eventType, objectName string, ids []int64) {
+	if h.sseHub == nil {
+		return
+	}
+	h.sseHub.TrackSubscription(sseClientID(r), eventType, r.URL.Query().Get("server"), objectName, ids)
+}
+
+// untrackSubscription снимает подписку клиента в SSEHub (без ids - все элементы объекта)
+func (h *Handlers) untrackSubscription(r *http.Request, eventType, objectName string, ids []int64) {
+	if h.sseHub == nil {
+		return
+	}
+	h.sseHub.UntrackSubscription(sseClientID(r), eventType, r.URL.Query().Get("server"), objectName, ids)
+}
+
+// trackSubscriptionNames запоминает подписку клиента на датчики UWebSocketGate (по именам)
+func (h *Handlers) trackSubscriptionNames(r *http.Request, objectName string, names []string) {
+	if h.sseHub == nil {
+		return
+	}
+	h.sseHub.TrackSubscriptionNames(sseClientID(r), EventUWSGateSensorBatch, r.URL.Query().Get("server"), objectName, names)
+}
+
+// untrackSubscriptionNames снимает подписку клиента на датчики UWebSocketGate (по именам)
+func (h *Handlers) untrackSubscriptionNames(r *http.Request, objectName string, names []string) {
+	if h.sseHub == nil {
+		return
+	}
+	h.sseHub.UntrackSubscriptionNames(sseClientID(r), EventUWSGateSensorBatch, r.URL.Query().Get("server"), objectName, names)
+}
+
+// GetSSEClients возвращает метрики очередей подключённых SSE клиентов
+// GET /api/sse/clients
+func (h *Handlers) GetSSEClients(w http.ResponseWriter, r *http.Request) {
+	clients := []SSEClientStats{}
+	if h.sseHub != nil {
+		clients = h.sseHub.ClientStats()
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"clients": clients,
+	})
+}
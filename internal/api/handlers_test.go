@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pv/uniset-panel/internal/alarm"
 	"github.com/pv/uniset-panel/internal/config"
 	"github.com/pv/uniset-panel/internal/logserver"
 	"github.com/pv/uniset-panel/internal/poller"
@@ -17,6 +18,8 @@ import (
 	"github.com/pv/uniset-panel/internal/sm"
 	"github.com/pv/uniset-panel/internal/storage"
 	"github.com/pv/uniset-panel/internal/uniset"
+	"github.com/pv/uniset-panel/internal/virtual"
+	"github.com/pv/uniset-panel/internal/watchdog"
 )
 
 func normalizeAPIPath(path string) string {
@@ -655,6 +658,123 @@ func TestGetVariableHistoryRange_WithTimeParams(t *testing.T) {
 	}
 }
 
+func TestGetVariableHistoryRange_WithStep(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store := storage.NewMemoryStorage()
+	p := poller.New(client, store, 5*time.Second, time.Hour)
+	handlers := NewHandlers(client, store, p, nil, 5*time.Second)
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 120; i++ {
+		store.Save("", "TestProc", "var1", i, from.Add(time.Duration(i)*time.Second))
+	}
+
+	to := from.Add(2 * time.Minute)
+	url := "/api/objects/TestProc/variables/var1/history/range?from=" + from.Format(time.RFC3339) +
+		"&to=" + to.Format(time.RFC3339) + "&step=1m"
+	req := httptest.NewRequest("GET", url, nil)
+	req.SetPathValue("name", "TestProc")
+	req.SetPathValue("variable", "var1")
+	w := httptest.NewRecorder()
+
+	handlers.GetVariableHistoryRange(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response storage.AggregatedHistory
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.BucketMs != 60000 {
+		t.Errorf("expected bucketMs=60000, got %d", response.BucketMs)
+	}
+	if len(response.Points) != 2 {
+		t.Fatalf("expected 2 aggregated points, got %d", len(response.Points))
+	}
+	if response.Points[0].Min != 0 || response.Points[0].Max != 59 {
+		t.Errorf("unexpected first bucket: %+v", response.Points[0])
+	}
+}
+
+func TestGetVariableHistoryRange_WithMaxPoints(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store := storage.NewMemoryStorage()
+	p := poller.New(client, store, 5*time.Second, time.Hour)
+	handlers := NewHandlers(client, store, p, nil, 5*time.Second)
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		store.Save("", "TestProc", "var1", i, from.Add(time.Duration(i)*time.Second))
+	}
+
+	to := from.Add(1000 * time.Second)
+	url := "/api/objects/TestProc/variables/var1/history/range?from=" + from.Format(time.RFC3339) +
+		"&to=" + to.Format(time.RFC3339) + "&maxPoints=100"
+	req := httptest.NewRequest("GET", url, nil)
+	req.SetPathValue("name", "TestProc")
+	req.SetPathValue("variable", "var1")
+	w := httptest.NewRecorder()
+
+	handlers.GetVariableHistoryRange(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response storage.AggregatedHistory
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.BucketMs != 10000 {
+		t.Errorf("expected bucketMs=10000, got %d", response.BucketMs)
+	}
+	if len(response.Points) > 100 {
+		t.Errorf("expected at most 100 points, got %d", len(response.Points))
+	}
+}
+
+func TestGetStorageStats(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store, err := storage.NewSQLiteStorage(t.TempDir() + "/history.db")
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+	handlers := NewHandlers(client, store, nil, nil, 5*time.Second)
+
+	store.Save("", "TestProc", "var1", 1, time.Now())
+
+	req := httptest.NewRequest("GET", "/api/storage/stats", nil)
+	w := httptest.NewRecorder()
+
+	handlers.GetStorageStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response storage.Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.Type != "sqlite" || response.Writer == nil {
+		t.Errorf("unexpected stats: %+v", response)
+	}
+}
+
 func TestGetVariableHistoryRange_MissingParams(t *testing.T) {
 	unisetServer := mockUnisetServer()
 	defer unisetServer.Close()
@@ -2297,6 +2417,46 @@ func TestSubscribeIONCSensors_EmptySensorIDs(t *testing.T) {
 	}
 }
 
+func TestSubscribeIONCSensors_WithRate(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlersWithServerManager(map[string]*httptest.Server{
+		"server1": unisetServer,
+	})
+
+	body := `{"sensor_ids": [1, 2], "rate": "fast"}`
+	req := httptest.NewRequest("POST", "/api/objects/SharedMemory/ionc/subscribe?server=server1", strings.NewReader(body))
+	req.SetPathValue("name", "SharedMemory")
+	w := httptest.NewRecorder()
+
+	handlers.SubscribeIONCSensors(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ioncPoller, _ := handlers.serverManager.GetIONCPoller("server1")
+	rates := ioncPoller.GetSubscriptionRates("SharedMemory")
+	if rates[1].Class != poller.RateFast || rates[2].Class != poller.RateFast {
+		t.Errorf("expected fast rate, got %+v", rates)
+	}
+
+	body = `{"sensor_ids": [3], "rate": "often"}`
+	req = httptest.NewRequest("POST", "/api/objects/SharedMemory/ionc/subscribe?server=server1", strings.NewReader(body))
+	req.SetPathValue("name", "SharedMemory")
+	w = httptest.NewRecorder()
+
+	handlers.SubscribeIONCSensors(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid rate, got %d", w.Code)
+	}
+	if ioncPoller.GetSubscriptionRates("SharedMemory")[3] != (poller.Rate{}) {
+		t.Error("sensor must not be subscribed with invalid rate")
+	}
+}
+
 func TestSubscribeIONCSensors_MissingSensorIDs(t *testing.T) {
 	unisetServer := mockUnisetServer()
 	defer unisetServer.Close()
@@ -2666,3 +2826,550 @@ func TestGetUNetSenders_ServerError(t *testing.T) {
 		t.Errorf("expected status 502, got %d", w.Code)
 	}
 }
+
+func TestGetStorageRetention(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store := storage.NewMemoryStorage()
+	handlers := NewHandlers(client, store, nil, nil, 5*time.Second)
+
+	req := httptest.NewRequest("GET", "/api/storage/retention", nil)
+	w := httptest.NewRecorder()
+	handlers.GetStorageRetention(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without policy, got %d", w.Code)
+	}
+
+	policy, err := storage.NewRetentionPolicy(time.Hour, []storage.RetentionRule{
+		{Variable: "io.*", TTL: 7 * 24 * time.Hour},
+		{Object: "Debug*", TTL: 10 * time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+	handlers.SetRetentionPolicy(policy)
+
+	req = httptest.NewRequest("GET", "/api/storage/retention?object=DebugProc&variable=counter", nil)
+	w = httptest.NewRecorder()
+	handlers.GetStorageRetention(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		DefaultTTLMs  int64 `json:"defaultTtlMs"`
+		Enforced      bool  `json:"enforced"`
+		ResolvedTTLMs int64 `json:"resolvedTtlMs"`
+		Rules         []struct {
+			Server   string `json:"server"`
+			Object   string `json:"object"`
+			Variable string `json:"variable"`
+			TTLMs    int64  `json:"ttlMs"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.DefaultTTLMs != time.Hour.Milliseconds() || !response.Enforced {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if len(response.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(response.Rules))
+	}
+	if r := response.Rules[0]; r.Server != "*" || r.Object != "*" || r.Variable != "io.*" {
+		t.Errorf("expected normalized first rule, got %+v", r)
+	}
+	if response.ResolvedTTLMs != (10 * time.Minute).Milliseconds() {
+		t.Errorf("expected resolved TTL 10m, got %dms", response.ResolvedTTLMs)
+	}
+}
+
+func TestGetHistorySeries(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store := storage.NewMemoryStorage()
+	handlers := NewHandlers(client, store, nil, nil, 5*time.Second)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Save("srv1", "TestProc", "var1", 1, base)
+	store.Save("srv1", "TestProc", "var1", 2, base.Add(time.Minute))
+	store.Save("srv1", "TestProc", "io.in.sensor", 1, base)
+	store.Save("srv2", "Other", "var1", 1, base)
+
+	req := httptest.NewRequest("GET", "/api/history/series?server=srv1&variable=var*", nil)
+	w := httptest.NewRecorder()
+	handlers.GetHistorySeries(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Series []storage.SeriesInfo `json:"series"`
+		Count  int                  `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.Count != 1 || len(response.Series) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(response.Series))
+	}
+	info := response.Series[0]
+	if info.ServerID != "srv1" || info.ObjectName != "TestProc" || info.VariableName != "var1" {
+		t.Errorf("unexpected series: %+v", info)
+	}
+	if info.Count != 2 || !info.First.Equal(base) || !info.Last.Equal(base.Add(time.Minute)) {
+		t.Errorf("unexpected series stats: %+v", info)
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	store := storage.NewMemoryStorage()
+	handlers := NewHandlers(client, store, nil, nil, 5*time.Second)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		ts := base.Add(time.Duration(i) * 15 * time.Second)
+		store.Save("srv1", "TestProc", "var1", i, ts)
+		store.Save("srv1", "TestProc", "var2", i*2, ts)
+	}
+
+	body := `{
+		"series": [
+			{"server": "srv1", "object": "TestProc", "variable": "var1"},
+			{"server": "srv1", "object": "TestProc", "variable": "var2"}
+		],
+		"from": "2024-01-01T12:00:00Z",
+		"to": "2024-01-01T12:01:00Z",
+		"step": "30s"
+	}`
+	req := httptest.NewRequest("POST", "/api/history/query", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handlers.QueryHistory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		BucketMs int64                       `json:"bucketMs"`
+		Series   []storage.AggregatedHistory `json:"series"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.BucketMs != 30000 || len(response.Series) != 2 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if response.Series[1].VariableName != "var2" || len(response.Series[1].Points) != 2 {
+		t.Errorf("unexpected second series: %+v", response.Series[1])
+	}
+	if p := response.Series[1].Points[1]; p.Min != 4 || p.Max != 6 {
+		t.Errorf("unexpected bucket: %+v", p)
+	}
+}
+
+func TestQueryHistoryValidation(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	client := uniset.NewClient(unisetServer.URL)
+	handlers := NewHandlers(client, storage.NewMemoryStorage(), nil, nil, 5*time.Second)
+
+	tests := []string{
+		`not json`,
+		`{"series": []}`,
+		`{"series": [{"object": "TestProc"}]}`,
+		`{"series": [{"object": "TestProc", "variable": "v"}], "from": "yesterday"}`,
+		`{"series": [{"object": "TestProc", "variable": "v"}], "step": "-1s"}`,
+	}
+	for _, body := range tests {
+		req := httptest.NewRequest("POST", "/api/history/query", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handlers.QueryHistory(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestGetServerPollStats(t *testing.T) {
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if pathEquals(r, "list") {
+			json.NewEncoder(w).Encode([]string{"TestProc"})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server1.Close()
+
+	handlers := setupTestHandlersWithServerManager(map[string]*httptest.Server{
+		"server1": server1,
+	})
+	handlers.serverManager.SetPollConcurrency(8)
+
+	req := httptest.NewRequest("GET", "/api/servers/server1/poll-stats", nil)
+	req.SetPathValue("id", "server1")
+	w := httptest.NewRecorder()
+
+	handlers.GetServerPollStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var stats poller.PollStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if stats.Concurrency != 8 {
+		t.Errorf("expected concurrency 8, got %d", stats.Concurrency)
+	}
+	if stats.ObjectTimeoutMs != 5000 {
+		t.Errorf("expected object timeout 5000ms, got %d", stats.ObjectTimeoutMs)
+	}
+
+	req = httptest.NewRequest("GET", "/api/servers/unknown/poll-stats", nil)
+	req.SetPathValue("id", "unknown")
+	w = httptest.NewRecorder()
+	handlers.GetServerPollStats(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown server, got %d", w.Code)
+	}
+}
+
+func TestServerPollIntervalHandlers(t *testing.T) {
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if pathEquals(r, "list") {
+			json.NewEncoder(w).Encode([]string{"TestProc"})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server1.Close()
+
+	handlers := setupTestHandlersWithServerManager(map[string]*httptest.Server{
+		"server1": server1,
+	})
+
+	req := httptest.NewRequest("POST", "/api/servers/server1/poll-interval", strings.NewReader(`{"interval": 7000}`))
+	req.SetPathValue("id", "server1")
+	w := httptest.NewRecorder()
+	handlers.SetServerPollInterval(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Interval int64 `json:"interval"`
+		Override bool  `json:"override"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Interval != 7000 || !resp.Override {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	instance, _ := handlers.serverManager.GetServer("server1")
+	if instance.Poller.Interval() != 7*time.Second {
+		t.Errorf("expected poller interval 7s, got %v", instance.Poller.Interval())
+	}
+
+	req = httptest.NewRequest("POST", "/api/servers/server1/poll-interval", strings.NewReader(`{"interval": 10}`))
+	req.SetPathValue("id", "server1")
+	w = httptest.NewRecorder()
+	handlers.SetServerPollInterval(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for too small interval, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/servers/server1/poll-interval", nil)
+	req.SetPathValue("id", "server1")
+	w = httptest.NewRecorder()
+	handlers.ResetServerPollInterval(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/servers/server1/poll-interval", nil)
+	req.SetPathValue("id", "server1")
+	w = httptest.NewRecorder()
+	handlers.GetServerPollInterval(w, req)
+	resp.Interval, resp.Override = 0, true
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Interval != handlers.serverManager.GetPollInterval().Milliseconds() || resp.Override {
+		t.Errorf("expected global interval after reset, got %+v", resp)
+	}
+
+	req = httptest.NewRequest("GET", "/api/servers/unknown/poll-interval", nil)
+	req.SetPathValue("id", "unknown")
+	w = httptest.NewRecorder()
+	handlers.GetServerPollInterval(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown server, got %d", w.Code)
+	}
+}
+
+func TestSubscriptionsOwnedBySSEClients(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlersWithServerManager(map[string]*httptest.Server{
+		"server1": unisetServer,
+	})
+
+	subscribe := func(clientID, body string) {
+		req := httptest.NewRequest("POST", "/api/objects/SharedMemory/ionc/subscribe?server=server1", strings.NewReader(body))
+		req.SetPathValue("name", "SharedMemory")
+		req.Header.Set("X-Client-ID", clientID)
+		w := httptest.NewRecorder()
+		handlers.SubscribeIONCSensors(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("subscribe %s: expected status 200, got %d: %s", clientID, w.Code, w.Body.String())
+		}
+	}
+	subscribe("tab1", `{"sensor_ids": [1, 2]}`)
+	subscribe("tab2", `{"sensor_ids": [2]}`)
+
+	ioncPoller, _ := handlers.serverManager.GetIONCPoller("server1")
+
+	// Закрытие tab1 не должно отписать tab2
+	handlers.ReleaseClientSubscriptions("tab1")
+	if ids := ioncPoller.GetSubscriptions("SharedMemory"); len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("expected only sensor 2 to remain subscribed, got %v", ids)
+	}
+
+	req := httptest.NewRequest("GET", "/api/subscriptions", nil)
+	w := httptest.NewRecorder()
+	handlers.GetSubscriptions(w, req)
+
+	var resp SubscriptionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Servers) != 1 || len(resp.Servers[0].IONC) != 1 {
+		t.Fatalf("unexpected subscriptions response: %+v", resp)
+	}
+	if sub := resp.Servers[0].IONC[0]; sub.ID != 2 || len(sub.Owners) != 1 || sub.Owners[0] != "tab2" {
+		t.Errorf("unexpected subscriber: %+v", sub)
+	}
+
+	handlers.ReleaseClientSubscriptions("tab2")
+	if ids := ioncPoller.GetSubscriptions("SharedMemory"); ids != nil {
+		t.Errorf("expected no subscriptions after all clients released, got %v", ids)
+	}
+}
+
+func TestGetActiveAlarms(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+
+	get := func() map[string]json.RawMessage {
+		req := httptest.NewRequest("GET", "/api/alarms/active", nil)
+		w := httptest.NewRecorder()
+		handlers.GetActiveAlarms(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp
+	}
+
+	// Без движка тревог
+	if resp := get(); string(resp["enabled"]) != "false" || string(resp["alarms"]) != "[]" {
+		t.Errorf("expected disabled empty response, got %v", resp)
+	}
+
+	hi := 80.0
+	engine, err := alarm.NewEngine([]alarm.Rule{{Name: "temp", Sensor: "Temp*", Hi: &hi}}, nil)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	handlers.SetAlarmEngine(engine)
+	engine.Update(alarm.SourceIONC, "server1", "SharedMemory", "Temp1_AS", 95, time.Now())
+
+	resp := get()
+	var alarms []alarm.Alarm
+	if err := json.Unmarshal(resp["alarms"], &alarms); err != nil {
+		t.Fatalf("failed to parse alarms: %v", err)
+	}
+	if string(resp["enabled"]) != "true" || len(alarms) != 1 {
+		t.Fatalf("expected one active alarm, got %v", resp)
+	}
+	if a := alarms[0]; a.Rule != "temp" || a.Level != alarm.LevelHi || a.Value != 95 {
+		t.Errorf("unexpected alarm: %+v", a)
+	}
+}
+
+func TestAlarmAckRequiresControlAndIsRecorded(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+	controlMgr := NewControlManager([]string{"admin"}, time.Minute, nil)
+	handlers.SetControlManager(controlMgr)
+
+	history, err := alarm.OpenHistory(t.TempDir()+"/alarms.db", 0)
+	if err != nil {
+		t.Fatalf("OpenHistory: %v", err)
+	}
+	defer history.Close()
+	handlers.SetAlarmHistory(history)
+
+	hi := 80.0
+	engine, err := alarm.NewEngine([]alarm.Rule{{Name: "temp", Sensor: "T1", Hi: &hi}}, func(ev alarm.Event) {
+		if err := history.Save(ev); err != nil {
+			t.Errorf("Save: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	handlers.SetAlarmEngine(engine)
+	engine.Update(alarm.SourceIONC, "server1", "SharedMemory", "T1", 95, time.Now())
+	id := engine.Active()[0].ID
+
+	ack := func(token string) *httptest.ResponseRecorder {
+		body := `{"id": "` + id + `", "user": "op", "comment": "checked"}`
+		req := httptest.NewRequest("POST", "/api/alarms/ack", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Control-Token", token)
+		}
+		w := httptest.NewRecorder()
+		handlers.AckAlarm(w, req)
+		return w
+	}
+
+	// Без управления квитирование запрещено
+	if w := ack(""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without control, got %d", w.Code)
+	}
+	if err := controlMgr.TakeControl("admin"); err != nil {
+		t.Fatalf("TakeControl: %v", err)
+	}
+	if w := ack("admin"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !engine.Active()[0].Acked {
+		t.Error("alarm not acknowledged")
+	}
+
+	req := httptest.NewRequest("GET", "/api/alarms/history?type=alarm_acknowledged,alarm_raised&limit=1", nil)
+	w := httptest.NewRecorder()
+	handlers.GetAlarmHistory(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp alarm.HistoryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Total != 2 || resp.Limit != 1 || len(resp.Events) != 1 {
+		t.Fatalf("expected first page of 2 events, got %+v", resp)
+	}
+	if ev := resp.Events[0]; ev.Type != alarm.EventAcknowledged || ev.User != "op" || ev.Comment != "checked" {
+		t.Errorf("unexpected history event: %+v", ev)
+	}
+}
+
+func TestGetStaleSensors(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+
+	w, err := watchdog.New([]watchdog.Rule{{Sensor: "AI*", Window: time.Second}}, nil)
+	if err != nil {
+		t.Fatalf("watchdog.New: %v", err)
+	}
+	handlers.SetStaleWatchdog(w)
+
+	start := time.Now()
+	w.Observe(watchdog.SourceIONC, "server1", "SharedMemory", "AI1_AS", 1, time.Unix(1000, 0), start)
+	w.Observe(watchdog.SourceIONC, "server1", "SharedMemory", "AI2_AS", 2, time.Unix(1000, 0), start)
+	w.Observe(watchdog.SourceIONC, "server1", "SharedMemory", "AI2_AS", 2, time.Unix(1001, 0), start.Add(1500*time.Millisecond))
+	w.Tick(start.Add(2 * time.Second))
+
+	req := httptest.NewRequest("GET", "/api/sensors/stale", nil)
+	rec := httptest.NewRecorder()
+	handlers.GetStaleSensors(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Enabled bool                   `json:"enabled"`
+		Watched int                    `json:"watched"`
+		Sensors []watchdog.SensorState `json:"sensors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !resp.Enabled || resp.Watched != 2 || len(resp.Sensors) != 1 || resp.Sensors[0].Sensor != "AI1_AS" {
+		t.Errorf("expected only AI1_AS stale, got %+v", resp)
+	}
+}
+
+func TestGetVirtualSensors(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+
+	engine, err := virtual.New([]virtual.Definition{{
+		Name:       "DeltaP",
+		Expression: "p1 - p2",
+		Inputs: map[string]virtual.Input{
+			"p1": {Source: virtual.SourceIONC, Object: "SharedMemory", Sensor: "AI1_AS"},
+			"p2": {Source: virtual.SourceIONC, Object: "SharedMemory", Sensor: "AI2_AS"},
+		},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("virtual.New: %v", err)
+	}
+	handlers.SetVirtualEngine(engine)
+
+	now := time.Now()
+	engine.Update(virtual.SourceIONC, "server1", "SharedMemory", "AI1_AS", 10, now)
+	engine.Update(virtual.SourceIONC, "server1", "SharedMemory", "AI2_AS", 4, now)
+
+	req := httptest.NewRequest("GET", "/api/sensors/virtual", nil)
+	rec := httptest.NewRecorder()
+	handlers.GetVirtualSensors(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Enabled bool                  `json:"enabled"`
+		Sensors []virtual.SensorState `json:"sensors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !resp.Enabled || len(resp.Sensors) != 1 || resp.Sensors[0].Value == nil || resp.Sensors[0].Value.Value != 6 {
+		t.Errorf("expected DeltaP=6, got %+v", resp)
+	}
+}
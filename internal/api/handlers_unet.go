@@ -23,7 +23,7 @@ func (h *Handlers) GetUNetStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := client.GetUNetStatus(name)
+	resp, err := client.GetUNetStatusContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -47,7 +47,7 @@ func (h *Handlers) GetUNetReceivers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := client.GetUNetReceivers(name)
+	resp, err := client.GetUNetReceiversContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -73,7 +73,7 @@ func (h *Handlers) GetUNetSenders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := client.GetUNetSenders(name)
+	resp, err := client.GetUNetSendersContext(r.Context(), name)
 	if err != nil {
 		h.writeError(w, http.StatusBadGateway, err.Error())
 		return
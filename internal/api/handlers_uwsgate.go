@@ -38,10 +38,11 @@ func (h *Handlers) SubscribeUWSGateSensors(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := poller.Subscribe(objectName, req.Sensors); err != nil {
+	if err := poller.SubscribeFor(sseClientID(r), objectName, req.Sensors); err != nil {
 		http.Error(w, fmt.Sprintf("Subscribe failed: %v", err), http.StatusInternalServerError)
 		return
 	}
+	h.trackSubscriptionNames(r, objectName, req.Sensors)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -76,10 +77,11 @@ func (h *Handlers) UnsubscribeUWSGateSensors(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := poller.Unsubscribe(objectName, req.Sensors); err != nil {
+	if err := poller.UnsubscribeFor(sseClientID(r), objectName, req.Sensors); err != nil {
 		http.Error(w, fmt.Sprintf("Unsubscribe failed: %v", err), http.StatusInternalServerError)
 		return
 	}
+	h.untrackSubscriptionNames(r, objectName, req.Sensors)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
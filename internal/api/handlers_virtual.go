@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pv/uniset-panel/internal/virtual"
+)
+
+// GetVirtualSensors возвращает виртуальные датчики с выражениями и текущими значениями
+// GET /api/sensors/virtual
+func (h *Handlers) GetVirtualSensors(w http.ResponseWriter, r *http.Request) {
+	if h.virtualEngine == nil {
+		h.writeJSON(w, map[string]interface{}{
+			"enabled": false,
+			"sensors": []virtual.SensorState{},
+		})
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"enabled": true,
+		"sensors": h.virtualEngine.Sensors(),
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/pv/uniset-panel/internal/ionc"
 	"github.com/pv/uniset-panel/internal/modbus"
 	"github.com/pv/uniset-panel/internal/opcua"
+	"github.com/pv/uniset-panel/internal/poller"
 	"github.com/pv/uniset-panel/internal/uniset"
 )
 
@@ -44,6 +45,26 @@ func (h *Handlers) decodeJSONBody(w http.ResponseWriter, r *http.Request, target
 	return true
 }
 
+// parseRate parses subscription poll rate (fast/normal/slow or duration).
+// Returns false if rate is invalid (error already written).
+func (h *Handlers) parseRate(w http.ResponseWriter, s string) (poller.Rate, bool) {
+	rate, err := poller.ParseRate(s)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return poller.Rate{}, false
+	}
+	return rate, true
+}
+
+// sseClientID returns SSE client ID owning the request's subscriptions
+// (X-Client-ID header or "client" query parameter). Empty means anonymous owner.
+func sseClientID(r *http.Request) string {
+	if id := r.Header.Get("X-Client-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("client")
+}
+
 // getPagination extracts offset and limit from query parameters with defaults.
 func getPagination(r *http.Request, defaultLimit int) (offset, limit int) {
 	offset = 0
@@ -58,12 +58,29 @@ func (s *Server) setupRoutes(staticFS fs.FS) {
 	s.mux.HandleFunc("GET /api/objects/{name}/variables/{variable}/history", s.handlers.GetVariableHistory)
 	s.mux.HandleFunc("GET /api/objects/{name}/variables/{variable}/history/range", s.handlers.GetVariableHistoryRange)
 
+	// History storage API
+	s.mux.HandleFunc("GET /api/history/series", s.handlers.GetHistorySeries)
+	s.mux.HandleFunc("POST /api/history/query", s.handlers.QueryHistory)
+	s.mux.HandleFunc("GET /api/storage/stats", s.handlers.GetStorageStats)
+	s.mux.HandleFunc("GET /api/storage/retention", s.handlers.GetStorageRetention)
+
 	// SSE endpoint
 	s.mux.HandleFunc("GET /api/events", s.handlers.HandleSSE)
+	s.mux.HandleFunc("GET /api/subscriptions", s.handlers.GetSubscriptions)
+	s.mux.HandleFunc("GET /api/sse/clients", s.handlers.GetSSEClients)
+
+	// Тревоги
+	s.mux.HandleFunc("GET /api/alarms/active", s.handlers.GetActiveAlarms)
+	s.mux.HandleFunc("GET /api/alarms/history", s.handlers.GetAlarmHistory)
+	s.mux.HandleFunc("POST /api/alarms/ack", s.handlers.AckAlarm)
+	s.mux.HandleFunc("POST /api/alarms/shelve", s.handlers.ShelveAlarm)
+	s.mux.HandleFunc("POST /api/alarms/unshelve", s.handlers.UnshelveAlarm)
 
 	// Sensor config API
 	s.mux.HandleFunc("GET /api/sensors", s.handlers.GetSensors)
 	s.mux.HandleFunc("GET /api/sensors/by-name/{name}", s.handlers.GetSensorByName)
+	s.mux.HandleFunc("GET /api/sensors/stale", s.handlers.GetStaleSensors)
+	s.mux.HandleFunc("GET /api/sensors/virtual", s.handlers.GetVirtualSensors)
 
 	// SharedMemory sensors API
 	s.mux.HandleFunc("GET /api/sm/sensors", s.handlers.GetSMSensors)
@@ -144,6 +161,10 @@ func (s *Server) setupRoutes(staticFS fs.FS) {
 	s.mux.HandleFunc("POST /api/servers", s.handlers.AddServer)
 	s.mux.HandleFunc("DELETE /api/servers/{id}", s.handlers.RemoveServer)
 	s.mux.HandleFunc("GET /api/servers/{id}/status", s.handlers.GetServerStatus)
+	s.mux.HandleFunc("GET /api/servers/{id}/poll-stats", s.handlers.GetServerPollStats)
+	s.mux.HandleFunc("GET /api/servers/{id}/poll-interval", s.handlers.GetServerPollInterval)
+	s.mux.HandleFunc("POST /api/servers/{id}/poll-interval", s.handlers.SetServerPollInterval)
+	s.mux.HandleFunc("DELETE /api/servers/{id}/poll-interval", s.handlers.ResetServerPollInterval)
 	s.mux.HandleFunc("GET /api/all-objects", s.handlers.GetAllObjectsWithServers)
 
 	// Settings API
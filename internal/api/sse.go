@@ -1,12 +1,18 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pv/uniset-panel/internal/alarm"
 	"github.com/pv/uniset-panel/internal/ionc"
 	"github.com/pv/uniset-panel/internal/journal"
 	"github.com/pv/uniset-panel/internal/logger"
@@ -15,24 +21,61 @@ import (
 	"github.com/pv/uniset-panel/internal/sm"
 	"github.com/pv/uniset-panel/internal/uniset"
 	"github.com/pv/uniset-panel/internal/uwsgate"
+	"github.com/pv/uniset-panel/internal/virtual"
+	"github.com/pv/uniset-panel/internal/watchdog"
 )
 
+// subscriptionReleaseDelay задержка освобождения подписок отключившегося клиента
+// (даёт время переподключиться с тем же ID)
+const subscriptionReleaseDelay = 3 * time.Second
+
 // SSEHub управляет SSE подключениями клиентов
 type SSEHub struct {
 	mu         sync.RWMutex
 	clients    map[*sseClient]bool
 	controlMgr *ControlManager // для освобождения контроля при отключении
+
+	// releaseSubscriptions освобождает подписки, принадлежащие ID клиента
+	releaseSubscriptions func(clientID string)
+	releaseDelay         time.Duration
+	pendingReleases      map[string]*time.Timer // ID клиента -> таймер отложенного освобождения
+
+	// subscriptions: ID клиента -> набор -> ключи элементов (ID или имя), на которые он подписан (для обрезки батчей)
+	subscriptions map[string]map[subscriptionKey]map[string]struct{}
+
+	// lastEventID ID последнего разосланного события, replay - буфер для Last-Event-ID
+	lastEventID uint64
+	replay      *replayRing
 }
 
 type sseClient struct {
-	objectName   string         // если пусто - получает все события
-	controlToken string         // токен контроля (если клиент контроллер)
+	id           string // владелец подписок на датчики
+	objectName   string // если пусто - получает все события
+	controlToken string // токен контроля (если клиент контроллер)
 	events       chan SSEEvent
 	done         chan struct{}
+
+	// cursor ID события, после которого клиент начинает получать события
+	cursor uint64
+	// lagging клиент воспроизводит пропущенное после переподключения: новые события
+	// не ставятся в очередь, писатель догоняет их из буфера воспроизведения
+	lagging atomic.Bool
+	// backlog накопитель последних значений при переполненной очереди (под h.mu),
+	// congested - признак его наличия для писателя
+	backlog   *clientBacklog
+	congested atomic.Bool
+
+	// Метрики очереди
+	connectedAt time.Time
+	sent        atomic.Uint64
+	coalesced   atomic.Uint64
+	congestions atomic.Uint64
+	resyncs     atomic.Uint64
 }
 
 // SSEEvent представляет событие для отправки клиенту
 type SSEEvent struct {
+	ID         uint64      `json:"-"`    // ID для поля id: (0 - событие только для одного клиента)
 	Type       string      `json:"type"` // "object_data", "object_list", "server_status", "error"
 	ServerID   string      `json:"serverId,omitempty"`
 	ServerName string      `json:"serverName,omitempty"`
@@ -44,10 +87,24 @@ type SSEEvent struct {
 // NewSSEHub создаёт новый SSE hub
 func NewSSEHub() *SSEHub {
 	return &SSEHub{
-		clients: make(map[*sseClient]bool),
+		clients:         make(map[*sseClient]bool),
+		releaseDelay:    subscriptionReleaseDelay,
+		pendingReleases: make(map[string]*time.Timer),
+		subscriptions:   make(map[string]map[subscriptionKey]map[string]struct{}),
+		// ID продолжают рост между перезапусками, чтобы Last-Event-ID прежнего процесса
+		// не совпал с событиями нового
+		lastEventID: uint64(time.Now().UnixMicro()),
+		replay:      newReplayRing(replayBufferSize),
 	}
 }
 
+// SetSubscriptionReleaser устанавливает функцию освобождения подписок отключившегося клиента
+func (h *SSEHub) SetSubscriptionReleaser(fn func(clientID string)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.releaseSubscriptions = fn
+}
+
 // SetControlManager устанавливает менеджер контроля (для освобождения при отключении)
 func (h *SSEHub) SetControlManager(mgr *ControlManager) {
 	h.mu.Lock()
@@ -62,14 +119,46 @@ func (h *SSEHub) AddClient(objectName string) *sseClient {
 
 // AddClientWithToken добавляет нового SSE клиента с токеном контроля
 func (h *SSEHub) AddClientWithToken(objectName, controlToken string) *sseClient {
+	return h.AddClientWithID("", objectName, controlToken)
+}
+
+// AddClientWithID добавляет SSE клиента, переподключающегося с прежним ID.
+// Если ID не задан, некорректен или занят подключённым клиентом - выдаётся новый.
+// Отложенное освобождение подписок прежнего подключения отменяется.
+func (h *SSEHub) AddClientWithID(clientID, objectName, controlToken string) *sseClient {
+	return h.addClient(clientID, objectName, controlToken, nil)
+}
+
+// ResumeClient добавляет SSE клиента, продолжающего поток после события lastEventID
+// (заголовок Last-Event-ID). Пропущенные события воспроизводятся из буфера; если их
+// там уже нет, клиент получит resync_required.
+func (h *SSEHub) ResumeClient(clientID, objectName, controlToken string, lastEventID uint64) *sseClient {
+	return h.addClient(clientID, objectName, controlToken, &lastEventID)
+}
+
+func (h *SSEHub) addClient(clientID, objectName, controlToken string, resumeAfter *uint64) *sseClient {
 	client := &sseClient{
 		objectName:   objectName,
 		controlToken: controlToken,
 		events:       make(chan SSEEvent, 10),
 		done:         make(chan struct{}),
+		connectedAt:  time.Now(),
 	}
 
 	h.mu.Lock()
+	if !validClientID(clientID) || h.hasClientID(clientID) {
+		clientID = newClientID()
+	}
+	client.id = clientID
+	client.cursor = h.lastEventID
+	if resumeAfter != nil {
+		client.cursor = *resumeAfter
+		client.lagging.Store(true)
+	}
+	if timer, ok := h.pendingReleases[clientID]; ok {
+		timer.Stop()
+		delete(h.pendingReleases, clientID)
+	}
 	h.clients[client] = true
 	controlMgr := h.controlMgr
 	h.mu.Unlock()
@@ -79,15 +168,16 @@ func (h *SSEHub) AddClientWithToken(objectName, controlToken string) *sseClient
 		controlMgr.CancelPendingRelease(controlToken)
 	}
 
-	logger.Debug("SSE client connected", "object", objectName, "hasToken", controlToken != "", "total_clients", len(h.clients))
+	logger.Debug("SSE client connected", "id", client.id, "object", objectName, "hasToken", controlToken != "", "total_clients", len(h.clients))
 	return client
 }
 
-// RemoveClient удаляет SSE клиента
+// RemoveClient удаляет SSE клиента и (с задержкой) освобождает его подписки
 func (h *SSEHub) RemoveClient(client *sseClient) {
 	h.mu.Lock()
 	delete(h.clients, client)
 	controlMgr := h.controlMgr
+	h.scheduleRelease(client.id)
 	h.mu.Unlock()
 
 	// Close done channel (avoid panic on double close)
@@ -104,27 +194,121 @@ func (h *SSEHub) RemoveClient(client *sseClient) {
 		logger.Debug("SSE client disconnected, released control", "object", client.objectName)
 	}
 
-	logger.Debug("SSE client disconnected", "object", client.objectName, "total_clients", len(h.clients))
+	logger.Debug("SSE client disconnected", "id", client.id, "object", client.objectName, "total_clients", len(h.clients))
+}
+
+// scheduleRelease запускает отложенное освобождение подписок клиента. Вызывается под h.mu.
+func (h *SSEHub) scheduleRelease(clientID string) {
+	if clientID == "" {
+		return
+	}
+	release := h.releaseSubscriptions
+	if timer, ok := h.pendingReleases[clientID]; ok {
+		timer.Stop()
+	}
+
+	h.pendingReleases[clientID] = time.AfterFunc(h.releaseDelay, func() {
+		h.mu.Lock()
+		// Клиент мог переподключиться с тем же ID
+		if h.hasClientID(clientID) {
+			h.mu.Unlock()
+			return
+		}
+		delete(h.pendingReleases, clientID)
+		delete(h.subscriptions, clientID)
+		h.mu.Unlock()
+
+		if release != nil {
+			release(clientID)
+		}
+		logger.Debug("SSE client subscriptions released", "id", clientID)
+	})
+}
+
+// hasClientID проверяет, подключён ли клиент с ID. Вызывается под h.mu.
+func (h *SSEHub) hasClientID(clientID string) bool {
+	for client := range h.clients {
+		if client.id == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIDLen длина ID SSE клиента в hex символах
+const clientIDLen = 16
+
+// newClientID генерирует случайный ID SSE клиента
+func newClientID() string {
+	b := make([]byte, clientIDLen/2)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand не должен отказывать; на всякий случай используем время
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// validClientID проверяет формат ID, присланного клиентом при переподключении
+func validClientID(id string) bool {
+	if len(id) != clientIDLen {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
 }
 
 // Broadcast отправляет событие всем подходящим клиентам
 func (h *SSEHub) Broadcast(event SSEEvent) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.publish(event, matchObject, mergeByObject)
+}
 
+// matchObject пропускает глобальные события и события объекта, на который подписан клиент
+func matchObject(client *sseClient, event SSEEvent) (SSEEvent, bool) {
 	// Глобальные события отправляются всем клиентам
-	isGlobalEvent := event.Type == "server_status" || event.Type == "objects_list" || event.Type == "control_status"
+	isGlobalEvent := event.Type == "server_status" || event.Type == "objects_list" || event.Type == "control_status" ||
+		alarm.IsEventType(event.Type) || event.Type == watchdog.EventStale
+
+	// Отправляем если: глобальное событие ИЛИ клиент подписан на все объекты ИЛИ на конкретный
+	return event, isGlobalEvent || client.objectName == "" || client.objectName == event.ObjectName
+}
+
+// publish присваивает событию ID, сохраняет его в буфере воспроизведения и
+// ставит в очередь подходящим клиентам. Клиенту с переполненной очередью событие
+// схлопывается в накопитель последних значений (merge).
+func (h *SSEHub) publish(event SSEEvent, match eventMatcher, merge backlogMerger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastEventID++
+	event.ID = h.lastEventID
+	h.replay.push(replayEntry{event: event, match: match})
 
 	for client := range h.clients {
-		// Отправляем если: глобальное событие ИЛИ клиент подписан на все объекты ИЛИ на конкретный
-		if isGlobalEvent || client.objectName == "" || client.objectName == event.ObjectName {
-			select {
-			case client.events <- event:
-			default:
-				// Канал переполнен, пропускаем событие
-				logger.Warn("SSE client event buffer full, dropping event",
-					"object", client.objectName)
-			}
+		// Отставший клиент получит событие из буфера воспроизведения
+		if client.lagging.Load() {
+			continue
+		}
+		clientEvent, ok := match(client, event)
+		if !ok {
+			continue
+		}
+		if client.backlog != nil {
+			merge(client.backlog, clientEvent)
+			client.coalesced.Add(1)
+			continue
+		}
+
+		select {
+		case client.events <- clientEvent:
+		default:
+			// Очередь переполнена: копим последние значения до её разгрузки
+			client.backlog = newClientBacklog()
+			client.congested.Store(true)
+			client.congestions.Add(1)
+			merge(client.backlog, clientEvent)
+			client.coalesced.Add(1)
+			logger.Warn("SSE client event buffer full, coalescing events",
+				"id", client.id, "object", client.objectName)
 		}
 	}
 }
@@ -174,13 +358,13 @@ const SharedMemoryServerID = "sm"
 
 // BroadcastSensorUpdate отправляет обновление внешнего датчика клиентам
 func (h *SSEHub) BroadcastSensorUpdate(update sm.SensorUpdate) {
-	h.Broadcast(SSEEvent{
+	h.publish(SSEEvent{
 		Type:       "sensor_data",
 		ServerID:   SharedMemoryServerID,
 		ObjectName: update.ObjectName,
 		Data:       update.Sensor,
 		Timestamp:  update.Timestamp,
-	})
+	}, matchObject, mergeByName(update.Sensor.Name))
 }
 
 // BroadcastIONCSensorBatchWithServer отправляет батч обновлений IONC датчиков с информацией о сервере
@@ -198,16 +382,15 @@ func (h *SSEHub) BroadcastIONCSensorBatchWithServer(serverID, serverName string,
 		timestamp = u.Timestamp
 	}
 
-	// Отправляем по одному событию на объект
+	// Отправляем по одному событию на объект, каждому клиенту - только его датчики
 	for objectName, sensors := range byObject {
-		h.Broadcast(SSEEvent{
-			Type:       "ionc_sensor_batch",
+		broadcastItems(h, SSEEvent{
+			Type:       EventIONCSensorBatch,
 			ServerID:   serverID,
 			ServerName: serverName,
 			ObjectName: objectName,
-			Data:       sensors,
 			Timestamp:  timestamp,
-		})
+		}, sensors, ioncSensors)
 	}
 }
 
@@ -226,16 +409,15 @@ func (h *SSEHub) BroadcastModbusRegisterBatchWithServer(serverID, serverName str
 		timestamp = u.Timestamp
 	}
 
-	// Отправляем по одному событию на объект
+	// Отправляем по одному событию на объект, каждому клиенту - только его регистры
 	for objectName, registers := range byObject {
-		h.Broadcast(SSEEvent{
-			Type:       "modbus_register_batch",
+		broadcastItems(h, SSEEvent{
+			Type:       EventModbusRegisterBatch,
 			ServerID:   serverID,
 			ServerName: serverName,
 			ObjectName: objectName,
-			Data:       registers,
 			Timestamp:  timestamp,
-		})
+		}, registers, modbusRegisters)
 	}
 }
 
@@ -254,16 +436,15 @@ func (h *SSEHub) BroadcastOPCUASensorBatchWithServer(serverID, serverName string
 		timestamp = u.Timestamp
 	}
 
-	// Отправляем по одному событию на объект
+	// Отправляем по одному событию на объект, каждому клиенту - только его датчики
 	for objectName, sensors := range byObject {
-		h.Broadcast(SSEEvent{
-			Type:       "opcua_sensor_batch",
+		broadcastItems(h, SSEEvent{
+			Type:       EventOPCUASensorBatch,
 			ServerID:   serverID,
 			ServerName: serverName,
 			ObjectName: objectName,
-			Data:       sensors,
 			Timestamp:  timestamp,
-		})
+		}, sensors, opcuaSensors)
 	}
 }
 
@@ -282,16 +463,15 @@ func (h *SSEHub) BroadcastUWSGateSensorBatchWithServer(serverID, serverName stri
 		timestamp = u.Timestamp
 	}
 
-	// Отправляем по одному событию на объект
+	// Отправляем по одному событию на объект, каждому клиенту - только его датчики
 	for objectName, sensors := range byObject {
-		h.Broadcast(SSEEvent{
-			Type:       "uwsgate_sensor_batch",
+		broadcastItems(h, SSEEvent{
+			Type:       EventUWSGateSensorBatch,
 			ServerID:   serverID,
 			ServerName: serverName,
 			ObjectName: objectName,
-			Data:       sensors,
 			Timestamp:  timestamp,
-		})
+		}, sensors, uwsgateSensors)
 	}
 }
 
@@ -302,6 +482,19 @@ func (h *SSEHub) ClientCount() int {
 	return len(h.clients)
 }
 
+// ClientIDs возвращает ID подключённых клиентов (по возрастанию)
+func (h *SSEHub) ClientIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ids := make([]string, 0, len(h.clients))
+	for client := range h.clients {
+		ids = append(ids, client.id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // Close закрывает все SSE соединения (для graceful shutdown)
 func (h *SSEHub) Close() {
 	h.mu.Lock()
@@ -316,6 +509,13 @@ func (h *SSEHub) Close() {
 		}
 	}
 	h.clients = make(map[*sseClient]bool)
+	h.subscriptions = make(map[string]map[subscriptionKey]map[string]struct{})
+
+	for id, timer := range h.pendingReleases {
+		timer.Stop()
+		delete(h.pendingReleases, id)
+	}
+	h.releaseSubscriptions = nil
 
 	logger.Info("SSE hub closed, all clients disconnected")
 }
@@ -353,8 +553,43 @@ func (h *SSEHub) BroadcastJournalMessages(journalID string, messages []journal.M
 	})
 }
 
+// BroadcastAlarmEvent отправляет событие тревоги (поднятие, снятие, квитирование, откладывание) всем клиентам
+func (h *SSEHub) BroadcastAlarmEvent(event alarm.Event) {
+	h.Broadcast(SSEEvent{
+		Type:       event.Type,
+		ServerID:   event.Alarm.ServerID,
+		ObjectName: event.Alarm.Object,
+		Data:       event,
+		Timestamp:  event.Timestamp,
+	})
+}
+
+// BroadcastStaleEvent отправляет смену признака устаревания датчика всем клиентам.
+// При перегрузке клиента остаётся последнее состояние датчика.
+func (h *SSEHub) BroadcastStaleEvent(event watchdog.Event) {
+	h.publish(SSEEvent{
+		Type:       event.Type,
+		ServerID:   event.Sensor.ServerID,
+		ObjectName: event.Sensor.Object,
+		Data:       event.Sensor,
+		Timestamp:  event.Timestamp,
+	}, matchObject, mergeByName(event.Sensor.Source+"/"+event.Sensor.Sensor))
+}
+
+// BroadcastVirtualValue отправляет новое значение виртуального датчика.
+// При перегрузке клиента остаётся последнее значение датчика.
+func (h *SSEHub) BroadcastVirtualValue(value virtual.Value) {
+	h.publish(SSEEvent{
+		Type:       virtual.EventValue,
+		ServerID:   virtual.ServerID,
+		ObjectName: value.Object,
+		Data:       value,
+		Timestamp:  value.Timestamp,
+	}, matchObject, mergeByName(value.Name))
+}
+
 // HandleSSE обрабатывает SSE подключение
-// GET /api/events?object=ObjectName&token=xxx (опционально)
+// GET /api/events?object=ObjectName&token=xxx&client=id&lastEventId=N (опционально)
 func (h *Handlers) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	// Проверяем поддержку SSE
 	flusher, ok := w.(http.Flusher)
@@ -366,6 +601,7 @@ func (h *Handlers) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	// Получаем параметры из query
 	objectName := r.URL.Query().Get("object")
 	controlToken := r.URL.Query().Get("token")
+	clientID := r.URL.Query().Get("client")
 
 	// Устанавливаем заголовки SSE
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -374,14 +610,20 @@ func (h *Handlers) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("X-Accel-Buffering", "no") // Для nginx
 
-	// Регистрируем клиента с токеном (если передан)
-	client := h.sseHub.AddClientWithToken(objectName, controlToken)
+	// Регистрируем клиента с токеном (если передан); при переподключении продолжаем с Last-Event-ID
+	var client *sseClient
+	if lastEventID, ok := parseLastEventID(r); ok {
+		client = h.sseHub.ResumeClient(clientID, objectName, controlToken, lastEventID)
+	} else {
+		client = h.sseHub.AddClientWithID(clientID, objectName, controlToken)
+	}
 	defer h.sseHub.RemoveClient(client)
 
 	// Формируем данные приветственного сообщения
 	connectedData := map[string]interface{}{
 		"pollInterval": h.pollInterval.Milliseconds(),
 		"smEnabled":    h.smPoller != nil,
+		"clientId":     client.id, // передаётся в X-Client-ID при подписке на датчики
 	}
 
 	// Добавляем статус контроля если менеджер настроен
@@ -406,8 +648,30 @@ func (h *Handlers) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	})
 	flusher.Flush()
 
-	// Слушаем события
+	// Слушаем события; lastID - ID последнего отправленного клиенту события
+	lastID := client.cursor
 	for {
+		// Очередь разобрана: догоняем пропущенное после переподключения
+		// или отправляем снимок значений, накопленных при перегрузке
+		if (client.lagging.Load() || client.congested.Load()) && len(client.events) == 0 {
+			events, last, resync := h.sseHub.catchUp(client, lastID)
+			if resync {
+				h.sendSSEEvent(w, SSEEvent{
+					Type:      EventResyncRequired,
+					Timestamp: time.Now(),
+					Data: map[string]interface{}{
+						"lastEventId": strconv.FormatUint(lastID, 10),
+					},
+				})
+			}
+			for _, event := range events {
+				h.sendSSEEvent(w, event)
+			}
+			client.sent.Add(uint64(len(events)))
+			lastID = last
+			flusher.Flush()
+		}
+
 		select {
 		case <-r.Context().Done():
 			return
@@ -415,11 +679,30 @@ func (h *Handlers) HandleSSE(w http.ResponseWriter, r *http.Request) {
 			return
 		case event := <-client.events:
 			h.sendSSEEvent(w, event)
+			client.sent.Add(1)
+			lastID = event.ID
 			flusher.Flush()
 		}
 	}
 }
 
+// parseLastEventID возвращает ID последнего полученного клиентом события:
+// заголовок Last-Event-ID (автопереподключение EventSource) или параметр lastEventId
+func parseLastEventID(r *http.Request) (uint64, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
 // sendSSEEvent отправляет одно SSE событие
 func (h *Handlers) sendSSEEvent(w http.ResponseWriter, event SSEEvent) {
 	data, err := json.Marshal(event)
@@ -428,6 +711,9 @@ func (h *Handlers) sendSSEEvent(w http.ResponseWriter, event SSEEvent) {
 		return
 	}
 
+	if event.ID != 0 {
+		fmt.Fprintf(w, "id: %d\n", event.ID)
+	}
 	fmt.Fprintf(w, "event: %s\n", event.Type)
 	fmt.Fprintf(w, "data: %s\n\n", data)
 }
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/alarm"
+)
+
+// backlogLimit макс. число ключей в накопителе перегруженного клиента.
+// Сверх него значения не копятся, а клиент после разгрузки получает resync_required.
+const backlogLimit = 10000
+
+// appendOnlyEvents события, которые нельзя схлопывать до последнего значения:
+// при перегрузке клиента сохраняется каждое
+var appendOnlyEvents = map[string]bool{
+	"journal_messages":      true,
+	alarm.EventRaised:       true,
+	alarm.EventCleared:      true,
+	alarm.EventAcknowledged: true,
+	alarm.EventShelved:      true,
+	alarm.EventUnshelved:    true,
+}
+
+// backlogKey ключ последнего значения: тип события, сервер, объект и элемент (имя или ID)
+type backlogKey struct {
+	eventType  string
+	serverID   string
+	objectName string
+	name       string
+}
+
+// backlogEntry последнее событие по ключу; для батчей - последние элементы по ID
+type backlogEntry struct {
+	event     SSEEvent
+	items     map[int64]interface{}
+	itemOrder []int64
+}
+
+// clientBacklog накопитель "последнее значение по ключу" для клиента с переполненной очередью.
+// После разгрузки очереди клиент получает один снимок - по событию на ключ.
+type clientBacklog struct {
+	order    []backlogKey
+	entries  map[backlogKey]*backlogEntry
+	overflow bool // превышен backlogLimit
+}
+
+func newClientBacklog() *clientBacklog {
+	return &clientBacklog{entries: make(map[backlogKey]*backlogEntry)}
+}
+
+// backlogMerger кладёт событие клиента в накопитель
+type backlogMerger func(b *clientBacklog, event SSEEvent)
+
+// entry возвращает запись ключа, запоминая последнее событие. nil - накопитель переполнен.
+func (b *clientBacklog) entry(key backlogKey, event SSEEvent) *backlogEntry {
+	e := b.entries[key]
+	if e == nil {
+		if len(b.order) >= backlogLimit {
+			b.overflow = true
+			return nil
+		}
+		e = &backlogEntry{}
+		b.entries[key] = e
+		b.order = append(b.order, key)
+	}
+	e.event = event
+	return e
+}
+
+// snapshot собирает схлопнутые события в порядке первого появления ключей
+func (b *clientBacklog) snapshot() []SSEEvent {
+	events := make([]SSEEvent, 0, len(b.order))
+	for _, key := range b.order {
+		e := b.entries[key]
+		event := e.event
+		event.ID = 0
+		if e.items != nil {
+			data := make([]interface{}, 0, len(e.itemOrder))
+			for _, id := range e.itemOrder {
+				data = append(data, e.items[id])
+			}
+			event.Data = data
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// size возвращает число ключей в накопителе
+func (b *clientBacklog) size() int {
+	return len(b.order)
+}
+
+func eventBacklogKey(event SSEEvent, name string) backlogKey {
+	return backlogKey{eventType: event.Type, serverID: event.ServerID, objectName: event.ObjectName, name: name}
+}
+
+// mergeByObject оставляет последнее событие по серверу/объекту; события из
+// appendOnlyEvents сохраняются все
+func mergeByObject(b *clientBacklog, event SSEEvent) {
+	if appendOnlyEvents[event.Type] {
+		b.entry(eventBacklogKey(event, "#"+strconv.FormatUint(event.ID, 10)), event)
+		return
+	}
+	b.entry(eventBacklogKey(event, ""), event)
+}
+
+// mergeByName оставляет последнее событие по серверу/объекту/имени датчика
+func mergeByName(name string) backlogMerger {
+	return func(b *clientBacklog, event SSEEvent) {
+		b.entry(eventBacklogKey(event, name), event)
+	}
+}
+
+// mergeItems оставляет последний элемент батча по серверу/объекту/ID элемента
+func mergeItems[T any](itemID func(T) int64) backlogMerger {
+	return func(b *clientBacklog, event SSEEvent) {
+		items, _ := event.Data.([]T)
+		e := b.entry(eventBacklogKey(event, ""), event)
+		if e == nil {
+			return
+		}
+		if e.items == nil {
+			e.items = make(map[int64]interface{}, len(items))
+		}
+		for _, item := range items {
+			id := itemID(item)
+			if _, ok := e.items[id]; !ok {
+				e.itemOrder = append(e.itemOrder, id)
+			}
+			e.items[id] = item
+		}
+	}
+}
+
+// SSEClientStats метрики очереди SSE клиента
+type SSEClientStats struct {
+	ID          string    `json:"id"`
+	Object      string    `json:"object,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	QueueLen    int       `json:"queueLen"`
+	QueueCap    int       `json:"queueCap"`
+	Congested   bool      `json:"congested"`   // очередь переполнена, события схлопываются
+	BacklogKeys int       `json:"backlogKeys"` // ключей в накопителе
+	Sent        uint64    `json:"sent"`        // событий записано в поток
+	Coalesced   uint64    `json:"coalesced"`   // событий поглощено накопителем
+	Congestions uint64    `json:"congestions"` // сколько раз переполнялась очередь
+	Resyncs     uint64    `json:"resyncs"`     // сколько раз отправлен resync_required
+}
+
+// ClientStats возвращает метрики очередей подключённых клиентов (по возрастанию ID)
+func (h *SSEHub) ClientStats() []SSEClientStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]SSEClientStats, 0, len(h.clients))
+	for client := range h.clients {
+		s := SSEClientStats{
+			ID:          client.id,
+			Object:      client.objectName,
+			ConnectedAt: client.connectedAt,
+			QueueLen:    len(client.events),
+			QueueCap:    cap(client.events),
+			Congested:   client.backlog != nil,
+			Sent:        client.sent.Load(),
+			Coalesced:   client.coalesced.Load(),
+			Congestions: client.congestions.Load(),
+			Resyncs:     client.resyncs.Load(),
+		}
+		if client.backlog != nil {
+			s.BacklogKeys = client.backlog.size()
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
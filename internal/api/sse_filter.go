@@ -0,0 +1,197 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/pv/uniset-panel/internal/opcua"
+	"github.com/pv/uniset-panel/internal/uniset"
+	"github.com/pv/uniset-panel/internal/uwsgate"
+)
+
+// Типы батчевых событий, которые обрезаются по подпискам клиента
+const (
+	EventIONCSensorBatch     = "ionc_sensor_batch"
+	EventModbusRegisterBatch = "modbus_register_batch"
+	EventOPCUASensorBatch    = "opcua_sensor_batch"
+	EventUWSGateSensorBatch  = "uwsgate_sensor_batch"
+)
+
+// subscriptionKey набор элементов клиента: тип батчевого события, сервер и объект.
+// Пустой serverID означает подписку без указания сервера (подходит для любого).
+type subscriptionKey struct {
+	eventType  string
+	serverID   string
+	objectName string
+}
+
+// TrackSubscription запоминает, что клиент подписан на элементы объекта.
+// Пустой clientID - анонимный владелец, его элементы получают все клиенты.
+func (h *SSEHub) TrackSubscription(clientID, eventType, serverID, objectName string, ids []int64) {
+	h.trackItems(clientID, eventType, serverID, objectName, idKeys(ids))
+}
+
+// TrackSubscriptionNames запоминает подписку клиента на элементы объекта по именам
+// (UWebSocketGate подписывает датчики по имени)
+func (h *SSEHub) TrackSubscriptionNames(clientID, eventType, serverID, objectName string, names []string) {
+	h.trackItems(clientID, eventType, serverID, objectName, names)
+}
+
+// UntrackSubscription забывает подписку клиента на элементы объекта.
+// Без ids снимаются все элементы объекта.
+func (h *SSEHub) UntrackSubscription(clientID, eventType, serverID, objectName string, ids []int64) {
+	h.untrackItems(clientID, eventType, serverID, objectName, idKeys(ids))
+}
+
+// UntrackSubscriptionNames забывает подписку клиента на элементы объекта по именам.
+// Без names снимаются все элементы объекта.
+func (h *SSEHub) UntrackSubscriptionNames(clientID, eventType, serverID, objectName string, names []string) {
+	h.untrackItems(clientID, eventType, serverID, objectName, names)
+}
+
+func (h *SSEHub) trackItems(clientID, eventType, serverID, objectName string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := h.subscriptions[clientID]
+	if keys == nil {
+		keys = make(map[subscriptionKey]map[string]struct{})
+		h.subscriptions[clientID] = keys
+	}
+	key := subscriptionKey{eventType: eventType, serverID: serverID, objectName: objectName}
+	set := keys[key]
+	if set == nil {
+		set = make(map[string]struct{}, len(items))
+		keys[key] = set
+	}
+	for _, item := range items {
+		set[item] = struct{}{}
+	}
+}
+
+func (h *SSEHub) untrackItems(clientID, eventType, serverID, objectName string, items []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := h.subscriptions[clientID]
+	if keys == nil {
+		return
+	}
+	key := subscriptionKey{eventType: eventType, serverID: serverID, objectName: objectName}
+	if len(items) == 0 {
+		delete(keys, key)
+	} else if set := keys[key]; set != nil {
+		for _, item := range items {
+			delete(set, item)
+		}
+		if len(set) == 0 {
+			delete(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		delete(h.subscriptions, clientID)
+	}
+}
+
+// idKeys переводит ID элементов в ключи подписки
+func idKeys(ids []int64) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = strconv.FormatInt(id, 10)
+	}
+	return keys
+}
+
+// SubscribedItems возвращает число элементов, на которые подписан клиент (для диагностики)
+func (h *SSEHub) SubscribedItems(clientID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, items := range h.subscriptions[clientID] {
+		count += len(items)
+	}
+	return count
+}
+
+// isSubscribed проверяет, подписан ли клиент (или анонимный владелец) на элемент
+// с одним из ключей. Вызывается под h.mu.
+func (h *SSEHub) isSubscribed(clientID string, event SSEEvent, itemKeys []string) bool {
+	for _, owner := range []string{clientID, ""} {
+		keys := h.subscriptions[owner]
+		if keys == nil {
+			continue
+		}
+		for _, serverID := range []string{event.ServerID, ""} {
+			key := subscriptionKey{eventType: event.Type, serverID: serverID, objectName: event.ObjectName}
+			set := keys[key]
+			for _, item := range itemKeys {
+				if _, ok := set[item]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// batchItem описывает элементы батча: ID для схлопывания в накопителе
+// и ключи, по которым элемент ищется в подписках клиента
+type batchItem[T any] struct {
+	id   func(T) int64
+	keys func(T) []string
+}
+
+// broadcastItems отправляет батч элементов объекта, оставляя каждому клиенту
+// только элементы, на которые он подписан. Клиенты без подходящих элементов событие не получают.
+func broadcastItems[T any](h *SSEHub, event SSEEvent, items []T, item batchItem[T]) {
+	event.Data = items
+	h.publish(event, func(client *sseClient, event SSEEvent) (SSEEvent, bool) {
+		if client.objectName != "" && client.objectName != event.ObjectName {
+			return event, false
+		}
+
+		filtered := make([]T, 0, len(items))
+		for _, it := range items {
+			if h.isSubscribed(client.id, event, item.keys(it)) {
+				filtered = append(filtered, it)
+			}
+		}
+		if len(filtered) == 0 {
+			return event, false
+		}
+		if len(filtered) < len(items) {
+			event.Data = filtered
+		}
+		return event, true
+	}, mergeItems(item.id))
+}
+
+// byID элементы батча, подписка на которые ведётся по ID
+func byID[T any](id func(T) int64) batchItem[T] {
+	return batchItem[T]{
+		id:   id,
+		keys: func(v T) []string { return []string{strconv.FormatInt(id(v), 10)} },
+	}
+}
+
+// Элементы батчей для broadcastItems
+var (
+	ioncSensors     = byID(func(s uniset.IONCSensor) int64 { return s.ID })
+	modbusRegisters = byID(func(r uniset.MBRegister) int64 { return r.ID })
+	opcuaSensors    = byID(func(s opcua.OPCUASensor) int64 { return s.ID })
+
+	// Датчики UWebSocketGate подписываются по имени, но клиент мог указать и ID
+	uwsgateSensors = batchItem[uwsgate.SensorData]{
+		id: func(s uwsgate.SensorData) int64 { return s.ID },
+		keys: func(s uwsgate.SensorData) []string {
+			return []string{s.Name, strconv.FormatInt(s.ID, 10)}
+		},
+	}
+)
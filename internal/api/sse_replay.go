@@ -0,0 +1,110 @@
+package api
+
+// replayBufferSize количество последних событий, которые SSEHub хранит для
+// воспроизведения переподключившимся и отставшим клиентам
+const replayBufferSize = 1024
+
+// EventResyncRequired событие для клиента, пропустившего события сверх буфера воспроизведения:
+// клиент должен перезагрузить состояние
+const EventResyncRequired = "resync_required"
+
+// eventMatcher решает, получает ли клиент событие, и подготавливает его для клиента
+// (например, обрезает батч по подпискам). Вызывается под h.mu.
+type eventMatcher func(client *sseClient, event SSEEvent) (SSEEvent, bool)
+
+// replayEntry событие в буфере воспроизведения
+type replayEntry struct {
+	event SSEEvent
+	match eventMatcher
+}
+
+// replayRing кольцевой буфер последних событий (по возрастанию ID)
+type replayRing struct {
+	entries []replayEntry
+	start   int // индекс самого старого события
+	count   int
+}
+
+func newReplayRing(size int) *replayRing {
+	if size <= 0 {
+		size = replayBufferSize
+	}
+	return &replayRing{entries: make([]replayEntry, size)}
+}
+
+// push добавляет событие, вытесняя самое старое при заполнении
+func (r *replayRing) push(entry replayEntry) {
+	if r.count < len(r.entries) {
+		r.entries[(r.start+r.count)%len(r.entries)] = entry
+		r.count++
+		return
+	}
+	r.entries[r.start] = entry
+	r.start = (r.start + 1) % len(r.entries)
+}
+
+// since возвращает события с ID > after. complete=false, если часть событий
+// после after уже вытеснена или after не относится к этому запуску (after > latest).
+func (r *replayRing) since(after, latest uint64) (entries []replayEntry, complete bool) {
+	if after > latest {
+		return nil, false
+	}
+	if after == latest {
+		return nil, true
+	}
+	if r.count == 0 || r.entries[r.start].event.ID > after+1 {
+		return nil, false
+	}
+
+	for i := 0; i < r.count; i++ {
+		entry := r.entries[(r.start+i)%len(r.entries)]
+		if entry.event.ID > after {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, true
+}
+
+// catchUp выдаёт клиенту с разобранной очередью пропущенные после переподключения события
+// из буфера воспроизведения либо снимок значений, накопленных при перегрузке, и возвращает его
+// в обычный режим доставки через канал. resync=true, если события потеряны и клиенту нужно
+// перезагрузить состояние; last - ID, с которого клиент продолжает.
+func (h *SSEHub) catchUp(client *sseClient, after uint64) (events []SSEEvent, last uint64, resync bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last = h.lastEventID
+
+	if client.lagging.Load() {
+		client.lagging.Store(false)
+		entries, complete := h.replay.since(after, last)
+		if !complete {
+			resync = true
+		}
+		for _, entry := range entries {
+			if event, ok := entry.match(client, entry.event); ok {
+				events = append(events, event)
+			}
+		}
+	}
+
+	if client.backlog != nil {
+		if client.backlog.overflow {
+			resync = true
+		} else {
+			events = append(events, client.backlog.snapshot()...)
+		}
+		client.backlog = nil
+		client.congested.Store(false)
+	}
+
+	if resync {
+		client.resyncs.Add(1)
+		return nil, last, true
+	}
+	// Последнее событие несёт текущий ID: с него клиент продолжит после переподключения
+	if n := len(events); n > 0 {
+		events[n-1].ID = last
+	}
+	return events, last, false
+}
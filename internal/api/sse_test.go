@@ -6,14 +6,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/pv/uniset-panel/internal/ionc"
+	"github.com/pv/uniset-panel/internal/journal"
 	"github.com/pv/uniset-panel/internal/poller"
 	"github.com/pv/uniset-panel/internal/storage"
 	"github.com/pv/uniset-panel/internal/uniset"
+	"github.com/pv/uniset-panel/internal/uwsgate"
 )
 
 // ============================================================================
@@ -845,3 +849,452 @@ func TestSSEEventObjectsListJSON(t *testing.T) {
 		t.Errorf("expected 2 objects, got %d", len(objects))
 	}
 }
+
+func TestSSEHubReleasesClientSubscriptions(t *testing.T) {
+	hub := NewSSEHub()
+	hub.releaseDelay = 20 * time.Millisecond
+
+	released := make(chan string, 2)
+	hub.SetSubscriptionReleaser(func(clientID string) { released <- clientID })
+
+	client := hub.AddClient("")
+	if !validClientID(client.id) {
+		t.Fatalf("invalid client id %q", client.id)
+	}
+	hub.RemoveClient(client)
+
+	select {
+	case id := <-released:
+		if id != client.id {
+			t.Errorf("released %q, want %q", id, client.id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriptions of removed client were not released")
+	}
+}
+
+func TestSSEHubReconnectKeepsSubscriptions(t *testing.T) {
+	hub := NewSSEHub()
+	hub.releaseDelay = 50 * time.Millisecond
+
+	released := make(chan string, 2)
+	hub.SetSubscriptionReleaser(func(clientID string) { released <- clientID })
+
+	client := hub.AddClient("")
+	hub.RemoveClient(client)
+
+	// Переподключение с прежним ID до истечения задержки
+	reconnected := hub.AddClientWithID(client.id, "", "")
+	defer hub.RemoveClient(reconnected)
+	if reconnected.id != client.id {
+		t.Fatalf("expected reconnected client to keep id %q, got %q", client.id, reconnected.id)
+	}
+
+	// Занятый ID не переиспользуется
+	other := hub.AddClientWithID(client.id, "", "")
+	defer hub.RemoveClient(other)
+	if other.id == client.id {
+		t.Error("id of connected client must not be shared")
+	}
+
+	select {
+	case id := <-released:
+		t.Errorf("unexpected release of %q after reconnect", id)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestSSEHubTrimsBatchBySubscriptions(t *testing.T) {
+	hub := NewSSEHub()
+
+	client1 := hub.AddClient("")
+	client2 := hub.AddClient("")
+	idle := hub.AddClient("")
+	defer hub.RemoveClient(client1)
+	defer hub.RemoveClient(client2)
+	defer hub.RemoveClient(idle)
+
+	hub.TrackSubscription(client1.id, EventIONCSensorBatch, "", "SharedMemory", []int64{1, 2})
+	hub.TrackSubscription(client2.id, EventIONCSensorBatch, "server1", "SharedMemory", []int64{2})
+	// Подписка на другой сервер не должна срабатывать
+	hub.TrackSubscription(idle.id, EventIONCSensorBatch, "server2", "SharedMemory", []int64{1, 2, 3})
+
+	hub.BroadcastIONCSensorBatchWithServer("server1", "Server 1", []ionc.SensorUpdate{
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 1}},
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 2}},
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 3}},
+	})
+
+	expectIDs := func(name string, client *sseClient, want []int64) {
+		t.Helper()
+		select {
+		case event := <-client.events:
+			sensors, ok := event.Data.([]uniset.IONCSensor)
+			if !ok {
+				t.Fatalf("%s: unexpected data type %T", name, event.Data)
+			}
+			var got []int64
+			for _, s := range sensors {
+				got = append(got, s.ID)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%s: got ids %v, want %v", name, got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("%s: got ids %v, want %v", name, got, want)
+				}
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("%s: did not receive event", name)
+		}
+	}
+
+	expectIDs("client1", client1, []int64{1, 2})
+	expectIDs("client2", client2, []int64{2})
+
+	select {
+	case event := <-idle.events:
+		t.Errorf("idle client should not receive batch, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Анонимные подписки (без ID клиента) получают все клиенты
+	hub.TrackSubscription("", EventIONCSensorBatch, "", "SharedMemory", []int64{3})
+	hub.UntrackSubscription(client1.id, EventIONCSensorBatch, "", "SharedMemory", nil)
+
+	hub.BroadcastIONCSensorBatchWithServer("server1", "Server 1", []ionc.SensorUpdate{
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 1}},
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 3}},
+	})
+
+	expectIDs("client1", client1, []int64{3})
+	expectIDs("client2", client2, []int64{3})
+	expectIDs("idle", idle, []int64{3})
+}
+
+func TestSSEHubTrimsUWSGateBatchBySubscriptions(t *testing.T) {
+	hub := NewSSEHub()
+
+	client1 := hub.AddClient("")
+	client2 := hub.AddClient("")
+	defer hub.RemoveClient(client1)
+	defer hub.RemoveClient(client2)
+
+	hub.TrackSubscriptionNames(client1.id, EventUWSGateSensorBatch, "server1", "UWebSocketGate", []string{"A"})
+	// Датчик можно указать и по ID
+	hub.TrackSubscriptionNames(client2.id, EventUWSGateSensorBatch, "", "UWebSocketGate", []string{"2"})
+
+	hub.BroadcastUWSGateSensorBatchWithServer("server1", "Server 1", []uwsgate.SensorUpdate{
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 1, Name: "A"}},
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 2, Name: "B"}},
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 3, Name: "C"}},
+	})
+
+	expectNames := func(name string, client *sseClient, want string) {
+		t.Helper()
+		select {
+		case event := <-client.events:
+			sensors, ok := event.Data.([]uwsgate.SensorData)
+			if !ok {
+				t.Fatalf("%s: unexpected data type %T", name, event.Data)
+			}
+			if len(sensors) != 1 || sensors[0].Name != want {
+				t.Errorf("%s: got %+v, want only %s", name, sensors, want)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("%s: did not receive event", name)
+		}
+	}
+	expectNames("client1", client1, "A")
+	expectNames("client2", client2, "B")
+
+	hub.UntrackSubscriptionNames(client1.id, EventUWSGateSensorBatch, "server1", "UWebSocketGate", []string{"A"})
+	hub.BroadcastUWSGateSensorBatchWithServer("server1", "Server 1", []uwsgate.SensorUpdate{
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 1, Name: "A"}},
+	})
+	select {
+	case event := <-client1.events:
+		t.Errorf("unsubscribed client should not receive batch, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSSEHubForgetsSubscriptionsOnRelease(t *testing.T) {
+	hub := NewSSEHub()
+	hub.releaseDelay = 20 * time.Millisecond
+
+	client := hub.AddClient("")
+	hub.TrackSubscription(client.id, EventModbusRegisterBatch, "", "MBTCPMaster", []int64{10, 11})
+	if n := hub.SubscribedItems(client.id); n != 2 {
+		t.Fatalf("expected 2 tracked items, got %d", n)
+	}
+	hub.RemoveClient(client)
+
+	deadline := time.Now().Add(time.Second)
+	for hub.SubscribedItems(client.id) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("tracked subscriptions of removed client were not forgotten")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplayRingSince(t *testing.T) {
+	ring := newReplayRing(3)
+	for id := uint64(1); id <= 5; id++ {
+		ring.push(replayEntry{event: SSEEvent{ID: id}})
+	}
+
+	entries, complete := ring.since(3, 5)
+	if !complete || len(entries) != 2 || entries[0].event.ID != 4 || entries[1].event.ID != 5 {
+		t.Errorf("since(3): complete=%v entries=%+v", complete, entries)
+	}
+	if entries, complete := ring.since(5, 5); !complete || len(entries) != 0 {
+		t.Errorf("since(latest): complete=%v entries=%d", complete, len(entries))
+	}
+	// Событие 2 вытеснено
+	if _, complete := ring.since(1, 5); complete {
+		t.Error("since(1) should be incomplete: event 2 was evicted")
+	}
+	// ID из другого запуска
+	if _, complete := ring.since(10, 5); complete {
+		t.Error("since(10) should be incomplete: id is ahead of latest")
+	}
+}
+
+func TestSSEHubResumeReplaysMissedEvents(t *testing.T) {
+	hub := NewSSEHub()
+
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "A"})
+	hub.mu.RLock()
+	after := hub.lastEventID
+	hub.mu.RUnlock()
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "B"})
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "C"})
+
+	client := hub.ResumeClient("", "", "", after)
+	defer hub.RemoveClient(client)
+
+	if !client.lagging.Load() {
+		t.Fatal("resumed client should catch up from replay buffer")
+	}
+	events, last, resync := hub.catchUp(client, client.cursor)
+	if resync {
+		t.Fatal("unexpected resync")
+	}
+	if len(events) != 2 || events[0].ObjectName != "B" || events[1].ObjectName != "C" {
+		t.Fatalf("unexpected replay: %+v", events)
+	}
+	if last != events[1].ID {
+		t.Errorf("expected last=%d, got %d", events[1].ID, last)
+	}
+
+	// После догоняния события снова идут через очередь
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "D"})
+	select {
+	case event := <-client.events:
+		if event.ObjectName != "D" || event.ID != last+1 {
+			t.Errorf("unexpected live event: %+v", event)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("did not receive live event after catch up")
+	}
+}
+
+func TestSSEHubResumeTooOldRequiresResync(t *testing.T) {
+	hub := NewSSEHub()
+	hub.replay = newReplayRing(2)
+
+	hub.mu.RLock()
+	after := hub.lastEventID
+	hub.mu.RUnlock()
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(SSEEvent{Type: "server_status"})
+	}
+
+	client := hub.ResumeClient("", "", "", after)
+	defer hub.RemoveClient(client)
+
+	if _, _, resync := hub.catchUp(client, client.cursor); !resync {
+		t.Error("expected resync when missed events were evicted")
+	}
+}
+
+func TestSSEHubOverflowCoalescesLatestValues(t *testing.T) {
+	hub := NewSSEHub()
+	client := hub.AddClient("")
+	defer hub.RemoveClient(client)
+
+	hub.TrackSubscription(client.id, EventIONCSensorBatch, "", "SharedMemory", []int64{1, 2})
+
+	// Заполняем очередь
+	for i := 0; i < cap(client.events); i++ {
+		hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Obj", Data: i})
+	}
+	// Дальше - перегрузка: значения схлопываются
+	for v := int64(1); v <= 5; v++ {
+		hub.BroadcastIONCSensorBatchWithServer("s1", "S1", []ionc.SensorUpdate{
+			{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 1, Value: v}},
+		})
+		hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Obj", Data: 100 + v})
+	}
+	hub.BroadcastIONCSensorBatchWithServer("s1", "S1", []ionc.SensorUpdate{
+		{ObjectName: "SharedMemory", Sensor: uniset.IONCSensor{ID: 2, Value: 7}},
+	})
+	hub.BroadcastJournalMessages("j1", []journal.Message{{}})
+	hub.BroadcastJournalMessages("j1", []journal.Message{{}})
+
+	stats := hub.ClientStats()
+	if len(stats) != 1 || !stats[0].Congested || stats[0].Coalesced != 13 || stats[0].Congestions != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats[0].BacklogKeys != 4 {
+		t.Errorf("expected 4 backlog keys (batch, object, 2 journal), got %d", stats[0].BacklogKeys)
+	}
+
+	// Писатель разбирает очередь и получает снимок
+	lastID := client.cursor
+	for len(client.events) > 0 {
+		lastID = (<-client.events).ID
+	}
+	events, last, resync := hub.catchUp(client, lastID)
+	if resync {
+		t.Fatal("unexpected resync")
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 coalesced events, got %d: %+v", len(events), events)
+	}
+
+	batch := events[0]
+	if batch.Type != EventIONCSensorBatch {
+		t.Fatalf("expected first event %s, got %s", EventIONCSensorBatch, batch.Type)
+	}
+	items := batch.Data.([]interface{})
+	if len(items) != 2 || items[0].(uniset.IONCSensor).Value != 5 || items[1].(uniset.IONCSensor).Value != 7 {
+		t.Errorf("unexpected coalesced batch: %+v", items)
+	}
+	if events[1].Data != int64(105) {
+		t.Errorf("expected latest object data 105, got %v", events[1].Data)
+	}
+	if events[2].Type != "journal_messages" || events[3].Type != "journal_messages" {
+		t.Error("journal messages must not be coalesced")
+	}
+	if events[3].ID != last {
+		t.Errorf("last snapshot event should carry id %d, got %d", last, events[3].ID)
+	}
+
+	// После снимка события снова идут через очередь
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Obj"})
+	if len(client.events) != 1 || hub.ClientStats()[0].Congested {
+		t.Error("client should return to normal delivery after snapshot")
+	}
+}
+
+func TestSSEHubOverflowKeepsUWSGateSensorsOfEarlierBatches(t *testing.T) {
+	hub := NewSSEHub()
+	client := hub.AddClient("")
+	defer hub.RemoveClient(client)
+
+	hub.TrackSubscriptionNames(client.id, EventUWSGateSensorBatch, "", "UWebSocketGate", []string{"A", "B"})
+
+	for i := 0; i < cap(client.events); i++ {
+		hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Obj", Data: i})
+	}
+	// Батчи с разными датчиками не должны вытеснять друг друга
+	hub.BroadcastUWSGateSensorBatchWithServer("s1", "S1", []uwsgate.SensorUpdate{
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 1, Name: "A", Value: 10}},
+	})
+	hub.BroadcastUWSGateSensorBatchWithServer("s1", "S1", []uwsgate.SensorUpdate{
+		{ObjectName: "UWebSocketGate", Sensor: uwsgate.SensorData{ID: 2, Name: "B", Value: 20}},
+	})
+
+	lastID := client.cursor
+	for len(client.events) > 0 {
+		lastID = (<-client.events).ID
+	}
+	events, _, resync := hub.catchUp(client, lastID)
+	if resync {
+		t.Fatal("unexpected resync")
+	}
+	if len(events) != 1 || events[0].Type != EventUWSGateSensorBatch {
+		t.Fatalf("expected one coalesced uwsgate batch, got %+v", events)
+	}
+	items := events[0].Data.([]interface{})
+	if len(items) != 2 || items[0].(uwsgate.SensorData).Value != 10 || items[1].(uwsgate.SensorData).Value != 20 {
+		t.Errorf("expected values of both sensors, got %+v", items)
+	}
+}
+
+func TestGetSSEClients(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+	client := handlers.GetSSEHub().AddClient("TestProc")
+	defer handlers.GetSSEHub().RemoveClient(client)
+
+	req := httptest.NewRequest("GET", "/api/sse/clients", nil)
+	w := httptest.NewRecorder()
+	handlers.GetSSEClients(w, req)
+
+	var resp struct {
+		Clients []SSEClientStats `json:"clients"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Clients) != 1 || resp.Clients[0].ID != client.id || resp.Clients[0].QueueCap != cap(client.events) {
+		t.Errorf("unexpected clients: %+v", resp.Clients)
+	}
+}
+
+func TestHandleSSELastEventID(t *testing.T) {
+	unisetServer := mockUnisetServer()
+	defer unisetServer.Close()
+
+	handlers := setupTestHandlers(unisetServer)
+	hub := handlers.GetSSEHub()
+
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Seen"})
+	hub.mu.RLock()
+	lastSeen := hub.lastEventID
+	hub.mu.RUnlock()
+	hub.Broadcast(SSEEvent{Type: "object_data", ObjectName: "Missed"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest("GET", "/api/events", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(lastSeen, 10))
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.HandleSSE(w, req)
+		close(done)
+	}()
+	<-done
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Missed") {
+		t.Error("missed event should be replayed")
+	}
+	if strings.Contains(body, `"objectName":"Seen"`) {
+		t.Error("already seen event must not be replayed")
+	}
+	if !strings.Contains(body, "id: "+strconv.FormatUint(lastSeen+1, 10)) {
+		t.Error("replayed event should carry its id")
+	}
+
+	// Слишком старый Last-Event-ID
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel2()
+	req2 := httptest.NewRequest("GET", "/api/events?lastEventId=1", nil).WithContext(ctx2)
+	w2 := httptest.NewRecorder()
+	handlers.HandleSSE(w2, req2)
+
+	if !strings.Contains(w2.Body.String(), "event: "+EventResyncRequired) {
+		t.Error("expected resync_required for too old Last-Event-ID")
+	}
+}
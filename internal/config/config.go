@@ -6,6 +6,7 @@ import (
 	"flag"
 	"log/slog"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -15,6 +16,7 @@ type StorageType string
 const (
 	StorageMemory StorageType = "memory"
 	StorageSQLite StorageType = "sqlite"
+	StorageTiered StorageType = "tiered" // свежие точки в памяти, старые в SQLite
 )
 
 // ServerConfig описывает конфигурацию одного UniSet2 сервера
@@ -22,6 +24,18 @@ type ServerConfig struct {
 	ID   string `yaml:"id,omitempty"`   // уникальный идентификатор (генерируется если не указан)
 	URL  string `yaml:"url"`            // URL UniSet2 HTTP API
 	Name string `yaml:"name,omitempty"` // человекочитаемое имя (опционально)
+
+	// Подключение к серверу (опционально)
+	Auth    *ServerAuthConfig `yaml:"auth,omitempty"`    // basic auth или bearer token
+	Headers map[string]string `yaml:"headers,omitempty"` // дополнительные заголовки запросов
+	TLS     *ServerTLSConfig  `yaml:"tls,omitempty"`     // CA, клиентский сертификат, insecureSkipVerify
+	Timeout time.Duration     `yaml:"timeout,omitempty"` // таймаут запроса (default: 10s)
+
+	// Переопределения глобальных настроек опроса (0/пусто = глобальное значение)
+	PollInterval    time.Duration `yaml:"pollInterval,omitempty"`    // интервал опроса объектов и датчиков
+	SensorBatchSize int           `yaml:"sensorBatchSize,omitempty"` // макс. датчиков в одном запросе
+	Supplier        string        `yaml:"supplier,omitempty"`        // поставщик для set/freeze/unfreeze
+	HealthInterval  time.Duration `yaml:"healthInterval,omitempty"`  // интервал проверки связи (default: интервал опроса)
 }
 
 // UIConfig описывает настройки UI
@@ -84,6 +98,153 @@ type ControlConfig struct {
 	Timeout time.Duration `yaml:"timeout,omitempty"` // таймаут неактивности (default: 60s)
 }
 
+// HistoryFilterConfig описывает сохранение истории только при изменении значения.
+// Незаданные (nil) поля наследуются от глобальных настроек.
+type HistoryFilterConfig struct {
+	ChangeOnly      *bool          `yaml:"changeOnly,omitempty"`      // сохранять только изменившиеся значения
+	Deadband        *float64       `yaml:"deadband,omitempty"`        // абсолютная зона нечувствительности для чисел
+	DeadbandPercent *float64       `yaml:"deadbandPercent,omitempty"` // зона нечувствительности в % от последнего сохранённого значения
+	Heartbeat       *time.Duration `yaml:"heartbeat,omitempty"`       // период принудительного сохранения (0 = выключено)
+}
+
+// HistoryConfig описывает настройки сохранения истории объектов
+type HistoryConfig struct {
+	HistoryFilterConfig `yaml:",inline"`
+	Objects             map[string]HistoryFilterConfig `yaml:"objects,omitempty"` // переопределения для отдельных объектов
+}
+
+// merge возвращает настройки, в которых заданные поля override заменяют поля f
+func (f HistoryFilterConfig) merge(override HistoryFilterConfig) HistoryFilterConfig {
+	if override.ChangeOnly != nil {
+		f.ChangeOnly = override.ChangeOnly
+	}
+	if override.Deadband != nil {
+		f.Deadband = override.Deadband
+	}
+	if override.DeadbandPercent != nil {
+		f.DeadbandPercent = override.DeadbandPercent
+	}
+	if override.Heartbeat != nil {
+		f.Heartbeat = override.Heartbeat
+	}
+	return f
+}
+
+// ForObject возвращает настройки для объекта с учётом глобальных значений
+func (h *HistoryConfig) ForObject(objectName string) HistoryFilterConfig {
+	if h == nil {
+		return HistoryFilterConfig{}
+	}
+	return h.HistoryFilterConfig.merge(h.Objects[objectName])
+}
+
+// GetChangeOnly возвращает значение с учётом default (false)
+func (f HistoryFilterConfig) GetChangeOnly() bool {
+	return f.ChangeOnly != nil && *f.ChangeOnly
+}
+
+// GetDeadband возвращает абсолютную зону нечувствительности с default (0)
+func (f HistoryFilterConfig) GetDeadband() float64 {
+	if f.Deadband == nil || *f.Deadband < 0 {
+		return 0
+	}
+	return *f.Deadband
+}
+
+// GetDeadbandPercent возвращает относительную зону нечувствительности с default (0)
+func (f HistoryFilterConfig) GetDeadbandPercent() float64 {
+	if f.DeadbandPercent == nil || *f.DeadbandPercent < 0 {
+		return 0
+	}
+	return *f.DeadbandPercent
+}
+
+// GetHeartbeat возвращает период принудительного сохранения с default (0)
+func (f HistoryFilterConfig) GetHeartbeat() time.Duration {
+	if f.Heartbeat == nil || *f.Heartbeat < 0 {
+		return 0
+	}
+	return *f.Heartbeat
+}
+
+// RetentionRuleConfig правило хранения истории для рядов, подходящих под glob-шаблоны
+type RetentionRuleConfig struct {
+	Server   string        `yaml:"server,omitempty"`   // шаблон ID сервера (default: *)
+	Object   string        `yaml:"object,omitempty"`   // шаблон имени объекта (default: *)
+	Variable string        `yaml:"variable,omitempty"` // шаблон имени переменной (default: *)
+	TTL      time.Duration `yaml:"ttl"`                // время хранения
+}
+
+// AlarmRuleConfig пороговое правило тревоги для значений, подходящих под glob-шаблоны
+type AlarmRuleConfig struct {
+	Name       string        `yaml:"name"`                 // уникальное имя правила
+	Source     string        `yaml:"source,omitempty"`     // object, ionc, modbus, opcua, uwsgate, sm, virtual (default: любой)
+	Server     string        `yaml:"server,omitempty"`     // шаблон ID сервера (default: *)
+	Object     string        `yaml:"object,omitempty"`     // шаблон имени объекта (default: *)
+	Sensor     string        `yaml:"sensor"`               // шаблон имени датчика/регистра/переменной
+	Message    string        `yaml:"message,omitempty"`    // текст тревоги
+	HiHi       *float64      `yaml:"hihi,omitempty"`       // аварийно высокий
+	Hi         *float64      `yaml:"hi,omitempty"`         // предупредительно высокий
+	Lo         *float64      `yaml:"lo,omitempty"`         // предупредительно низкий
+	LoLo       *float64      `yaml:"lolo,omitempty"`       // аварийно низкий
+	Hysteresis float64       `yaml:"hysteresis,omitempty"` // зона возврата от порога
+	OnDelay    time.Duration `yaml:"onDelay,omitempty"`    // задержка поднятия
+	OffDelay   time.Duration `yaml:"offDelay,omitempty"`   // задержка снятия
+}
+
+// StalenessRuleConfig окно устаревания значений датчиков, подходящих под glob-шаблоны.
+// Датчик считается устаревшим, если время источника (tv_sec / sm_tv_sec) не менялось дольше window.
+type StalenessRuleConfig struct {
+	Source string        `yaml:"source,omitempty"` // ionc, uwsgate (default: любой)
+	Server string        `yaml:"server,omitempty"` // шаблон ID сервера (default: *)
+	Object string        `yaml:"object,omitempty"` // шаблон имени объекта (default: *)
+	Sensor string        `yaml:"sensor"`           // шаблон имени датчика
+	Window time.Duration `yaml:"window"`           // допустимое время без обновления
+}
+
+// NotificationConfig канал уведомлений о событиях (webhook, smtp, syslog)
+type NotificationConfig struct {
+	Type       string        `yaml:"type"`                 // webhook, smtp, syslog
+	Events     []string      `yaml:"events,omitempty"`     // server_disconnected, server_connected, alarm_raised, alarm_cleared, control_taken (default: все)
+	RateLimit  int           `yaml:"rateLimit,omitempty"`  // макс. уведомлений за ratePeriod (0 = без ограничения)
+	RatePeriod time.Duration `yaml:"ratePeriod,omitempty"` // окно ограничения (default: 1m)
+
+	// webhook
+	URL     string            `yaml:"url,omitempty"`     // адрес для JSON POST
+	Headers map[string]string `yaml:"headers,omitempty"` // дополнительные заголовки
+	Retries int               `yaml:"retries,omitempty"` // повторов при ошибке сети или 5xx
+
+	// smtp и syslog
+	Addr string `yaml:"addr,omitempty"` // host:port
+
+	// smtp
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+
+	// syslog
+	Network string `yaml:"network,omitempty"` // udp (default) или tcp
+	AppName string `yaml:"appName,omitempty"` // APP-NAME (default: uniset-panel)
+}
+
+// VirtualSensorConfig виртуальный датчик, вычисляемый по выражению над другими источниками
+type VirtualSensorConfig struct {
+	Name       string                        `yaml:"name"`             // уникальное имя датчика
+	Object     string                        `yaml:"object,omitempty"` // объект для публикации и истории (default: Virtual)
+	Expression string                        `yaml:"expression"`       // выражение над именами входов
+	Inputs     map[string]VirtualInputConfig `yaml:"inputs"`           // имя в выражении -> источник
+}
+
+// VirtualInputConfig вход виртуального датчика
+type VirtualInputConfig struct {
+	Source string `yaml:"source"`           // object, ionc, modbus, opcua, uwsgate, sm
+	Server string `yaml:"server,omitempty"` // ID сервера (default: любой)
+	Object string `yaml:"object"`           // имя объекта
+	Sensor string `yaml:"sensor"`           // имя датчика/регистра/переменной
+	ID     int64  `yaml:"id,omitempty"`     // ID для подписки ionc/modbus/opcua (default: по имени из --uniset-config)
+}
+
 // stringSlice реализует flag.Value для множественных строковых флагов
 type stringSlice []string
 
@@ -106,11 +267,32 @@ type Config struct {
 	// Настройки стриминга логов
 	LogStream *LogStreamConfig
 
+	// Настройки сохранения истории (change-only, deadband, heartbeat)
+	History *HistoryConfig
+
+	// Правила хранения истории (первое подошедшее, иначе HistoryTTL)
+	Retention []RetentionRuleConfig
+
+	// Пороговые правила тревог
+	Alarms []AlarmRuleConfig
+
+	// Окна устаревания значений (первое подходящее правило)
+	Staleness []StalenessRuleConfig
+
+	// Каналы уведомлений о событиях
+	Notifications []NotificationConfig
+
+	// Виртуальные датчики (вычисляемые по выражениям)
+	VirtualSensors []VirtualSensorConfig
+
 	Addr            string // адрес для прослушивания (формат: :port или host:port)
 	PollInterval    time.Duration
 	Storage         StorageType
 	SQLitePath      string
 	HistoryTTL      time.Duration
+	MemoryLimitMB   int64         // Лимит памяти хранилища memory в МБ (0 = без ограничения)
+	MemoryMaxPoints int           // Макс. точек в одном ряду хранилища memory (0 = без ограничения)
+	HotWindow       time.Duration // Окно хранения в памяти для хранилища tiered
 	LogFormat       string
 	LogLevel        string
 	ConFile         string
@@ -119,6 +301,7 @@ type Config struct {
 	SMPollInterval  time.Duration // Интервал опроса SM (0 = использовать PollInterval)
 	UnisetSupplier  string        // Имя поставщика для операций set/freeze/unfreeze
 	SensorBatchSize int           // Макс. количество датчиков в одном запросе (default: 300)
+	PollConcurrency int           // Количество объектов, опрашиваемых одновременно (default: 4)
 	ControlTokens   []string      // Токены для управления (пусто = управление для всех)
 	ControlTimeout  time.Duration // Таймаут неактивности контроллера (default: 60s)
 
@@ -127,6 +310,9 @@ type Config struct {
 	RecordingEnabled bool   // Запись включена по умолчанию (default: false)
 	MaxRecords       int64  // Макс. записей (циклический буфер, default: 1000000)
 
+	// AlarmHistoryPath путь к SQLite истории тревог (default: alarms.db рядом с файлом записи)
+	AlarmHistoryPath string
+
 	// Dashboard settings
 	DashboardsDir string // Директория с серверными dashboard'ами (опционально)
 
@@ -159,6 +345,14 @@ func (c *Config) GetSensorBatchSize() int {
 	return c.SensorBatchSize
 }
 
+// GetPollConcurrency возвращает количество одновременно опрашиваемых объектов с default
+func (c *Config) GetPollConcurrency() int {
+	if c.PollConcurrency <= 0 {
+		return 4
+	}
+	return c.PollConcurrency
+}
+
 // GetMaxRecords возвращает максимальное количество записей с default
 func (c *Config) GetMaxRecords() int64 {
 	if c.MaxRecords <= 0 {
@@ -175,6 +369,14 @@ func (c *Config) GetRecordingPath() string {
 	return c.RecordingPath
 }
 
+// GetAlarmHistoryPath возвращает путь к истории тревог: по умолчанию alarms.db рядом с файлом записи
+func (c *Config) GetAlarmHistoryPath() string {
+	if c.AlarmHistoryPath != "" {
+		return c.AlarmHistoryPath
+	}
+	return filepath.Join(filepath.Dir(c.GetRecordingPath()), "alarms.db")
+}
+
 func Parse() *Config {
 	cfg := &Config{}
 
@@ -188,10 +390,13 @@ func Parse() *Config {
 	flag.DurationVar(&cfg.PollInterval, "poll-interval", 1*time.Second, "UniSet2 polling interval")
 
 	var storageStr string
-	flag.StringVar(&storageStr, "storage", "memory", "Storage type: memory or sqlite")
+	flag.StringVar(&storageStr, "storage", "memory", "Storage type: memory, sqlite or tiered")
 
 	flag.StringVar(&cfg.SQLitePath, "sqlite-path", "./history.db", "SQLite database path")
 	flag.DurationVar(&cfg.HistoryTTL, "history-ttl", time.Hour, "History retention time")
+	flag.Int64Var(&cfg.MemoryLimitMB, "memory-limit", 0, "Memory storage budget in MB (0 = unlimited)")
+	flag.IntVar(&cfg.MemoryMaxPoints, "memory-max-points", 0, "Max points per series in memory storage (0 = unlimited)")
+	flag.DurationVar(&cfg.HotWindow, "hot-window", 15*time.Minute, "Recent history kept in memory for tiered storage")
 	flag.StringVar(&cfg.LogFormat, "log-format", "text", "Log format: text or json")
 	flag.StringVar(&cfg.LogLevel, "log-level", "warn", "Log level: debug, info, warn, error")
 	flag.StringVar(&cfg.ConFile, "uniset-config", "", "UniSet2 XML configuration file (sensors metadata)")
@@ -200,6 +405,7 @@ func Parse() *Config {
 	flag.DurationVar(&cfg.SMPollInterval, "sm-poll-interval", 0, "SharedMemory polling interval (0 = use poll-interval)")
 	flag.StringVar(&cfg.UnisetSupplier, "uniset-supplier", "TestProc", "UniSet2 supplier name for set/freeze/unfreeze operations")
 	flag.IntVar(&cfg.SensorBatchSize, "sensor-batch-size", 300, "Max sensors per request to UniSet2 (default: 300)")
+	flag.IntVar(&cfg.PollConcurrency, "poll-concurrency", 4, "Max objects polled concurrently per server (default: 4)")
 	flag.Var(&controlTokens, "control-token", "Control token for write access (can be specified multiple times, empty = allow all)")
 	flag.DurationVar(&cfg.ControlTimeout, "control-timeout", 60*time.Second, "Control session timeout (default: 60s)")
 
@@ -207,6 +413,7 @@ func Parse() *Config {
 	flag.StringVar(&cfg.RecordingPath, "recording-path", "./recording.db", "Recording SQLite database path")
 	flag.BoolVar(&cfg.RecordingEnabled, "recording-enabled", false, "Start recording on startup")
 	flag.Int64Var(&cfg.MaxRecords, "max-records", 1000000, "Max records in recording database (circular buffer)")
+	flag.StringVar(&cfg.AlarmHistoryPath, "alarm-history-path", "", "Alarm history SQLite database path (default: alarms.db next to recording-path)")
 
 	// Dashboard flags
 	flag.StringVar(&cfg.DashboardsDir, "dashboards-dir", "", "Directory with server dashboards (optional)")
@@ -220,7 +427,7 @@ func Parse() *Config {
 	cfg.JournalURLs = journalURLs
 
 	cfg.Storage = StorageType(storageStr)
-	if cfg.Storage != StorageMemory && cfg.Storage != StorageSQLite {
+	if cfg.Storage != StorageMemory && cfg.Storage != StorageSQLite && cfg.Storage != StorageTiered {
 		cfg.Storage = StorageMemory
 	}
 
@@ -233,9 +440,18 @@ func Parse() *Config {
 			cfg.Servers = yamlConfig.Servers
 			cfg.UI = yamlConfig.UI
 			cfg.LogStream = yamlConfig.LogStream
+			cfg.History = yamlConfig.History
+			cfg.Retention = yamlConfig.Retention
+			cfg.Alarms = yamlConfig.Alarms
+			cfg.Staleness = yamlConfig.Staleness
+			cfg.Notifications = yamlConfig.Notifications
+			cfg.VirtualSensors = yamlConfig.VirtualSensors
 			if yamlConfig.SensorBatchSize > 0 {
 				cfg.SensorBatchSize = yamlConfig.SensorBatchSize
 			}
+			if yamlConfig.PollConcurrency > 0 {
+				cfg.PollConcurrency = yamlConfig.PollConcurrency
+			}
 			// Control settings from YAML
 			if yamlConfig.Control != nil {
 				cfg.ControlTokens = append(cfg.ControlTokens, yamlConfig.Control.Tokens...)
@@ -220,6 +220,28 @@ func TestConfigGetRecordingPath(t *testing.T) {
 	}
 }
 
+func TestConfigGetAlarmHistoryPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		recording string
+		path      string
+		expected  string
+	}{
+		{"default", "", "", "alarms.db"},
+		{"next to recording", "/data/recording.db", "", "/data/alarms.db"},
+		{"custom", "/data/recording.db", "/var/lib/alarms.db", "/var/lib/alarms.db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{RecordingPath: tt.recording, AlarmHistoryPath: tt.path}
+			if got := cfg.GetAlarmHistoryPath(); got != tt.expected {
+				t.Errorf("GetAlarmHistoryPath() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestParseLogLevel(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -443,3 +465,11 @@ func TestJournalConfigFields(t *testing.T) {
 		t.Errorf("Database = %q, want custom", cfg.Database)
 	}
 }
+
+func TestHistoryConfigForObjectNil(t *testing.T) {
+	var h *HistoryConfig
+	f := h.ForObject("Obj")
+	if f.GetChangeOnly() || f.GetDeadband() != 0 || f.GetDeadbandPercent() != 0 || f.GetHeartbeat() != 0 {
+		t.Errorf("nil HistoryConfig should give defaults, got %+v", f)
+	}
+}
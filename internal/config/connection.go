@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultServerTimeout таймаут запроса к серверу UniSet2 по умолчанию
+const DefaultServerTimeout = 10 * time.Second
+
+// ServerAuthConfig описывает авторизацию на сервере (basic auth или bearer token)
+type ServerAuthConfig struct {
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Token    string `yaml:"token,omitempty"` // bearer token (взаимоисключающий с username/password)
+}
+
+// ServerTLSConfig описывает настройки TLS подключения к серверу
+type ServerTLSConfig struct {
+	CAFile             string `yaml:"caFile,omitempty"`   // PEM-файл с сертификатами доверенных CA
+	CertFile           string `yaml:"certFile,omitempty"` // клиентский сертификат (mTLS)
+	KeyFile            string `yaml:"keyFile,omitempty"`  // ключ клиентского сертификата (mTLS)
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+// GetTimeout возвращает таймаут запроса с default (10s)
+func (s ServerConfig) GetTimeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DefaultServerTimeout
+	}
+	return s.Timeout
+}
+
+// RequestHeader возвращает заголовки, добавляемые к каждому запросу (включая Authorization)
+func (s ServerConfig) RequestHeader() http.Header {
+	header := make(http.Header, len(s.Headers)+1)
+	for name, value := range s.Headers {
+		header.Set(name, value)
+	}
+	if s.Auth != nil {
+		switch {
+		case s.Auth.Token != "":
+			header.Set("Authorization", "Bearer "+s.Auth.Token)
+		case s.Auth.Username != "":
+			credentials := s.Auth.Username + ":" + s.Auth.Password
+			header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+		}
+	}
+	return header
+}
+
+// TLSClientConfig возвращает настройки TLS (nil, если TLS не настроен)
+func (s ServerConfig) TLSClientConfig() (*tls.Config, error) {
+	if s.TLS == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: s.TLS.InsecureSkipVerify,
+	}
+
+	if s.TLS.CAFile != "" {
+		pem, err := os.ReadFile(s.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", s.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if s.TLS.CertFile != "" || s.TLS.KeyFile != "" {
+		if s.TLS.CertFile == "" || s.TLS.KeyFile == "" {
+			return nil, fmt.Errorf("both certFile and keyFile are required for client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(s.TLS.CertFile, s.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// HTTPClient создаёт HTTP-клиент с авторизацией, заголовками, TLS и таймаутом сервера
+func (s ServerConfig) HTTPClient() (*http.Client, error) {
+	tlsConfig, err := s.TLSClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var rt http.RoundTripper = transport
+	if header := s.RequestHeader(); len(header) > 0 {
+		rt = &headerTransport{header: header, next: transport}
+	}
+
+	return &http.Client{
+		Transport: rt,
+		Timeout:   s.GetTimeout(),
+	}, nil
+}
+
+// ValidateConnection проверяет настройки авторизации и TLS
+func (s ServerConfig) ValidateConnection() error {
+	if s.Auth != nil && s.Auth.Token != "" && s.Auth.Username != "" {
+		return fmt.Errorf("auth: token and username are mutually exclusive")
+	}
+	if _, err := s.TLSClientConfig(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	return nil
+}
+
+// SameHost проверяет, что rawURL указывает на тот же хост (схема, хост и порт), что и сервер
+func (s ServerConfig) SameHost(rawURL string) bool {
+	a, err := url.Parse(s.URL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return a.Scheme == b.Scheme && a.Host == b.Host
+}
+
+// ServerForURL возвращает сервер, расположенный на том же хосте, что и rawURL
+func (c *Config) ServerForURL(rawURL string) (ServerConfig, bool) {
+	for _, srv := range c.Servers {
+		if srv.SameHost(rawURL) {
+			return srv, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// headerTransport добавляет заголовки к каждому запросу
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.header {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	return t.next.RoundTrip(req)
+}
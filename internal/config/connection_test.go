@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServerConfigGetTimeout(t *testing.T) {
+	if got := (ServerConfig{}).GetTimeout(); got != DefaultServerTimeout {
+		t.Errorf("default timeout = %v, want %v", got, DefaultServerTimeout)
+	}
+	if got := (ServerConfig{Timeout: 3 * time.Second}).GetTimeout(); got != 3*time.Second {
+		t.Errorf("timeout = %v, want 3s", got)
+	}
+}
+
+func TestServerConfigRequestHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ServerConfig
+		want string
+	}{
+		{"no auth", ServerConfig{}, ""},
+		{"basic", ServerConfig{Auth: &ServerAuthConfig{Username: "user", Password: "pass"}}, "Basic dXNlcjpwYXNz"},
+		{"bearer", ServerConfig{Auth: &ServerAuthConfig{Token: "secret"}}, "Bearer secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.RequestHeader().Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	cfg := ServerConfig{Headers: map[string]string{"x-plant": "north"}}
+	if got := cfg.RequestHeader().Get("X-Plant"); got != "north" {
+		t.Errorf("X-Plant = %q, want north", got)
+	}
+}
+
+func TestServerConfigHTTPClient(t *testing.T) {
+	var gotAuth, gotPlant string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotPlant = r.Header.Get("X-Plant")
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ServerConfig{
+		URL:     srv.URL,
+		Auth:    &ServerAuthConfig{Token: "secret"},
+		Headers: map[string]string{"X-Plant": "north"},
+		TLS:     &ServerTLSConfig{CAFile: caFile},
+		Timeout: 5 * time.Second,
+	}
+	client, err := cfg.HTTPClient()
+	if err != nil {
+		t.Fatalf("HTTPClient failed: %v", err)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("client timeout = %v, want 5s", client.Timeout)
+	}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with custom CA failed: %v", err)
+	}
+	resp.Body.Close()
+	if gotAuth != "Bearer secret" || gotPlant != "north" {
+		t.Errorf("headers not applied: Authorization=%q X-Plant=%q", gotAuth, gotPlant)
+	}
+
+	// Без CA сертификат сервера не проходит проверку
+	plain, _ := ServerConfig{URL: srv.URL}.HTTPClient()
+	if _, err := plain.Get(srv.URL); err == nil {
+		t.Error("expected certificate verification error without CA")
+	}
+
+	insecure, _ := ServerConfig{URL: srv.URL, TLS: &ServerTLSConfig{InsecureSkipVerify: true}}.HTTPClient()
+	resp, err = insecure.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("insecureSkipVerify request failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestServerConfigValidateConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ServerConfig
+		wantErr bool
+	}{
+		{"empty", ServerConfig{}, false},
+		{"token and username", ServerConfig{Auth: &ServerAuthConfig{Username: "u", Token: "t"}}, true},
+		{"missing CA file", ServerConfig{TLS: &ServerTLSConfig{CAFile: "/nonexistent/ca.pem"}}, true},
+		{"cert without key", ServerConfig{TLS: &ServerTLSConfig{CertFile: "client.pem"}}, true},
+		{"insecure only", ServerConfig{TLS: &ServerTLSConfig{InsecureSkipVerify: true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.ValidateConnection()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConnection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigServerForURL(t *testing.T) {
+	cfg := &Config{Servers: []ServerConfig{
+		{ID: "a", URL: "http://host-a:8080"},
+		{ID: "b", URL: "https://host-b:8443/api"},
+	}}
+
+	if srv, ok := cfg.ServerForURL("https://host-b:8443"); !ok || srv.ID != "b" {
+		t.Errorf("expected server b, got %q (%v)", srv.ID, ok)
+	}
+	if _, ok := cfg.ServerForURL("http://host-b:8443"); ok {
+		t.Error("scheme mismatch must not match")
+	}
+	if _, ok := cfg.ServerForURL("http://host-c:8080"); ok {
+		t.Error("unknown host must not match")
+	}
+}
+
+func TestLoadFromYAML_ServerConnection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yamlContent := `
+servers:
+  - url: https://plant:8443
+    auth:
+      username: panel
+      password: secret
+    headers:
+      X-Plant: north
+    tls:
+      insecureSkipVerify: true
+    timeout: 3s
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	srv := cfg.Servers[0]
+	if srv.Auth == nil || srv.Auth.Username != "panel" || srv.Auth.Password != "secret" {
+		t.Errorf("unexpected auth: %+v", srv.Auth)
+	}
+	if srv.Headers["X-Plant"] != "north" {
+		t.Errorf("unexpected headers: %v", srv.Headers)
+	}
+	if srv.TLS == nil || !srv.TLS.InsecureSkipVerify {
+		t.Errorf("unexpected tls: %+v", srv.TLS)
+	}
+	if srv.Timeout != 3*time.Second {
+		t.Errorf("timeout = %v, want 3s", srv.Timeout)
+	}
+
+	invalid := `
+servers:
+  - url: https://plant:8443
+    tls:
+      caFile: /nonexistent/ca.pem
+`
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if _, err := LoadFromYAML(configPath); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
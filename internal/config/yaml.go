@@ -17,12 +17,19 @@ type JournalConfig struct {
 
 // ConfigFile представляет структуру YAML файла конфигурации
 type ConfigFile struct {
-	Servers         []ServerConfig   `yaml:"servers"`
-	UI              *UIConfig        `yaml:"ui,omitempty"`
-	LogStream       *LogStreamConfig `yaml:"logStream,omitempty"`
-	SensorBatchSize int              `yaml:"sensorBatchSize,omitempty"` // Макс. датчиков в одном запросе (default: 300)
-	Control         *ControlConfig   `yaml:"control,omitempty"`         // Настройки контроля доступа
-	Journals        []JournalConfig  `yaml:"journals,omitempty"`        // Журналы сообщений (ClickHouse)
+	Servers         []ServerConfig        `yaml:"servers"`
+	UI              *UIConfig             `yaml:"ui,omitempty"`
+	LogStream       *LogStreamConfig      `yaml:"logStream,omitempty"`
+	SensorBatchSize int                   `yaml:"sensorBatchSize,omitempty"` // Макс. датчиков в одном запросе (default: 300)
+	PollConcurrency int                   `yaml:"pollConcurrency,omitempty"` // Объектов, опрашиваемых одновременно (default: 4)
+	Control         *ControlConfig        `yaml:"control,omitempty"`         // Настройки контроля доступа
+	Journals        []JournalConfig       `yaml:"journals,omitempty"`        // Журналы сообщений (ClickHouse)
+	History         *HistoryConfig        `yaml:"history,omitempty"`         // Сохранение истории только при изменениях
+	Retention       []RetentionRuleConfig `yaml:"retention,omitempty"`       // Правила хранения истории
+	Alarms          []AlarmRuleConfig     `yaml:"alarms,omitempty"`          // Пороговые правила тревог
+	Staleness       []StalenessRuleConfig `yaml:"staleness,omitempty"`       // Окна устаревания значений датчиков
+	Notifications   []NotificationConfig  `yaml:"notifications,omitempty"`   // Каналы уведомлений о событиях
+	VirtualSensors  []VirtualSensorConfig `yaml:"virtualSensors,omitempty"`  // Виртуальные датчики (выражения)
 }
 
 // LoadFromYAML загружает полную конфигурацию из YAML файла
@@ -37,11 +44,14 @@ func LoadFromYAML(path string) (*ConfigFile, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	// Валидация: каждый сервер должен иметь URL
+	// Валидация: каждый сервер должен иметь URL и корректные настройки подключения
 	for i, srv := range configFile.Servers {
 		if srv.URL == "" {
 			return nil, fmt.Errorf("server at index %d has no URL", i)
 		}
+		if err := srv.ValidateConnection(); err != nil {
+			return nil, fmt.Errorf("server at index %d: %w", i, err)
+		}
 	}
 
 	return &configFile, nil
@@ -214,3 +214,227 @@ journals:
 		t.Errorf("expected database custom_db, got %s", cfg.Journals[0].Database)
 	}
 }
+
+func TestLoadFromYAML_WithHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+servers:
+  - url: http://localhost:9090
+
+history:
+  changeOnly: true
+  deadband: 0.5
+  heartbeat: 60s
+  objects:
+    Boiler:
+      deadbandPercent: 2
+      heartbeat: 10s
+    Logger:
+      changeOnly: false
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if cfg.History == nil {
+		t.Fatal("expected History to be set")
+	}
+
+	global := cfg.History.ForObject("Unknown")
+	if !global.GetChangeOnly() || global.GetDeadband() != 0.5 || global.GetHeartbeat() != time.Minute {
+		t.Errorf("unexpected global settings: changeOnly=%v deadband=%v heartbeat=%v",
+			global.GetChangeOnly(), global.GetDeadband(), global.GetHeartbeat())
+	}
+
+	boiler := cfg.History.ForObject("Boiler")
+	if !boiler.GetChangeOnly() {
+		t.Error("Boiler should inherit changeOnly=true")
+	}
+	if boiler.GetDeadband() != 0.5 {
+		t.Errorf("Boiler should inherit deadband 0.5, got %v", boiler.GetDeadband())
+	}
+	if boiler.GetDeadbandPercent() != 2 {
+		t.Errorf("expected Boiler deadbandPercent 2, got %v", boiler.GetDeadbandPercent())
+	}
+	if boiler.GetHeartbeat() != 10*time.Second {
+		t.Errorf("expected Boiler heartbeat 10s, got %v", boiler.GetHeartbeat())
+	}
+
+	if cfg.History.ForObject("Logger").GetChangeOnly() {
+		t.Error("Logger should override changeOnly=false")
+	}
+}
+
+func TestLoadFromYAML_WithAlarms(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+servers:
+  - url: http://localhost:9090
+
+alarms:
+  - name: boiler-pressure
+    source: ionc
+    object: SharedMemory
+    sensor: "AI_Pressure*"
+    hihi: 10
+    hi: 8
+    lo: 2
+    hysteresis: 0.5
+    onDelay: 5s
+    offDelay: 2s
+    message: Boiler pressure
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if len(cfg.Alarms) != 1 {
+		t.Fatalf("expected 1 alarm rule, got %d", len(cfg.Alarms))
+	}
+
+	rule := cfg.Alarms[0]
+	if rule.Name != "boiler-pressure" || rule.Source != "ionc" || rule.Sensor != "AI_Pressure*" {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if rule.HiHi == nil || *rule.HiHi != 10 || rule.Hi == nil || *rule.Hi != 8 || rule.Lo == nil || *rule.Lo != 2 {
+		t.Errorf("unexpected limits: %+v", rule)
+	}
+	if rule.LoLo != nil {
+		t.Error("lolo should not be set")
+	}
+	if rule.Hysteresis != 0.5 || rule.OnDelay != 5*time.Second || rule.OffDelay != 2*time.Second {
+		t.Errorf("unexpected hysteresis/delays: %+v", rule)
+	}
+}
+
+func TestLoadFromYAML_WithStaleness(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+servers:
+  - url: http://localhost:9090
+
+staleness:
+  - sensor: AI_Pressure1_AS
+    window: 5s
+  - source: ionc
+    object: SharedMemory
+    sensor: "AI*"
+    window: 1m
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if len(cfg.Staleness) != 2 {
+		t.Fatalf("expected 2 staleness rules, got %d", len(cfg.Staleness))
+	}
+	if rule := cfg.Staleness[0]; rule.Sensor != "AI_Pressure1_AS" || rule.Window != 5*time.Second {
+		t.Errorf("unexpected first rule: %+v", rule)
+	}
+	if rule := cfg.Staleness[1]; rule.Source != "ionc" || rule.Object != "SharedMemory" || rule.Window != time.Minute {
+		t.Errorf("unexpected second rule: %+v", rule)
+	}
+}
+
+func TestLoadFromYAML_WithNotifications(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+servers:
+  - url: http://localhost:9090
+
+notifications:
+  - type: webhook
+    url: https://hooks.example.com/panel
+    headers:
+      Authorization: Bearer xxx
+    retries: 3
+    events: [server_disconnected, alarm_raised]
+    rateLimit: 10
+    ratePeriod: 5m
+  - type: syslog
+    network: tcp
+    addr: logs.example.com:514
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if len(cfg.Notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(cfg.Notifications))
+	}
+
+	webhook := cfg.Notifications[0]
+	if webhook.Type != "webhook" || webhook.URL != "https://hooks.example.com/panel" || webhook.Retries != 3 ||
+		webhook.Headers["Authorization"] != "Bearer xxx" {
+		t.Errorf("unexpected webhook: %+v", webhook)
+	}
+	if len(webhook.Events) != 2 || webhook.RateLimit != 10 || webhook.RatePeriod != 5*time.Minute {
+		t.Errorf("unexpected routing: %+v", webhook)
+	}
+	if syslog := cfg.Notifications[1]; syslog.Network != "tcp" || syslog.Addr != "logs.example.com:514" {
+		t.Errorf("unexpected syslog: %+v", syslog)
+	}
+}
+
+func TestLoadFromYAML_WithVirtualSensors(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+servers:
+  - url: http://localhost:9090
+
+virtualSensors:
+  - name: DeltaP
+    expression: p1 - p2
+    inputs:
+      p1: {source: ionc, object: SharedMemory, sensor: AI_P1_AS}
+      p2: {source: modbus, server: s2, object: MBMaster1, sensor: AI_P2_AS, id: 42}
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if len(cfg.VirtualSensors) != 1 {
+		t.Fatalf("expected 1 virtual sensor, got %d", len(cfg.VirtualSensors))
+	}
+
+	vs := cfg.VirtualSensors[0]
+	if vs.Name != "DeltaP" || vs.Expression != "p1 - p2" || len(vs.Inputs) != 2 {
+		t.Errorf("unexpected virtual sensor: %+v", vs)
+	}
+	if p1 := vs.Inputs["p1"]; p1.Source != "ionc" || p1.Object != "SharedMemory" || p1.Sensor != "AI_P1_AS" {
+		t.Errorf("unexpected input p1: %+v", p1)
+	}
+	if p2 := vs.Inputs["p2"]; p2.Source != "modbus" || p2.Server != "s2" || p2.ID != 42 {
+		t.Errorf("unexpected input p2: %+v", p2)
+	}
+}
@@ -0,0 +1,30 @@
+// Package glob сопоставляет имена серверов, объектов и датчиков с шаблонами
+// правил (синтаксис path.Match: *, ?, [...])
+package glob
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Match проверяет, подходит ли имя под шаблон. Некорректный шаблон ни с чем не совпадает.
+func Match(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// Validate проверяет синтаксис шаблонов
+func Validate(patterns ...string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Literal проверяет, что шаблон не содержит метасимволов и совпадает только с самим собой
+func Literal(pattern string) bool {
+	return !strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,46 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "SharedMemory", true},
+		{"Boiler*", "Boiler1", true},
+		{"Boiler?", "Boiler12", false},
+		{"[AB]_Temp", "B_Temp", true},
+		{"exact", "exact", true},
+		{"exact", "other", false},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("*", "Boiler?", "[AB]*"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Validate("*", "["); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	for pattern, want := range map[string]bool{
+		"SharedMemory": true,
+		"Temp*_AS":     false,
+		"Boiler?":      false,
+		"[AB]_Temp":    false,
+		`Esc\*`:        false,
+	} {
+		if got := Literal(pattern); got != want {
+			t.Errorf("Literal(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
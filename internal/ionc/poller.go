@@ -1,6 +1,7 @@
 package ionc
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -29,10 +30,10 @@ type ioncFetcher struct {
 	client *uniset.Client
 }
 
-func (f *ioncFetcher) FetchItems(objectName string, ids []int64) ([]uniset.IONCSensor, error) {
+func (f *ioncFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]uniset.IONCSensor, error) {
 	query := poller.BuildIDQuery(ids)
 
-	resp, err := f.client.GetIONCSensorValues(objectName, query)
+	resp, err := f.client.GetIONCSensorValuesContext(ctx, objectName, query)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +46,9 @@ func (f *ioncFetcher) GetItemID(sensor uniset.IONCSensor) int64 {
 }
 
 func (f *ioncFetcher) GetValueHash(sensor uniset.IONCSensor) string {
-	// Хеш включает Value и RealValue - чтобы обновлять и при изменении real_value (для замороженных датчиков)
-	return fmt.Sprintf("%d|%d", sensor.Value, sensor.RealValue)
+	// Хеш включает Value и RealValue - чтобы обновлять и при изменении real_value (для замороженных датчиков),
+	// и время изменения - чтобы watchdog устаревания видел, что источник жив
+	return fmt.Sprintf("%d|%d|%d.%d", sensor.Value, sensor.RealValue, sensor.TVSec, sensor.TVNsec)
 }
 
 // NewPoller создает новый IONC poller
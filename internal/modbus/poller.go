@@ -1,6 +1,7 @@
 package modbus
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"time"
@@ -30,12 +31,12 @@ type modbusFetcher struct {
 	client *uniset.Client
 }
 
-func (f *modbusFetcher) FetchItems(objectName string, ids []int64) ([]uniset.MBRegister, error) {
+func (f *modbusFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]uniset.MBRegister, error) {
 	query := poller.BuildIDQuery(ids)
 
 	slog.Debug("Modbus polling registers", "object", objectName, "ids_count", len(ids), "query_length", len(query))
 
-	resp, err := f.client.GetMBRegisterValues(objectName, query)
+	resp, err := f.client.GetMBRegisterValuesContext(ctx, objectName, query)
 	if err != nil {
 		slog.Error("Modbus GetMBRegisterValues failed", "object", objectName, "error", err)
 		return nil, err
@@ -0,0 +1,317 @@
+// Package notify рассылает события панели (потеря связи с сервером, тревоги,
+// захват управления) во внешние каналы: webhook, email, syslog
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/logger"
+)
+
+// Типы событий уведомлений
+const (
+	EventServerDisconnected = "server_disconnected" // потеря связи с сервером
+	EventServerConnected    = "server_connected"    // связь восстановлена
+	EventAlarmRaised        = "alarm_raised"        // тревога поднята или сменила уровень
+	EventAlarmCleared       = "alarm_cleared"       // тревога снята
+	EventControlTaken       = "control_taken"       // захвачено управление
+
+	// EventSuppressed сводка об отброшенных ограничением событиях; отправляется
+	// маршруту по окончании окна независимо от его фильтра events
+	EventSuppressed = "notifications_suppressed"
+)
+
+// knownEvents типы событий, допустимые в маршрутах
+var knownEvents = map[string]bool{
+	EventServerDisconnected: true,
+	EventServerConnected:    true,
+	EventAlarmRaised:        true,
+	EventAlarmCleared:       true,
+	EventControlTaken:       true,
+}
+
+// Severity важность события (для syslog и темы письма)
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Event событие для внешних каналов
+type Event struct {
+	Type       string      `json:"type"`
+	Severity   Severity    `json:"severity"`
+	Title      string      `json:"title"` // кратко (тема письма)
+	Message    string      `json:"message"`
+	ServerID   string      `json:"serverId,omitempty"`
+	Object     string      `json:"object,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Suppressed int         `json:"suppressed,omitempty"` // сколько событий маршрута отброшено ограничением перед этим
+}
+
+// Notifier канал доставки событий
+type Notifier interface {
+	// Name имя канала для логов
+	Name() string
+	// Notify доставляет событие (с повторами, если канал их поддерживает)
+	Notify(ctx context.Context, event Event) error
+}
+
+// Route направляет события в канал
+type Route struct {
+	Notifier   Notifier
+	Events     []string      // типы событий (пусто = все)
+	RateLimit  int           // макс. уведомлений за RatePeriod (0 = без ограничения)
+	RatePeriod time.Duration // окно ограничения (default: 1m)
+	Timeout    time.Duration // таймаут доставки одного события (default: 30s)
+}
+
+const (
+	defaultRatePeriod  = time.Minute
+	defaultTimeout     = 30 * time.Second
+	routeQueueCapacity = 100
+)
+
+// limiter ограничивает число уведомлений в фиксированном окне
+type limiter struct {
+	limit       int
+	period      time.Duration
+	windowStart time.Time
+	count       int
+	suppressed  int
+}
+
+// allow проверяет, можно ли отправить событие; при разрешении возвращает
+// число отброшенных с прошлой отправки событий
+func (l *limiter) allow(now time.Time) (bool, int) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+	if now.Sub(l.windowStart) >= l.period {
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.limit {
+		l.suppressed++
+		return false, 0
+	}
+	l.count++
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return true, suppressed
+}
+
+// rollover возвращает число отброшенных событий, если окно, в котором их отбросили,
+// закончилось к now. Сводка открывает новое окно и занимает в нём место.
+func (l *limiter) rollover(now time.Time) int {
+	if l.suppressed == 0 || now.Sub(l.windowStart) < l.period {
+		return 0
+	}
+	l.windowStart = now
+	l.count = 1
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return suppressed
+}
+
+// windowEnd время окончания текущего окна
+func (l *limiter) windowEnd() time.Time {
+	return l.windowStart.Add(l.period)
+}
+
+type route struct {
+	Route
+	events map[string]bool
+
+	mu      sync.Mutex
+	limiter limiter
+	flush   *time.Timer // сводка об отброшенных событиях по окончании окна
+
+	queue chan Event
+}
+
+func (r *route) accepts(eventType string) bool {
+	return len(r.events) == 0 || r.events[eventType]
+}
+
+// Dispatcher рассылает события по маршрутам. Каждый маршрут доставляет события
+// в своей горутине, чтобы медленный канал не задерживал остальные и источник событий.
+type Dispatcher struct {
+	routes []*route
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewDispatcher создаёт диспетчер маршрутов
+func NewDispatcher(routes []Route) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for i, r := range routes {
+		if r.Notifier == nil {
+			return nil, fmt.Errorf("notification route %d: notifier is required", i)
+		}
+		if r.RateLimit < 0 {
+			return nil, fmt.Errorf("notification route %d: rateLimit must not be negative", i)
+		}
+		if r.RatePeriod <= 0 {
+			r.RatePeriod = defaultRatePeriod
+		}
+		if r.Timeout <= 0 {
+			r.Timeout = defaultTimeout
+		}
+		rt := &route{
+			Route:   r,
+			events:  make(map[string]bool, len(r.Events)),
+			limiter: limiter{limit: r.RateLimit, period: r.RatePeriod},
+			queue:   make(chan Event, routeQueueCapacity),
+		}
+		for _, t := range r.Events {
+			if !knownEvents[t] {
+				return nil, fmt.Errorf("notification route %d: unknown event type %q", i, t)
+			}
+			rt.events[t] = true
+		}
+		d.routes = append(d.routes, rt)
+	}
+	return d, nil
+}
+
+// Start запускает доставку
+func (d *Dispatcher) Start() {
+	for _, r := range d.routes {
+		d.wg.Add(1)
+		go func(r *route) {
+			defer d.wg.Done()
+			for event := range r.queue {
+				ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+				if err := r.Notifier.Notify(ctx, event); err != nil {
+					logger.Warn("Notification failed", "notifier", r.Notifier.Name(), "type", event.Type, "error", err)
+				}
+				cancel()
+			}
+		}(r)
+	}
+	logger.Info("Notifications started", "routes", len(d.routes))
+}
+
+// Stop дожидается доставки уже поставленных в очередь событий
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	d.mu.Unlock()
+
+	for _, r := range d.routes {
+		// Отброшенные в последнем окне события сообщаются сводкой до остановки
+		r.mu.Lock()
+		if r.flush != nil {
+			r.flush.Stop()
+			r.flush = nil
+		}
+		suppressed := r.limiter.suppressed
+		r.limiter.suppressed = 0
+		r.mu.Unlock()
+		if suppressed > 0 {
+			r.enqueue(suppressedEvent(suppressed, time.Now()))
+		}
+		close(r.queue)
+	}
+	d.wg.Wait()
+}
+
+// Notify ставит событие в очередь подходящим маршрутам. Не блокирует:
+// при превышении ограничения или переполненной очереди событие отбрасывается.
+func (d *Dispatcher) Notify(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return
+	}
+
+	for _, r := range d.routes {
+		if !r.accepts(event.Type) {
+			continue
+		}
+
+		r.mu.Lock()
+		ok, suppressed := r.limiter.allow(event.Timestamp)
+		if !ok && r.flush == nil {
+			r.scheduleFlush(d, time.Until(r.limiter.windowEnd()))
+		}
+		r.mu.Unlock()
+		if !ok {
+			logger.Debug("Notification rate limited", "notifier", r.Notifier.Name(), "type", event.Type)
+			continue
+		}
+
+		ev := event
+		ev.Suppressed = suppressed
+		r.enqueue(ev)
+	}
+}
+
+// scheduleFlush запускает отправку сводки через wait. Вызывается под r.mu.
+func (r *route) scheduleFlush(d *Dispatcher, wait time.Duration) {
+	r.flush = time.AfterFunc(wait, func() { d.flushSuppressed(r) })
+}
+
+// flushSuppressed отправляет сводку об отброшенных событиях, если их не сообщило
+// следующее уведомление, а окно уже закончилось
+func (d *Dispatcher) flushSuppressed(r *route) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return
+	}
+
+	r.mu.Lock()
+	r.flush = nil
+	if wait := time.Until(r.limiter.windowEnd()); wait > 0 && r.limiter.suppressed > 0 {
+		// Окно сдвинулось: события отброшены уже в новом окне
+		r.scheduleFlush(d, wait)
+		r.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	suppressed := r.limiter.rollover(now)
+	r.mu.Unlock()
+
+	if suppressed > 0 {
+		r.enqueue(suppressedEvent(suppressed, now))
+	}
+}
+
+// enqueue ставит событие в очередь маршрута; при переполненной очереди событие отбрасывается
+func (r *route) enqueue(event Event) {
+	select {
+	case r.queue <- event:
+	default:
+		logger.Warn("Notification queue full, event dropped", "notifier", r.Notifier.Name(), "type", event.Type)
+	}
+}
+
+// suppressedEvent сводка об отброшенных ограничением событиях маршрута
+func suppressedEvent(suppressed int, now time.Time) Event {
+	return Event{
+		Type:       EventSuppressed,
+		Severity:   SeverityWarning,
+		Title:      "Notifications suppressed",
+		Message:    fmt.Sprintf("%d notifications suppressed by rate limit", suppressed),
+		Timestamp:  now,
+		Suppressed: suppressed,
+	}
+}
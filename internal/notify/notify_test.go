@@ -0,0 +1,377 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// captureNotifier запоминает доставленные события
+type captureNotifier struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (n *captureNotifier) Name() string { return "capture" }
+
+func (n *captureNotifier) Notify(ctx context.Context, event Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *captureNotifier) take() []Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	events := n.events
+	n.events = nil
+	return events
+}
+
+func TestDispatcherRoutingAndRateLimit(t *testing.T) {
+	alarms := &captureNotifier{}
+	all := &captureNotifier{}
+	d, err := NewDispatcher([]Route{
+		{Notifier: alarms, Events: []string{EventAlarmRaised}, RateLimit: 2, RatePeriod: time.Minute},
+		{Notifier: all},
+	})
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	d.Start()
+
+	start := time.Now()
+	d.Notify(Event{Type: EventServerDisconnected, Timestamp: start})
+	for i := 0; i < 5; i++ {
+		d.Notify(Event{Type: EventAlarmRaised, Timestamp: start.Add(time.Duration(i) * time.Second)})
+	}
+	// Новое окно: первое событие сообщает, сколько было отброшено
+	d.Notify(Event{Type: EventAlarmRaised, Timestamp: start.Add(2 * time.Minute)})
+	d.Stop()
+
+	got := alarms.take()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 rate-limited alarm notifications, got %d", len(got))
+	}
+	if got[2].Suppressed != 3 {
+		t.Errorf("expected 3 suppressed reported, got %d", got[2].Suppressed)
+	}
+	for _, ev := range got {
+		if ev.Type != EventAlarmRaised {
+			t.Errorf("route received unexpected event type %s", ev.Type)
+		}
+	}
+	if n := len(all.take()); n != 7 {
+		t.Errorf("expected unfiltered route to receive 7 events, got %d", n)
+	}
+}
+
+func TestDispatcherFlushesSuppressedAtWindowEnd(t *testing.T) {
+	capture := &captureNotifier{}
+	d, err := NewDispatcher([]Route{{Notifier: capture, RateLimit: 1, RatePeriod: 50 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	d.Start()
+	defer d.Stop()
+
+	for i := 0; i < 3; i++ {
+		d.Notify(Event{Type: EventAlarmRaised})
+	}
+
+	// Новых событий нет: сводка приходит по окончании окна
+	deadline := time.Now().Add(2 * time.Second)
+	var got []Event
+	for len(got) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		got = append(got, capture.take()...)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected event and summary, got %+v", got)
+	}
+	if got[1].Type != EventSuppressed || got[1].Suppressed != 2 {
+		t.Errorf("expected summary of 2 suppressed events, got %+v", got[1])
+	}
+}
+
+func TestDispatcherFlushesSuppressedOnStop(t *testing.T) {
+	capture := &captureNotifier{}
+	d, err := NewDispatcher([]Route{{Notifier: capture, RateLimit: 1, RatePeriod: time.Hour}})
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	d.Start()
+
+	d.Notify(Event{Type: EventAlarmRaised})
+	d.Notify(Event{Type: EventAlarmRaised})
+	d.Stop()
+
+	got := capture.take()
+	if len(got) != 2 || got[1].Type != EventSuppressed || got[1].Suppressed != 1 {
+		t.Errorf("expected summary of 1 suppressed event on stop, got %+v", got)
+	}
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var received Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+
+	n, err := NewWebhookNotifier(srv.URL, map[string]string{"X-Token": "secret"}, 2)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier: %v", err)
+	}
+	n.backoff = time.Millisecond
+
+	event := Event{Type: EventAlarmRaised, Title: "T1 hi", Timestamp: time.Now()}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if attempts.Load() != 3 || received.Type != EventAlarmRaised || received.Title != "T1 hi" {
+		t.Errorf("unexpected delivery: attempts=%d event=%+v", attempts.Load(), received)
+	}
+
+	// Ответ 4xx не повторяется
+	attempts.Store(0)
+	n.headers = nil
+	if err := n.Notify(context.Background(), event); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if attempts.Load() != 0 {
+		t.Errorf("client error must not be retried")
+	}
+}
+
+// fakeSMTPServer минимальный SMTP сервер: принимает одно письмо
+func fakeSMTPServer(t *testing.T) (addr string, messages <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				data.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				out <- data.String()
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+
+	n, err := NewSMTPNotifier(SMTPOptions{Addr: addr, From: "panel@example.com", To: []string{"ops@example.com"}})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event := Event{
+		Type: EventServerDisconnected, Severity: SeverityCritical, Title: "Server s1 disconnected",
+		Message: "connection refused", ServerID: "s1", Timestamp: time.Now(),
+	}
+	if err := n.Notify(ctx, event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		for _, want := range []string{"MAIL FROM:<panel@example.com>", "RCPT TO:<ops@example.com>",
+			"Subject: [CRITICAL] Server s1 disconnected", "connection refused", "Event: server_disconnected"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message missing %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp server did not receive message")
+	}
+}
+
+func TestSyslogNotifierUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+
+	n, err := NewSyslogNotifier("udp", pc.LocalAddr().String(), "panel")
+	if err != nil {
+		t.Fatalf("NewSyslogNotifier: %v", err)
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := Event{Type: EventAlarmRaised, Severity: SeverityWarning, Title: "T1 hi", Message: "value 95",
+		ServerID: "s1", Object: `SM"1`, Timestamp: ts}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	msg := string(buf[:size])
+	// local0 (16) * 8 + warning (4) = 132
+	prefix := "<132>1 2024-01-02T03:04:05Z "
+	if !strings.HasPrefix(msg, prefix) {
+		t.Errorf("expected prefix %q, got %q", prefix, msg)
+	}
+	for _, want := range []string{" panel ", " alarm_raised ", `[panel@32473 type="alarm_raised" server="s1" object="SM\"1"]`, "T1 hi: value 95"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q missing %q", msg, want)
+		}
+	}
+}
+
+func TestSyslogHeaderField(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"", 48, "-"},
+		{"uniset-panel", 48, "uniset-panel"},
+		{"my panel", 48, "my_panel"},
+		{"панель", 48, "______"},
+		{strings.Repeat("a", 60), 48, strings.Repeat("a", 48)},
+	}
+	for _, tt := range tests {
+		if got := syslogHeaderField(tt.value, tt.max); got != tt.want {
+			t.Errorf("syslogHeaderField(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+
+	n, err := NewSyslogNotifier("udp", "127.0.0.1:514", "uniset panel")
+	if err != nil {
+		t.Fatalf("NewSyslogNotifier: %v", err)
+	}
+	if n.appName != "uniset_panel" {
+		t.Errorf("expected sanitized app name, got %q", n.appName)
+	}
+}
+
+func TestSyslogNotifierTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			length, err := readFrameLength(r)
+			if err != nil {
+				return
+			}
+			buf := make([]byte, length)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			lines <- string(buf)
+		}
+	}()
+
+	n, err := NewSyslogNotifier("tcp", ln.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("NewSyslogNotifier: %v", err)
+	}
+	for _, title := range []string{"first", "second"} {
+		if err := n.Notify(context.Background(), Event{Type: EventControlTaken, Title: title, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case msg := <-lines:
+			if !strings.HasPrefix(msg, "<134>1 ") || !strings.HasSuffix(msg, want) {
+				t.Errorf("unexpected framed message %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("did not receive %q", want)
+		}
+	}
+}
+
+// readFrameLength читает длину сообщения до пробела (RFC 6587 octet counting)
+func readFrameLength(r *bufio.Reader) (int, error) {
+	s, err := r.ReadString(' ')
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(s))
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPOptions параметры email канала
+type SMTPOptions struct {
+	Addr     string   // host:port SMTP сервера
+	From     string   // адрес отправителя
+	To       []string // адреса получателей
+	Username string   // логин (пусто = без аутентификации)
+	Password string
+}
+
+// SMTPNotifier отправляет событие письмом
+type SMTPNotifier struct {
+	opts SMTPOptions
+	host string
+}
+
+// NewSMTPNotifier создаёт email канал
+func NewSMTPNotifier(opts SMTPOptions) (*SMTPNotifier, error) {
+	if opts.Addr == "" || opts.From == "" || len(opts.To) == 0 {
+		return nil, fmt.Errorf("smtp addr, from and to are required")
+	}
+	host, _, err := net.SplitHostPort(opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp addr %q: %w", opts.Addr, err)
+	}
+	return &SMTPNotifier{opts: opts, host: host}, nil
+}
+
+// Name возвращает имя канала
+func (n *SMTPNotifier) Name() string {
+	return "smtp " + n.opts.Addr
+}
+
+// Notify отправляет письмо. net/smtp не принимает контекст, поэтому отправка
+// выполняется в горутине и прерывается по ctx (соединение закрывается).
+func (n *SMTPNotifier) Notify(ctx context.Context, event Event) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("dial smtp: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- n.send(conn, n.message(event))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		conn.Close()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (n *SMTPNotifier) send(conn net.Conn, msg []byte) error {
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if n.opts.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(n.opts.From); err != nil {
+		return fmt.Errorf("smtp mail: %w", err)
+	}
+	for _, to := range n.opts.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return c.Quit()
+}
+
+// message формирует письмо (тема в UTF-8 кодируется по RFC 2047)
+func (n *SMTPNotifier) message(event Event) []byte {
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(string(event.Severity)), event.Title)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.opts.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.opts.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+
+	b.WriteString(event.Message)
+	b.WriteString("\r\n\r\n")
+	fmt.Fprintf(&b, "Event: %s\r\n", event.Type)
+	if event.ServerID != "" {
+		fmt.Fprintf(&b, "Server: %s\r\n", event.ServerID)
+	}
+	if event.Object != "" {
+		fmt.Fprintf(&b, "Object: %s\r\n", event.Object)
+	}
+	fmt.Fprintf(&b, "Time: %s\r\n", event.Timestamp.Format(time.RFC3339))
+	if event.Suppressed > 0 {
+		fmt.Fprintf(&b, "Suppressed by rate limit since last notification: %d\r\n", event.Suppressed)
+	}
+	return b.Bytes()
+}
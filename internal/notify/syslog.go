@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры syslog сообщений (RFC 5424)
+const (
+	syslogFacilityLocal0 = 16
+	syslogVersion        = 1
+	defaultSyslogApp     = "uniset-panel"
+
+	// Максимальная длина полей заголовка
+	syslogMaxHostname = 255
+	syslogMaxAppName  = 48
+	syslogMaxMsgID    = 32
+)
+
+// SyslogNotifier отправляет событие syslog сообщением RFC 5424 по UDP или TCP.
+// По TCP сообщения разделяются счётчиком октетов (RFC 6587).
+type SyslogNotifier struct {
+	network  string
+	addr     string
+	appName  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn // TCP соединение переиспользуется между событиями
+}
+
+// NewSyslogNotifier создаёт syslog канал. network - "udp" или "tcp".
+func NewSyslogNotifier(network, addr, appName string) (*SyslogNotifier, error) {
+	switch network {
+	case "":
+		network = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("syslog addr is required")
+	}
+	if appName == "" {
+		appName = defaultSyslogApp
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+	return &SyslogNotifier{
+		network:  network,
+		addr:     addr,
+		appName:  syslogHeaderField(appName, syslogMaxAppName),
+		hostname: syslogHeaderField(hostname, syslogMaxHostname),
+	}, nil
+}
+
+// Name возвращает имя канала
+func (n *SyslogNotifier) Name() string {
+	return "syslog " + n.network + "://" + n.addr
+}
+
+// Notify отправляет событие. При ошибке записи TCP соединение переоткрывается один раз.
+func (n *SyslogNotifier) Notify(ctx context.Context, event Event) error {
+	msg := n.format(event)
+	if n.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if n.conn == nil {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, n.network, n.addr)
+			if err != nil {
+				return fmt.Errorf("dial syslog: %w", err)
+			}
+			n.conn = conn
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			n.conn.SetWriteDeadline(deadline)
+		}
+		_, err := n.conn.Write([]byte(msg))
+		if n.network == "udp" {
+			n.conn.Close()
+			n.conn = nil
+		}
+		if err == nil {
+			return nil
+		}
+		if n.conn != nil {
+			n.conn.Close()
+			n.conn = nil
+		}
+		if attempt == 1 {
+			return fmt.Errorf("write syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// format формирует сообщение RFC 5424:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (n *SyslogNotifier) format(event Event) string {
+	pri := syslogFacilityLocal0*8 + syslogSeverity(event.Severity)
+	sd := fmt.Sprintf(`[panel@32473 type="%s" server="%s" object="%s"]`,
+		sdEscape(event.Type), sdEscape(event.ServerID), sdEscape(event.Object))
+
+	msg := event.Title
+	if event.Message != "" && event.Message != event.Title {
+		msg += ": " + event.Message
+	}
+	if event.Suppressed > 0 {
+		msg += fmt.Sprintf(" (%d suppressed)", event.Suppressed)
+	}
+
+	return fmt.Sprintf("<%d>%d %s %s %s %d %s %s %s",
+		pri, syslogVersion, event.Timestamp.UTC().Format(time.RFC3339Nano),
+		n.hostname, n.appName, os.Getpid(), syslogHeaderField(event.Type, syslogMaxMsgID), sd, msg)
+}
+
+// syslogSeverity код важности syslog
+func syslogSeverity(s Severity) int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 4
+	}
+	return 6 // informational
+}
+
+// syslogHeaderField поле заголовка (HOSTNAME, APP-NAME, MSGID): печатные ASCII
+// без пробелов, не длиннее max; пустое значение - "-"
+func syslogHeaderField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > max {
+		value = value[:max]
+	}
+	return value
+}
+
+// sdEscape экранирует значение параметра structured data
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultWebhookBackoff пауза перед первым повтором (удваивается)
+const defaultWebhookBackoff = time.Second
+
+// WebhookNotifier отправляет событие JSON POST запросом
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	retries int
+	backoff time.Duration
+	client  *http.Client
+}
+
+// NewWebhookNotifier создаёт webhook канал. retries - число повторов после неудачной
+// попытки (ошибка сети или ответ 5xx/429).
+func NewWebhookNotifier(url string, headers map[string]string, retries int) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	return &WebhookNotifier{
+		url:     url,
+		headers: headers,
+		retries: retries,
+		backoff: defaultWebhookBackoff,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Name возвращает имя канала
+func (n *WebhookNotifier) Name() string {
+	return "webhook " + n.url
+}
+
+// Notify отправляет событие с повторами
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= n.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post выполняет одну попытку; retryable=true, если попытку стоит повторить
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
@@ -1,6 +1,7 @@
 package opcua
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -46,7 +47,7 @@ type opcuaFetcher struct {
 	typesMu     *sync.RWMutex
 }
 
-func (f *opcuaFetcher) FetchItems(objectName string, ids []int64) ([]OPCUASensor, error) {
+func (f *opcuaFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]OPCUASensor, error) {
 	query := poller.BuildIDQuery(ids)
 
 	// Определяем тип объекта для выбора правильного API метода
@@ -59,9 +60,9 @@ func (f *opcuaFetcher) FetchItems(objectName string, ids []int64) ([]OPCUASensor
 
 	// OPCUAServer использует параметр id=, OPCUAExchange использует filter=
 	if objectType == "OPCUAServer" {
-		resp, err = f.client.GetOPCUAServerSensorValues(objectName, query)
+		resp, err = f.client.GetOPCUAServerSensorValuesContext(ctx, objectName, query)
 	} else {
-		resp, err = f.client.GetOPCUASensorValues(objectName, query)
+		resp, err = f.client.GetOPCUASensorValuesContext(ctx, objectName, query)
 	}
 	if err != nil {
 		return nil, err
@@ -162,15 +163,20 @@ func NewPoller(client *uniset.Client, interval time.Duration, batchSize int, cal
 }
 
 // Subscribe подписывает на датчики объекта (использует OPCUAExchange по умолчанию)
-func (p *Poller) Subscribe(objectName string, sensorIDs []int64) {
-	p.SubscribeWithType(objectName, sensorIDs, "")
+func (p *Poller) Subscribe(objectName string, sensorIDs []int64, rate ...poller.Rate) {
+	p.SubscribeWithType(objectName, sensorIDs, "", rate...)
 }
 
 // SubscribeWithType подписывает на датчики объекта с указанием типа
 // extensionType: "OPCUAExchange" или "OPCUAServer" (пустая строка = OPCUAExchange по умолчанию)
-func (p *Poller) SubscribeWithType(objectName string, sensorIDs []int64, extensionType string) {
+func (p *Poller) SubscribeWithType(objectName string, sensorIDs []int64, extensionType string, rate ...poller.Rate) {
+	p.SubscribeWithTypeFor("", objectName, sensorIDs, extensionType, rate...)
+}
+
+// SubscribeWithTypeFor подписывает владельца (ID SSE клиента) на датчики объекта с указанием типа
+func (p *Poller) SubscribeWithTypeFor(owner, objectName string, sensorIDs []int64, extensionType string, rate ...poller.Rate) {
 	// Сначала вызываем базовую подписку
-	p.BasePoller.Subscribe(objectName, sensorIDs)
+	p.BasePoller.SubscribeFor(owner, objectName, sensorIDs, rate...)
 
 	// Сохраняем тип объекта (если указан)
 	if extensionType != "" {
@@ -182,14 +188,19 @@ func (p *Poller) SubscribeWithType(objectName string, sensorIDs []int64, extensi
 
 // Unsubscribe отписывает от датчиков объекта
 func (p *Poller) Unsubscribe(objectName string, sensorIDs []int64) {
-	p.BasePoller.Unsubscribe(objectName, sensorIDs)
+	p.UnsubscribeFor("", objectName, sensorIDs)
+}
 
-	// Проверяем, остались ли подписки для объекта
-	if len(p.BasePoller.GetSubscriptions(objectName)) == 0 {
-		p.typesMu.Lock()
-		delete(p.objectTypes, objectName)
-		p.typesMu.Unlock()
-	}
+// UnsubscribeFor снимает подписку владельца с датчиков объекта
+func (p *Poller) UnsubscribeFor(owner, objectName string, sensorIDs []int64) {
+	p.BasePoller.UnsubscribeFor(owner, objectName, sensorIDs)
+	p.dropUnusedTypes()
+}
+
+// UnsubscribeAllFor снимает все подписки владельца на датчики объекта
+func (p *Poller) UnsubscribeAllFor(owner, objectName string) {
+	p.BasePoller.UnsubscribeAllFor(owner, objectName)
+	p.dropUnusedTypes()
 }
 
 // UnsubscribeAll отписывает объект от всех датчиков
@@ -201,6 +212,27 @@ func (p *Poller) UnsubscribeAll(objectName string) {
 	p.typesMu.Unlock()
 }
 
+// ReleaseOwner снимает все подписки владельца
+func (p *Poller) ReleaseOwner(owner string) int {
+	released := p.BasePoller.ReleaseOwner(owner)
+	if released > 0 {
+		p.dropUnusedTypes()
+	}
+	return released
+}
+
+// dropUnusedTypes забывает типы объектов, у которых не осталось подписок
+func (p *Poller) dropUnusedTypes() {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+
+	for objectName := range p.objectTypes {
+		if len(p.BasePoller.GetSubscriptions(objectName)) == 0 {
+			delete(p.objectTypes, objectName)
+		}
+	}
+}
+
 // Helper functions for value hashing
 
 func formatValueHash(value interface{}, tick int64) string {
@@ -3,10 +3,12 @@ package poller
 import (
 	"context"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/pv/uniset-panel/internal/recording"
+	"github.com/pv/uniset-panel/internal/uniset"
 )
 
 // BatchUpdateCallback функция обратного вызова для батчевых обновлений
@@ -28,8 +30,10 @@ type BasePoller[T any, U any] struct {
 	logPrefix string
 
 	mu sync.RWMutex
-	// subscriptions: objectName -> set of IDs
-	subscriptions map[string]map[int64]struct{}
+	// subscriptions: objectName -> ID -> частота опроса
+	subscriptions map[string]map[int64]Rate
+	// owners: objectName -> ID -> владелец (ID SSE клиента) -> запрошенная частота
+	owners map[string]map[int64]map[string]Rate
 	// lastValues: objectName -> ID -> value hash
 	lastValues map[string]map[int64]string
 
@@ -38,6 +42,9 @@ type BasePoller[T any, U any] struct {
 	recordingMgr   *recording.Manager
 	toDataRecord   ToDataRecordFunc[U]
 
+	// wake будит цикл опроса при изменении подписок или интервала
+	wake chan struct{}
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -61,8 +68,10 @@ func NewBasePoller[T any, U any](
 		makeUpdate:    makeUpdate,
 		callback:      callback,
 		logPrefix:     logPrefix,
-		subscriptions: make(map[string]map[int64]struct{}),
+		subscriptions: make(map[string]map[int64]Rate),
+		owners:        make(map[string]map[int64]map[string]Rate),
 		lastValues:    make(map[string]map[int64]string),
+		wake:          make(chan struct{}, 1),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -72,7 +81,7 @@ func NewBasePoller[T any, U any](
 func (p *BasePoller[T, U]) Start() {
 	p.wg.Add(1)
 	go p.pollLoop()
-	slog.Info(p.logPrefix+" Poller started", "interval", p.interval)
+	slog.Info(p.logPrefix+" Poller started", "interval", p.Interval())
 }
 
 // Stop останавливает polling
@@ -82,6 +91,37 @@ func (p *BasePoller[T, U]) Stop() {
 	slog.Info(p.logPrefix + " Poller stopped")
 }
 
+// Interval возвращает текущий интервал опроса
+func (p *BasePoller[T, U]) Interval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+// BatchSize возвращает макс. количество элементов в одном запросе
+func (p *BasePoller[T, U]) BatchSize() int {
+	return p.batchSize
+}
+
+// SetInterval изменяет интервал опроса (применяется со следующего тика)
+func (p *BasePoller[T, U]) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.interval = interval
+	p.mu.Unlock()
+	p.notify()
+}
+
+// notify будит цикл опроса для пересчёта расписания групп
+func (p *BasePoller[T, U]) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
 // SetServerID устанавливает ID сервера для recording
 func (p *BasePoller[T, U]) SetServerID(serverID string) {
 	p.mu.Lock()
@@ -127,20 +167,44 @@ func (p *BasePoller[T, U]) saveToRecording(batch []U) {
 	}
 }
 
-// Subscribe подписывает на элементы объекта
-func (p *BasePoller[T, U]) Subscribe(objectName string, ids []int64) {
+// Subscribe подписывает на элементы объекта без владельца. Необязательный rate задаёт частоту опроса
+// (по умолчанию RateNormal); при повторной подписке остаётся более частая из двух.
+func (p *BasePoller[T, U]) Subscribe(objectName string, ids []int64, rate ...Rate) {
+	p.SubscribeFor("", objectName, ids, rate...)
+}
+
+// SubscribeFor подписывает владельца (ID SSE клиента) на элементы объекта.
+// Элемент опрашивается, пока на него подписан хотя бы один владелец, с самой частой из их частот.
+func (p *BasePoller[T, U]) SubscribeFor(owner, objectName string, ids []int64, rate ...Rate) {
+	var r Rate
+	if len(rate) > 0 {
+		r = rate[0]
+	}
+
 	p.mu.Lock()
+	defer p.notify()
 	defer p.mu.Unlock()
 
 	if p.subscriptions[objectName] == nil {
-		p.subscriptions[objectName] = make(map[int64]struct{})
+		p.subscriptions[objectName] = make(map[int64]Rate)
+	}
+	if p.owners[objectName] == nil {
+		p.owners[objectName] = make(map[int64]map[string]Rate)
 	}
 	if p.lastValues[objectName] == nil {
 		p.lastValues[objectName] = make(map[int64]string)
 	}
 
 	for _, id := range ids {
-		p.subscriptions[objectName][id] = struct{}{}
+		owners := p.owners[objectName][id]
+		if owners == nil {
+			owners = make(map[string]Rate)
+			p.owners[objectName][id] = owners
+		}
+		if prev, ok := owners[owner]; !ok || r.effective(p.interval) < prev.effective(p.interval) {
+			owners[owner] = r
+		}
+		p.updateRate(objectName, id)
 	}
 
 	// Считаем общее количество подписок
@@ -149,38 +213,106 @@ func (p *BasePoller[T, U]) Subscribe(objectName string, ids []int64) {
 		totalCount += len(items)
 	}
 
-	slog.Info(p.logPrefix+" items subscribed", "object", objectName, "count", len(ids), "total_subscriptions", totalCount)
+	slog.Info(p.logPrefix+" items subscribed", "object", objectName, "owner", owner, "count", len(ids), "rate", r.String(), "total_subscriptions", totalCount)
 }
 
-// Unsubscribe отписывает от элементов объекта
+// Unsubscribe снимает подписку без владельца с элементов объекта
 func (p *BasePoller[T, U]) Unsubscribe(objectName string, ids []int64) {
+	p.UnsubscribeFor("", objectName, ids)
+}
+
+// UnsubscribeFor снимает подписку владельца с элементов объекта.
+// Элемент перестаёт опрашиваться, когда у него не остаётся владельцев.
+func (p *BasePoller[T, U]) UnsubscribeFor(owner, objectName string, ids []int64) {
 	p.mu.Lock()
+	defer p.notify()
 	defer p.mu.Unlock()
 
-	if items, ok := p.subscriptions[objectName]; ok {
-		for _, id := range ids {
-			delete(items, id)
-			delete(p.lastValues[objectName], id)
-		}
-		if len(items) == 0 {
-			delete(p.subscriptions, objectName)
-			delete(p.lastValues, objectName)
-		}
+	for _, id := range ids {
+		delete(p.owners[objectName][id], owner)
+		p.updateRate(objectName, id)
 	}
 
-	slog.Debug(p.logPrefix+" items unsubscribed", "object", objectName, "count", len(ids))
+	slog.Debug(p.logPrefix+" items unsubscribed", "object", objectName, "owner", owner, "count", len(ids))
 }
 
-// UnsubscribeAll отписывает объект от всех элементов
+// UnsubscribeAllFor снимает все подписки владельца на элементы объекта
+func (p *BasePoller[T, U]) UnsubscribeAllFor(owner, objectName string) {
+	p.mu.Lock()
+	defer p.notify()
+	defer p.mu.Unlock()
+
+	for id, owners := range p.owners[objectName] {
+		delete(owners, owner)
+		p.updateRate(objectName, id)
+	}
+
+	slog.Debug(p.logPrefix+" all items unsubscribed", "object", objectName, "owner", owner)
+}
+
+// UnsubscribeAll отписывает объект от всех элементов независимо от владельцев
 func (p *BasePoller[T, U]) UnsubscribeAll(objectName string) {
 	p.mu.Lock()
+	defer p.notify()
 	defer p.mu.Unlock()
 
 	delete(p.subscriptions, objectName)
+	delete(p.owners, objectName)
 	delete(p.lastValues, objectName)
 	slog.Debug(p.logPrefix+" all items unsubscribed", "object", objectName)
 }
 
+// ReleaseOwner снимает все подписки владельца, возвращает количество освобождённых элементов
+func (p *BasePoller[T, U]) ReleaseOwner(owner string) int {
+	p.mu.Lock()
+	defer p.notify()
+	defer p.mu.Unlock()
+
+	released := 0
+	for obj, items := range p.owners {
+		for id, owners := range items {
+			if _, ok := owners[owner]; !ok {
+				continue
+			}
+			delete(owners, owner)
+			p.updateRate(obj, id)
+			released++
+		}
+	}
+
+	if released > 0 {
+		slog.Debug(p.logPrefix+" owner released", "owner", owner, "count", released)
+	}
+	return released
+}
+
+// updateRate пересчитывает частоту опроса элемента по его владельцам
+// и удаляет элемент, если владельцев не осталось. Вызывается под p.mu.
+func (p *BasePoller[T, U]) updateRate(objectName string, id int64) {
+	owners := p.owners[objectName][id]
+	if len(owners) == 0 {
+		delete(p.owners[objectName], id)
+		delete(p.subscriptions[objectName], id)
+		delete(p.lastValues[objectName], id)
+		if len(p.subscriptions[objectName]) == 0 {
+			delete(p.subscriptions, objectName)
+			delete(p.owners, objectName)
+			delete(p.lastValues, objectName)
+		}
+		return
+	}
+
+	first := true
+	var fastest Rate
+	for _, r := range owners {
+		if first || r.effective(p.interval) < fastest.effective(p.interval) {
+			fastest = r
+			first = false
+		}
+	}
+	p.subscriptions[objectName][id] = fastest
+}
+
 // GetSubscriptions возвращает список подписок для объекта
 func (p *BasePoller[T, U]) GetSubscriptions(objectName string) []int64 {
 	p.mu.RLock()
@@ -214,6 +346,96 @@ func (p *BasePoller[T, U]) GetAllSubscriptions() map[string][]int64 {
 	return result
 }
 
+// GetSubscriptionRates возвращает частоты опроса подписок объекта
+func (p *BasePoller[T, U]) GetSubscriptionRates(objectName string) map[int64]Rate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	items, ok := p.subscriptions[objectName]
+	if !ok {
+		return nil
+	}
+
+	result := make(map[int64]Rate, len(items))
+	for id, rate := range items {
+		result[id] = rate
+	}
+	return result
+}
+
+// Subscriber элемент подписки с его владельцами (для диагностики).
+// Пустой владелец - подписка без ID SSE клиента.
+type Subscriber struct {
+	Object string   `json:"object"`
+	ID     int64    `json:"id"`
+	Rate   string   `json:"rate"`
+	Owners []string `json:"owners"`
+}
+
+// Subscribers возвращает подписанные элементы с владельцами, упорядоченные по объекту и ID
+func (p *BasePoller[T, U]) Subscribers() []Subscriber {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]Subscriber, 0)
+	for obj, items := range p.owners {
+		for id, owners := range items {
+			names := make([]string, 0, len(owners))
+			for owner := range owners {
+				names = append(names, owner)
+			}
+			sort.Strings(names)
+			result = append(result, Subscriber{
+				Object: obj,
+				ID:     id,
+				Rate:   p.subscriptions[obj][id].String(),
+				Owners: names,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Object != result[j].Object {
+			return result[i].Object < result[j].Object
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// RateGroups возвращает группы подписок по интервалу опроса (по возрастанию интервала)
+func (p *BasePoller[T, U]) RateGroups() []RateGroup {
+	groups := p.rateGroups()
+
+	result := make([]RateGroup, 0, len(groups))
+	for interval, objects := range groups {
+		count := 0
+		for _, ids := range objects {
+			count += len(ids)
+		}
+		result = append(result, RateGroup{IntervalMs: interval.Milliseconds(), Items: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].IntervalMs < result[j].IntervalMs })
+	return result
+}
+
+// rateGroups группирует подписки по интервалу опроса: interval -> objectName -> IDs
+func (p *BasePoller[T, U]) rateGroups() map[time.Duration]map[string][]int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	groups := make(map[time.Duration]map[string][]int64)
+	for obj, items := range p.subscriptions {
+		for id, rate := range items {
+			interval := rate.effective(p.interval)
+			if groups[interval] == nil {
+				groups[interval] = make(map[string][]int64)
+			}
+			groups[interval][obj] = append(groups[interval][obj], id)
+		}
+	}
+	return groups
+}
+
 // SubscriptionCount возвращает количество подписок
 func (p *BasePoller[T, U]) SubscriptionCount() int {
 	p.mu.RLock()
@@ -256,8 +478,11 @@ func (p *BasePoller[T, U]) ForceEmitAll() {
 			continue
 		}
 
-		items, err := p.pollObject(objectName, ids)
+		items, err := p.pollObject(p.ctx, objectName, ids)
 		if err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
 			slog.Error(p.logPrefix+" ForceEmitAll poll failed", "object", objectName, "error", err)
 			continue
 		}
@@ -297,35 +522,68 @@ func (p *BasePoller[T, U]) updateLastValue(objectName string, item T) {
 	p.lastValues[objectName][itemID] = newHash
 }
 
+// pollLoop опрашивает группы подписок каждую со своим интервалом.
+// Группы, срок опроса которых совпал, опрашиваются одним запросом на объект.
 func (p *BasePoller[T, U]) pollLoop() {
 	defer p.wg.Done()
 
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	// due: интервал группы -> время следующего опроса (используется только этой горутиной)
+	due := make(map[time.Duration]time.Time)
+
+	timer := time.NewTimer(p.Interval())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case <-ticker.C:
-			p.poll()
+		case <-p.wake:
+		case <-timer.C:
 		}
+		timer.Reset(p.pollDue(due, time.Now()))
 	}
 }
 
-func (p *BasePoller[T, U]) poll() {
-	// Копируем подписки под блокировкой
-	p.mu.RLock()
-	subsSnapshot := make(map[string][]int64)
-	for obj, items := range p.subscriptions {
-		ids := make([]int64, 0, len(items))
-		for id := range items {
-			ids = append(ids, id)
+// pollDue опрашивает группы, для которых наступил срок, и возвращает паузу до следующего опроса.
+// Новая группа опрашивается сразу.
+func (p *BasePoller[T, U]) pollDue(due map[time.Duration]time.Time, now time.Time) time.Duration {
+	groups := p.rateGroups()
+	for interval := range due {
+		if _, ok := groups[interval]; !ok {
+			delete(due, interval)
+		}
+	}
+
+	subs := make(map[string][]int64)
+	for interval, objects := range groups {
+		if next, ok := due[interval]; ok && now.Before(next) {
+			continue
+		}
+		due[interval] = now.Add(interval)
+		for obj, ids := range objects {
+			subs[obj] = append(subs[obj], ids...)
 		}
-		subsSnapshot[obj] = ids
 	}
-	p.mu.RUnlock()
 
+	if len(subs) > 0 {
+		p.pollItems(subs)
+	}
+
+	wait := p.Interval()
+	after := time.Now()
+	for _, next := range due {
+		if d := next.Sub(after); d < wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return wait
+}
+
+// pollItems опрашивает элементы объектов и отправляет изменившиеся значения одним batch
+func (p *BasePoller[T, U]) pollItems(subsSnapshot map[string][]int64) {
 	if len(subsSnapshot) == 0 {
 		return
 	}
@@ -342,8 +600,16 @@ func (p *BasePoller[T, U]) poll() {
 			continue
 		}
 
-		items, err := p.pollObject(objectName, ids)
+		items, err := p.pollObject(p.ctx, objectName, ids)
 		if err != nil {
+			// Poller остановлен - запрос прерван, результаты не отправляем
+			if p.ctx.Err() != nil {
+				return
+			}
+			if uniset.IsBackoff(err) {
+				slog.Debug(p.logPrefix+" poll skipped", "object", objectName, "reason", err)
+				continue
+			}
 			slog.Error(p.logPrefix+" poll failed", "object", objectName, "error", err)
 			continue
 		}
@@ -375,16 +641,16 @@ func (p *BasePoller[T, U]) poll() {
 	}
 }
 
-func (p *BasePoller[T, U]) pollObject(objectName string, ids []int64) ([]T, error) {
+func (p *BasePoller[T, U]) pollObject(ctx context.Context, objectName string, ids []int64) ([]T, error) {
 	// Если батчинг включен и элементов больше чем batchSize, разбиваем на батчи
 	if p.batchSize > 0 && len(ids) > p.batchSize {
-		return p.pollObjectBatched(objectName, ids)
+		return p.pollObjectBatched(ctx, objectName, ids)
 	}
 
-	return p.fetcher.FetchItems(objectName, ids)
+	return p.fetcher.FetchItems(ctx, objectName, ids)
 }
 
-func (p *BasePoller[T, U]) pollObjectBatched(objectName string, ids []int64) ([]T, error) {
+func (p *BasePoller[T, U]) pollObjectBatched(ctx context.Context, objectName string, ids []int64) ([]T, error) {
 	var allItems []T
 	var lastErr error
 
@@ -396,7 +662,11 @@ func (p *BasePoller[T, U]) pollObjectBatched(objectName string, ids []int64) ([]
 		}
 		batch := ids[i:end]
 
-		items, err := p.fetcher.FetchItems(objectName, batch)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		items, err := p.fetcher.FetchItems(ctx, objectName, batch)
 		if err != nil {
 			lastErr = err
 			slog.Debug(p.logPrefix+" batch poll failed", "object", objectName, "batch", i/p.batchSize, "error", err)
@@ -1,7 +1,9 @@
 package poller
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,7 +28,7 @@ type MockFetcher struct {
 	callCount int
 }
 
-func (f *MockFetcher) FetchItems(objectName string, ids []int64) ([]MockItem, error) {
+func (f *MockFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]MockItem, error) {
 	f.callCount++
 	if f.fetchErr != nil {
 		return nil, f.fetchErr
@@ -433,7 +435,7 @@ func TestBasePollerPollObject(t *testing.T) {
 		nil, "Test",
 	)
 
-	items, err := bp.pollObject("Object1", []int64{1, 2})
+	items, err := bp.pollObject(context.Background(), "Object1", []int64{1, 2})
 	if err != nil {
 		t.Fatalf("pollObject failed: %v", err)
 	}
@@ -463,7 +465,7 @@ func TestBasePollerPollObjectBatched(t *testing.T) {
 		nil, "Test",
 	)
 
-	items, err := bp.pollObject("Object1", []int64{1, 2, 3, 4, 5})
+	items, err := bp.pollObject(context.Background(), "Object1", []int64{1, 2, 3, 4, 5})
 	if err != nil {
 		t.Fatalf("pollObject failed: %v", err)
 	}
@@ -488,7 +490,7 @@ func TestBasePollerPollObjectBatchedWithError(t *testing.T) {
 		nil, "Test",
 	)
 
-	_, err := bp.pollObject("Object1", []int64{1, 2, 3, 4, 5})
+	_, err := bp.pollObject(context.Background(), "Object1", []int64{1, 2, 3, 4, 5})
 	if err == nil {
 		t.Error("expected error when all batches fail")
 	}
@@ -588,8 +590,10 @@ func TestBasePollerPollWithCallback(t *testing.T) {
 
 	bp.Subscribe("Object1", []int64{1})
 
-	// Manual poll
-	bp.poll()
+	// Новая группа опрашивается сразу
+	due := make(map[time.Duration]time.Time)
+	now := time.Now()
+	bp.pollDue(due, now)
 
 	if len(receivedUpdates) != 1 {
 		t.Errorf("len(receivedUpdates) = %d, want 1", len(receivedUpdates))
@@ -597,7 +601,7 @@ func TestBasePollerPollWithCallback(t *testing.T) {
 
 	// Second poll with same value should not trigger callback
 	prevLen := len(receivedUpdates)
-	bp.poll()
+	bp.pollDue(due, now.Add(50*time.Millisecond))
 
 	if len(receivedUpdates) != prevLen {
 		t.Error("callback should not be called for unchanged values")
@@ -625,7 +629,7 @@ func TestBasePollerPollWithFetchError(t *testing.T) {
 	bp.Subscribe("Object1", []int64{1})
 
 	// Should not panic on fetch error
-	bp.poll()
+	bp.pollDue(make(map[time.Duration]time.Time), time.Now())
 
 	if callbackCalled {
 		t.Error("callback should not be called on fetch error")
@@ -683,3 +687,105 @@ func TestBasePollerUnsubscribeAllRemovesObject(t *testing.T) {
 		t.Error("Object1 should be removed when all IDs are unsubscribed")
 	}
 }
+
+// blockingFetcher blocks FetchItems until the context is cancelled
+type blockingFetcher struct {
+	MockFetcher
+	started chan struct{}
+}
+
+func (f *blockingFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]MockItem, error) {
+	select {
+	case f.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBasePollerStopAbortsFetch(t *testing.T) {
+	fetcher := &blockingFetcher{started: make(chan struct{}, 1)}
+	var called int32
+	bp := NewBasePoller[MockItem, MockUpdate](
+		10*time.Millisecond, 100, fetcher,
+		func(objectName string, item MockItem, ts time.Time) MockUpdate {
+			return MockUpdate{}
+		},
+		func(updates []MockUpdate) { atomic.AddInt32(&called, 1) },
+		"Test",
+	)
+	bp.Subscribe("Object1", []int64{1})
+
+	bp.Start()
+	<-fetcher.started
+
+	stopped := make(chan struct{})
+	go func() {
+		bp.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Stop did not abort in-flight fetch")
+	}
+	if atomic.LoadInt32(&called) != 0 {
+		t.Error("callback should not be called for aborted poll")
+	}
+}
+
+func TestBasePollerOwnerRefCount(t *testing.T) {
+	bp := NewBasePoller[MockItem, MockUpdate](
+		time.Second, 100, &MockFetcher{},
+		func(objectName string, item MockItem, ts time.Time) MockUpdate { return MockUpdate{} },
+		nil, "Test",
+	)
+
+	bp.SubscribeFor("tab1", "Object1", []int64{1, 2}, Rate{Class: RateFast})
+	bp.SubscribeFor("tab2", "Object1", []int64{2, 3})
+
+	// Отписка одной вкладки не затрагивает датчики другой
+	bp.UnsubscribeFor("tab1", "Object1", []int64{2})
+	if got := len(bp.GetSubscriptions("Object1")); got != 3 {
+		t.Fatalf("expected 3 subscriptions after tab1 unsubscribed shared item, got %d", got)
+	}
+
+	subs := bp.Subscribers()
+	if len(subs) != 3 || subs[0].ID != 1 || subs[0].Rate != "fast" || len(subs[1].Owners) != 1 || subs[1].Owners[0] != "tab2" {
+		t.Errorf("unexpected subscribers: %+v", subs)
+	}
+
+	// Освобождение tab1 снимает только её элементы
+	if released := bp.ReleaseOwner("tab1"); released != 1 {
+		t.Errorf("ReleaseOwner(tab1) = %d, want 1", released)
+	}
+	if got := bp.GetSubscriptions("Object1"); len(got) != 2 {
+		t.Errorf("expected items of tab2 only, got %v", got)
+	}
+
+	bp.UnsubscribeAllFor("tab2", "Object1")
+	if got := bp.GetSubscriptions("Object1"); got != nil {
+		t.Errorf("expected no subscriptions, got %v", got)
+	}
+}
+
+func TestBasePollerOwnerRateRecalculated(t *testing.T) {
+	bp := NewBasePoller[MockItem, MockUpdate](
+		time.Second, 100, &MockFetcher{},
+		func(objectName string, item MockItem, ts time.Time) MockUpdate { return MockUpdate{} },
+		nil, "Test",
+	)
+
+	bp.SubscribeFor("tab1", "Object1", []int64{1}, Rate{Class: RateFast})
+	bp.SubscribeFor("tab2", "Object1", []int64{1}, Rate{Class: RateSlow})
+	if rate := bp.GetSubscriptionRates("Object1")[1]; rate.Class != RateFast {
+		t.Fatalf("expected fastest owner rate, got %s", rate)
+	}
+
+	// После ухода быстрого владельца элемент опрашивается с частотой оставшегося
+	bp.ReleaseOwner("tab1")
+	if rate := bp.GetSubscriptionRates("Object1")[1]; rate.Class != RateSlow {
+		t.Errorf("expected slow rate after fast owner released, got %s", rate)
+	}
+}
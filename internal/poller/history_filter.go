@@ -0,0 +1,75 @@
+package poller
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/storage"
+)
+
+// HistoryFilter определяет, какие значения переменных сохранять в историю.
+// Нулевое значение сохраняет каждую точку (поведение по умолчанию).
+type HistoryFilter struct {
+	ChangeOnly      bool          // сохранять только изменившиеся значения
+	Deadband        float64       // абсолютная зона нечувствительности для чисел
+	DeadbandPercent float64       // зона нечувствительности в % от последнего сохранённого значения
+	Heartbeat       time.Duration // период принудительного сохранения неизменного значения (0 = выключено)
+}
+
+// savedValue последнее сохранённое в историю значение переменной
+type savedValue struct {
+	hash  string
+	num   float64
+	isNum bool
+	at    time.Time
+}
+
+// newSavedValue запоминает значение для последующего сравнения
+func newSavedValue(value interface{}, at time.Time) savedValue {
+	num, isNum := storage.NumericValue(value)
+	return savedValue{
+		hash:  fmt.Sprint(value),
+		num:   num,
+		isNum: isNum,
+		at:    at,
+	}
+}
+
+// shouldSave решает, нужно ли сохранять новое значение с учётом последнего сохранённого
+func (f HistoryFilter) shouldSave(last savedValue, exists bool, value interface{}, now time.Time) bool {
+	if !f.ChangeOnly || !exists {
+		return true
+	}
+
+	if f.Heartbeat > 0 && now.Sub(last.at) >= f.Heartbeat {
+		return true
+	}
+
+	num, isNum := storage.NumericValue(value)
+	if isNum && last.isNum {
+		return f.exceedsDeadband(last.num, num)
+	}
+
+	return fmt.Sprint(value) != last.hash
+}
+
+// exceedsDeadband проверяет, вышло ли числовое значение за зону нечувствительности.
+// Если заданы обе зоны, значение сохраняется при выходе за любую из них.
+func (f HistoryFilter) exceedsDeadband(last, value float64) bool {
+	delta := math.Abs(value - last)
+	if delta == 0 {
+		return false
+	}
+
+	if f.Deadband <= 0 && f.DeadbandPercent <= 0 {
+		return true
+	}
+	if f.Deadband > 0 && delta > f.Deadband {
+		return true
+	}
+	if f.DeadbandPercent > 0 && delta > math.Abs(last)*f.DeadbandPercent/100 {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package poller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryFilterDisabledSavesEverything(t *testing.T) {
+	f := HistoryFilter{}
+	now := time.Now()
+	last := newSavedValue(10.0, now)
+
+	if !f.shouldSave(last, true, 10.0, now) {
+		t.Error("filter without ChangeOnly should save unchanged values")
+	}
+}
+
+func TestHistoryFilterChangeOnly(t *testing.T) {
+	f := HistoryFilter{ChangeOnly: true}
+	now := time.Now()
+
+	if !f.shouldSave(savedValue{}, false, 10.0, now) {
+		t.Error("first value should always be saved")
+	}
+
+	last := newSavedValue(10.0, now)
+	if f.shouldSave(last, true, 10.0, now.Add(time.Second)) {
+		t.Error("unchanged numeric value should not be saved")
+	}
+	if !f.shouldSave(last, true, 10.5, now.Add(time.Second)) {
+		t.Error("changed numeric value should be saved")
+	}
+
+	last = newSavedValue("on", now)
+	if f.shouldSave(last, true, "on", now.Add(time.Second)) {
+		t.Error("unchanged string value should not be saved")
+	}
+	if !f.shouldSave(last, true, "off", now.Add(time.Second)) {
+		t.Error("changed string value should be saved")
+	}
+
+	last = newSavedValue(map[string]interface{}{"a": 1.0}, now)
+	if f.shouldSave(last, true, map[string]interface{}{"a": 1.0}, now.Add(time.Second)) {
+		t.Error("unchanged map value should not be saved")
+	}
+}
+
+func TestHistoryFilterDeadband(t *testing.T) {
+	now := time.Now()
+	last := newSavedValue(100.0, now)
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		value  float64
+		want   bool
+	}{
+		{"absolute inside", HistoryFilter{ChangeOnly: true, Deadband: 1}, 100.5, false},
+		{"absolute boundary", HistoryFilter{ChangeOnly: true, Deadband: 1}, 101, false},
+		{"absolute outside", HistoryFilter{ChangeOnly: true, Deadband: 1}, 101.5, true},
+		{"absolute outside negative", HistoryFilter{ChangeOnly: true, Deadband: 1}, 98, true},
+		{"percent inside", HistoryFilter{ChangeOnly: true, DeadbandPercent: 5}, 104, false},
+		{"percent outside", HistoryFilter{ChangeOnly: true, DeadbandPercent: 5}, 106, true},
+		{"either zone exceeded", HistoryFilter{ChangeOnly: true, Deadband: 10, DeadbandPercent: 2}, 103, true},
+		{"both zones inside", HistoryFilter{ChangeOnly: true, Deadband: 10, DeadbandPercent: 5}, 103, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.shouldSave(last, true, tt.value, now.Add(time.Second)); got != tt.want {
+				t.Errorf("shouldSave(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryFilterHeartbeat(t *testing.T) {
+	f := HistoryFilter{ChangeOnly: true, Deadband: 5, Heartbeat: time.Minute}
+	now := time.Now()
+	last := newSavedValue(10.0, now)
+
+	if f.shouldSave(last, true, 11.0, now.Add(30*time.Second)) {
+		t.Error("value inside deadband should not be saved before heartbeat")
+	}
+	if !f.shouldSave(last, true, 11.0, now.Add(time.Minute)) {
+		t.Error("value should be saved after heartbeat period")
+	}
+	if !f.shouldSave(last, true, 10.0, now.Add(2*time.Minute)) {
+		t.Error("unchanged value should be saved after heartbeat period")
+	}
+}
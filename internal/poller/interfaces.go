@@ -1,9 +1,11 @@
 package poller
 
+import "context"
+
 // ItemFetcher определяет специфичные для типа операции получения и обработки элементов
 type ItemFetcher[T any] interface {
-	// FetchItems получает элементы по списку ID
-	FetchItems(objectName string, ids []int64) ([]T, error)
+	// FetchItems получает элементы по списку ID; отмена ctx прерывает запрос
+	FetchItems(ctx context.Context, objectName string, ids []int64) ([]T, error)
 
 	// GetItemID возвращает ID элемента
 	GetItemID(item T) int64
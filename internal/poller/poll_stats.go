@@ -0,0 +1,94 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// latencySmoothing коэффициент сглаживания средней задержки опроса
+const latencySmoothing = 0.2
+
+// ObjectPollStats статистика опроса одного объекта
+type ObjectPollStats struct {
+	Object        string    `json:"object"`
+	LastLatencyMs int64     `json:"lastLatencyMs"`
+	AvgLatencyMs  float64   `json:"avgLatencyMs"` // экспоненциальное скользящее среднее
+	MaxLatencyMs  int64     `json:"maxLatencyMs"`
+	Polls         int64     `json:"polls"`
+	Errors        int64     `json:"errors"`
+	Timeouts      int64     `json:"timeouts"` // опросы, прерванные по дедлайну
+	LastPoll      time.Time `json:"lastPoll"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// PollStats сводная статистика опроса объектов
+type PollStats struct {
+	Concurrency     int               `json:"concurrency"`
+	ObjectTimeoutMs int64             `json:"objectTimeoutMs"`
+	SkippedTicks    int64             `json:"skippedTicks"` // тики, пропущенные из-за незавершённого опроса
+	Objects         []ObjectPollStats `json:"objects"`
+
+	// RateGroups группы подписок IONC/Modbus/OPCUA pollers по интервалу опроса
+	RateGroups map[string][]RateGroup `json:"rateGroups,omitempty"`
+}
+
+// objectStats накопленная статистика опроса объекта
+type objectStats struct {
+	last, max time.Duration
+	avg       float64 // мс
+	polls     int64
+	errors    int64
+	timeouts  int64
+	lastPoll  time.Time
+	lastError string
+}
+
+func (s *objectStats) record(latency time.Duration, at time.Time, err error) {
+	ms := float64(latency) / float64(time.Millisecond)
+	if s.polls == 0 {
+		s.avg = ms
+	} else {
+		s.avg += latencySmoothing * (ms - s.avg)
+	}
+	s.last = latency
+	if latency > s.max {
+		s.max = latency
+	}
+	s.polls++
+	s.lastPoll = at
+
+	s.lastError = ""
+	if err != nil {
+		s.errors++
+		s.lastError = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.timeouts++
+		}
+	}
+}
+
+func (s *objectStats) snapshot(objectName string) ObjectPollStats {
+	return ObjectPollStats{
+		Object:        objectName,
+		LastLatencyMs: s.last.Milliseconds(),
+		AvgLatencyMs:  s.avg,
+		MaxLatencyMs:  s.max.Milliseconds(),
+		Polls:         s.polls,
+		Errors:        s.errors,
+		Timeouts:      s.timeouts,
+		LastPoll:      s.lastPoll,
+		LastError:     s.lastError,
+	}
+}
+
+// sortBySlowest упорядочивает объекты по убыванию средней задержки
+func sortBySlowest(stats []ObjectPollStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].AvgLatencyMs != stats[j].AvgLatencyMs {
+			return stats[i].AvgLatencyMs > stats[j].AvgLatencyMs
+		}
+		return stats[i].Object < stats[j].Object
+	})
+}
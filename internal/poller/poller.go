@@ -3,6 +3,7 @@ package poller
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pv/uniset-panel/internal/logger"
@@ -11,6 +12,13 @@ import (
 	"github.com/pv/uniset-panel/internal/uniset"
 )
 
+const (
+	// DefaultConcurrency количество объектов, опрашиваемых одновременно
+	DefaultConcurrency = 4
+	// minObjectTimeout минимальный дедлайн запроса данных объекта
+	minObjectTimeout = time.Second
+)
+
 // EventCallback вызывается при получении новых данных объекта
 type EventCallback func(objectName string, data *uniset.ObjectData)
 
@@ -23,11 +31,23 @@ type Poller struct {
 
 	mu              sync.RWMutex
 	watchedObjects  map[string]bool
+	pinnedObjects   map[string]bool // наблюдаются независимо от клиентов (Unwatch их не снимает)
 	lastObjectData  map[string]*uniset.ObjectData
 	lastCleanupTime time.Time
 
 	eventCallback EventCallback
 	recordingMgr  *recording.Manager // менеджер записи истории
+
+	historyFilter HistoryFilter                    // фильтр сохранения истории по умолчанию
+	objectFilters map[string]HistoryFilter         // фильтры для отдельных объектов
+	lastSaved     map[string]map[string]savedValue // objectName -> varName -> последнее сохранённое значение
+
+	retention *storage.RetentionPolicy // правила хранения (nil = только ttl)
+
+	concurrency  int                     // количество одновременно опрашиваемых объектов
+	stats        map[string]*objectStats // objectName -> статистика опроса
+	polling      atomic.Bool             // идёт опрос (следующий тик пропускается)
+	skippedTicks atomic.Int64
 }
 
 func New(client *uniset.Client, store storage.Storage, interval, ttl time.Duration) *Poller {
@@ -38,8 +58,12 @@ func New(client *uniset.Client, store storage.Storage, interval, ttl time.Durati
 		ttl:             ttl,
 		serverID:        "", // будет использоваться DefaultServerID
 		watchedObjects:  make(map[string]bool),
+		pinnedObjects:   make(map[string]bool),
 		lastObjectData:  make(map[string]*uniset.ObjectData),
 		lastCleanupTime: time.Now(),
+		lastSaved:       make(map[string]map[string]savedValue),
+		concurrency:     DefaultConcurrency,
+		stats:           make(map[string]*objectStats),
 	}
 }
 
@@ -64,6 +88,32 @@ func (p *Poller) SetRecordingManager(mgr *recording.Manager) {
 	p.recordingMgr = mgr
 }
 
+// SetHistoryFilter устанавливает фильтр сохранения истории по умолчанию
+// и переопределения для отдельных объектов
+func (p *Poller) SetHistoryFilter(def HistoryFilter, objects map[string]HistoryFilter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.historyFilter = def
+	p.objectFilters = objects
+}
+
+// SetRetentionPolicy устанавливает правила хранения истории для периодической очистки
+func (p *Poller) SetRetentionPolicy(policy *storage.RetentionPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retention = policy
+}
+
+// SetConcurrency устанавливает количество объектов, опрашиваемых одновременно
+func (p *Poller) SetConcurrency(n int) {
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.concurrency = n
+}
+
 // Watch добавляет объект в список наблюдения
 func (p *Poller) Watch(objectName string) {
 	p.mu.Lock()
@@ -71,11 +121,25 @@ func (p *Poller) Watch(objectName string) {
 	p.watchedObjects[objectName] = true
 }
 
-// Unwatch удаляет объект из списка наблюдения
+// Pin добавляет объект в список наблюдения постоянно: Unwatch (закрытие вкладки клиентом)
+// его не снимает. Используется для объектов, переменные которых нужны серверу.
+func (p *Poller) Pin(objectName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchedObjects[objectName] = true
+	p.pinnedObjects[objectName] = true
+}
+
+// Unwatch удаляет объект из списка наблюдения (кроме закреплённых через Pin)
 func (p *Poller) Unwatch(objectName string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.pinnedObjects[objectName] {
+		return
+	}
 	delete(p.watchedObjects, objectName)
+	delete(p.lastSaved, objectName)
+	delete(p.stats, objectName)
 }
 
 // GetLastData возвращает последние полученные данные объекта
@@ -85,98 +149,294 @@ func (p *Poller) GetLastData(objectName string) *uniset.ObjectData {
 	return p.lastObjectData[objectName]
 }
 
-// Run запускает цикл опроса
+// Interval возвращает текущий интервал опроса
+func (p *Poller) Interval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+// SetInterval изменяет интервал опроса (применяется со следующего тика)
+func (p *Poller) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interval = interval
+}
+
+// Run запускает цикл опроса. Если предыдущий опрос ещё не завершён,
+// очередной тик пропускается.
 func (p *Poller) Run(ctx context.Context) {
-	ticker := time.NewTicker(p.interval)
+	interval := p.Interval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
 	// Первый опрос сразу
-	p.poll()
+	p.tick(ctx, &wg)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			p.poll()
+			p.tick(ctx, &wg)
+			if current := p.Interval(); current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
-func (p *Poller) poll() {
+// tick запускает опрос в фоне, если предыдущий уже завершился
+func (p *Poller) tick(ctx context.Context, wg *sync.WaitGroup) {
+	if ctx.Err() != nil {
+		return
+	}
+	if !p.polling.CompareAndSwap(false, true) {
+		p.skippedTicks.Add(1)
+		logger.Debug("Poll still in progress, tick skipped", "server", p.serverID)
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.polling.Store(false)
+		p.poll(ctx)
+	}()
+}
+
+// objectTimeout дедлайн запроса одного объекта: не больше интервала опроса
+func (p *Poller) objectTimeout() time.Duration {
+	interval := p.Interval()
+	if interval < minObjectTimeout {
+		return minObjectTimeout
+	}
+	return interval
+}
+
+// poll опрашивает отслеживаемые объекты пулом из concurrency воркеров.
+// Отмена ctx прекращает раздачу объектов и прерывает начатые запросы.
+func (p *Poller) poll(ctx context.Context) {
 	p.mu.RLock()
 	objects := make([]string, 0, len(p.watchedObjects))
 	for obj := range p.watchedObjects {
 		objects = append(objects, obj)
 	}
+	workers := p.concurrency
 	p.mu.RUnlock()
 
 	now := time.Now().UTC()
 
+	if workers > len(objects) {
+		workers = len(objects)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for objectName := range jobs {
+				p.pollObject(ctx, objectName, now)
+			}
+		}()
+	}
+
 	for _, objectName := range objects {
-		data, err := p.client.GetObjectData(objectName)
-		if err != nil {
-			logger.Warn("Poll failed", "object", objectName, "error", err)
-			continue
+		select {
+		case jobs <- objectName:
+		case <-ctx.Done():
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		p.mu.Lock()
-		p.lastObjectData[objectName] = data
-		callback := p.eventCallback
-		p.mu.Unlock()
-
-		// Уведомляем SSE клиентов о новых данных
-		if callback != nil {
-			callback(objectName, data)
+	// Периодическая очистка старых данных
+	if time.Since(p.lastCleanupTime) > time.Minute {
+		if err := p.cleanup(now); err != nil {
+			logger.Warn("Cleanup failed", "error", err)
 		}
+		p.lastCleanupTime = now
+	}
+}
 
-		// Сохраняем переменные в историю
-		if data.Variables != nil {
-			for varName, value := range data.Variables {
-				if err := p.storage.Save(p.serverID, objectName, varName, value, now); err != nil {
-					logger.Warn("Save variable failed", "object", objectName, "var", varName, "error", err)
-				}
-				// Сохраняем в recording (если включено)
-				if p.recordingMgr != nil {
-					p.recordingMgr.Save(p.serverID, objectName, varName, value, now)
-				}
-			}
+// pollObject запрашивает данные объекта с дедлайном, уведомляет подписчиков и сохраняет историю
+func (p *Poller) pollObject(ctx context.Context, objectName string, now time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, p.objectTimeout())
+	start := time.Now()
+	data, err := p.client.GetObjectDataContext(reqCtx, objectName)
+	latency := time.Since(start)
+	cancel()
+
+	// Остановка poller'а прерывает запрос - это не ошибка опроса и не пропуск данных
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Запрос пропущен: сервер или объект на паузе после повторных ошибок
+	if uniset.IsBackoff(err) {
+		logger.Debug("Poll skipped", "object", objectName, "reason", err)
+		p.markGap(objectName, now)
+		return
+	}
+
+	p.recordLatency(objectName, latency, err)
+
+	if err != nil {
+		logger.Warn("Poll failed", "object", objectName, "latency", latency, "error", err)
+		p.markGap(objectName, now)
+		return
+	}
+
+	p.mu.Lock()
+	p.lastObjectData[objectName] = data
+	callback := p.eventCallback
+	p.mu.Unlock()
+
+	// Уведомляем SSE клиентов о новых данных
+	if callback != nil {
+		callback(objectName, data)
+	}
+
+	// Сохраняем переменные в историю
+	if data.Variables != nil {
+		for varName, value := range data.Variables {
+			p.saveValue(objectName, varName, value, now)
 		}
+	}
 
-		// Сохраняем IO данные
-		if data.IO != nil {
-			if data.IO.In != nil {
-				for key, io := range data.IO.In {
-					varName := "io.in." + key
-					if err := p.storage.Save(p.serverID, objectName, varName, io.Value, now); err != nil {
-						logger.Warn("Save IO input failed", "object", objectName, "var", varName, "error", err)
-					}
-					// Сохраняем в recording (если включено)
-					if p.recordingMgr != nil {
-						p.recordingMgr.Save(p.serverID, objectName, varName, io.Value, now)
-					}
-				}
+	// Сохраняем IO данные
+	if data.IO != nil {
+		if data.IO.In != nil {
+			for key, io := range data.IO.In {
+				p.saveValue(objectName, "io.in."+key, io.Value, now)
 			}
-			if data.IO.Out != nil {
-				for key, io := range data.IO.Out {
-					varName := "io.out." + key
-					if err := p.storage.Save(p.serverID, objectName, varName, io.Value, now); err != nil {
-						logger.Warn("Save IO output failed", "object", objectName, "var", varName, "error", err)
-					}
-					// Сохраняем в recording (если включено)
-					if p.recordingMgr != nil {
-						p.recordingMgr.Save(p.serverID, objectName, varName, io.Value, now)
-					}
-				}
+		}
+		if data.IO.Out != nil {
+			for key, io := range data.IO.Out {
+				p.saveValue(objectName, "io.out."+key, io.Value, now)
 			}
 		}
 	}
+}
 
-	// Периодическая очистка старых данных
-	if time.Since(p.lastCleanupTime) > time.Minute {
-		if err := p.storage.Cleanup(now.Add(-p.ttl)); err != nil {
-			logger.Warn("Cleanup failed", "error", err)
+// recordLatency учитывает время опроса объекта
+func (p *Poller) recordLatency(objectName string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Объект могли снять с наблюдения во время запроса
+	if !p.watchedObjects[objectName] {
+		return
+	}
+	st, ok := p.stats[objectName]
+	if !ok {
+		st = &objectStats{}
+		p.stats[objectName] = st
+	}
+	st.record(latency, time.Now(), err)
+}
+
+// PollStats возвращает статистику опроса; объекты упорядочены от самых медленных
+func (p *Poller) PollStats() PollStats {
+	p.mu.RLock()
+	result := PollStats{
+		Concurrency:     p.concurrency,
+		ObjectTimeoutMs: p.objectTimeout().Milliseconds(),
+		SkippedTicks:    p.skippedTicks.Load(),
+		Objects:         make([]ObjectPollStats, 0, len(p.stats)),
+	}
+	for name, st := range p.stats {
+		result.Objects = append(result.Objects, st.snapshot(name))
+	}
+	p.mu.RUnlock()
+
+	sortBySlowest(result.Objects)
+	return result
+}
+
+// MarkGaps отмечает начало периода без данных для всех отслеживаемых объектов
+// (например, при потере связи с сервером)
+func (p *Poller) MarkGaps(now time.Time) {
+	p.mu.RLock()
+	objects := make([]string, 0, len(p.watchedObjects))
+	for obj := range p.watchedObjects {
+		objects = append(objects, obj)
+	}
+	p.mu.RUnlock()
+
+	for _, objectName := range objects {
+		p.markGap(objectName, now)
+	}
+}
+
+// markGap сохраняет отметку пропуска для переменных объекта, сохранённых ранее.
+// Повторные вызовы до восстановления опроса ничего не делают; после восстановления
+// первое значение каждой переменной будет сохранено независимо от фильтра.
+func (p *Poller) markGap(objectName string, now time.Time) {
+	p.mu.Lock()
+	vars := make([]string, 0, len(p.lastSaved[objectName]))
+	for varName := range p.lastSaved[objectName] {
+		vars = append(vars, varName)
+	}
+	delete(p.lastSaved, objectName)
+	p.mu.Unlock()
+
+	for _, varName := range vars {
+		if err := p.storage.SaveGap(p.serverID, objectName, varName, now); err != nil {
+			logger.Warn("Save gap failed", "object", objectName, "var", varName, "error", err)
 		}
-		p.lastCleanupTime = now
+	}
+}
+
+// cleanup удаляет устаревшие данные по правилам хранения, если хранилище их поддерживает
+func (p *Poller) cleanup(now time.Time) error {
+	p.mu.RLock()
+	policy := p.retention
+	p.mu.RUnlock()
+
+	if cleaner, ok := p.storage.(storage.RetentionCleaner); ok && policy != nil {
+		return cleaner.CleanupRetention(policy, now)
+	}
+	return p.storage.Cleanup(now.Add(-p.ttl))
+}
+
+// saveValue сохраняет значение переменной в историю и recording,
+// если оно прошло фильтр сохранения (изменение, deadband, heartbeat)
+func (p *Poller) saveValue(objectName, varName string, value interface{}, now time.Time) {
+	p.mu.Lock()
+	filter, ok := p.objectFilters[objectName]
+	if !ok {
+		filter = p.historyFilter
+	}
+	last, exists := p.lastSaved[objectName][varName]
+	if !filter.shouldSave(last, exists, value, now) {
+		p.mu.Unlock()
+		return
+	}
+	if p.lastSaved[objectName] == nil {
+		p.lastSaved[objectName] = make(map[string]savedValue)
+	}
+	p.lastSaved[objectName][varName] = newSavedValue(value, now)
+	p.mu.Unlock()
+
+	if err := p.storage.Save(p.serverID, objectName, varName, value, now); err != nil {
+		logger.Warn("Save variable failed", "object", objectName, "var", varName, "error", err)
+	}
+	// Сохраняем в recording (если включено)
+	if p.recordingMgr != nil {
+		p.recordingMgr.Save(p.serverID, objectName, varName, value, now)
 	}
 }
@@ -74,6 +74,24 @@ func TestPollerWatchUnwatch(t *testing.T) {
 	p.mu.RUnlock()
 }
 
+func TestPollerPinSurvivesUnwatch(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	client := uniset.NewClient("http://localhost:9999")
+	p := New(client, store, time.Second, time.Hour)
+
+	p.Pin("TestProc")
+	p.Watch("TestProc")
+	p.Unwatch("TestProc")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.watchedObjects["TestProc"] {
+		t.Error("pinned object must stay watched after Unwatch")
+	}
+}
+
 func TestPollerGetLastData(t *testing.T) {
 	store := storage.NewMemoryStorage()
 	defer store.Close()
@@ -257,6 +275,8 @@ func TestPollerContextCancellation(t *testing.T) {
 		t.Fatal("Run did not exit after context cancellation")
 	}
 
+	// A request aborted by cancellation may still reach the server; let it settle
+	time.Sleep(20 * time.Millisecond)
 	countBefore := atomic.LoadInt32(&pollCount)
 
 	// Wait and verify no more polls
@@ -393,7 +413,7 @@ func TestPollerCleanup(t *testing.T) {
 	p.Watch("TestProc")
 
 	// Single poll should trigger cleanup
-	p.poll()
+	p.poll(context.Background())
 
 	// Verify cleanup was called (lastCleanupTime should be updated)
 	if time.Since(p.lastCleanupTime) > time.Second {
@@ -546,7 +566,7 @@ func TestPollerSetEventCallbackNil(t *testing.T) {
 	p.Watch("TestProc")
 
 	// Не должен паниковать при nil callback
-	p.poll()
+	p.poll(context.Background())
 
 	// Проверяем что данные всё равно сохраняются
 	data := p.GetLastData("TestProc")
@@ -645,7 +665,7 @@ func TestPollerPollWithIOData(t *testing.T) {
 	p.SetServerID("test-server")
 	p.Watch("TestProc")
 
-	p.poll()
+	p.poll(context.Background())
 
 	// Check variable was saved with serverID
 	history, err := store.GetLatest("test-server", "TestProc", "value", 10)
@@ -674,3 +694,310 @@ func TestPollerPollWithIOData(t *testing.T) {
 		t.Error("expected IO out history points")
 	}
 }
+
+func TestPollerHistoryFilterChangeOnly(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	var pollCount int32
+
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&pollCount, 1)
+		response := map[string]interface{}{
+			"TestProc": map[string]interface{}{
+				"Variables": map[string]interface{}{
+					"constant": 42,
+					"changing": count,
+				},
+			},
+			"Other": map[string]interface{}{
+				"Variables": map[string]interface{}{
+					"constant": 42,
+				},
+			},
+			"object": map[string]interface{}{
+				"id":   6000,
+				"name": "TestProc",
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	defer server.Close()
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, time.Second, time.Hour)
+	// Для Other фильтр выключен - сохраняется каждая точка
+	p.SetHistoryFilter(HistoryFilter{ChangeOnly: true}, map[string]HistoryFilter{
+		"Other": {},
+	})
+	p.Watch("TestProc")
+
+	for i := 0; i < 3; i++ {
+		p.poll(context.Background())
+	}
+
+	constant, _ := store.GetLatest("", "TestProc", "constant", 10)
+	if len(constant.Points) != 1 {
+		t.Errorf("expected 1 point for unchanged variable, got %d", len(constant.Points))
+	}
+
+	changing, _ := store.GetLatest("", "TestProc", "changing", 10)
+	if len(changing.Points) != 3 {
+		t.Errorf("expected 3 points for changing variable, got %d", len(changing.Points))
+	}
+
+	p.Unwatch("TestProc")
+	p.Watch("Other")
+	for i := 0; i < 3; i++ {
+		p.poll(context.Background())
+	}
+
+	other, _ := store.GetLatest("", "Other", "constant", 10)
+	if len(other.Points) != 3 {
+		t.Errorf("expected 3 points for object without filter, got %d", len(other.Points))
+	}
+}
+
+func TestPollerCleanupUsesRetentionPolicy(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	client := uniset.NewClient("http://localhost:9999")
+	p := New(client, store, time.Second, time.Hour)
+
+	policy, err := storage.NewRetentionPolicy(time.Hour, []storage.RetentionRule{
+		{Variable: "io.*", TTL: 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+	p.SetRetentionPolicy(policy)
+
+	now := time.Now()
+	store.Save("", "Obj", "io.in.x", 1, now.Add(-2*time.Hour))
+	store.Save("", "Obj", "var", 1, now.Add(-2*time.Hour))
+
+	if err := p.cleanup(now); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+
+	io, _ := store.GetLatest("", "Obj", "io.in.x", 10)
+	if len(io.Points) != 1 {
+		t.Errorf("expected io.* point to be kept by rule, got %d points", len(io.Points))
+	}
+	v, _ := store.GetLatest("", "Obj", "var", 10)
+	if len(v.Points) != 0 {
+		t.Errorf("expected var point to be removed by default TTL, got %d points", len(v.Points))
+	}
+}
+
+func TestPollerMarksGapOnFailure(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	var failing int32
+
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response := map[string]interface{}{
+			"TestProc": map[string]interface{}{
+				"Variables": map[string]interface{}{
+					"value": 42,
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	defer server.Close()
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, time.Second, time.Hour)
+	p.SetHistoryFilter(HistoryFilter{ChangeOnly: true}, nil)
+	p.Watch("TestProc")
+
+	p.poll(context.Background())
+
+	// Повторные ошибки дают одну отметку пропуска
+	atomic.StoreInt32(&failing, 1)
+	p.poll(context.Background())
+	p.poll(context.Background())
+
+	// После восстановления значение сохраняется, хотя оно не изменилось
+	atomic.StoreInt32(&failing, 0)
+	p.poll(context.Background())
+
+	history, _ := store.GetLatest("", "TestProc", "value", 10)
+	if len(history.Points) != 3 {
+		t.Fatalf("expected value, gap, value; got %+v", history.Points)
+	}
+	if history.Points[0].Gap || !history.Points[1].Gap || history.Points[2].Gap {
+		t.Errorf("expected gap marker in the middle, got %+v", history.Points)
+	}
+}
+
+func TestPollerMarkGaps(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"TestProc": map[string]interface{}{
+				"Variables": map[string]interface{}{
+					"a": 1,
+					"b": 2,
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	defer server.Close()
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, time.Second, time.Hour)
+	p.Watch("TestProc")
+	p.poll(context.Background())
+
+	p.MarkGaps(time.Now().UTC())
+
+	for _, name := range []string{"a", "b"} {
+		history, _ := store.GetLatest("", "TestProc", name, 1)
+		if len(history.Points) != 1 || !history.Points[0].Gap {
+			t.Errorf("expected gap marker for %s, got %+v", name, history.Points)
+		}
+	}
+}
+
+func TestPollerSlowObjectDoesNotBlockOthers(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	release := make(chan struct{})
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v2/")
+		if name == "Slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		response := map[string]interface{}{
+			name: map[string]interface{}{
+				"Variables": map[string]interface{}{"value": 1},
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	defer server.Close()
+	defer close(release)
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, 100*time.Millisecond, time.Hour)
+	p.SetConcurrency(2)
+	p.Watch("Slow")
+	p.Watch("Fast1")
+	p.Watch("Fast2")
+
+	start := time.Now()
+	p.poll(context.Background())
+	elapsed := time.Since(start)
+
+	// Медленный объект ограничен дедлайном (не меньше minObjectTimeout)
+	if elapsed > minObjectTimeout+500*time.Millisecond {
+		t.Errorf("poll took %v, expected it to be bounded by object deadline", elapsed)
+	}
+	if p.GetLastData("Fast1") == nil || p.GetLastData("Fast2") == nil {
+		t.Error("fast objects should be polled while slow object hangs")
+	}
+	if p.GetLastData("Slow") != nil {
+		t.Error("slow object should time out")
+	}
+
+	stats := p.PollStats()
+	if stats.Concurrency != 2 {
+		t.Errorf("expected concurrency 2, got %d", stats.Concurrency)
+	}
+	if len(stats.Objects) != 3 {
+		t.Fatalf("expected stats for 3 objects, got %d", len(stats.Objects))
+	}
+	// Самый медленный объект - первым
+	if stats.Objects[0].Object != "Slow" || stats.Objects[0].Timeouts != 1 {
+		t.Errorf("expected Slow with 1 timeout first, got %+v", stats.Objects[0])
+	}
+}
+
+func TestPollerSkipsOverlappingTicks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	var requests int32
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(120 * time.Millisecond)
+		response := map[string]interface{}{
+			"TestProc": map[string]interface{}{
+				"Variables": map[string]interface{}{"value": 1},
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	defer server.Close()
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, 20*time.Millisecond, time.Hour)
+	p.Watch("TestProc")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	if got := atomic.LoadInt32(&requests); got > 3 {
+		t.Errorf("expected overlapping ticks to be skipped, got %d requests", got)
+	}
+	if p.PollStats().SkippedTicks == 0 {
+		t.Error("expected skipped ticks to be counted")
+	}
+}
+
+func TestPollerCancellationAbortsInFlightRequest(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	started := make(chan struct{}, 1)
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	})
+	defer server.Close()
+
+	client := uniset.NewClient(server.URL)
+	p := New(client, store, 10*time.Second, time.Hour)
+	p.Watch("TestProc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Run did not abort in-flight request on cancellation")
+	}
+
+	// Прерванный запрос не считается ни ошибкой, ни пропуском данных
+	if len(p.PollStats().Objects) != 0 {
+		t.Error("aborted request should not be recorded in poll stats")
+	}
+}
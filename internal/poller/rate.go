@@ -0,0 +1,85 @@
+package poller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RateClass класс частоты опроса подписки
+type RateClass string
+
+const (
+	RateNormal RateClass = "normal" // интервал poller'а
+	RateFast   RateClass = "fast"   // интервал / fastRateDivisor
+	RateSlow   RateClass = "slow"   // интервал * slowRateFactor
+)
+
+const (
+	fastRateDivisor = 4
+	slowRateFactor  = 5
+
+	// minRateInterval минимальный интервал опроса группы
+	minRateInterval = 100 * time.Millisecond
+)
+
+// Rate частота опроса подписки: класс или явный интервал (Interval имеет приоритет).
+// Нулевое значение - RateNormal.
+type Rate struct {
+	Class    RateClass
+	Interval time.Duration
+}
+
+// ParseRate разбирает частоту опроса: "fast", "normal", "slow" или длительность ("250ms", "10s").
+// Пустая строка - RateNormal.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	switch RateClass(strings.ToLower(s)) {
+	case "", RateNormal:
+		return Rate{Class: RateNormal}, nil
+	case RateFast:
+		return Rate{Class: RateFast}, nil
+	case RateSlow:
+		return Rate{Class: RateSlow}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected fast, normal, slow or duration", s)
+	}
+	if d < minRateInterval {
+		return Rate{}, fmt.Errorf("invalid rate %q: must be at least %s", s, minRateInterval)
+	}
+	return Rate{Interval: d}, nil
+}
+
+// String возвращает класс или интервал в формате ParseRate
+func (r Rate) String() string {
+	if r.Interval > 0 {
+		return r.Interval.String()
+	}
+	if r.Class == "" {
+		return string(RateNormal)
+	}
+	return string(r.Class)
+}
+
+// effective возвращает интервал опроса для базового интервала poller'а
+func (r Rate) effective(base time.Duration) time.Duration {
+	switch {
+	case r.Interval > 0:
+		return r.Interval
+	case r.Class == RateFast:
+		// Не чаще minRateInterval, но и не реже базового интервала
+		return max(base/fastRateDivisor, min(minRateInterval, base))
+	case r.Class == RateSlow:
+		return base * slowRateFactor
+	}
+	return base
+}
+
+// RateGroup группа подписок с общим интервалом опроса
+type RateGroup struct {
+	IntervalMs int64 `json:"intervalMs"`
+	Items      int   `json:"items"`
+}
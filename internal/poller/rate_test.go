@@ -0,0 +1,160 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{"", Rate{Class: RateNormal}, false},
+		{"fast", Rate{Class: RateFast}, false},
+		{"SLOW", Rate{Class: RateSlow}, false},
+		{"250ms", Rate{Interval: 250 * time.Millisecond}, false},
+		{"10ms", Rate{}, true},
+		{"often", Rate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateEffective(t *testing.T) {
+	base := time.Second
+	tests := []struct {
+		rate Rate
+		want time.Duration
+	}{
+		{Rate{}, time.Second},
+		{Rate{Class: RateFast}, 250 * time.Millisecond},
+		{Rate{Class: RateSlow}, 5 * time.Second},
+		{Rate{Class: RateSlow, Interval: 2 * time.Second}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.rate.effective(base); got != tt.want {
+			t.Errorf("%s.effective(1s) = %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+
+	// fast не чаще minRateInterval и не реже базового интервала
+	if got := (Rate{Class: RateFast}).effective(200 * time.Millisecond); got != minRateInterval {
+		t.Errorf("fast.effective(200ms) = %v, want %v", got, minRateInterval)
+	}
+	if got := (Rate{Class: RateFast}).effective(50 * time.Millisecond); got != 50*time.Millisecond {
+		t.Errorf("fast.effective(50ms) = %v, want 50ms", got)
+	}
+}
+
+func TestBasePollerRateGroups(t *testing.T) {
+	bp := NewBasePoller[MockItem, MockUpdate](
+		time.Second, 100, &MockFetcher{},
+		func(objectName string, item MockItem, ts time.Time) MockUpdate { return MockUpdate{} },
+		nil, "Test",
+	)
+
+	bp.Subscribe("Object1", []int64{1, 2}, Rate{Class: RateFast})
+	bp.Subscribe("Object1", []int64{3, 4, 5})
+	bp.Subscribe("Object2", []int64{6}, Rate{Class: RateSlow})
+
+	// Повторная подписка с более редкой частотой не замедляет элемент
+	bp.Subscribe("Object1", []int64{1}, Rate{Class: RateSlow})
+	// Повторная подписка с более частой - ускоряет
+	bp.Subscribe("Object2", []int64{6}, Rate{Class: RateFast})
+
+	groups := bp.RateGroups()
+	want := []RateGroup{
+		{IntervalMs: 250, Items: 3},
+		{IntervalMs: 1000, Items: 3},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("RateGroups() = %+v, want %+v", groups, want)
+	}
+	for i := range want {
+		if groups[i] != want[i] {
+			t.Errorf("RateGroups()[%d] = %+v, want %+v", i, groups[i], want[i])
+		}
+	}
+
+	rates := bp.GetSubscriptionRates("Object1")
+	if rates[1].Class != RateFast || rates[3].Class != "" {
+		t.Errorf("unexpected rates: %+v", rates)
+	}
+}
+
+// recordingFetcher запоминает ID, запрошенные в каждом вызове
+type recordingFetcher struct {
+	mu    sync.Mutex
+	calls [][]int64
+}
+
+func (f *recordingFetcher) FetchItems(ctx context.Context, objectName string, ids []int64) ([]MockItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]int64(nil), ids...))
+	return nil, nil
+}
+
+func (f *recordingFetcher) GetItemID(item MockItem) int64     { return item.ID }
+func (f *recordingFetcher) GetValueHash(item MockItem) string { return "" }
+
+// polled возвращает количество вызовов, в которых запрашивался id
+func (f *recordingFetcher) polled(id int64) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, ids := range f.calls {
+		for _, got := range ids {
+			if got == id {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+func TestBasePollerPollsRateGroupsIndependently(t *testing.T) {
+	fetcher := &recordingFetcher{}
+	bp := NewBasePoller[MockItem, MockUpdate](
+		400*time.Millisecond, 100, fetcher,
+		func(objectName string, item MockItem, ts time.Time) MockUpdate { return MockUpdate{} },
+		nil, "Test",
+	)
+
+	bp.Subscribe("Object1", []int64{1}, Rate{Class: RateFast}) // 100ms
+	bp.Subscribe("Object1", []int64{2})                        // 400ms
+	bp.Subscribe("Object1", []int64{3}, Rate{Class: RateSlow}) // 2s
+
+	bp.Start()
+	time.Sleep(950 * time.Millisecond)
+	bp.Stop()
+
+	fast, normal, slow := fetcher.polled(1), fetcher.polled(2), fetcher.polled(3)
+	if fast < 5 {
+		t.Errorf("fast item polled %d times, want at least 5", fast)
+	}
+	if normal < 2 || normal > 3 {
+		t.Errorf("normal item polled %d times, want 2..3", normal)
+	}
+	// Новая группа опрашивается сразу, повторно - только через свой интервал
+	if slow != 1 {
+		t.Errorf("slow item polled %d times, want 1 within first interval", slow)
+	}
+	if fast <= normal*2 {
+		t.Errorf("fast item must be polled more often: fast=%d normal=%d", fast, normal)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
@@ -37,6 +38,9 @@ type StatusEventCallback func(serverID, serverName string, connected bool, lastE
 // ObjectsChangedCallback вызывается при изменении списка объектов (восстановление связи)
 type ObjectsChangedCallback func(serverID, serverName string, objects []string)
 
+// ServerAddedCallback вызывается после добавления и запуска сервера
+type ServerAddedCallback func(instance *Instance)
+
 // Instance представляет подключение к одному UniSet2 серверу
 type Instance struct {
 	Config        config.ServerConfig
@@ -55,6 +59,8 @@ type Instance struct {
 	statusCallback   StatusEventCallback
 	objectsCallback  ObjectsChangedCallback
 	healthInterval   time.Duration
+	pollInterval     time.Duration
+	ownPollInterval  bool // интервал опроса задан для сервера (не меняется глобальной настройкой)
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -75,8 +81,28 @@ func NewInstance(
 	opcuaCallback OPCUAEventCallback,
 	statusCallback StatusEventCallback,
 	objectsCallback ObjectsChangedCallback,
-) *Instance {
+) (*Instance, error) {
+	// Настройки сервера переопределяют глобальные
+	if cfg.PollInterval > 0 {
+		pollInterval = cfg.PollInterval
+	}
+	if cfg.SensorBatchSize > 0 {
+		sensorBatchSize = cfg.SensorBatchSize
+	}
+	if cfg.Supplier != "" {
+		supplier = cfg.Supplier
+	}
+	healthInterval := pollInterval // по умолчанию health check с интервалом опроса
+	if cfg.HealthInterval > 0 {
+		healthInterval = cfg.HealthInterval
+	}
+
+	httpClient, err := cfg.HTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("server %q connection settings: %w", cfg.ID, err)
+	}
 	client := uniset.NewClientWithSupplier(cfg.URL, supplier)
+	client.SetHTTPClient(httpClient)
 
 	// Создаём poller
 	p := poller.New(client, store, pollInterval, historyTTL)
@@ -130,10 +156,12 @@ func NewInstance(
 		OPCUAPoller:     opcuaPoller,
 		statusCallback:  statusCallback,
 		objectsCallback: objectsCallback,
-		healthInterval:  pollInterval, // используем poll interval для health check
+		healthInterval:  healthInterval,
+		pollInterval:    pollInterval,
+		ownPollInterval: cfg.PollInterval > 0,
 		ctx:             ctx,
 		cancel:          cancel,
-	}
+	}, nil
 }
 
 // Start запускает все pollers и health check
@@ -181,7 +209,11 @@ func (i *Instance) runHealthCheck() {
 
 // checkHealth проверяет доступность сервера и обновляет статус
 func (i *Instance) checkHealth(serverName string) {
-	objects, err := i.Client.GetObjectList()
+	objects, err := i.Client.GetObjectListContext(i.ctx)
+	// Остановка экземпляра прерывает запрос - это не потеря связи
+	if i.ctx.Err() != nil {
+		return
+	}
 
 	i.mu.RLock()
 	wasConnected := i.connected
@@ -228,12 +260,17 @@ func (i *Instance) Stop() {
 
 // CreateUWSGatePoller создаёт UWebSocketGate poller "лениво"
 // Возвращает существующий poller если уже создан
-func (i *Instance) CreateUWSGatePoller(callback UWSGateEventCallback) *uwsgate.Poller {
+func (i *Instance) CreateUWSGatePoller(callback UWSGateEventCallback) (*uwsgate.Poller, error) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
 	if i.UWSGatePoller != nil {
-		return i.UWSGatePoller
+		return i.UWSGatePoller, nil
+	}
+
+	tlsConfig, err := i.Config.TLSClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("server %q TLS settings: %w", i.Config.ID, err)
 	}
 
 	serverID := i.Config.ID
@@ -248,16 +285,16 @@ func (i *Instance) CreateUWSGatePoller(callback UWSGateEventCallback) *uwsgate.P
 		}
 	}, slog.Default())
 	i.UWSGatePoller.SetServerID(serverID)
+	i.UWSGatePoller.SetConnection(tlsConfig, i.Config.RequestHeader(), i.Config.GetTimeout())
 
 	// Запускаем poller
 	if err := i.UWSGatePoller.Start(i.ctx); err != nil {
-		slog.Error("Failed to start UWSGate poller", "error", err)
 		i.UWSGatePoller = nil
-		return nil
+		return nil, fmt.Errorf("start UWSGate poller: %w", err)
 	}
 
 	slog.Info("UWSGate poller created", "id", i.Config.ID)
-	return i.UWSGatePoller
+	return i.UWSGatePoller, nil
 }
 
 // GetStatus возвращает текущий статус сервера
@@ -270,6 +307,8 @@ func (i *Instance) GetStatus() Status {
 		name = i.Config.URL
 	}
 
+	breaker, backoff := i.Client.ResilienceStatus()
+
 	return Status{
 		ID:          i.Config.ID,
 		URL:         i.Config.URL,
@@ -278,6 +317,8 @@ func (i *Instance) GetStatus() Status {
 		LastPoll:    i.lastPoll,
 		LastError:   i.lastError,
 		ObjectCount: i.objectCount,
+		Breaker:     &breaker,
+		Backoff:     backoff,
 	}
 }
 
@@ -289,6 +330,59 @@ func (i *Instance) SetHealthInterval(interval time.Duration) {
 	// Примечание: ticker будет обновлён при следующей итерации runHealthCheck
 }
 
+// PollSettings действующие настройки опроса сервера
+type PollSettings struct {
+	Interval        time.Duration
+	OwnInterval     bool // интервал задан для сервера, а не взят из глобальной настройки
+	HealthInterval  time.Duration
+	SensorBatchSize int
+	Supplier        string
+}
+
+// GetPollSettings возвращает действующие настройки опроса
+func (i *Instance) GetPollSettings() PollSettings {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return PollSettings{
+		Interval:        i.pollInterval,
+		OwnInterval:     i.ownPollInterval,
+		HealthInterval:  i.healthInterval,
+		SensorBatchSize: i.IONCPoller.BatchSize(),
+		Supplier:        i.Client.Supplier,
+	}
+}
+
+// SetPollInterval изменяет интервал опроса всех pollers сервера.
+// Health check следует за интервалом опроса, если для сервера не задан healthInterval.
+func (i *Instance) SetPollInterval(interval time.Duration) {
+	i.mu.Lock()
+	i.pollInterval = interval
+	if i.Config.HealthInterval <= 0 {
+		i.healthInterval = interval
+	}
+	i.mu.Unlock()
+
+	i.Poller.SetInterval(interval)
+	i.IONCPoller.SetInterval(interval)
+	i.ModbusPoller.SetInterval(interval)
+	i.OPCUAPoller.SetInterval(interval)
+}
+
+// setOwnPollInterval задаёт интервал опроса сервера; own=false - сервер снова следует глобальной настройке
+func (i *Instance) setOwnPollInterval(interval time.Duration, own bool) {
+	i.mu.Lock()
+	i.ownPollInterval = own
+	i.mu.Unlock()
+	i.SetPollInterval(interval)
+}
+
+// hasOwnPollInterval возвращает true, если интервал опроса задан для сервера
+func (i *Instance) hasOwnPollInterval() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.ownPollInterval
+}
+
 // UpdateStatus обновляет статус подключения
 func (i *Instance) UpdateStatus(connected bool, err error) {
 	i.mu.Lock()
@@ -314,8 +408,14 @@ func (i *Instance) UpdateStatus(connected bool, err error) {
 		serverName = i.Config.URL
 	}
 
+	objectPoller := i.Poller
 	i.mu.Unlock()
 
+	// При потере связи отмечаем пропуск данных в истории
+	if prevConnected && !connected && objectPoller != nil {
+		objectPoller.MarkGaps(time.Now().UTC())
+	}
+
 	// Вызываем callback вне лока, только если статус изменился
 	if statusChanged && callback != nil {
 		slog.Info("Server status changed",
@@ -336,9 +436,17 @@ func (i *Instance) SetObjectCount(count int) {
 
 // GetObjects возвращает список объектов сервера
 func (i *Instance) GetObjects() ([]string, error) {
-	objects, err := i.Client.GetObjectList()
+	return i.GetObjectsContext(context.Background())
+}
+
+// GetObjectsContext возвращает список объектов сервера с учётом отмены ctx
+func (i *Instance) GetObjectsContext(ctx context.Context) ([]string, error) {
+	objects, err := i.Client.GetObjectListContext(ctx)
 	if err != nil {
-		i.UpdateStatus(false, err)
+		// Отменённый запрос (клиент отключился) не говорит о недоступности сервера
+		if ctx.Err() == nil {
+			i.UpdateStatus(false, err)
+		}
 		return nil, err
 	}
 
@@ -349,9 +457,16 @@ func (i *Instance) GetObjects() ([]string, error) {
 
 // GetObjectData возвращает данные объекта
 func (i *Instance) GetObjectData(objectName string) (*uniset.ObjectData, error) {
-	data, err := i.Client.GetObjectData(objectName)
+	return i.GetObjectDataContext(context.Background(), objectName)
+}
+
+// GetObjectDataContext возвращает данные объекта с учётом отмены ctx
+func (i *Instance) GetObjectDataContext(ctx context.Context, objectName string) (*uniset.ObjectData, error) {
+	data, err := i.Client.GetObjectDataContext(ctx, objectName)
 	if err != nil {
-		i.UpdateStatus(false, err)
+		if ctx.Err() == nil {
+			i.UpdateStatus(false, err)
+		}
 		return nil, err
 	}
 
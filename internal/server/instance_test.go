@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -52,7 +53,10 @@ func TestNewInstance(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "TestSupplier", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "TestSupplier", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	if instance == nil {
 		t.Fatal("NewInstance returned nil")
@@ -75,6 +79,20 @@ func TestNewInstance(t *testing.T) {
 	}
 }
 
+func TestNewInstanceRejectsInvalidTLS(t *testing.T) {
+	cfg := config.ServerConfig{
+		ID:  "tls-server",
+		URL: "https://localhost:1",
+		TLS: &config.ServerTLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	}
+
+	// Без доверенных CA клиент не должен молча подключаться с настройками по умолчанию
+	instance, err := NewInstance(cfg, storage.NewMemoryStorage(), time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err == nil || instance != nil {
+		t.Fatalf("expected error for missing CA file, got instance=%v err=%v", instance, err)
+	}
+}
+
 func TestInstanceGetStatus(t *testing.T) {
 	server := mockUnisetServer()
 	defer server.Close()
@@ -86,7 +104,10 @@ func TestInstanceGetStatus(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	status := instance.GetStatus()
 
@@ -118,7 +139,10 @@ func TestInstanceGetStatusWithEmptyName(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	status := instance.GetStatus()
 
@@ -151,7 +175,10 @@ func TestInstanceUpdateStatus(t *testing.T) {
 		mu.Unlock()
 	}
 
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Update status to connected
 	instance.UpdateStatus(true, nil)
@@ -186,7 +213,10 @@ func TestInstanceUpdateStatusWithError(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// First set connected
 	instance.UpdateStatus(true, nil)
@@ -216,7 +246,10 @@ func TestInstanceSetHealthInterval(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Change health interval
 	instance.SetHealthInterval(5 * time.Second)
@@ -242,7 +275,10 @@ func TestInstanceSetObjectCount(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	instance.SetObjectCount(42)
 
@@ -263,7 +299,10 @@ func TestInstanceGetObjects(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	objects, err := instance.GetObjects()
 	if err != nil {
@@ -296,12 +335,15 @@ func TestInstanceGetObjectsError(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// First mark as connected
 	instance.UpdateStatus(true, nil)
 
-	_, err := instance.GetObjects()
+	_, err = instance.GetObjects()
 	if err == nil {
 		t.Fatal("expected error from unavailable server")
 	}
@@ -324,7 +366,10 @@ func TestInstanceGetObjectData(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	data, err := instance.GetObjectData("TestProc")
 	if err != nil {
@@ -357,7 +402,10 @@ func TestInstanceWatchUnwatch(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Watch should not panic
 	instance.Watch("TestProc")
@@ -377,7 +425,10 @@ func TestInstanceStartStop(t *testing.T) {
 	}
 
 	store := storage.NewMemoryStorage()
-	instance := NewInstance(cfg, store, 100*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	instance, err := NewInstance(cfg, store, 100*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Start should not panic
 	instance.Start()
@@ -421,7 +472,10 @@ func TestInstanceHealthCheckUpdatesStatus(t *testing.T) {
 		mu.Unlock()
 	}
 
-	instance := NewInstance(cfg, store, 50*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	instance, err := NewInstance(cfg, store, 50*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Start instance to run health check
 	instance.Start()
@@ -468,7 +522,10 @@ func TestInstanceObjectsCallbackOnReconnect(t *testing.T) {
 		mu.Unlock()
 	}
 
-	instance := NewInstance(cfg, store, 50*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, nil, objectsCallback)
+	instance, err := NewInstance(cfg, store, 50*time.Millisecond, time.Hour, "", 0, nil, nil, nil, nil, nil, objectsCallback)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Start instance - this triggers health check which should call objectsCallback on first connect
 	instance.Start()
@@ -511,7 +568,10 @@ func TestInstanceStatusCallbackOnlyOnChange(t *testing.T) {
 		mu.Unlock()
 	}
 
-	instance := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	instance, err := NewInstance(cfg, store, time.Second, time.Hour, "", 0, nil, nil, nil, nil, statusCallback, nil)
+	if err != nil {
+		t.Fatalf("NewInstance failed: %v", err)
+	}
 
 	// Update status multiple times with same value
 	instance.UpdateStatus(true, nil)
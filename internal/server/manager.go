@@ -11,6 +11,7 @@ import (
 	"github.com/pv/uniset-panel/internal/ionc"
 	"github.com/pv/uniset-panel/internal/modbus"
 	"github.com/pv/uniset-panel/internal/opcua"
+	"github.com/pv/uniset-panel/internal/poller"
 	"github.com/pv/uniset-panel/internal/recording"
 	"github.com/pv/uniset-panel/internal/storage"
 	"github.com/pv/uniset-panel/internal/uniset"
@@ -52,9 +53,19 @@ type Manager struct {
 	uwsgateCallback UWSGateEventCallback
 	statusCallback  StatusEventCallback
 	objectsCallback ObjectsChangedCallback
+	addedCallback   ServerAddedCallback
 
 	// Recording manager for history recording
 	recordingMgr *recording.Manager
+
+	// Настройки сохранения истории (change-only, deadband, heartbeat)
+	historyConfig *config.HistoryConfig
+
+	// Правила хранения истории
+	retention *storage.RetentionPolicy
+
+	// Количество объектов, опрашиваемых одновременно (0 = по умолчанию)
+	pollConcurrency int
 }
 
 // NewManager создаёт новый менеджер серверов
@@ -124,6 +135,14 @@ func (m *Manager) SetObjectsCallback(cb ObjectsChangedCallback) {
 	m.objectsCallback = cb
 }
 
+// SetServerAddedCallback устанавливает callback, вызываемый после добавления сервера
+// (из конфигурации и через API)
+func (m *Manager) SetServerAddedCallback(cb ServerAddedCallback) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addedCallback = cb
+}
+
 // SetRecordingManager устанавливает менеджер записи для всех pollers
 func (m *Manager) SetRecordingManager(mgr *recording.Manager) {
 	m.mu.Lock()
@@ -139,22 +158,97 @@ func (m *Manager) SetRecordingManager(mgr *recording.Manager) {
 	}
 }
 
+// SetHistoryConfig устанавливает настройки сохранения истории для всех pollers
+func (m *Manager) SetHistoryConfig(cfg *config.HistoryConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.historyConfig = cfg
+
+	def, objects := historyFilters(cfg)
+	for _, instance := range m.instances {
+		instance.Poller.SetHistoryFilter(def, objects)
+	}
+}
+
+// SetRetentionPolicy устанавливает правила хранения истории для всех pollers
+func (m *Manager) SetRetentionPolicy(policy *storage.RetentionPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = policy
+
+	for _, instance := range m.instances {
+		instance.Poller.SetRetentionPolicy(policy)
+	}
+}
+
+// SetPollConcurrency устанавливает количество одновременно опрашиваемых объектов для всех pollers
+func (m *Manager) SetPollConcurrency(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pollConcurrency = n
+
+	for _, instance := range m.instances {
+		instance.Poller.SetConcurrency(n)
+	}
+}
+
+// historyFilters преобразует настройки истории из конфига в фильтры poller'а
+func historyFilters(cfg *config.HistoryConfig) (poller.HistoryFilter, map[string]poller.HistoryFilter) {
+	toFilter := func(f config.HistoryFilterConfig) poller.HistoryFilter {
+		return poller.HistoryFilter{
+			ChangeOnly:      f.GetChangeOnly(),
+			Deadband:        f.GetDeadband(),
+			DeadbandPercent: f.GetDeadbandPercent(),
+			Heartbeat:       f.GetHeartbeat(),
+		}
+	}
+
+	if cfg == nil {
+		return poller.HistoryFilter{}, nil
+	}
+
+	objects := make(map[string]poller.HistoryFilter, len(cfg.Objects))
+	for name := range cfg.Objects {
+		objects[name] = toFilter(cfg.ForObject(name))
+	}
+	return toFilter(cfg.HistoryFilterConfig), objects
+}
+
 // AddServer добавляет новый сервер
 func (m *Manager) AddServer(cfg config.ServerConfig) error {
+	instance, err := m.addServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Callback вызывается без блокировки: он может обращаться к менеджеру
+	m.mu.RLock()
+	cb := m.addedCallback
+	m.mu.RUnlock()
+	if cb != nil {
+		cb(instance)
+	}
+	return nil
+}
+
+func (m *Manager) addServer(cfg config.ServerConfig) (*Instance, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Проверяем, что сервер с таким ID ещё не существует
 	if _, exists := m.instances[cfg.ID]; exists {
-		return fmt.Errorf("server with ID %q already exists", cfg.ID)
+		return nil, fmt.Errorf("server with ID %q already exists", cfg.ID)
 	}
 
 	// Проверяем URL
 	if cfg.URL == "" {
-		return fmt.Errorf("server URL is required")
+		return nil, fmt.Errorf("server URL is required")
+	}
+	if err := cfg.ValidateConnection(); err != nil {
+		return nil, err
 	}
 
-	instance := NewInstance(
+	instance, err := NewInstance(
 		cfg,
 		m.storage,
 		m.pollInterval,
@@ -168,6 +262,9 @@ func (m *Manager) AddServer(cfg config.ServerConfig) error {
 		m.statusCallback,
 		m.objectsCallback,
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set recording manager on all pollers if configured
 	if m.recordingMgr != nil {
@@ -177,12 +274,22 @@ func (m *Manager) AddServer(cfg config.ServerConfig) error {
 		instance.OPCUAPoller.SetRecordingManager(m.recordingMgr)
 	}
 
+	if m.historyConfig != nil {
+		instance.Poller.SetHistoryFilter(historyFilters(m.historyConfig))
+	}
+	if m.retention != nil {
+		instance.Poller.SetRetentionPolicy(m.retention)
+	}
+	if m.pollConcurrency > 0 {
+		instance.Poller.SetConcurrency(m.pollConcurrency)
+	}
+
 	m.instances[cfg.ID] = instance
 	instance.Start()
 
 	slog.Info("Server added", "id", cfg.ID, "url", cfg.URL, "name", cfg.Name)
 
-	return nil
+	return instance, nil
 }
 
 // RemoveServer удаляет сервер по ID
@@ -251,16 +358,45 @@ func (m *Manager) SetPollInterval(interval time.Duration) {
 
 	m.pollInterval = interval
 
-	// Обновляем интервал для всех экземпляров
+	// Обновляем интервал экземпляров, для которых не задан собственный
 	for _, instance := range m.instances {
-		instance.SetHealthInterval(interval)
+		if !instance.hasOwnPollInterval() {
+			instance.SetPollInterval(interval)
+		}
 	}
 
 	slog.Info("Poll interval changed", "interval", interval)
 }
 
+// SetServerPollInterval задаёт интервал опроса для одного сервера
+func (m *Manager) SetServerPollInterval(serverID string, interval time.Duration) error {
+	instance, exists := m.GetServer(serverID)
+	if !exists {
+		return fmt.Errorf("server with ID %q not found", serverID)
+	}
+	instance.setOwnPollInterval(interval, true)
+	slog.Info("Server poll interval changed", "id", serverID, "interval", interval)
+	return nil
+}
+
+// ResetServerPollInterval возвращает серверу глобальный интервал опроса
+func (m *Manager) ResetServerPollInterval(serverID string) error {
+	instance, exists := m.GetServer(serverID)
+	if !exists {
+		return fmt.Errorf("server with ID %q not found", serverID)
+	}
+	instance.setOwnPollInterval(m.GetPollInterval(), false)
+	slog.Info("Server poll interval reset", "id", serverID)
+	return nil
+}
+
 // GetAllObjects возвращает объекты со всех серверов (плоский список)
 func (m *Manager) GetAllObjects() ([]ObjectWithServer, error) {
+	return m.GetAllObjectsContext(context.Background())
+}
+
+// GetAllObjectsContext то же, что GetAllObjects, с учётом отмены ctx
+func (m *Manager) GetAllObjectsContext(ctx context.Context) ([]ObjectWithServer, error) {
 	m.mu.RLock()
 	instances := make([]*Instance, 0, len(m.instances))
 	for _, instance := range m.instances {
@@ -272,7 +408,7 @@ func (m *Manager) GetAllObjects() ([]ObjectWithServer, error) {
 	var errors []error
 
 	for _, instance := range instances {
-		objects, err := instance.GetObjects()
+		objects, err := instance.GetObjectsContext(ctx)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("server %s: %w", instance.Config.ID, err))
 			continue
@@ -303,6 +439,11 @@ func (m *Manager) GetAllObjects() ([]ObjectWithServer, error) {
 
 // GetAllObjectsGrouped возвращает объекты сгруппированные по серверам
 func (m *Manager) GetAllObjectsGrouped() ([]ServerObjects, error) {
+	return m.GetAllObjectsGroupedContext(context.Background())
+}
+
+// GetAllObjectsGroupedContext то же, что GetAllObjectsGrouped, с учётом отмены ctx
+func (m *Manager) GetAllObjectsGroupedContext(ctx context.Context) ([]ServerObjects, error) {
 	m.mu.RLock()
 	instances := make([]*Instance, 0, len(m.instances))
 	for _, instance := range m.instances {
@@ -327,7 +468,7 @@ func (m *Manager) GetAllObjectsGrouped() ([]ServerObjects, error) {
 			Objects:    []string{},
 		}
 
-		objects, err := instance.GetObjects()
+		objects, err := instance.GetObjectsContext(ctx)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("server %s: %w", instance.Config.ID, err))
 			// Добавляем сервер в результат даже если не удалось получить объекты
@@ -349,12 +490,17 @@ func (m *Manager) GetAllObjectsGrouped() ([]ServerObjects, error) {
 
 // GetObjectData возвращает данные объекта с указанного сервера
 func (m *Manager) GetObjectData(serverID, objectName string) (*uniset.ObjectData, error) {
+	return m.GetObjectDataContext(context.Background(), serverID, objectName)
+}
+
+// GetObjectDataContext то же, что GetObjectData, с учётом отмены ctx
+func (m *Manager) GetObjectDataContext(ctx context.Context, serverID, objectName string) (*uniset.ObjectData, error) {
 	instance, exists := m.GetServer(serverID)
 	if !exists {
 		return nil, fmt.Errorf("server %q not found", serverID)
 	}
 
-	return instance.GetObjectData(objectName)
+	return instance.GetObjectDataContext(ctx, objectName)
 }
 
 // Watch добавляет объект в наблюдение
@@ -437,7 +583,12 @@ func (m *Manager) GetUWSGatePoller(serverID string) *uwsgate.Poller {
 	callback := m.uwsgateCallback
 	m.mu.RUnlock()
 
-	return instance.CreateUWSGatePoller(callback)
+	poller, err := instance.CreateUWSGatePoller(callback)
+	if err != nil {
+		slog.Error("Failed to create UWSGate poller", "error", err)
+		return nil
+	}
+	return poller
 }
 
 // GetClient возвращает клиент для указанного сервера
@@ -57,6 +57,35 @@ func TestManagerAddServer(t *testing.T) {
 	}
 }
 
+func TestManagerServerAddedCallback(t *testing.T) {
+	server := mockUnisetServer()
+	defer server.Close()
+
+	mgr := NewManager(storage.NewMemoryStorage(), time.Second, time.Hour, "", 0)
+	defer mgr.Shutdown(context.Background())
+
+	var added []string
+	mgr.SetServerAddedCallback(func(instance *Instance) {
+		// Callback может обращаться к менеджеру
+		if _, ok := mgr.GetServer(instance.Config.ID); !ok {
+			t.Errorf("server %s not registered before callback", instance.Config.ID)
+		}
+		added = append(added, instance.Config.ID)
+	})
+
+	cfg := config.ServerConfig{ID: "server1", URL: server.URL}
+	if err := mgr.AddServer(cfg); err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+	if err := mgr.AddServer(cfg); err == nil {
+		t.Fatal("expected error when adding duplicate server")
+	}
+
+	if len(added) != 1 || added[0] != "server1" {
+		t.Errorf("expected callback once for server1, got %v", added)
+	}
+}
+
 func TestManagerAddServerEmptyURL(t *testing.T) {
 	store := storage.NewMemoryStorage()
 	mgr := NewManager(store, time.Second, time.Hour, "", 0)
@@ -233,6 +262,70 @@ func TestManagerSetPollInterval(t *testing.T) {
 	}
 }
 
+func TestManagerServerPollOverrides(t *testing.T) {
+	server := mockUnisetServer()
+	defer server.Close()
+
+	store := storage.NewMemoryStorage()
+	mgr := NewManager(store, time.Second, time.Hour, "TestProc", 300)
+
+	mgr.AddServer(config.ServerConfig{ID: "local", URL: server.URL})
+	mgr.AddServer(config.ServerConfig{
+		ID:              "remote",
+		URL:             server.URL + "/",
+		PollInterval:    5 * time.Second,
+		SensorBatchSize: 50,
+		Supplier:        "RemoteProc",
+		HealthInterval:  30 * time.Second,
+	})
+	defer mgr.Shutdown(context.Background())
+
+	remote, _ := mgr.GetServer("remote")
+	settings := remote.GetPollSettings()
+	if settings.Interval != 5*time.Second || !settings.OwnInterval {
+		t.Errorf("expected own interval 5s, got %+v", settings)
+	}
+	if settings.SensorBatchSize != 50 || settings.Supplier != "RemoteProc" || settings.HealthInterval != 30*time.Second {
+		t.Errorf("unexpected remote settings: %+v", settings)
+	}
+	if remote.Poller.Interval() != 5*time.Second || remote.IONCPoller.Interval() != 5*time.Second {
+		t.Error("server poll interval must be applied to its pollers")
+	}
+
+	// Глобальная настройка не меняет серверы с собственным интервалом
+	mgr.SetPollInterval(2 * time.Second)
+	local, _ := mgr.GetServer("local")
+	if local.GetPollSettings().Interval != 2*time.Second || local.ModbusPoller.Interval() != 2*time.Second {
+		t.Errorf("expected local interval 2s, got %+v", local.GetPollSettings())
+	}
+	if remote.GetPollSettings().Interval != 5*time.Second {
+		t.Errorf("remote interval must not follow global setting, got %v", remote.GetPollSettings().Interval)
+	}
+
+	if err := mgr.SetServerPollInterval("local", 3*time.Second); err != nil {
+		t.Fatalf("SetServerPollInterval failed: %v", err)
+	}
+	settings = local.GetPollSettings()
+	if settings.Interval != 3*time.Second || !settings.OwnInterval || settings.HealthInterval != 3*time.Second {
+		t.Errorf("unexpected local settings: %+v", settings)
+	}
+	if local.OPCUAPoller.Interval() != 3*time.Second {
+		t.Error("per-server interval must be applied to OPCUA poller")
+	}
+
+	if err := mgr.ResetServerPollInterval("local"); err != nil {
+		t.Fatalf("ResetServerPollInterval failed: %v", err)
+	}
+	settings = local.GetPollSettings()
+	if settings.Interval != 2*time.Second || settings.OwnInterval {
+		t.Errorf("expected global interval after reset, got %+v", settings)
+	}
+
+	if err := mgr.SetServerPollInterval("unknown", time.Second); err == nil {
+		t.Error("expected error for unknown server")
+	}
+}
+
 func TestManagerSetObjectCallback(t *testing.T) {
 	store := storage.NewMemoryStorage()
 	mgr := NewManager(store, time.Second, time.Hour, "", 0)
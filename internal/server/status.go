@@ -1,6 +1,10 @@
 package server
 
-import "time"
+import (
+	"time"
+
+	"github.com/pv/uniset-panel/internal/uniset"
+)
 
 // Status представляет текущее состояние сервера
 type Status struct {
@@ -11,4 +15,9 @@ type Status struct {
 	LastPoll    time.Time `json:"lastPoll"`
 	LastError   string    `json:"lastError,omitempty"`
 	ObjectCount int       `json:"objectCount"`
+
+	// Breaker состояние circuit breaker: при open запросы к серверу не выполняются до nextRetry
+	Breaker *uniset.BreakerStatus `json:"breaker,omitempty"`
+	// Backoff объекты, опрос которых приостановлен после повторных ошибок
+	Backoff []uniset.ObjectBackoff `json:"backoff,omitempty"`
 }
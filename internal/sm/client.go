@@ -45,6 +45,11 @@ func NewClient(baseURL string) *Client {
 	}
 }
 
+// SetHTTPClient задаёт HTTP-клиент (авторизация, TLS, таймаут)
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
 // GetValues получает значения датчиков по именам или ID
 // sensors - список имен или ID датчиков через запятую
 func (c *Client) GetValues(sensors []string) (map[string]SensorValue, error) {
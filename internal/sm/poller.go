@@ -3,6 +3,7 @@ package sm
 import (
 	"context"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -33,8 +34,8 @@ type Poller struct {
 	callback     UpdateCallback
 
 	mu           sync.RWMutex
-	// subscriptions: objectName -> set of sensorNames
-	subscriptions map[string]map[string]struct{}
+	// subscriptions: objectName -> sensorName -> set of owners (ID SSE клиентов)
+	subscriptions map[string]map[string]map[string]struct{}
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -50,7 +51,7 @@ func NewPoller(client *Client, store storage.Storage, interval time.Duration, ca
 		storage:       store,
 		interval:      interval,
 		callback:      callback,
-		subscriptions: make(map[string]map[string]struct{}),
+		subscriptions: make(map[string]map[string]map[string]struct{}),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -70,35 +71,44 @@ func (p *Poller) Stop() {
 	slog.Info("SM Poller stopped")
 }
 
-// Subscribe подписывает объект на датчик
+// Subscribe подписывает объект на датчик без владельца
 func (p *Poller) Subscribe(objectName, sensorName string) {
+	p.SubscribeFor("", objectName, sensorName)
+}
+
+// SubscribeFor подписывает объект на датчик от имени владельца (ID SSE клиента).
+// Датчик опрашивается, пока на него подписан хотя бы один владелец.
+func (p *Poller) SubscribeFor(owner, objectName, sensorName string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if p.subscriptions[objectName] == nil {
-		p.subscriptions[objectName] = make(map[string]struct{})
+		p.subscriptions[objectName] = make(map[string]map[string]struct{})
+	}
+	if p.subscriptions[objectName][sensorName] == nil {
+		p.subscriptions[objectName][sensorName] = make(map[string]struct{})
 	}
-	p.subscriptions[objectName][sensorName] = struct{}{}
+	p.subscriptions[objectName][sensorName][owner] = struct{}{}
 
-	slog.Debug("SM sensor subscribed", "object", objectName, "sensor", sensorName)
+	slog.Debug("SM sensor subscribed", "object", objectName, "sensor", sensorName, "owner", owner)
 }
 
-// Unsubscribe отписывает объект от датчика
+// Unsubscribe снимает подписку без владельца с датчика
 func (p *Poller) Unsubscribe(objectName, sensorName string) {
+	p.UnsubscribeFor("", objectName, sensorName)
+}
+
+// UnsubscribeFor снимает подписку владельца с датчика
+func (p *Poller) UnsubscribeFor(owner, objectName, sensorName string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if sensors, ok := p.subscriptions[objectName]; ok {
-		delete(sensors, sensorName)
-		if len(sensors) == 0 {
-			delete(p.subscriptions, objectName)
-		}
-	}
+	p.release(owner, objectName, sensorName)
 
-	slog.Debug("SM sensor unsubscribed", "object", objectName, "sensor", sensorName)
+	slog.Debug("SM sensor unsubscribed", "object", objectName, "sensor", sensorName, "owner", owner)
 }
 
-// UnsubscribeAll отписывает объект от всех датчиков
+// UnsubscribeAll отписывает объект от всех датчиков независимо от владельцев
 func (p *Poller) UnsubscribeAll(objectName string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -107,6 +117,76 @@ func (p *Poller) UnsubscribeAll(objectName string) {
 	slog.Debug("SM all sensors unsubscribed", "object", objectName)
 }
 
+// ReleaseOwner снимает все подписки владельца, возвращает количество освобождённых датчиков
+func (p *Poller) ReleaseOwner(owner string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	released := 0
+	for obj, sensors := range p.subscriptions {
+		for name, owners := range sensors {
+			if _, ok := owners[owner]; ok {
+				p.release(owner, obj, name)
+				released++
+			}
+		}
+	}
+
+	if released > 0 {
+		slog.Debug("SM owner released", "owner", owner, "count", released)
+	}
+	return released
+}
+
+// release удаляет владельца датчика и сам датчик, если владельцев не осталось. Вызывается под p.mu.
+func (p *Poller) release(owner, objectName, sensorName string) {
+	sensors, ok := p.subscriptions[objectName]
+	if !ok {
+		return
+	}
+	if owners, ok := sensors[sensorName]; ok {
+		delete(owners, owner)
+		if len(owners) == 0 {
+			delete(sensors, sensorName)
+		}
+	}
+	if len(sensors) == 0 {
+		delete(p.subscriptions, objectName)
+	}
+}
+
+// Subscriber подписка объекта на датчик с владельцами (для диагностики)
+type Subscriber struct {
+	Object string   `json:"object"`
+	Sensor string   `json:"sensor"`
+	Owners []string `json:"owners"`
+}
+
+// Subscribers возвращает подписки с владельцами, упорядоченные по объекту и датчику
+func (p *Poller) Subscribers() []Subscriber {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]Subscriber, 0)
+	for obj, sensors := range p.subscriptions {
+		for name, owners := range sensors {
+			names := make([]string, 0, len(owners))
+			for owner := range owners {
+				names = append(names, owner)
+			}
+			sort.Strings(names)
+			result = append(result, Subscriber{Object: obj, Sensor: name, Owners: names})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Object != result[j].Object {
+			return result[i].Object < result[j].Object
+		}
+		return result[i].Sensor < result[j].Sensor
+	})
+	return result
+}
+
 // GetSubscriptions возвращает список подписок для объекта
 func (p *Poller) GetSubscriptions(objectName string) []string {
 	p.mu.RLock()
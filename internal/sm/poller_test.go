@@ -285,3 +285,30 @@ func TestSensorUpdate_Fields(t *testing.T) {
 		t.Errorf("expected sensor value 42, got %d", update.Sensor.Value)
 	}
 }
+
+func TestPoller_OwnerRefCount(t *testing.T) {
+	client := NewClient("http://localhost:9999")
+	store := storage.NewMemoryStorage()
+	poller := NewPoller(client, store, time.Second, nil)
+
+	poller.SubscribeFor("tab1", "Object1", "AI100_AS")
+	poller.SubscribeFor("tab2", "Object1", "AI100_AS")
+	poller.SubscribeFor("tab2", "Object1", "AI101_AS")
+
+	poller.UnsubscribeFor("tab1", "Object1", "AI100_AS")
+	if subs := poller.GetSubscriptions("Object1"); len(subs) != 2 {
+		t.Errorf("expected 2 subscriptions while tab2 holds them, got %v", subs)
+	}
+
+	subscribers := poller.Subscribers()
+	if len(subscribers) != 2 || subscribers[0].Sensor != "AI100_AS" || subscribers[0].Owners[0] != "tab2" {
+		t.Errorf("unexpected subscribers: %+v", subscribers)
+	}
+
+	if released := poller.ReleaseOwner("tab2"); released != 2 {
+		t.Errorf("ReleaseOwner(tab2) = %d, want 2", released)
+	}
+	if subs := poller.GetSubscriptions("Object1"); subs != nil {
+		t.Errorf("expected nil subscriptions after release, got %v", subs)
+	}
+}
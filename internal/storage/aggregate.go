@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aggregator накапливает точки и раскладывает их по интервалам фиксированной длины.
+// Точки должны поступать в порядке возрастания времени.
+type aggregator struct {
+	from   time.Time
+	bucket time.Duration
+	points []AggregatedPoint
+	sum    float64
+	gaps   *gapTracker
+}
+
+func newAggregator(from, to time.Time, bucket time.Duration) *aggregator {
+	return &aggregator{
+		from:   from,
+		bucket: bucket,
+		gaps:   newGapTracker(from, to),
+	}
+}
+
+// seed учитывает последнюю точку до начала периода: она влияет только на пропуски
+func (a *aggregator) seed(p DataPoint, ok bool) {
+	if ok {
+		a.gaps.point(p)
+	}
+}
+
+// add добавляет точку в соответствующий интервал
+func (a *aggregator) add(p DataPoint) {
+	a.gaps.point(p)
+	if p.Gap {
+		return
+	}
+
+	v, ok := ToFloat64(p.Value)
+	if !ok {
+		return
+	}
+	timestamp := p.Timestamp
+
+	offset := timestamp.Sub(a.from)
+	if offset < 0 {
+		offset = 0
+	}
+	start := a.from.Add(offset - offset%a.bucket)
+
+	n := len(a.points)
+	if n > 0 && a.points[n-1].Timestamp.Equal(start) {
+		p := &a.points[n-1]
+		if v < p.Min {
+			p.Min = v
+		}
+		if v > p.Max {
+			p.Max = v
+		}
+		p.Last = v
+		p.Count++
+		a.sum += v
+		return
+	}
+
+	a.finishLast()
+	a.points = append(a.points, AggregatedPoint{
+		Timestamp: start,
+		Min:       v,
+		Max:       v,
+		First:     v,
+		Last:      v,
+		Count:     1,
+	})
+	a.sum = v
+}
+
+// finishLast вычисляет среднее для последнего незакрытого интервала
+func (a *aggregator) finishLast() {
+	n := len(a.points)
+	if n == 0 {
+		return
+	}
+	p := &a.points[n-1]
+	p.Avg = a.sum / float64(p.Count)
+	p.Value = p.Avg
+}
+
+// result возвращает накопленные интервалы
+func (a *aggregator) result(serverID, objectName, variableName string) *AggregatedHistory {
+	a.finishLast()
+	gaps, availability := a.gaps.finish(time.Now())
+	return &AggregatedHistory{
+		ServerID:     serverID,
+		ObjectName:   objectName,
+		VariableName: variableName,
+		BucketMs:     a.bucket.Milliseconds(),
+		Points:       a.points,
+		Gaps:         gaps,
+		Availability: &availability,
+	}
+}
+
+// normalizeBucket возвращает допустимую длину интервала (не меньше 1ms)
+func normalizeBucket(bucket time.Duration) time.Duration {
+	if bucket < time.Millisecond {
+		return time.Millisecond
+	}
+	return bucket
+}
+
+// NumericValue возвращает значение, если оно имеет числовой тип (строки и bool не приводятся)
+func NumericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ToFloat64 приводит значение к числу.
+// Переменные и датчики UniSet2 могут приходить числами, строками ("100") или bool.
+func ToFloat64(value interface{}) (float64, bool) {
+	if f, ok := NumericValue(value); ok {
+		return f, true
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,105 @@
+package storage
+
+import "time"
+
+// gapTracker собирает интервалы без данных по точкам, поступающим в порядке времени
+type gapTracker struct {
+	from, to time.Time
+	open     bool      // идёт период без данных
+	openedAt time.Time // начало текущего периода без данных
+	gaps     []Gap
+}
+
+func newGapTracker(from, to time.Time) *gapTracker {
+	return &gapTracker{from: from, to: to}
+}
+
+// point учитывает очередную точку
+func (g *gapTracker) point(p DataPoint) {
+	if p.Gap {
+		if !g.open {
+			g.open = true
+			g.openedAt = p.Timestamp
+		}
+		return
+	}
+	if g.open {
+		g.gaps = append(g.gaps, Gap{From: g.openedAt, To: p.Timestamp})
+		g.open = false
+	}
+}
+
+// finish закрывает незавершённый период и вычисляет долю времени с данными (в процентах).
+// Время после текущего момента не учитывается.
+func (g *gapTracker) finish(now time.Time) ([]Gap, float64) {
+	end := g.to
+	if now.Before(end) {
+		end = now
+	}
+
+	if g.open {
+		closeAt := end
+		if closeAt.Before(g.openedAt) {
+			closeAt = g.openedAt
+		}
+		g.gaps = append(g.gaps, Gap{From: g.openedAt, To: closeAt})
+		g.open = false
+	}
+
+	total := end.Sub(g.from)
+	if total <= 0 {
+		return g.gaps, 100
+	}
+
+	var missing time.Duration
+	for _, gap := range g.gaps {
+		from, to := gap.From, gap.To
+		if from.Before(g.from) {
+			from = g.from
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			missing += to.Sub(from)
+		}
+	}
+
+	return g.gaps, 100 * float64(total-missing) / float64(total)
+}
+
+// lastPointFinder хранилище, умеющее найти последнюю точку ряда не новее момента at
+type lastPointFinder interface {
+	lastPoint(serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error)
+}
+
+// lastPointOf возвращает последнюю точку ряда не новее at, если хранилище это поддерживает
+func lastPointOf(store Storage, serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error) {
+	finder, ok := store.(lastPointFinder)
+	if !ok {
+		return DataPoint{}, false, nil
+	}
+	return finder.lastPoint(serverID, objectName, variableName, at)
+}
+
+// DetectGaps заполняет Gaps и Availability истории по отметкам пропусков в точках.
+// Период без данных, начавшийся до from, определяется по последней точке ряда не новее from.
+func DetectGaps(store Storage, history *VariableHistory, from, to time.Time) error {
+	tracker := newGapTracker(from, to)
+
+	prev, ok, err := lastPointOf(store, history.ServerID, history.ObjectName, history.VariableName, from)
+	if err != nil {
+		return err
+	}
+	if ok {
+		tracker.point(prev)
+	}
+
+	for _, p := range history.Points {
+		tracker.point(p)
+	}
+	gaps, availability := tracker.finish(time.Now())
+	history.Gaps = gaps
+	history.Availability = &availability
+	return nil
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetectGaps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := &VariableHistory{
+		Points: []DataPoint{
+			{Timestamp: base.Add(time.Minute), Value: 1},
+			{Timestamp: base.Add(2 * time.Minute), Gap: true},
+			{Timestamp: base.Add(3 * time.Minute), Gap: true},
+			{Timestamp: base.Add(4 * time.Minute), Value: 2},
+			{Timestamp: base.Add(8 * time.Minute), Gap: true},
+		},
+	}
+
+	if err := DetectGaps(NewMemoryStorage(), history, base, base.Add(10*time.Minute)); err != nil {
+		t.Fatalf("DetectGaps failed: %v", err)
+	}
+
+	if len(history.Gaps) != 2 {
+		t.Fatalf("expected 2 gaps, got %d", len(history.Gaps))
+	}
+	if !history.Gaps[0].From.Equal(base.Add(2*time.Minute)) || !history.Gaps[0].To.Equal(base.Add(4*time.Minute)) {
+		t.Errorf("unexpected first gap: %+v", history.Gaps[0])
+	}
+	// Незакрытый пропуск длится до конца периода
+	if !history.Gaps[1].To.Equal(base.Add(10 * time.Minute)) {
+		t.Errorf("expected open gap to end at range end, got %v", history.Gaps[1].To)
+	}
+
+	// 4 минуты без данных из 10
+	if history.Availability == nil || *history.Availability != 60 {
+		t.Errorf("expected availability 60, got %v", history.Availability)
+	}
+}
+
+func TestDetectGapsNoGaps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := &VariableHistory{
+		Points: []DataPoint{{Timestamp: base, Value: 1}},
+	}
+
+	if err := DetectGaps(NewMemoryStorage(), history, base, base.Add(time.Minute)); err != nil {
+		t.Fatalf("DetectGaps failed: %v", err)
+	}
+
+	if len(history.Gaps) != 0 {
+		t.Errorf("expected no gaps, got %d", len(history.Gaps))
+	}
+	if history.Availability == nil || *history.Availability != 100 {
+		t.Errorf("expected availability 100, got %v", history.Availability)
+	}
+}
+
+func TestGapStartedBeforeRange(t *testing.T) {
+	stores := map[string]func(t *testing.T) Storage{
+		"memory": func(t *testing.T) Storage { return NewMemoryStorage() },
+		"sqlite": func(t *testing.T) Storage {
+			store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("NewSQLiteStorage failed: %v", err)
+			}
+			return store
+		},
+		"tiered": func(t *testing.T) Storage {
+			return newTestTieredStorage(t, filepath.Join(t.TempDir(), "test.db"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			store.Save("", "TestObj", "var1", 10, base.Add(-10*time.Minute))
+			store.SaveGap("", "TestObj", "var1", base.Add(-5*time.Minute))
+			store.Save("", "TestObj", "var1", 20, base.Add(2*time.Minute))
+			if tiered, ok := store.(*tieredStorage); ok {
+				// Начало пропуска - в cold, конец - в памяти
+				tiered.spill(base)
+			}
+
+			from, to := base, base.Add(10*time.Minute)
+			history, err := store.GetHistory("", "TestObj", "var1", from, to)
+			if err != nil {
+				t.Fatalf("GetHistory failed: %v", err)
+			}
+			if err := DetectGaps(store, history, from, to); err != nil {
+				t.Fatalf("DetectGaps failed: %v", err)
+			}
+			// Пропуск начался до from и длится первые 2 минуты периода
+			if len(history.Gaps) != 1 || !history.Gaps[0].To.Equal(base.Add(2*time.Minute)) {
+				t.Errorf("unexpected gaps: %+v", history.Gaps)
+			}
+			if history.Availability == nil || *history.Availability != 80 {
+				t.Errorf("expected availability 80, got %v", history.Availability)
+			}
+
+			aggregated, err := store.GetAggregated("", "TestObj", "var1", from, to, time.Minute)
+			if err != nil {
+				t.Fatalf("GetAggregated failed: %v", err)
+			}
+			if aggregated.Availability == nil || *aggregated.Availability != 80 {
+				t.Errorf("expected aggregated availability 80, got %v", aggregated.Availability)
+			}
+
+			multi, err := store.GetAggregatedMulti([]SeriesKey{{ObjectName: "TestObj", VariableName: "var1"}}, from, to, time.Minute)
+			if err != nil {
+				t.Fatalf("GetAggregatedMulti failed: %v", err)
+			}
+			if multi[0].Availability == nil || *multi[0].Availability != 80 {
+				t.Errorf("expected multi availability 80, got %v", multi[0].Availability)
+			}
+		})
+	}
+}
+
+func TestMemoryStorageSaveGap(t *testing.T) {
+	store := NewMemoryStorage()
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Save("", "TestObj", "var1", 10, base)
+	store.SaveGap("", "TestObj", "var1", base.Add(time.Minute))
+	store.Save("", "TestObj", "var1", 20, base.Add(3*time.Minute))
+
+	history, err := store.GetHistory("", "TestObj", "var1", base, base.Add(4*time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 3 || !history.Points[1].Gap {
+		t.Fatalf("expected gap marker as second point, got %+v", history.Points)
+	}
+
+	aggregated, err := store.GetAggregated("", "TestObj", "var1", base, base.Add(4*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetAggregated failed: %v", err)
+	}
+	// Отметка пропуска не попадает в агрегаты
+	if len(aggregated.Points) != 2 {
+		t.Errorf("expected 2 aggregated points, got %d", len(aggregated.Points))
+	}
+	if len(aggregated.Gaps) != 1 || !aggregated.Gaps[0].To.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("unexpected gaps: %+v", aggregated.Gaps)
+	}
+	if aggregated.Availability == nil || *aggregated.Availability != 50 {
+		t.Errorf("expected availability 50, got %v", aggregated.Availability)
+	}
+}
+
+func TestSQLiteStorageSaveGap(t *testing.T) {
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Save("", "TestObj", "var1", 10, base)
+	store.SaveGap("", "TestObj", "var1", base.Add(time.Minute))
+	store.Save("", "TestObj", "var1", 20, base.Add(3*time.Minute))
+
+	history, err := store.GetHistory("", "TestObj", "var1", base, base.Add(4*time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(history.Points))
+	}
+	if !history.Points[1].Gap || history.Points[1].Value != nil {
+		t.Errorf("expected gap marker without value, got %+v", history.Points[1])
+	}
+	if history.Points[0].Gap || history.Points[2].Gap {
+		t.Error("regular points must not be marked as gaps")
+	}
+
+	multi, err := store.GetAggregatedMulti([]SeriesKey{{ObjectName: "TestObj", VariableName: "var1"}}, base, base.Add(4*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetAggregatedMulti failed: %v", err)
+	}
+	if len(multi[0].Gaps) != 1 {
+		t.Errorf("expected 1 gap, got %d", len(multi[0].Gaps))
+	}
+}
@@ -1,19 +1,62 @@
 package storage
 
 import (
+	"container/heap"
+	"sort"
 	"sync"
 	"time"
 )
 
+// MemoryOption функциональная опция для NewMemoryStorage
+type MemoryOption func(*memoryStorage)
+
+// WithMemoryBudget ограничивает оценочный объём памяти под историю (в байтах).
+// При превышении вытесняются самые старые точки. 0 - без ограничения.
+func WithMemoryBudget(bytes int64) MemoryOption {
+	return func(m *memoryStorage) {
+		if bytes > 0 {
+			m.budget = bytes
+		}
+	}
+}
+
+// WithMaxPointsPerSeries ограничивает количество точек в одном ряду. 0 - без ограничения.
+func WithMaxPointsPerSeries(n int) MemoryOption {
+	return func(m *memoryStorage) {
+		if n > 0 {
+			m.maxPoints = n
+		}
+	}
+}
+
 type memoryStorage struct {
-	mu   sync.RWMutex
-	data map[string][]DataPoint // key: "serverID:objectName:variableName"
+	mu     sync.RWMutex
+	data   map[string]*ring // key: "serverID:objectName:variableName"
+	bySize seriesHeap       // ряды по убыванию количества точек (для вытеснения по бюджету)
+
+	budget    int64 // лимит памяти в байтах (0 = без ограничения)
+	maxPoints int   // лимит точек в ряду (0 = без ограничения)
+
+	used   int64 // оценка занимаемой памяти
+	points int   // всего точек
+
+	evictedBySeriesLimit uint64
+	evictedByBudget      uint64
+
+	// onEvict получает вытесненные по лимитам точки (вызывается под mu)
+	onEvict func(id seriesID, p DataPoint)
 }
 
-func NewMemoryStorage() Storage {
-	return &memoryStorage{
-		data: make(map[string][]DataPoint),
+// NewMemoryStorage создаёт хранилище истории в памяти.
+// Каждый ряд хранится в кольцевом буфере; лимиты задаются опциями.
+func NewMemoryStorage(opts ...MemoryOption) Storage {
+	m := &memoryStorage{
+		data: make(map[string]*ring),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 func makeKey(serverID, objectName, variableName string) string {
@@ -24,27 +67,126 @@ func makeKey(serverID, objectName, variableName string) string {
 }
 
 func (m *memoryStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
+	return m.save(serverID, objectName, variableName, DataPoint{Timestamp: timestamp, Value: value})
+}
+
+func (m *memoryStorage) SaveGap(serverID, objectName, variableName string, timestamp time.Time) error {
+	return m.save(serverID, objectName, variableName, DataPoint{Timestamp: timestamp, Gap: true})
+}
+
+func (m *memoryStorage) save(serverID, objectName, variableName string, p DataPoint) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	key := makeKey(serverID, objectName, variableName)
-	m.data[key] = append(m.data[key], DataPoint{
-		Timestamp: timestamp,
-		Value:     value,
-	})
+	r, ok := m.data[key]
+	if !ok {
+		if serverID == "" {
+			serverID = DefaultServerID
+		}
+		r = newRing(seriesID{serverID, objectName, variableName}, m.maxPoints)
+		m.data[key] = r
+		heap.Push(&m.bySize, r)
+	}
+
+	before := r.bytes
+	index := r.first
+	if old, evicted := r.push(p); evicted {
+		m.evictedBySeriesLimit++
+		m.evicted(r, index, old)
+	} else {
+		m.points++
+		heap.Fix(&m.bySize, r.heapIndex)
+	}
+	m.used += r.bytes - before
+
+	if m.budget > 0 && m.used > m.budget {
+		m.evictForBudget(key, r)
+	}
 
 	return nil
 }
 
+// evictForBudget вытесняет самые старые точки, пока память не уложится в бюджет.
+// Точки удаляются из текущего ряда, если он не меньше среднего, иначе - из самого
+// большого ряда, чтобы новые ряды не вытеснялись старыми.
+func (m *memoryStorage) evictForBudget(key string, current *ring) {
+	for m.used > m.budget && m.points > 1 {
+		victimKey, victim := key, current
+		if current.len()*len(m.data) < m.points {
+			victim = m.bySize[0]
+			victimKey = makeKey(victim.id.serverID, victim.id.objectName, victim.id.variableName)
+		}
+
+		before := victim.bytes
+		index := victim.first
+		p, ok := victim.popOldest()
+		if !ok {
+			return
+		}
+		m.used -= before - victim.bytes
+		m.points--
+		m.evictedByBudget++
+		m.evicted(victim, index, p)
+
+		m.resized(victimKey, victim)
+	}
+}
+
+// evicted передаёт вытесненную точку в onEvict. Точки, которые уже переносятся
+// в cold (spillOlderThan), не передаются повторно.
+func (m *memoryStorage) evicted(r *ring, index uint64, p DataPoint) {
+	if m.onEvict != nil && index >= r.spillEnd {
+		m.onEvict(r.id, p)
+	}
+}
+
+// resized обновляет положение ряда в куче после удаления точек; пустой ряд удаляется
+func (m *memoryStorage) resized(key string, r *ring) {
+	if r.len() == 0 {
+		heap.Remove(&m.bySize, r.heapIndex)
+		delete(m.data, key)
+		return
+	}
+	heap.Fix(&m.bySize, r.heapIndex)
+}
+
+// seriesHeap max-куча рядов по количеству точек: самый большой ряд находится
+// за O(1), обновление размера ряда стоит O(log n)
+type seriesHeap []*ring
+
+func (h seriesHeap) Len() int           { return len(h) }
+func (h seriesHeap) Less(i, j int) bool { return h[i].len() > h[j].len() }
+
+func (h seriesHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *seriesHeap) Push(x interface{}) {
+	r := x.(*ring)
+	r.heapIndex = len(*h)
+	*h = append(*h, r)
+}
+
+func (h *seriesHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	r.heapIndex = -1
+	return r
+}
+
 func (m *memoryStorage) GetHistory(serverID, objectName, variableName string, from, to time.Time) (*VariableHistory, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	key := makeKey(serverID, objectName, variableName)
-	points := m.data[key]
 
 	var filtered []DataPoint
-	for _, p := range points {
+	for _, p := range m.series(key) {
 		if (p.Timestamp.Equal(from) || p.Timestamp.After(from)) &&
 			(p.Timestamp.Equal(to) || p.Timestamp.Before(to)) {
 			filtered = append(filtered, p)
@@ -59,20 +201,42 @@ func (m *memoryStorage) GetHistory(serverID, objectName, variableName string, fr
 	}, nil
 }
 
+func (m *memoryStorage) GetAggregated(serverID, objectName, variableName string, from, to time.Time, bucket time.Duration) (*AggregatedHistory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := makeKey(serverID, objectName, variableName)
+	agg := newAggregator(from, to, normalizeBucket(bucket))
+	agg.seed(m.lastPointLocked(key, from))
+
+	for _, p := range m.series(key) {
+		if (p.Timestamp.Equal(from) || p.Timestamp.After(from)) &&
+			(p.Timestamp.Equal(to) || p.Timestamp.Before(to)) {
+			agg.add(p)
+		}
+	}
+
+	return agg.result(serverID, objectName, variableName), nil
+}
+
 func (m *memoryStorage) GetLatest(serverID, objectName, variableName string, count int) (*VariableHistory, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	key := makeKey(serverID, objectName, variableName)
-	points := m.data[key]
 
 	var result []DataPoint
-	if len(points) <= count {
-		result = make([]DataPoint, len(points))
-		copy(result, points)
+	if r, ok := m.data[key]; ok {
+		start := 0
+		if r.len() > count {
+			start = r.len() - count
+		}
+		result = make([]DataPoint, 0, r.len()-start)
+		for i := start; i < r.len(); i++ {
+			result = append(result, r.at(i))
+		}
 	} else {
-		result = make([]DataPoint, count)
-		copy(result, points[len(points)-count:])
+		result = []DataPoint{}
 	}
 
 	return &VariableHistory{
@@ -83,25 +247,205 @@ func (m *memoryStorage) GetLatest(serverID, objectName, variableName string, cou
 	}, nil
 }
 
+func (m *memoryStorage) GetHistoryMulti(keys []SeriesKey, from, to time.Time) ([]*VariableHistory, error) {
+	result := make([]*VariableHistory, len(keys))
+	for i, key := range keys {
+		history, err := m.GetHistory(key.ServerID, key.ObjectName, key.VariableName, from, to)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = history
+	}
+	return result, nil
+}
+
+func (m *memoryStorage) GetAggregatedMulti(keys []SeriesKey, from, to time.Time, bucket time.Duration) ([]*AggregatedHistory, error) {
+	result := make([]*AggregatedHistory, len(keys))
+	for i, key := range keys {
+		history, err := m.GetAggregated(key.ServerID, key.ObjectName, key.VariableName, from, to, bucket)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = history
+	}
+	return result, nil
+}
+
+// ListSeries возвращает ряды, подходящие под фильтр
+func (m *memoryStorage) ListSeries(filter SeriesFilter) ([]SeriesInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]SeriesInfo, 0)
+	for _, r := range m.data {
+		if r.len() == 0 || !filter.Match(r.id.serverID, r.id.objectName, r.id.variableName) {
+			continue
+		}
+		result = append(result, SeriesInfo{
+			ServerID:     r.id.serverID,
+			ObjectName:   r.id.objectName,
+			VariableName: r.id.variableName,
+			Count:        int64(r.len()),
+			First:        r.at(0).Timestamp,
+			Last:         r.at(r.len() - 1).Timestamp,
+		})
+	}
+
+	sortSeries(result)
+	return result, nil
+}
+
+// lastPoint возвращает последнюю точку ряда не новее at
+func (m *memoryStorage) lastPoint(serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.lastPointLocked(makeKey(serverID, objectName, variableName), at)
+	return p, ok, nil
+}
+
+// lastPointLocked ищет последнюю точку ряда не новее at двоичным поиском (вызывается под mu)
+func (m *memoryStorage) lastPointLocked(key string, at time.Time) (DataPoint, bool) {
+	r, ok := m.data[key]
+	if !ok {
+		return DataPoint{}, false
+	}
+	i := sort.Search(r.len(), func(i int) bool { return r.at(i).Timestamp.After(at) })
+	if i == 0 {
+		return DataPoint{}, false
+	}
+	return r.at(i - 1), true
+}
+
+// series возвращает точки ряда в порядке добавления
+func (m *memoryStorage) series(key string) []DataPoint {
+	r, ok := m.data[key]
+	if !ok {
+		return nil
+	}
+	points := make([]DataPoint, r.len())
+	for i := range points {
+		points[i] = r.at(i)
+	}
+	return points
+}
+
+// Cleanup удаляет точки старше olderThan
 func (m *memoryStorage) Cleanup(olderThan time.Time) error {
+	m.removeOlderThan(olderThan, nil)
+	return nil
+}
+
+// CleanupRetention удаляет точки с истёкшим временем хранения по правилам policy
+func (m *memoryStorage) CleanupRetention(policy *RetentionPolicy, now time.Time) error {
+	m.removeExpired(func(id seriesID) time.Time {
+		return now.Add(-policy.TTLFor(id.serverID, id.objectName, id.variableName))
+	}, nil)
+	return nil
+}
+
+// removeOlderThan удаляет точки не новее olderThan и передаёт каждую удалённую точку в fn, если он задан
+func (m *memoryStorage) removeOlderThan(olderThan time.Time, fn func(id seriesID, p DataPoint)) {
+	m.removeExpired(func(seriesID) time.Time { return olderThan }, fn)
+}
+
+// removeExpired удаляет точки не новее границы, вычисляемой cutoff для каждого ряда
+// (точки в ряду упорядочены по времени)
+func (m *memoryStorage) removeExpired(cutoff func(id seriesID) time.Time, fn func(id seriesID, p DataPoint)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for key, points := range m.data {
-		var filtered []DataPoint
-		for _, p := range points {
-			if p.Timestamp.After(olderThan) {
-				filtered = append(filtered, p)
+	for key, r := range m.data {
+		olderThan := cutoff(r.id)
+		before := r.bytes
+		for r.len() > 0 && !r.at(0).Timestamp.After(olderThan) {
+			p, _ := r.popOldest()
+			m.points--
+			if fn != nil {
+				fn(r.id, p)
 			}
 		}
-		if len(filtered) == 0 {
-			delete(m.data, key)
-		} else {
-			m.data[key] = filtered
+		m.used -= before - r.bytes
+		m.resized(key, r)
+	}
+}
+
+// spillMark точки ряда, отданные на перенос: сквозные номера [start, start+count)
+type spillMark struct {
+	key   string
+	r     *ring
+	start uint64
+	count int
+}
+
+// collectOlderThan копирует точки не новее olderThan, не удаляя их из памяти.
+// Точки удаляются releaseSpill после того, как cold их принял.
+func (m *memoryStorage) collectOlderThan(olderThan time.Time) ([]seriesPoint, []spillMark) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var points []seriesPoint
+	var marks []spillMark
+	for key, r := range m.data {
+		n := 0
+		for n < r.len() && !r.at(n).Timestamp.After(olderThan) {
+			points = append(points, seriesPoint{id: r.id, point: r.at(n)})
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		r.spillEnd = r.first + uint64(n)
+		marks = append(marks, spillMark{key: key, r: r, start: r.first, count: n})
+	}
+	return points, marks
+}
+
+// releaseSpill удаляет из памяти первые accepted точек, собранных collectOlderThan
+// (в том же порядке). Точки, вытесненные за время переноса, уже удалены.
+func (m *memoryStorage) releaseSpill(marks []spillMark, accepted int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mark := range marks {
+		n := min(mark.count, accepted)
+		accepted -= n
+
+		r := mark.r
+		r.spillEnd = 0
+		if m.data[mark.key] != r {
+			continue // ряд опустел и удалён
 		}
+
+		end := mark.start + uint64(n)
+		before := r.bytes
+		for r.len() > 0 && r.first < end {
+			r.popOldest()
+			m.points--
+		}
+		m.used -= before - r.bytes
+		m.resized(mark.key, r)
 	}
+}
 
-	return nil
+// Stats возвращает использование памяти и счётчики вытеснения
+func (m *memoryStorage) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return Stats{
+		Type: "memory",
+		Memory: &MemoryStats{
+			Series:               len(m.data),
+			Points:               m.points,
+			UsedBytes:            m.used,
+			BudgetBytes:          m.budget,
+			MaxPointsPerSeries:   m.maxPoints,
+			Evicted:              m.evictedBySeriesLimit + m.evictedByBudget,
+			EvictedBySeriesLimit: m.evictedBySeriesLimit,
+			EvictedByBudget:      m.evictedByBudget,
+		},
+	}
 }
 
 func (m *memoryStorage) Close() error {
@@ -187,3 +187,187 @@ func TestMemoryStorageWithServerID(t *testing.T) {
 		t.Errorf("expected ServerID=server1, got %s", h1.ServerID)
 	}
 }
+
+func TestMemoryStorageGetAggregated(t *testing.T) {
+	store := NewMemoryStorage()
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 10 точек с интервалом 10 секунд: 0, 1, ..., 9
+	for i := 0; i < 10; i++ {
+		store.Save("", "TestObj", "var1", i, base.Add(time.Duration(i)*10*time.Second))
+	}
+
+	// Интервалы по минуте: [0..5] и [6..9]
+	history, err := store.GetAggregated("", "TestObj", "var1", base, base.Add(2*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetAggregated failed: %v", err)
+	}
+
+	if history.BucketMs != 60000 {
+		t.Errorf("expected BucketMs=60000, got %d", history.BucketMs)
+	}
+	if len(history.Points) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(history.Points))
+	}
+
+	p := history.Points[0]
+	if !p.Timestamp.Equal(base) {
+		t.Errorf("expected first bucket at %v, got %v", base, p.Timestamp)
+	}
+	if p.Count != 6 || p.Min != 0 || p.Max != 5 || p.First != 0 || p.Last != 5 || p.Avg != 2.5 {
+		t.Errorf("unexpected first bucket: %+v", p)
+	}
+	if p.Value != p.Avg {
+		t.Errorf("expected Value=Avg, got %v", p.Value)
+	}
+
+	p = history.Points[1]
+	if !p.Timestamp.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected second bucket at %v, got %v", base.Add(time.Minute), p.Timestamp)
+	}
+	if p.Count != 4 || p.Min != 6 || p.Max != 9 || p.First != 6 || p.Last != 9 || p.Avg != 7.5 {
+		t.Errorf("unexpected second bucket: %+v", p)
+	}
+}
+
+func TestMemoryStorageGetAggregatedSkipsNonNumeric(t *testing.T) {
+	store := NewMemoryStorage()
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Save("", "Obj", "var", "10", base)
+	store.Save("", "Obj", "var", "not a number", base.Add(time.Second))
+	store.Save("", "Obj", "var", true, base.Add(2*time.Second))
+	store.Save("", "Obj", "var", map[string]int{"a": 1}, base.Add(3*time.Second))
+
+	history, err := store.GetAggregated("", "Obj", "var", base, base.Add(time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetAggregated failed: %v", err)
+	}
+
+	if len(history.Points) != 1 {
+		t.Fatalf("expected 1 bucket, got %d", len(history.Points))
+	}
+	p := history.Points[0]
+	if p.Count != 2 || p.Min != 1 || p.Max != 10 || p.First != 10 || p.Last != 1 {
+		t.Errorf("unexpected bucket: %+v", p)
+	}
+}
+
+func TestMemoryStorageMaxPointsPerSeries(t *testing.T) {
+	store := NewMemoryStorage(WithMaxPointsPerSeries(3))
+	defer store.Close()
+
+	base := time.Now()
+	for i := 0; i < 10; i++ {
+		store.Save("", "Obj", "var", i, base.Add(time.Duration(i)*time.Second))
+	}
+
+	history, _ := store.GetLatest("", "Obj", "var", 100)
+	if len(history.Points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(history.Points))
+	}
+	if history.Points[0].Value != 7 || history.Points[2].Value != 9 {
+		t.Errorf("expected points 7..9, got %v..%v", history.Points[0].Value, history.Points[2].Value)
+	}
+
+	stats := store.(StatsProvider).Stats()
+	if stats.Type != "memory" || stats.Memory == nil {
+		t.Fatalf("expected memory stats, got %+v", stats)
+	}
+	if stats.Memory.Points != 3 || stats.Memory.EvictedBySeriesLimit != 7 || stats.Memory.Evicted != 7 {
+		t.Errorf("unexpected stats: %+v", stats.Memory)
+	}
+}
+
+func TestMemoryStorageBudget(t *testing.T) {
+	perPoint := pointSize(DataPoint{Value: 0})
+	store := NewMemoryStorage(WithMemoryBudget(10 * perPoint))
+	defer store.Close()
+
+	base := time.Now()
+	for i := 0; i < 20; i++ {
+		store.Save("", "Obj", "a", i, base.Add(time.Duration(i)*time.Second))
+	}
+
+	stats := store.(StatsProvider).Stats().Memory
+	if stats.UsedBytes > 10*perPoint {
+		t.Errorf("used %d bytes exceeds budget %d", stats.UsedBytes, 10*perPoint)
+	}
+	if stats.Points != 10 || stats.EvictedByBudget != 10 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// Новый ряд вытесняет точки из самого большого ряда, а не из себя
+	for i := 0; i < 4; i++ {
+		store.Save("", "Obj", "b", i, base.Add(time.Duration(20+i)*time.Second))
+	}
+
+	a, _ := store.GetLatest("", "Obj", "a", 100)
+	b, _ := store.GetLatest("", "Obj", "b", 100)
+	if len(a.Points) != 6 || len(b.Points) != 4 {
+		t.Errorf("expected 6 points in a and 4 in b, got %d and %d", len(a.Points), len(b.Points))
+	}
+	if a.Points[len(a.Points)-1].Value != 19 {
+		t.Errorf("expected newest point of a to be kept, got %v", a.Points[len(a.Points)-1].Value)
+	}
+}
+
+func TestMemoryStorageBudgetHeapAfterCleanup(t *testing.T) {
+	perPoint := pointSize(DataPoint{Value: 0})
+	store := NewMemoryStorage(WithMemoryBudget(8 * perPoint))
+	defer store.Close()
+	m := store.(*memoryStorage)
+
+	base := time.Now()
+	for i := 0; i < 8; i++ {
+		store.Save("", "Obj", "b", i, base.Add(time.Duration(i)*time.Second))
+	}
+	// Ряд a исчезает целиком, b укорачивается до 6 точек
+	store.Save("", "Obj", "a", 0, base)
+	store.Cleanup(base.Add(time.Second))
+
+	if len(m.bySize) != len(m.data) || len(m.data) != 1 {
+		t.Fatalf("heap has %d series, map has %d", len(m.bySize), len(m.data))
+	}
+	for i, r := range m.bySize {
+		if r.heapIndex != i {
+			t.Fatalf("series %v has heap index %d at position %d", r.id, r.heapIndex, i)
+		}
+	}
+
+	// Переполнение из маленького ряда c вытесняет точки самого большого ряда b
+	for i := 0; i < 3; i++ {
+		store.Save("", "Obj", "c", i, base.Add(time.Hour+time.Duration(i)*time.Second))
+	}
+	b, _ := store.GetLatest("", "Obj", "b", 100)
+	c, _ := store.GetLatest("", "Obj", "c", 100)
+	if len(b.Points) != 5 || len(c.Points) != 3 {
+		t.Errorf("expected 5 points in b and 3 in c, got %d and %d", len(b.Points), len(c.Points))
+	}
+}
+
+func TestMemoryStorageCleanupUpdatesUsage(t *testing.T) {
+	store := NewMemoryStorage()
+	defer store.Close()
+
+	now := time.Now()
+	store.Save("", "Obj", "var", 1, now.Add(-2*time.Hour))
+	store.Save("", "Obj", "var", "text", now)
+
+	store.Cleanup(now.Add(-time.Hour))
+
+	stats := store.(StatsProvider).Stats().Memory
+	if stats.Points != 1 || stats.Series != 1 {
+		t.Errorf("unexpected stats after cleanup: %+v", stats)
+	}
+	if stats.UsedBytes != pointSize(DataPoint{Value: "text"}) {
+		t.Errorf("expected usage of one string point, got %d", stats.UsedBytes)
+	}
+	if stats.Evicted != 0 {
+		t.Errorf("cleanup should not count as eviction, got %d", stats.Evicted)
+	}
+}
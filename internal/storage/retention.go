@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/glob"
+)
+
+// RetentionRule время хранения для рядов, подходящих под glob-шаблоны.
+// Шаблоны поддерживают *, ? и [...]; пустой шаблон равен "*".
+type RetentionRule struct {
+	Server   string        `json:"server"`
+	Object   string        `json:"object"`
+	Variable string        `json:"variable"`
+	TTL      time.Duration `json:"-"`
+}
+
+// RetentionPolicy набор правил хранения. Применяется первое подошедшее правило,
+// для остальных рядов действует DefaultTTL.
+type RetentionPolicy struct {
+	DefaultTTL time.Duration
+	Rules      []RetentionRule
+}
+
+// RetentionCleaner реализуется хранилищами, умеющими удалять данные по правилам хранения
+type RetentionCleaner interface {
+	// CleanupRetention удаляет точки, время хранения которых истекло к моменту now
+	CleanupRetention(policy *RetentionPolicy, now time.Time) error
+}
+
+// NewRetentionPolicy проверяет правила и создаёт политику хранения
+func NewRetentionPolicy(defaultTTL time.Duration, rules []RetentionRule) (*RetentionPolicy, error) {
+	if defaultTTL <= 0 {
+		return nil, fmt.Errorf("default TTL must be positive")
+	}
+
+	normalized := make([]RetentionRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.TTL <= 0 {
+			return nil, fmt.Errorf("retention rule %d: TTL must be positive", i)
+		}
+		if rule.Server == "" {
+			rule.Server = "*"
+		}
+		if rule.Object == "" {
+			rule.Object = "*"
+		}
+		if rule.Variable == "" {
+			rule.Variable = "*"
+		}
+		if err := glob.Validate(rule.Server, rule.Object, rule.Variable); err != nil {
+			return nil, fmt.Errorf("retention rule %d: %w", i, err)
+		}
+		normalized = append(normalized, rule)
+	}
+
+	return &RetentionPolicy{DefaultTTL: defaultTTL, Rules: normalized}, nil
+}
+
+// Match проверяет, подходит ли ряд под правило
+func (r RetentionRule) Match(serverID, objectName, variableName string) bool {
+	return glob.Match(r.Server, serverID) &&
+		glob.Match(r.Object, objectName) &&
+		glob.Match(r.Variable, variableName)
+}
+
+// TTLFor возвращает время хранения ряда
+func (p *RetentionPolicy) TTLFor(serverID, objectName, variableName string) time.Duration {
+	if serverID == "" {
+		serverID = DefaultServerID
+	}
+	for _, rule := range p.Rules {
+		if rule.Match(serverID, objectName, variableName) {
+			return rule.TTL
+		}
+	}
+	return p.DefaultTTL
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyTTLFor(t *testing.T) {
+	policy, err := NewRetentionPolicy(time.Hour, []RetentionRule{
+		{Object: "Debug*", Variable: "counter*", TTL: 10 * time.Minute},
+		{Variable: "io.*", TTL: 7 * 24 * time.Hour},
+		{Server: "backup", TTL: 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		server, object, variable string
+		want                     time.Duration
+	}{
+		{"", "DebugProc", "counter1", 10 * time.Minute},
+		{"", "DebugProc", "io.in.sensor", 7 * 24 * time.Hour},
+		{"srv1", "Proc", "io.out.x", 7 * 24 * time.Hour},
+		{"backup", "Proc", "var", 24 * time.Hour},
+		{"srv1", "Proc", "var", time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.TTLFor(tt.server, tt.object, tt.variable); got != tt.want {
+			t.Errorf("TTLFor(%q, %q, %q) = %v, want %v", tt.server, tt.object, tt.variable, got, tt.want)
+		}
+	}
+}
+
+func TestNewRetentionPolicyValidation(t *testing.T) {
+	if _, err := NewRetentionPolicy(0, nil); err == nil {
+		t.Error("expected error for zero default TTL")
+	}
+	if _, err := NewRetentionPolicy(time.Hour, []RetentionRule{{Variable: "io.*"}}); err == nil {
+		t.Error("expected error for rule without TTL")
+	}
+	if _, err := NewRetentionPolicy(time.Hour, []RetentionRule{{Object: "[", TTL: time.Minute}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestCleanupRetention(t *testing.T) {
+	policy, err := NewRetentionPolicy(time.Hour, []RetentionRule{
+		{Variable: "io.*", TTL: 24 * time.Hour},
+		{Variable: "debug*", TTL: 10 * time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+
+	sqliteStore, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	stores := map[string]Storage{
+		"memory": NewMemoryStorage(),
+		"sqlite": sqliteStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, variable := range []string{"io.in.x", "debugCounter", "var"} {
+				store.Save("", "Obj", variable, 1, now.Add(-2*time.Hour))
+				store.Save("", "Obj", variable, 2, now.Add(-30*time.Minute))
+				store.Save("", "Obj", variable, 3, now)
+			}
+
+			if err := store.(RetentionCleaner).CleanupRetention(policy, now); err != nil {
+				t.Fatalf("CleanupRetention failed: %v", err)
+			}
+
+			expected := map[string]int{"io.in.x": 3, "debugCounter": 1, "var": 2}
+			for variable, want := range expected {
+				h, _ := store.GetLatest("", "Obj", variable, 10)
+				if len(h.Points) != want {
+					t.Errorf("%s: expected %d points, got %d", variable, want, len(h.Points))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import "unsafe"
+
+// ringInitialCapacity начальная ёмкость буфера ряда (растёт удвоением до limit)
+const ringInitialCapacity = 16
+
+// pointOverhead размер DataPoint без данных, на которые ссылается Value
+var pointOverhead = int64(unsafe.Sizeof(DataPoint{}))
+
+// seriesID идентификатор ряда истории
+type seriesID struct {
+	serverID     string
+	objectName   string
+	variableName string
+}
+
+// ring кольцевой буфер точек одного ряда в порядке добавления.
+// Ёмкость растёт по мере заполнения, но не превышает limit:
+// при заполненном буфере новая точка вытесняет самую старую.
+type ring struct {
+	id    seriesID
+	buf   []DataPoint
+	head  int   // индекс самой старой точки
+	size  int   // количество точек
+	limit int   // максимальная ёмкость (0 = без ограничения)
+	bytes int64 // оценка занимаемой памяти
+
+	heapIndex int // позиция в seriesHeap хранилища
+
+	// first сквозной номер самой старой точки (сколько точек ряда уже удалено)
+	first uint64
+	// spillEnd точки с номерами меньше spillEnd сейчас переносятся в cold
+	spillEnd uint64
+}
+
+func newRing(id seriesID, limit int) *ring {
+	capacity := ringInitialCapacity
+	if limit > 0 && limit < capacity {
+		capacity = limit
+	}
+	return &ring{
+		id:    id,
+		buf:   make([]DataPoint, capacity),
+		limit: limit,
+	}
+}
+
+// len возвращает количество точек в буфере
+func (r *ring) len() int {
+	return r.size
+}
+
+// at возвращает i-ю точку, начиная с самой старой
+func (r *ring) at(i int) DataPoint {
+	return r.buf[(r.head+i)%len(r.buf)]
+}
+
+// push добавляет точку. Если буфер достиг limit, самая старая точка вытесняется
+// и возвращается с evicted = true.
+func (r *ring) push(p DataPoint) (old DataPoint, evicted bool) {
+	if r.size == len(r.buf) {
+		if r.limit > 0 && r.size >= r.limit {
+			old = r.buf[r.head]
+			r.bytes -= pointSize(old)
+			r.buf[r.head] = p
+			r.head = (r.head + 1) % len(r.buf)
+			r.first++
+			r.bytes += pointSize(p)
+			return old, true
+		}
+		r.resize(r.growCapacity())
+	}
+
+	r.buf[(r.head+r.size)%len(r.buf)] = p
+	r.size++
+	r.bytes += pointSize(p)
+	return DataPoint{}, false
+}
+
+// popOldest удаляет и возвращает самую старую точку
+func (r *ring) popOldest() (DataPoint, bool) {
+	if r.size == 0 {
+		return DataPoint{}, false
+	}
+
+	p := r.buf[r.head]
+	r.buf[r.head] = DataPoint{} // не удерживаем Value от сборщика мусора
+	r.head = (r.head + 1) % len(r.buf)
+	r.first++
+	r.size--
+	r.bytes -= pointSize(p)
+
+	// Освобождаем память, если буфер опустел больше чем на 3/4
+	if len(r.buf) > ringInitialCapacity && r.size < len(r.buf)/4 {
+		r.resize(len(r.buf) / 2)
+	}
+	return p, true
+}
+
+func (r *ring) growCapacity() int {
+	capacity := len(r.buf) * 2
+	if r.limit > 0 && capacity > r.limit {
+		capacity = r.limit
+	}
+	return capacity
+}
+
+// resize переносит точки в буфер новой ёмкости, начиная с индекса 0
+func (r *ring) resize(capacity int) {
+	buf := make([]DataPoint, capacity)
+	for i := 0; i < r.size; i++ {
+		buf[i] = r.at(i)
+	}
+	r.buf = buf
+	r.head = 0
+}
+
+// pointSize оценивает объём памяти, занимаемый точкой
+func pointSize(p DataPoint) int64 {
+	switch v := p.Value.(type) {
+	case nil, bool:
+		return pointOverhead
+	case string:
+		return pointOverhead + 16 + int64(len(v))
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return pointOverhead + 8
+	default:
+		// Составные значения (map, slice) оцениваем грубо
+		return pointOverhead + 64
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRingGrowAndOrder(t *testing.T) {
+	r := newRing(seriesID{}, 0)
+	base := time.Now()
+
+	for i := 0; i < 100; i++ {
+		if _, evicted := r.push(DataPoint{Timestamp: base.Add(time.Duration(i) * time.Second), Value: i}); evicted {
+			t.Fatal("unlimited ring should not evict")
+		}
+	}
+
+	if r.len() != 100 {
+		t.Fatalf("expected 100 points, got %d", r.len())
+	}
+	for i := 0; i < r.len(); i++ {
+		if r.at(i).Value != i {
+			t.Fatalf("point %d: expected %d, got %v", i, i, r.at(i).Value)
+		}
+	}
+}
+
+func TestRingLimitEvictsOldest(t *testing.T) {
+	r := newRing(seriesID{}, 5)
+	base := time.Now()
+
+	evictedCount := 0
+	for i := 0; i < 8; i++ {
+		old, evicted := r.push(DataPoint{Timestamp: base.Add(time.Duration(i) * time.Second), Value: i})
+		if evicted {
+			if old.Value != evictedCount {
+				t.Errorf("expected evicted value %d, got %v", evictedCount, old.Value)
+			}
+			evictedCount++
+		}
+	}
+
+	if evictedCount != 3 {
+		t.Errorf("expected 3 evicted points, got %d", evictedCount)
+	}
+	if r.len() != 5 || len(r.buf) != 5 {
+		t.Fatalf("expected 5 points in buffer of 5, got %d in %d", r.len(), len(r.buf))
+	}
+	if r.at(0).Value != 3 || r.at(4).Value != 7 {
+		t.Errorf("expected points 3..7, got %v..%v", r.at(0).Value, r.at(4).Value)
+	}
+}
+
+func TestRingPopOldestShrinks(t *testing.T) {
+	r := newRing(seriesID{}, 0)
+	for i := 0; i < 128; i++ {
+		r.push(DataPoint{Value: i})
+	}
+	for i := 0; i < 120; i++ {
+		p, ok := r.popOldest()
+		if !ok || p.Value != i {
+			t.Fatalf("pop %d: expected %d, got %v (%v)", i, i, p.Value, ok)
+		}
+	}
+
+	if r.len() != 8 {
+		t.Fatalf("expected 8 points, got %d", r.len())
+	}
+	if len(r.buf) >= 128 {
+		t.Errorf("expected buffer to shrink, capacity %d", len(r.buf))
+	}
+	if r.at(0).Value != 120 || r.at(7).Value != 127 {
+		t.Errorf("expected points 120..127, got %v..%v", r.at(0).Value, r.at(7).Value)
+	}
+	if r.bytes != 8*pointSize(DataPoint{Value: 0}) {
+		t.Errorf("unexpected bytes estimate %d", r.bytes)
+	}
+}
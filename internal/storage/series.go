@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pv/uniset-panel/internal/glob"
+)
+
+// Match проверяет, подходит ли ряд под фильтр
+func (f SeriesFilter) Match(serverID, objectName, variableName string) bool {
+	return (f.ServerID == "" || glob.Match(f.ServerID, serverID)) &&
+		(f.ObjectName == "" || glob.Match(f.ObjectName, objectName)) &&
+		(f.VariableName == "" || glob.Match(f.VariableName, variableName))
+}
+
+// isGlob проверяет, содержит ли шаблон спецсимволы glob
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// sortSeries упорядочивает ряды по серверу, объекту и переменной
+func sortSeries(series []SeriesInfo) {
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		if a.ServerID != b.ServerID {
+			return a.ServerID < b.ServerID
+		}
+		if a.ObjectName != b.ObjectName {
+			return a.ObjectName < b.ObjectName
+		}
+		return a.VariableName < b.VariableName
+	})
+}
+
+// mergeSeries объединяет описания одних и тех же рядов из разных источников
+func mergeSeries(lists ...[]SeriesInfo) []SeriesInfo {
+	merged := make(map[seriesID]*SeriesInfo)
+	var order []seriesID
+	for _, list := range lists {
+		for _, info := range list {
+			id := seriesID{info.ServerID, info.ObjectName, info.VariableName}
+			existing, ok := merged[id]
+			if !ok {
+				info := info
+				merged[id] = &info
+				order = append(order, id)
+				continue
+			}
+			existing.Count += info.Count
+			if info.First.Before(existing.First) {
+				existing.First = info.First
+			}
+			if info.Last.After(existing.Last) {
+				existing.Last = info.Last
+			}
+		}
+	}
+
+	result := make([]SeriesInfo, 0, len(order))
+	for _, id := range order {
+		result = append(result, *merged[id])
+	}
+	sortSeries(result)
+	return result
+}
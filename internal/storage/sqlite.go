@@ -4,17 +4,34 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/pv/uniset-panel/internal/logger"
 	_ "modernc.org/sqlite"
 )
 
 type sqliteStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	writer *sqliteWriter
 }
 
-func NewSQLiteStorage(dbPath string) (Storage, error) {
-	db, err := sql.Open("sqlite", dbPath)
+// NewSQLiteStorage создаёт хранилище истории в SQLite.
+// Запись выполняется асинхронно: Save ставит точку в очередь, а отдельная горутина
+// сохраняет накопленные точки батчами (по размеру батча или по интервалу).
+func NewSQLiteStorage(dbPath string, opts ...SQLiteOption) (Storage, error) {
+	cfg := sqliteWriterConfig{
+		batchSize:     DefaultWriteBatchSize,
+		flushInterval: DefaultWriteFlushInterval,
+		queueSize:     DefaultWriteQueueSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// WAL позволяет читать историю параллельно с записью батчей
+	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -30,7 +47,25 @@ func NewSQLiteStorage(dbPath string) (Storage, error) {
 		return nil, err
 	}
 
-	return &sqliteStorage{db: db}, nil
+	// Миграция: типизированные колонки значений
+	if err := migrateAddTypedValues(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Миграция: отметки пропусков данных
+	if err := migrateAddGap(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	writer, err := newSQLiteWriter(db, cfg)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteStorage{db: db, writer: writer}, nil
 }
 
 func createTables(db *sql.DB) error {
@@ -41,6 +76,9 @@ func createTables(db *sql.DB) error {
 			object_name TEXT NOT NULL,
 			variable_name TEXT NOT NULL,
 			value TEXT NOT NULL,
+			num_value REAL,
+			str_value TEXT,
+			gap INTEGER NOT NULL DEFAULT 0,
 			timestamp DATETIME NOT NULL
 		);
 		CREATE INDEX IF NOT EXISTS idx_history_lookup
@@ -52,30 +90,37 @@ func createTables(db *sql.DB) error {
 	return nil
 }
 
-// migrateAddServerID добавляет колонку server_id к существующей таблице
-func migrateAddServerID(db *sql.DB) error {
-	// Проверяем, есть ли уже колонка server_id
+// hasColumn проверяет наличие колонки в таблице history
+func hasColumn(db *sql.DB, column string) (bool, error) {
 	rows, err := db.Query("PRAGMA table_info(history)")
 	if err != nil {
-		return fmt.Errorf("check table info: %w", err)
+		return false, fmt.Errorf("check table info: %w", err)
 	}
 	defer rows.Close()
 
-	hasServerID := false
 	for rows.Next() {
 		var cid int
 		var name, typ string
 		var notNull, pk int
 		var dfltValue interface{}
 		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
-			return fmt.Errorf("scan column info: %w", err)
+			return false, fmt.Errorf("scan column info: %w", err)
 		}
-		if name == "server_id" {
-			hasServerID = true
-			break
+		if name == column {
+			return true, nil
 		}
 	}
 
+	return false, rows.Err()
+}
+
+// migrateAddServerID добавляет колонку server_id к существующей таблице
+func migrateAddServerID(db *sql.DB) error {
+	hasServerID, err := hasColumn(db, "server_id")
+	if err != nil {
+		return err
+	}
+
 	if !hasServerID {
 		// Добавляем колонку server_id со значением по умолчанию
 		_, err := db.Exec(`ALTER TABLE history ADD COLUMN server_id TEXT NOT NULL DEFAULT 'default'`)
@@ -97,34 +142,152 @@ func migrateAddServerID(db *sql.DB) error {
 	return nil
 }
 
-func (s *sqliteStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
-	if serverID == "" {
-		serverID = DefaultServerID
+// migrateAddTypedValues добавляет типизированные колонки num_value/str_value
+// и переносит в них значения из JSON колонки value для уже сохранённых строк
+func migrateAddTypedValues(db *sql.DB) error {
+	hasNumValue, err := hasColumn(db, "num_value")
+	if err != nil {
+		return err
+	}
+	if hasNumValue {
+		return nil
 	}
 
-	valueJSON, err := json.Marshal(value)
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("marshal value: %w", err)
+		return fmt.Errorf("begin migration: %w", err)
 	}
+	defer tx.Rollback()
 
-	_, err = s.db.Exec(
-		`INSERT INTO history (server_id, object_name, variable_name, value, timestamp) VALUES (?, ?, ?, ?, ?)`,
-		serverID, objectName, variableName, string(valueJSON), timestamp,
-	)
+	if _, err := tx.Exec(`ALTER TABLE history ADD COLUMN num_value REAL`); err != nil {
+		return fmt.Errorf("add num_value column: %w", err)
+	}
+	if _, err := tx.Exec(`ALTER TABLE history ADD COLUMN str_value TEXT`); err != nil {
+		return fmt.Errorf("add str_value column: %w", err)
+	}
+
+	// Числа и строки переносим в типизированные колонки, остальное (bool, объекты, массивы)
+	// остаётся только в JSON
+	_, err = tx.Exec(`
+		UPDATE history SET num_value = json_extract(value, '$')
+		WHERE json_valid(value) AND json_type(value) IN ('integer', 'real')
+	`)
+	if err != nil {
+		return fmt.Errorf("backfill num_value: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE history SET str_value = json_extract(value, '$')
+		WHERE json_valid(value) AND json_type(value) = 'text'
+	`)
+	if err != nil {
+		return fmt.Errorf("backfill str_value: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration: %w", err)
+	}
+	return nil
+}
+
+// migrateAddGap добавляет колонку gap (отметка начала периода без данных)
+func migrateAddGap(db *sql.DB) error {
+	hasGap, err := hasColumn(db, "gap")
 	if err != nil {
-		return fmt.Errorf("insert: %w", err)
+		return err
+	}
+	if hasGap {
+		return nil
 	}
 
+	if _, err := db.Exec(`ALTER TABLE history ADD COLUMN gap INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return fmt.Errorf("add gap column: %w", err)
+	}
 	return nil
 }
 
+func (s *sqliteStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
+	p, err := newPendingPoint(seriesID{serverID, objectName, variableName}, DataPoint{Timestamp: timestamp, Value: value})
+	if err != nil {
+		return err
+	}
+	return s.writer.enqueue(p)
+}
+
+func (s *sqliteStorage) SaveGap(serverID, objectName, variableName string, timestamp time.Time) error {
+	p, _ := newPendingPoint(seriesID{serverID, objectName, variableName}, DataPoint{Timestamp: timestamp, Gap: true})
+	return s.writer.enqueue(p)
+}
+
+// saveBatch синхронно записывает точки транзакциями по batchSize, минуя очередь.
+// Возвращает количество записанных точек (с начала списка).
+func (s *sqliteStorage) saveBatch(points []seriesPoint) (int, error) {
+	batch := make([]pendingPoint, 0, s.writer.cfg.batchSize)
+	for start := 0; start < len(points); start += s.writer.cfg.batchSize {
+		end := min(start+s.writer.cfg.batchSize, len(points))
+
+		batch = batch[:0]
+		for _, sp := range points[start:end] {
+			p, err := newPendingPoint(sp.id, sp.point)
+			if err != nil {
+				// Такую точку не записать никогда - отбрасываем, чтобы не блокировать перенос
+				s.writer.dropped.Add(1)
+				logger.Warn("SQLite history point dropped", "object", sp.id.objectName, "variable", sp.id.variableName, "error", err)
+				continue
+			}
+			batch = append(batch, p)
+		}
+
+		if err := s.writer.write(batch); err != nil {
+			return start, err
+		}
+	}
+	return len(points), nil
+}
+
+// newPendingPoint готовит точку к записи в БД
+func newPendingPoint(id seriesID, dp DataPoint) (pendingPoint, error) {
+	if id.serverID == "" {
+		id.serverID = DefaultServerID
+	}
+
+	p := pendingPoint{
+		serverID:     id.serverID,
+		objectName:   id.objectName,
+		variableName: id.variableName,
+		timestamp:    dp.Timestamp,
+	}
+
+	if dp.Gap {
+		p.valueJSON = "null"
+		p.gap = true
+		return p, nil
+	}
+
+	// Числа и строки пишем в типизированные колонки, остальное - JSON в value
+	if num, ok := NumericValue(dp.Value); ok {
+		p.numValue = sql.NullFloat64{Float64: num, Valid: true}
+	} else if str, ok := dp.Value.(string); ok {
+		p.strValue = sql.NullString{String: str, Valid: true}
+	} else {
+		valueJSON, err := json.Marshal(dp.Value)
+		if err != nil {
+			return pendingPoint{}, fmt.Errorf("marshal value: %w", err)
+		}
+		p.valueJSON = string(valueJSON)
+	}
+	return p, nil
+}
+
 func (s *sqliteStorage) GetHistory(serverID, objectName, variableName string, from, to time.Time) (*VariableHistory, error) {
 	if serverID == "" {
 		serverID = DefaultServerID
 	}
 
+	// Дописываем точки из очереди, чтобы чтение видело все сохранённые данные
+	s.writer.sync()
+
 	rows, err := s.db.Query(
-		`SELECT value, timestamp FROM history
+		`SELECT num_value, str_value, value, gap, timestamp FROM history
 		 WHERE server_id = ? AND object_name = ? AND variable_name = ? AND timestamp >= ? AND timestamp <= ?
 		 ORDER BY timestamp ASC`,
 		serverID, objectName, variableName, from, to,
@@ -137,14 +300,57 @@ func (s *sqliteStorage) GetHistory(serverID, objectName, variableName string, fr
 	return scanPoints(rows, serverID, objectName, variableName)
 }
 
+func (s *sqliteStorage) GetAggregated(serverID, objectName, variableName string, from, to time.Time, bucket time.Duration) (*AggregatedHistory, error) {
+	if serverID == "" {
+		serverID = DefaultServerID
+	}
+
+	s.writer.sync()
+
+	// Последняя точка до периода - для пропуска, начавшегося раньше from
+	prev, ok, err := s.queryLastPoint(serverID, objectName, variableName, from)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT num_value, str_value, value, gap, timestamp FROM history
+		 WHERE server_id = ? AND object_name = ? AND variable_name = ? AND timestamp >= ? AND timestamp <= ?
+		 ORDER BY timestamp ASC`,
+		serverID, objectName, variableName, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	// Агрегируем потоково, не накапливая все точки в памяти
+	agg := newAggregator(from, to, normalizeBucket(bucket))
+	agg.seed(prev, ok)
+	for rows.Next() {
+		p, err := scanPoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		agg.add(p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return agg.result(serverID, objectName, variableName), nil
+}
+
 func (s *sqliteStorage) GetLatest(serverID, objectName, variableName string, count int) (*VariableHistory, error) {
 	if serverID == "" {
 		serverID = DefaultServerID
 	}
 
+	s.writer.sync()
+
 	rows, err := s.db.Query(
-		`SELECT value, timestamp FROM (
-			SELECT value, timestamp FROM history
+		`SELECT num_value, str_value, value, gap, timestamp FROM (
+			SELECT num_value, str_value, value, gap, timestamp FROM history
 			WHERE server_id = ? AND object_name = ? AND variable_name = ?
 			ORDER BY timestamp DESC
 			LIMIT ?
@@ -159,24 +365,46 @@ func (s *sqliteStorage) GetLatest(serverID, objectName, variableName string, cou
 	return scanPoints(rows, serverID, objectName, variableName)
 }
 
+// lastPoint возвращает последнюю точку ряда не новее at
+func (s *sqliteStorage) lastPoint(serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error) {
+	if serverID == "" {
+		serverID = DefaultServerID
+	}
+	s.writer.sync()
+	return s.queryLastPoint(serverID, objectName, variableName, at)
+}
+
+// queryLastPoint ищет последнюю точку ряда не новее at без сброса очереди записи
+func (s *sqliteStorage) queryLastPoint(serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error) {
+	rows, err := s.db.Query(
+		`SELECT num_value, str_value, value, gap, timestamp FROM history
+		 WHERE server_id = ? AND object_name = ? AND variable_name = ? AND timestamp <= ?
+		 ORDER BY timestamp DESC LIMIT 1`,
+		serverID, objectName, variableName, at,
+	)
+	if err != nil {
+		return DataPoint{}, false, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return DataPoint{}, false, rows.Err()
+	}
+	p, err := scanPoint(rows)
+	if err != nil {
+		return DataPoint{}, false, err
+	}
+	return p, true, nil
+}
+
 func scanPoints(rows *sql.Rows, serverID, objectName, variableName string) (*VariableHistory, error) {
 	var points []DataPoint
 	for rows.Next() {
-		var valueJSON string
-		var timestamp time.Time
-		if err := rows.Scan(&valueJSON, &timestamp); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
-		}
-
-		var value interface{}
-		if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
-			return nil, fmt.Errorf("unmarshal value: %w", err)
+		p, err := scanPoint(rows)
+		if err != nil {
+			return nil, err
 		}
-
-		points = append(points, DataPoint{
-			Timestamp: timestamp,
-			Value:     value,
-		})
+		points = append(points, p)
 	}
 
 	return &VariableHistory{
@@ -187,7 +415,221 @@ func scanPoints(rows *sql.Rows, serverID, objectName, variableName string) (*Var
 	}, nil
 }
 
+// scanPoint читает точку из строки (num_value, str_value, value, gap, timestamp).
+// JSON декодируется только для значений, не попавших в типизированные колонки.
+func scanPoint(rows *sql.Rows) (DataPoint, error) {
+	var num sql.NullFloat64
+	var str sql.NullString
+	var valueJSON string
+	var p DataPoint
+	if err := rows.Scan(&num, &str, &valueJSON, &p.Gap, &p.Timestamp); err != nil {
+		return p, fmt.Errorf("scan: %w", err)
+	}
+
+	value, err := decodeValue(num, str, valueJSON)
+	p.Value = value
+	return p, err
+}
+
+// decodeValue восстанавливает значение из типизированных колонок или JSON
+func decodeValue(num sql.NullFloat64, str sql.NullString, valueJSON string) (interface{}, error) {
+	if num.Valid {
+		return num.Float64, nil
+	}
+	if str.Valid {
+		return str.String, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+		return nil, fmt.Errorf("unmarshal value: %w", err)
+	}
+	return value, nil
+}
+
+// GetHistoryMulti читает все ряды одним запросом
+func (s *sqliteStorage) GetHistoryMulti(keys []SeriesKey, from, to time.Time) ([]*VariableHistory, error) {
+	result := make([]*VariableHistory, len(keys))
+	for i, key := range keys {
+		result[i] = &VariableHistory{
+			ServerID:     key.ServerID,
+			ObjectName:   key.ObjectName,
+			VariableName: key.VariableName,
+		}
+	}
+
+	err := s.queryMulti(keys, from, to, func(indexes []int, p DataPoint) {
+		for _, i := range indexes {
+			result[i].Points = append(result[i].Points, p)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAggregatedMulti агрегирует все ряды потоково по результату одного запроса
+func (s *sqliteStorage) GetAggregatedMulti(keys []SeriesKey, from, to time.Time, bucket time.Duration) ([]*AggregatedHistory, error) {
+	aggs := make([]*aggregator, len(keys))
+	for i, key := range keys {
+		aggs[i] = newAggregator(from, to, normalizeBucket(bucket))
+		prev, ok, err := s.lastPoint(key.ServerID, key.ObjectName, key.VariableName, from)
+		if err != nil {
+			return nil, err
+		}
+		aggs[i].seed(prev, ok)
+	}
+
+	err := s.queryMulti(keys, from, to, func(indexes []int, p DataPoint) {
+		for _, i := range indexes {
+			aggs[i].add(p)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*AggregatedHistory, len(keys))
+	for i, key := range keys {
+		result[i] = aggs[i].result(key.ServerID, key.ObjectName, key.VariableName)
+	}
+	return result, nil
+}
+
+// queryMulti выбирает точки нескольких рядов одним запросом в порядке времени
+// и передаёт каждую точку в fn вместе с индексами запрошенных рядов
+func (s *sqliteStorage) queryMulti(keys []SeriesKey, from, to time.Time, fn func(indexes []int, p DataPoint)) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	s.writer.sync()
+
+	// Один ряд может быть запрошен несколько раз
+	indexes := make(map[seriesID][]int, len(keys))
+	var conds []string
+	var args []interface{}
+	for i, key := range keys {
+		serverID := key.ServerID
+		if serverID == "" {
+			serverID = DefaultServerID
+		}
+		id := seriesID{serverID, key.ObjectName, key.VariableName}
+		if _, exists := indexes[id]; !exists {
+			conds = append(conds, "(server_id = ? AND object_name = ? AND variable_name = ?)")
+			args = append(args, id.serverID, id.objectName, id.variableName)
+		}
+		indexes[id] = append(indexes[id], i)
+	}
+	args = append(args, from, to)
+
+	rows, err := s.db.Query(
+		`SELECT server_id, object_name, variable_name, num_value, str_value, value, gap, timestamp FROM history
+		 WHERE (`+strings.Join(conds, " OR ")+`) AND timestamp >= ? AND timestamp <= ?
+		 ORDER BY timestamp ASC`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id seriesID
+		var num sql.NullFloat64
+		var str sql.NullString
+		var valueJSON string
+		var p DataPoint
+		if err := rows.Scan(&id.serverID, &id.objectName, &id.variableName, &num, &str, &valueJSON, &p.Gap, &p.Timestamp); err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+		value, err := decodeValue(num, str, valueJSON)
+		if err != nil {
+			return err
+		}
+		p.Value = value
+		fn(indexes[id], p)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows: %w", err)
+	}
+	return nil
+}
+
+// ListSeries возвращает ряды, подходящие под фильтр. Точные значения фильтра
+// передаются в SQL, glob-шаблоны проверяются после группировки.
+func (s *sqliteStorage) ListSeries(filter SeriesFilter) ([]SeriesInfo, error) {
+	s.writer.sync()
+
+	query := `SELECT server_id, object_name, variable_name, COUNT(*) FROM history`
+	var conds []string
+	var args []interface{}
+	for _, f := range []struct{ column, value string }{
+		{"server_id", filter.ServerID},
+		{"object_name", filter.ObjectName},
+		{"variable_name", filter.VariableName},
+	} {
+		if f.value != "" && !isGlob(f.value) {
+			conds = append(conds, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " GROUP BY server_id, object_name, variable_name"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query series: %w", err)
+	}
+	result := make([]SeriesInfo, 0)
+	for rows.Next() {
+		var info SeriesInfo
+		if err := rows.Scan(&info.ServerID, &info.ObjectName, &info.VariableName, &info.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan series: %w", err)
+		}
+		if filter.Match(info.ServerID, info.ObjectName, info.VariableName) {
+			result = append(result, info)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	// Границы ряда читаем по индексу: MIN/MAX по timestamp теряют тип DATETIME
+	for i := range result {
+		info := &result[i]
+		err := s.db.QueryRow(
+			`SELECT timestamp FROM history
+			 WHERE server_id = ? AND object_name = ? AND variable_name = ?
+			 ORDER BY timestamp ASC LIMIT 1`,
+			info.ServerID, info.ObjectName, info.VariableName,
+		).Scan(&info.First)
+		if err != nil {
+			return nil, fmt.Errorf("query first timestamp: %w", err)
+		}
+		err = s.db.QueryRow(
+			`SELECT timestamp FROM history
+			 WHERE server_id = ? AND object_name = ? AND variable_name = ?
+			 ORDER BY timestamp DESC LIMIT 1`,
+			info.ServerID, info.ObjectName, info.VariableName,
+		).Scan(&info.Last)
+		if err != nil {
+			return nil, fmt.Errorf("query last timestamp: %w", err)
+		}
+	}
+
+	sortSeries(result)
+	return result, nil
+}
+
 func (s *sqliteStorage) Cleanup(olderThan time.Time) error {
+	s.writer.sync()
+
 	_, err := s.db.Exec(`DELETE FROM history WHERE timestamp < ?`, olderThan)
 	if err != nil {
 		return fmt.Errorf("delete: %w", err)
@@ -195,6 +637,69 @@ func (s *sqliteStorage) Cleanup(olderThan time.Time) error {
 	return nil
 }
 
+// CleanupRetention удаляет точки с истёкшим временем хранения по правилам policy.
+// Правила сопоставляются в Go для каждого ряда, поэтому семантика шаблонов
+// совпадает с хранилищем в памяти.
+func (s *sqliteStorage) CleanupRetention(policy *RetentionPolicy, now time.Time) error {
+	s.writer.sync()
+
+	rows, err := s.db.Query(`SELECT DISTINCT server_id, object_name, variable_name FROM history`)
+	if err != nil {
+		return fmt.Errorf("query series: %w", err)
+	}
+	var series []seriesID
+	for rows.Next() {
+		var id seriesID
+		if err := rows.Scan(&id.serverID, &id.objectName, &id.variableName); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan series: %w", err)
+		}
+		series = append(series, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`DELETE FROM history
+		 WHERE server_id = ? AND object_name = ? AND variable_name = ? AND timestamp < ?`,
+	)
+	if err != nil {
+		return fmt.Errorf("prepare delete: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range series {
+		olderThan := now.Add(-policy.TTLFor(id.serverID, id.objectName, id.variableName))
+		if _, err := stmt.Exec(id.serverID, id.objectName, id.variableName, olderThan); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// Stats возвращает статистику буферизованной записи
+func (s *sqliteStorage) Stats() Stats {
+	writer := s.writer.stats()
+	return Stats{
+		Type:   "sqlite",
+		Writer: &writer,
+	}
+}
+
+// Close сбрасывает очередь записи и закрывает БД
 func (s *sqliteStorage) Close() error {
+	s.writer.close()
 	return s.db.Close()
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
@@ -458,3 +459,415 @@ func TestSQLiteStorageFileCreation(t *testing.T) {
 		t.Error("database file should exist")
 	}
 }
+
+func TestSQLiteStorageGetAggregated(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 10 points with 10 second interval: 0, 1, ..., 9
+	for i := 0; i < 10; i++ {
+		if err := store.Save("", "TestObj", "var1", i, base.Add(time.Duration(i)*10*time.Second)); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	history, err := store.GetAggregated("", "TestObj", "var1", base, base.Add(2*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetAggregated failed: %v", err)
+	}
+
+	if len(history.Points) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(history.Points))
+	}
+
+	p := history.Points[0]
+	if p.Count != 6 || p.Min != 0 || p.Max != 5 || p.First != 0 || p.Last != 5 || p.Avg != 2.5 {
+		t.Errorf("unexpected first bucket: %+v", p)
+	}
+	p = history.Points[1]
+	if p.Count != 4 || p.Min != 6 || p.Max != 9 || p.First != 6 || p.Last != 9 || p.Avg != 7.5 {
+		t.Errorf("unexpected second bucket: %+v", p)
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected float64
+		ok       bool
+	}{
+		{42, 42, true},
+		{int64(-7), -7, true},
+		{3.5, 3.5, true},
+		{"100", 100, true},
+		{" 1.5 ", 1.5, true},
+		{true, 1, true},
+		{false, 0, true},
+		{"abc", 0, false},
+		{nil, 0, false},
+		{[]int{1}, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ToFloat64(tt.value)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("ToFloat64(%v) = %v, %v; want %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestSQLiteStorageWriterFlushOnClose(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	// Большой батч и интервал: без Close точки остались бы в очереди
+	store, err := NewSQLiteStorage(dbPath, WithWriteBatchSize(1000), WithWriteFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		if err := store.Save("", "Obj", "var", i, now.Add(time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if err := store.Save("", "Obj", "var", 1, now); err != ErrStorageClosed {
+		t.Errorf("expected ErrStorageClosed after Close, got %v", err)
+	}
+
+	reopened, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage (reopen) failed: %v", err)
+	}
+	defer reopened.Close()
+
+	history, err := reopened.GetLatest("", "Obj", "var", 100)
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if len(history.Points) != 50 {
+		t.Errorf("expected 50 points after close, got %d", len(history.Points))
+	}
+}
+
+func TestSQLiteStorageWriterFlushOnInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := NewSQLiteStorage(dbPath, WithWriteBatchSize(1000), WithWriteFlushInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	store.Save("", "Obj", "var", 1, time.Now())
+
+	provider := store.(StatsProvider)
+	deadline := time.Now().Add(2 * time.Second)
+	for provider.Stats().Writer.Written == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("point was not written by interval flush")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSQLiteStorageWriterStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := NewSQLiteStorage(dbPath, WithWriteBatchSize(10), WithWriteQueueSize(100))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	for i := 0; i < 25; i++ {
+		store.Save("", "Obj", "var", i, now.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	// Чтение дожидается записи очереди
+	history, _ := store.GetLatest("", "Obj", "var", 100)
+	if len(history.Points) != 25 {
+		t.Fatalf("expected 25 points, got %d", len(history.Points))
+	}
+
+	stats := store.(StatsProvider).Stats()
+	if stats.Type != "sqlite" {
+		t.Errorf("expected type=sqlite, got %s", stats.Type)
+	}
+	if stats.Writer == nil {
+		t.Fatal("expected writer stats")
+	}
+	if stats.Writer.Written != 25 {
+		t.Errorf("expected Written=25, got %d", stats.Writer.Written)
+	}
+	if stats.Writer.Batches < 3 {
+		t.Errorf("expected at least 3 batches, got %d", stats.Writer.Batches)
+	}
+	if stats.Writer.QueueCapacity != 100 {
+		t.Errorf("expected QueueCapacity=100, got %d", stats.Writer.QueueCapacity)
+	}
+	if stats.Writer.QueueDepth != 0 {
+		t.Errorf("expected empty queue, got %d", stats.Writer.QueueDepth)
+	}
+}
+
+func TestSQLiteWriterQueueFull(t *testing.T) {
+	w := &sqliteWriter{
+		queue: make(chan pendingPoint, 2),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := w.enqueue(pendingPoint{}); err != nil {
+			t.Fatalf("enqueue %d failed: %v", i, err)
+		}
+	}
+
+	if err := w.enqueue(pendingPoint{}); err != ErrWriteQueueFull {
+		t.Errorf("expected ErrWriteQueueFull, got %v", err)
+	}
+
+	stats := w.stats()
+	if stats.Dropped != 1 {
+		t.Errorf("expected Dropped=1, got %d", stats.Dropped)
+	}
+	if stats.QueueDepth != 2 {
+		t.Errorf("expected QueueDepth=2, got %d", stats.QueueDepth)
+	}
+}
+
+func TestSQLiteStorageWALMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	var mode string
+	if err := store.(*sqliteStorage).db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("query journal_mode failed: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("expected journal_mode=wal, got %s", mode)
+	}
+}
+
+func TestSQLiteStorageTypedColumns(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.Save("", "Obj", "num", 42, now)
+	store.Save("", "Obj", "str", "hello", now)
+	store.Save("", "Obj", "bool", true, now)
+
+	s := store.(*sqliteStorage)
+	s.writer.sync()
+
+	var num sql.NullFloat64
+	var str sql.NullString
+	if err := s.db.QueryRow(`SELECT num_value, str_value FROM history WHERE variable_name = 'num'`).Scan(&num, &str); err != nil {
+		t.Fatalf("query num failed: %v", err)
+	}
+	if !num.Valid || num.Float64 != 42 || str.Valid {
+		t.Errorf("expected num_value=42, str_value=NULL, got %v, %v", num, str)
+	}
+
+	if err := s.db.QueryRow(`SELECT num_value, str_value FROM history WHERE variable_name = 'str'`).Scan(&num, &str); err != nil {
+		t.Fatalf("query str failed: %v", err)
+	}
+	if num.Valid || !str.Valid || str.String != "hello" {
+		t.Errorf("expected num_value=NULL, str_value=hello, got %v, %v", num, str)
+	}
+
+	h, _ := store.GetLatest("", "Obj", "num", 1)
+	if len(h.Points) != 1 || h.Points[0].Value != float64(42) {
+		t.Errorf("expected float64(42), got %v", h.Points)
+	}
+	h, _ = store.GetLatest("", "Obj", "str", 1)
+	if len(h.Points) != 1 || h.Points[0].Value != "hello" {
+		t.Errorf("expected hello, got %v", h.Points)
+	}
+	h, _ = store.GetLatest("", "Obj", "bool", 1)
+	if len(h.Points) != 1 || h.Points[0].Value != true {
+		t.Errorf("expected true, got %v", h.Points)
+	}
+}
+
+func TestSQLiteStorageMigrateTypedValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	// Старая схема: значение только в JSON колонке value
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			server_id TEXT NOT NULL DEFAULT 'default',
+			object_name TEXT NOT NULL,
+			variable_name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			timestamp DATETIME NOT NULL
+		)`)
+	if err != nil {
+		t.Fatalf("create old table failed: %v", err)
+	}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []string{`10`, `2.5`, `"text"`, `true`} {
+		_, err := db.Exec(`INSERT INTO history (object_name, variable_name, value, timestamp) VALUES ('Obj', 'var', ?, ?)`,
+			v, base.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	var numCount, strCount int
+	s := store.(*sqliteStorage)
+	s.db.QueryRow(`SELECT COUNT(*) FROM history WHERE num_value IS NOT NULL`).Scan(&numCount)
+	s.db.QueryRow(`SELECT COUNT(*) FROM history WHERE str_value IS NOT NULL`).Scan(&strCount)
+	if numCount != 2 || strCount != 1 {
+		t.Errorf("expected 2 numeric and 1 string rows after backfill, got %d and %d", numCount, strCount)
+	}
+
+	history, err := store.GetHistory("", "Obj", "var", base, base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	expected := []interface{}{float64(10), 2.5, "text", true}
+	if len(history.Points) != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), len(history.Points))
+	}
+	for i, want := range expected {
+		if history.Points[i].Value != want {
+			t.Errorf("point %d: expected %v, got %v", i, want, history.Points[i].Value)
+		}
+	}
+}
+
+func TestSQLiteStorageListSeries(t *testing.T) {
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Save("", "Obj", "var1", 1, base.Add(time.Minute))
+	store.Save("", "Obj", "var1", 2, base)
+	store.Save("", "Obj", "io.in.x", 3, base)
+	store.Save("srv2", "Other", "var1", 4, base)
+
+	series, err := store.ListSeries(SeriesFilter{})
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	if len(series) != 3 {
+		t.Fatalf("expected 3 series, got %d", len(series))
+	}
+	// Упорядочено по серверу, объекту, переменной
+	if series[0].VariableName != "io.in.x" || series[1].VariableName != "var1" || series[2].ServerID != "srv2" {
+		t.Errorf("unexpected order: %+v", series)
+	}
+	if s := series[1]; s.ServerID != DefaultServerID || s.Count != 2 ||
+		!s.First.Equal(base) || !s.Last.Equal(base.Add(time.Minute)) {
+		t.Errorf("unexpected series info: %+v", s)
+	}
+
+	series, err = store.ListSeries(SeriesFilter{ObjectName: "Obj", VariableName: "io.*"})
+	if err != nil {
+		t.Fatalf("ListSeries with filter failed: %v", err)
+	}
+	if len(series) != 1 || series[0].VariableName != "io.in.x" {
+		t.Errorf("expected only io.in.x, got %+v", series)
+	}
+}
+
+func TestSQLiteStorageGetMulti(t *testing.T) {
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		ts := base.Add(time.Duration(i) * 10 * time.Second)
+		store.Save("", "Obj", "a", i, ts)
+		store.Save("srv2", "Obj", "b", i*10, ts)
+		store.Save("", "Obj", "other", "x", ts)
+	}
+
+	keys := []SeriesKey{
+		{ObjectName: "Obj", VariableName: "a"},
+		{ServerID: "srv2", ObjectName: "Obj", VariableName: "b"},
+		{ObjectName: "Obj", VariableName: "missing"},
+		{ObjectName: "Obj", VariableName: "a"}, // повтор
+	}
+
+	histories, err := store.GetHistoryMulti(keys, base, base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistoryMulti failed: %v", err)
+	}
+	if len(histories) != 4 {
+		t.Fatalf("expected 4 histories, got %d", len(histories))
+	}
+	if len(histories[0].Points) != 6 || len(histories[1].Points) != 6 || len(histories[2].Points) != 0 || len(histories[3].Points) != 6 {
+		t.Errorf("unexpected point counts: %d %d %d %d",
+			len(histories[0].Points), len(histories[1].Points), len(histories[2].Points), len(histories[3].Points))
+	}
+	if histories[1].Points[5].Value != float64(50) {
+		t.Errorf("expected last b value 50, got %v", histories[1].Points[5].Value)
+	}
+
+	aggregated, err := store.GetAggregatedMulti(keys[:2], base, base.Add(time.Minute), 30*time.Second)
+	if err != nil {
+		t.Fatalf("GetAggregatedMulti failed: %v", err)
+	}
+	if len(aggregated) != 2 || len(aggregated[0].Points) != 2 || len(aggregated[1].Points) != 2 {
+		t.Fatalf("expected 2 buckets per series, got %+v", aggregated)
+	}
+	// Интервалы рядов совпадают
+	for i := range aggregated[0].Points {
+		if !aggregated[0].Points[i].Timestamp.Equal(aggregated[1].Points[i].Timestamp) {
+			t.Errorf("bucket %d not aligned", i)
+		}
+	}
+	if p := aggregated[1].Points[1]; p.Min != 30 || p.Max != 50 || p.Count != 3 {
+		t.Errorf("unexpected second bucket of b: %+v", p)
+	}
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/logger"
+)
+
+// Значения по умолчанию для буферизованной записи
+const (
+	DefaultWriteBatchSize     = 500
+	DefaultWriteFlushInterval = time.Second
+	DefaultWriteQueueSize     = 10000
+)
+
+// ErrWriteQueueFull возвращается Save, когда очередь записи переполнена и точка отброшена
+var ErrWriteQueueFull = errors.New("write queue full, point dropped")
+
+// ErrStorageClosed возвращается при записи в закрытое хранилище
+var ErrStorageClosed = errors.New("storage closed")
+
+// SQLiteOption функциональная опция для NewSQLiteStorage
+type SQLiteOption func(*sqliteWriterConfig)
+
+type sqliteWriterConfig struct {
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+}
+
+// WithWriteBatchSize задаёт максимальное количество точек в одной транзакции
+func WithWriteBatchSize(n int) SQLiteOption {
+	return func(c *sqliteWriterConfig) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithWriteFlushInterval задаёт максимальное время ожидания точки в очереди
+func WithWriteFlushInterval(d time.Duration) SQLiteOption {
+	return func(c *sqliteWriterConfig) {
+		if d > 0 {
+			c.flushInterval = d
+		}
+	}
+}
+
+// WithWriteQueueSize задаёт ёмкость очереди записи
+func WithWriteQueueSize(n int) SQLiteOption {
+	return func(c *sqliteWriterConfig) {
+		if n > 0 {
+			c.queueSize = n
+		}
+	}
+}
+
+// pendingPoint точка, ожидающая записи в БД
+type pendingPoint struct {
+	serverID     string
+	objectName   string
+	variableName string
+	numValue     sql.NullFloat64
+	strValue     sql.NullString
+	valueJSON    string // JSON для значений, не попавших в num_value/str_value
+	gap          bool   // отметка начала периода без данных
+	timestamp    time.Time
+}
+
+// sqliteWriter пишет точки в БД батчами в отдельной горутине
+type sqliteWriter struct {
+	db     *sql.DB
+	insert *sql.Stmt
+	cfg    sqliteWriterConfig
+
+	// mu защищает отправку в queue от закрытия канала
+	mu     sync.RWMutex
+	closed bool
+	queue  chan pendingPoint
+	flush  chan chan struct{}
+	done   chan struct{}
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	batches atomic.Uint64
+
+	errMu     sync.Mutex
+	lastError string
+}
+
+func newSQLiteWriter(db *sql.DB, cfg sqliteWriterConfig) (*sqliteWriter, error) {
+	insert, err := db.Prepare(
+		`INSERT INTO history (server_id, object_name, variable_name, value, num_value, str_value, gap, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("prepare insert: %w", err)
+	}
+
+	w := &sqliteWriter{
+		db:     db,
+		insert: insert,
+		cfg:    cfg,
+		queue:  make(chan pendingPoint, cfg.queueSize),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// enqueue ставит точку в очередь без блокировки
+func (w *sqliteWriter) enqueue(p pendingPoint) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrStorageClosed
+	}
+
+	select {
+	case w.queue <- p:
+		return nil
+	default:
+		w.dropped.Add(1)
+		return ErrWriteQueueFull
+	}
+}
+
+// write синхронно записывает батч одной транзакцией, минуя очередь
+func (w *sqliteWriter) write(batch []pendingPoint) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrStorageClosed
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := w.commitTx(batch); err != nil {
+		w.setError(err)
+		return err
+	}
+	w.written.Add(uint64(len(batch)))
+	w.batches.Add(1)
+	return nil
+}
+
+// sync дожидается записи всех точек, поставленных в очередь до вызова
+func (w *sqliteWriter) sync() {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return
+	}
+	ack := make(chan struct{})
+	w.mu.RUnlock()
+
+	select {
+	case w.flush <- ack:
+		<-ack
+	case <-w.done:
+	}
+}
+
+// close сбрасывает оставшиеся точки и останавливает горутину записи
+func (w *sqliteWriter) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	w.insert.Close()
+}
+
+func (w *sqliteWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]pendingPoint, 0, w.cfg.batchSize)
+
+	for {
+		select {
+		case p, ok := <-w.queue:
+			if !ok {
+				w.commit(batch)
+				return
+			}
+			batch = append(batch, p)
+			if len(batch) >= w.cfg.batchSize {
+				w.commit(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			w.commit(batch)
+			batch = batch[:0]
+
+		case ack := <-w.flush:
+			batch = w.drain(batch)
+			close(ack)
+		}
+	}
+}
+
+// drain записывает всё, что уже лежит в очереди, батчами не больше batchSize
+func (w *sqliteWriter) drain(batch []pendingPoint) []pendingPoint {
+	for {
+		select {
+		case p, ok := <-w.queue:
+			if !ok {
+				w.commit(batch)
+				return batch[:0]
+			}
+			batch = append(batch, p)
+			if len(batch) >= w.cfg.batchSize {
+				w.commit(batch)
+				batch = batch[:0]
+			}
+		default:
+			w.commit(batch)
+			return batch[:0]
+		}
+	}
+}
+
+// commit записывает батч одной транзакцией
+func (w *sqliteWriter) commit(batch []pendingPoint) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := w.commitTx(batch); err != nil {
+		w.dropped.Add(uint64(len(batch)))
+		w.setError(err)
+		logger.Warn("SQLite history batch write failed", "points", len(batch), "error", err)
+		return
+	}
+
+	w.written.Add(uint64(len(batch)))
+	w.batches.Add(1)
+}
+
+func (w *sqliteWriter) commitTx(batch []pendingPoint) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt := tx.Stmt(w.insert)
+	defer stmt.Close()
+
+	for _, p := range batch {
+		if _, err := stmt.Exec(p.serverID, p.objectName, p.variableName, p.valueJSON, p.numValue, p.strValue, p.gap, p.timestamp); err != nil {
+			return fmt.Errorf("exec insert: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (w *sqliteWriter) setError(err error) {
+	w.errMu.Lock()
+	w.lastError = err.Error()
+	w.errMu.Unlock()
+}
+
+// stats возвращает текущие счётчики записи
+func (w *sqliteWriter) stats() WriterStats {
+	w.errMu.Lock()
+	lastError := w.lastError
+	w.errMu.Unlock()
+
+	return WriterStats{
+		QueueDepth:    len(w.queue),
+		QueueCapacity: cap(w.queue),
+		Written:       w.written.Load(),
+		Dropped:       w.dropped.Load(),
+		Batches:       w.batches.Load(),
+		LastError:     lastError,
+	}
+}
@@ -4,10 +4,19 @@ import (
 	"time"
 )
 
-// DataPoint точка данных с временной меткой
+// DataPoint точка данных с временной меткой.
+// Gap = true отмечает начало периода без данных (опрос не удался, сервер недоступен);
+// период длится до следующей обычной точки.
 type DataPoint struct {
 	Timestamp time.Time   `json:"timestamp"`
 	Value     interface{} `json:"value"`
+	Gap       bool        `json:"gap,omitempty"`
+}
+
+// Gap интервал без данных
+type Gap struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
 }
 
 // VariableHistory история значений переменной
@@ -16,6 +25,56 @@ type VariableHistory struct {
 	ObjectName   string      `json:"objectName"`
 	VariableName string      `json:"variableName"`
 	Points       []DataPoint `json:"points"`
+	Gaps         []Gap       `json:"gaps,omitempty"`
+	Availability *float64    `json:"availability,omitempty"` // % времени запрошенного периода с данными
+}
+
+// AggregatedPoint агрегированные значения за один интервал (bucket)
+type AggregatedPoint struct {
+	Timestamp time.Time `json:"timestamp"` // начало интервала
+	Value     float64   `json:"value"`     // совпадает с Avg (для совместимости с графиками)
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Avg       float64   `json:"avg"`
+	First     float64   `json:"first"`
+	Last      float64   `json:"last"`
+	Count     int       `json:"count"`
+}
+
+// AggregatedHistory агрегированная история значений переменной
+type AggregatedHistory struct {
+	ServerID     string            `json:"serverId,omitempty"`
+	ObjectName   string            `json:"objectName"`
+	VariableName string            `json:"variableName"`
+	BucketMs     int64             `json:"bucketMs"`
+	Points       []AggregatedPoint `json:"points"`
+	Gaps         []Gap             `json:"gaps,omitempty"`
+	Availability *float64          `json:"availability,omitempty"` // % времени периода с данными
+}
+
+// SeriesKey идентификатор ряда в запросе нескольких рядов
+type SeriesKey struct {
+	ServerID     string `json:"server"`
+	ObjectName   string `json:"object"`
+	VariableName string `json:"variable"`
+}
+
+// SeriesFilter фильтр списка рядов. Поля задаются glob-шаблонами (*, ?, [...]),
+// пустое поле не ограничивает выборку.
+type SeriesFilter struct {
+	ServerID     string
+	ObjectName   string
+	VariableName string
+}
+
+// SeriesInfo описание ряда, сохранённого в хранилище
+type SeriesInfo struct {
+	ServerID     string    `json:"serverId"`
+	ObjectName   string    `json:"objectName"`
+	VariableName string    `json:"variableName"`
+	Count        int64     `json:"count"`
+	First        time.Time `json:"first"`
+	Last         time.Time `json:"last"`
 }
 
 // Storage интерфейс хранилища истории
@@ -23,12 +82,30 @@ type Storage interface {
 	// Save сохраняет значение переменной
 	Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error
 
+	// SaveGap сохраняет отметку начала периода без данных
+	SaveGap(serverID, objectName, variableName string, timestamp time.Time) error
+
 	// GetHistory возвращает историю переменной за указанный период
 	GetHistory(serverID, objectName, variableName string, from, to time.Time) (*VariableHistory, error)
 
 	// GetLatest возвращает последние N точек
 	GetLatest(serverID, objectName, variableName string, count int) (*VariableHistory, error)
 
+	// GetAggregated возвращает историю за период, агрегированную по интервалам bucket
+	// (min/max/avg/first/last). Нечисловые значения пропускаются, отметки пропусков
+	// попадают в Gaps.
+	GetAggregated(serverID, objectName, variableName string, from, to time.Time, bucket time.Duration) (*AggregatedHistory, error)
+
+	// GetHistoryMulti возвращает истории нескольких рядов за период (в порядке keys)
+	GetHistoryMulti(keys []SeriesKey, from, to time.Time) ([]*VariableHistory, error)
+
+	// GetAggregatedMulti возвращает агрегированные истории нескольких рядов (в порядке keys).
+	// Интервалы всех рядов отсчитываются от from и поэтому совпадают.
+	GetAggregatedMulti(keys []SeriesKey, from, to time.Time, bucket time.Duration) ([]*AggregatedHistory, error)
+
+	// ListSeries возвращает ряды, подходящие под фильтр, упорядоченные по серверу, объекту и переменной
+	ListSeries(filter SeriesFilter) ([]SeriesInfo, error)
+
 	// Cleanup удаляет данные старше указанного времени
 	Cleanup(olderThan time.Time) error
 
@@ -36,5 +113,39 @@ type Storage interface {
 	Close() error
 }
 
+// WriterStats счётчики буферизованной записи в хранилище
+type WriterStats struct {
+	QueueDepth    int    `json:"queueDepth"`    // точек в очереди
+	QueueCapacity int    `json:"queueCapacity"` // ёмкость очереди
+	Written       uint64 `json:"written"`       // записано точек
+	Dropped       uint64 `json:"dropped"`       // отброшено (очередь переполнена или ошибка записи)
+	Batches       uint64 `json:"batches"`       // выполнено транзакций
+	LastError     string `json:"lastError,omitempty"`
+}
+
+// MemoryStats использование памяти хранилищем в памяти
+type MemoryStats struct {
+	Series               int    `json:"series"`               // количество рядов
+	Points               int    `json:"points"`               // всего точек
+	UsedBytes            int64  `json:"usedBytes"`            // оценка занимаемой памяти
+	BudgetBytes          int64  `json:"budgetBytes"`          // лимит памяти (0 = без ограничения)
+	MaxPointsPerSeries   int    `json:"maxPointsPerSeries"`   // лимит точек в ряду (0 = без ограничения)
+	Evicted              uint64 `json:"evicted"`              // всего вытеснено точек
+	EvictedBySeriesLimit uint64 `json:"evictedBySeriesLimit"` // вытеснено из-за лимита ряда
+	EvictedByBudget      uint64 `json:"evictedByBudget"`      // вытеснено из-за лимита памяти
+}
+
+// Stats статистика хранилища истории
+type Stats struct {
+	Type   string       `json:"type"`
+	Writer *WriterStats `json:"writer,omitempty"`
+	Memory *MemoryStats `json:"memory,omitempty"`
+}
+
+// StatsProvider реализуется хранилищами, которые могут сообщать статистику
+type StatsProvider interface {
+	Stats() Stats
+}
+
 // DefaultServerID используется когда serverID не указан
 const DefaultServerID = "default"
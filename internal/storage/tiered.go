@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/logger"
+)
+
+// DefaultHotWindow период, в течение которого точки хранятся в памяти
+const DefaultHotWindow = 15 * time.Minute
+
+// farFuture граница переноса, при которой в cold переносятся все точки
+var farFuture = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// tieredStorage хранит свежие точки в памяти (hot), а более старые
+// переносит в фоне в постоянное хранилище (cold, обычно SQLite).
+// Каждая точка находится ровно в одном уровне: из памяти точки удаляются
+// только после того, как cold их принял. Точки, вытесненные из памяти
+// по лимитам, тоже переносятся в cold.
+type tieredStorage struct {
+	hot       *memoryStorage
+	cold      Storage
+	hotWindow time.Duration
+
+	// mu исключает чтение во время переноса точек между уровнями
+	mu sync.RWMutex
+	// spilledUntil граница: в cold нет точек новее этого момента
+	spilledUntil time.Time
+
+	// evicted точки, вытесненные из памяти и ещё не записанные в cold
+	evictMu      sync.Mutex
+	evicted      []seriesPoint
+	evictPending chan struct{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// seriesPoint точка с идентификатором ряда (для переноса между уровнями)
+type seriesPoint struct {
+	id    seriesID
+	point DataPoint
+}
+
+// batchSaver хранилище, синхронно принимающее точки пачкой.
+// Возвращает количество принятых точек с начала списка.
+type batchSaver interface {
+	saveBatch(points []seriesPoint) (int, error)
+}
+
+// NewTieredStorage создаёт двухуровневое хранилище. Точки старше hotWindow
+// переносятся из памяти в cold в фоне; при закрытии в cold переносится всё.
+func NewTieredStorage(cold Storage, hotWindow time.Duration, memOpts ...MemoryOption) Storage {
+	if hotWindow <= 0 {
+		hotWindow = DefaultHotWindow
+	}
+
+	t := &tieredStorage{
+		hot:          NewMemoryStorage(memOpts...).(*memoryStorage),
+		cold:         cold,
+		hotWindow:    hotWindow,
+		spilledUntil: time.Now(),
+		evictPending: make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	t.hot.onEvict = t.onEvict
+
+	go t.run(spillInterval(hotWindow))
+	return t
+}
+
+// spillInterval период переноса: четверть окна, но от 1 до 30 секунд
+func spillInterval(hotWindow time.Duration) time.Duration {
+	interval := hotWindow / 4
+	if interval < time.Second {
+		return time.Second
+	}
+	if interval > 30*time.Second {
+		return 30 * time.Second
+	}
+	return interval
+}
+
+func (t *tieredStorage) run(interval time.Duration) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-t.evictPending:
+			t.flushEvicted()
+		case now := <-ticker.C:
+			t.spill(now.Add(-t.hotWindow))
+		}
+	}
+}
+
+// onEvict собирает вытесненные из памяти точки; при накоплении батча
+// будит фоновую горутину (вызывается под блокировкой hot)
+func (t *tieredStorage) onEvict(id seriesID, p DataPoint) {
+	t.evictMu.Lock()
+	t.evicted = append(t.evicted, seriesPoint{id: id, point: p})
+	full := len(t.evicted) >= DefaultWriteBatchSize
+	t.evictMu.Unlock()
+
+	if full {
+		select {
+		case t.evictPending <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// spill переносит в cold точки не новее cutoff и вытесненные из памяти точки.
+// Запись в cold идёт без mu: до releaseSpill точки остаются в памяти,
+// поэтому чтение видит их в hot (совпадения с cold убирает mergePoints).
+// Вызывается только из run и из Close после остановки run.
+func (t *tieredStorage) spill(cutoff time.Time) {
+	t.flushEvicted()
+
+	points, marks := t.hot.collectOlderThan(cutoff)
+	if len(points) == 0 {
+		return
+	}
+	accepted, err := t.saveCold(points)
+
+	t.mu.Lock()
+	t.hot.releaseSpill(marks, accepted)
+	t.advanceSpilled(points[:accepted])
+	t.mu.Unlock()
+
+	if err != nil {
+		// Непринятые точки остаются в памяти до следующего переноса
+		logger.Warn("Tiered storage spill failed", "moved", accepted, "kept", len(points)-accepted, "error", err)
+	}
+}
+
+// flushEvicted записывает в cold вытесненные из памяти точки
+func (t *tieredStorage) flushEvicted() {
+	t.evictMu.Lock()
+	points := t.evicted
+	t.evicted = nil
+	t.evictMu.Unlock()
+
+	if len(points) == 0 {
+		return
+	}
+
+	accepted, err := t.saveCold(points)
+
+	t.mu.Lock()
+	t.advanceSpilled(points[:accepted])
+	t.mu.Unlock()
+
+	if err != nil {
+		// Вернуть точки в память нельзя - они вытеснены по лимиту
+		logger.Warn("Tiered storage failed to save evicted points", "moved", accepted, "dropped", len(points)-accepted, "error", err)
+	}
+}
+
+// advanceSpilled сдвигает spilledUntil на самую новую из принятых cold точек (вызывается под mu)
+func (t *tieredStorage) advanceSpilled(accepted []seriesPoint) {
+	for _, sp := range accepted {
+		if sp.point.Timestamp.After(t.spilledUntil) {
+			t.spilledUntil = sp.point.Timestamp
+		}
+	}
+}
+
+// saveCold записывает точки в cold; возвращает количество принятых точек с начала списка
+func (t *tieredStorage) saveCold(points []seriesPoint) (int, error) {
+	if saver, ok := t.cold.(batchSaver); ok {
+		return saver.saveBatch(points)
+	}
+
+	for i, sp := range points {
+		var err error
+		if sp.point.Gap {
+			err = t.cold.SaveGap(sp.id.serverID, sp.id.objectName, sp.id.variableName, sp.point.Timestamp)
+		} else {
+			err = t.cold.Save(sp.id.serverID, sp.id.objectName, sp.id.variableName, sp.point.Value, sp.point.Timestamp)
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(points), nil
+}
+
+func (t *tieredStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
+	return t.hot.Save(serverID, objectName, variableName, value, timestamp)
+}
+
+func (t *tieredStorage) SaveGap(serverID, objectName, variableName string, timestamp time.Time) error {
+	return t.hot.SaveGap(serverID, objectName, variableName, timestamp)
+}
+
+// needCold проверяет, может ли в cold быть что-то новее from
+func (t *tieredStorage) needCold(from time.Time) bool {
+	return !from.After(t.spilledUntil)
+}
+
+func (t *tieredStorage) GetHistory(serverID, objectName, variableName string, from, to time.Time) (*VariableHistory, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hot, err := t.hot.GetHistory(serverID, objectName, variableName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !t.needCold(from) {
+		return hot, nil
+	}
+
+	cold, err := t.cold.GetHistory(serverID, objectName, variableName, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	hot.Points = mergePoints(cold.Points, hot.Points)
+	return hot, nil
+}
+
+func (t *tieredStorage) GetAggregated(serverID, objectName, variableName string, from, to time.Time, bucket time.Duration) (*AggregatedHistory, error) {
+	history, err := t.GetHistory(serverID, objectName, variableName, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, ok, err := t.lastPoint(serverID, objectName, variableName, from)
+	if err != nil {
+		return nil, err
+	}
+
+	agg := newAggregator(from, to, normalizeBucket(bucket))
+	agg.seed(prev, ok)
+	for _, p := range history.Points {
+		agg.add(p)
+	}
+	return agg.result(serverID, objectName, variableName), nil
+}
+
+// lastPoint возвращает последнюю точку ряда не новее at из обоих уровней
+func (t *tieredStorage) lastPoint(serverID, objectName, variableName string, at time.Time) (DataPoint, bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hot, hotOK, err := t.hot.lastPoint(serverID, objectName, variableName, at)
+	if err != nil || !t.needCold(at) {
+		return hot, hotOK, err
+	}
+
+	cold, coldOK, err := lastPointOf(t.cold, serverID, objectName, variableName, at)
+	if err != nil {
+		return DataPoint{}, false, err
+	}
+	if coldOK && (!hotOK || cold.Timestamp.After(hot.Timestamp)) {
+		return cold, true, nil
+	}
+	return hot, hotOK, nil
+}
+
+func (t *tieredStorage) GetLatest(serverID, objectName, variableName string, count int) (*VariableHistory, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hot, err := t.hot.GetLatest(serverID, objectName, variableName, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(hot.Points) >= count {
+		return hot, nil
+	}
+
+	// Недостающие точки берём из cold - там только более старые
+	cold, err := t.cold.GetLatest(serverID, objectName, variableName, count-len(hot.Points))
+	if err != nil {
+		return nil, err
+	}
+
+	hot.Points = mergePoints(cold.Points, hot.Points)
+	return hot, nil
+}
+
+func (t *tieredStorage) GetHistoryMulti(keys []SeriesKey, from, to time.Time) ([]*VariableHistory, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hot, err := t.hot.GetHistoryMulti(keys, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !t.needCold(from) {
+		return hot, nil
+	}
+
+	cold, err := t.cold.GetHistoryMulti(keys, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hot {
+		hot[i].Points = mergePoints(cold[i].Points, hot[i].Points)
+	}
+	return hot, nil
+}
+
+func (t *tieredStorage) GetAggregatedMulti(keys []SeriesKey, from, to time.Time, bucket time.Duration) ([]*AggregatedHistory, error) {
+	histories, err := t.GetHistoryMulti(keys, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*AggregatedHistory, len(histories))
+	for i, history := range histories {
+		prev, ok, err := t.lastPoint(history.ServerID, history.ObjectName, history.VariableName, from)
+		if err != nil {
+			return nil, err
+		}
+
+		agg := newAggregator(from, to, normalizeBucket(bucket))
+		agg.seed(prev, ok)
+		for _, p := range history.Points {
+			agg.add(p)
+		}
+		result[i] = agg.result(history.ServerID, history.ObjectName, history.VariableName)
+	}
+	return result, nil
+}
+
+// ListSeries объединяет ряды обоих уровней
+func (t *tieredStorage) ListSeries(filter SeriesFilter) ([]SeriesInfo, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hot, err := t.hot.ListSeries(filter)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := t.cold.ListSeries(filter)
+	if err != nil {
+		return nil, err
+	}
+	return mergeSeries(cold, hot), nil
+}
+
+// mergePoints объединяет два упорядоченных по времени списка точек.
+// Точка a с тем же временем, что и точка b, пропускается: во время переноса
+// одна и та же точка может быть и в cold (a), и ещё в памяти (b).
+func mergePoints(a, b []DataPoint) []DataPoint {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+
+	result := make([]DataPoint, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case b[j].Timestamp.Equal(a[i].Timestamp):
+			i++
+		case b[j].Timestamp.Before(a[i].Timestamp):
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+func (t *tieredStorage) Cleanup(olderThan time.Time) error {
+	if err := t.hot.Cleanup(olderThan); err != nil {
+		return err
+	}
+	return t.cold.Cleanup(olderThan)
+}
+
+// CleanupRetention применяет правила хранения к обоим уровням
+func (t *tieredStorage) CleanupRetention(policy *RetentionPolicy, now time.Time) error {
+	if err := t.hot.CleanupRetention(policy, now); err != nil {
+		return err
+	}
+	if cleaner, ok := t.cold.(RetentionCleaner); ok {
+		return cleaner.CleanupRetention(policy, now)
+	}
+	return t.cold.Cleanup(now.Add(-policy.DefaultTTL))
+}
+
+// Stats возвращает статистику обоих уровней: память (hot) и запись в cold
+func (t *tieredStorage) Stats() Stats {
+	stats := Stats{
+		Type:   "tiered",
+		Memory: t.hot.Stats().Memory,
+	}
+	if provider, ok := t.cold.(StatsProvider); ok {
+		stats.Writer = provider.Stats().Writer
+	}
+	return stats
+}
+
+// Close переносит все точки из памяти в cold и закрывает его
+func (t *tieredStorage) Close() error {
+	select {
+	case <-t.stop:
+		return nil
+	default:
+	}
+	close(t.stop)
+	<-t.done
+
+	t.spill(farFuture)
+	return t.cold.Close()
+}
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTieredStorage(t *testing.T, dbPath string) *tieredStorage {
+	t.Helper()
+	cold, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	return NewTieredStorage(cold, time.Hour).(*tieredStorage)
+}
+
+func TestTieredStorageMergesTiers(t *testing.T) {
+	store := newTestTieredStorage(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	now := time.Now()
+	base := now.Add(-3 * time.Hour)
+	for i := 0; i < 6; i++ {
+		store.Save("", "Obj", "var", i, base.Add(time.Duration(i)*30*time.Minute))
+	}
+
+	// Точки не новее часа назад уходят в cold
+	store.spill(now.Add(-time.Hour))
+
+	if n := store.hot.Stats().Memory.Points; n != 1 {
+		t.Fatalf("expected 1 point in hot tier, got %d", n)
+	}
+
+	history, err := store.GetHistory("", "Obj", "var", base, now)
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 6 {
+		t.Fatalf("expected 6 merged points, got %d", len(history.Points))
+	}
+	for i, p := range history.Points {
+		if v, _ := ToFloat64(p.Value); v != float64(i) {
+			t.Errorf("point %d: expected %d, got %v", i, i, p.Value)
+		}
+	}
+
+	latest, err := store.GetLatest("", "Obj", "var", 3)
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if len(latest.Points) != 3 {
+		t.Fatalf("expected 3 latest points, got %d", len(latest.Points))
+	}
+	if v, _ := ToFloat64(latest.Points[0].Value); v != 3 {
+		t.Errorf("expected first latest value 3 (from cold tier), got %v", latest.Points[0].Value)
+	}
+
+	agg, err := store.GetAggregated("", "Obj", "var", base, now, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetAggregated failed: %v", err)
+	}
+	if len(agg.Points) != 1 || agg.Points[0].Count != 6 {
+		t.Errorf("expected one bucket with 6 points, got %+v", agg.Points)
+	}
+}
+
+func TestTieredStorageSkipsColdForRecentRange(t *testing.T) {
+	store := newTestTieredStorage(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	now := time.Now()
+	store.Save("", "Obj", "var", 1, now)
+
+	// Запрос свежего окна не должен обращаться к cold
+	if !store.needCold(now.Add(-time.Hour)) {
+		t.Error("range before spill boundary should query cold tier")
+	}
+	if store.needCold(now.Add(time.Minute)) {
+		t.Error("range after spill boundary should not query cold tier")
+	}
+}
+
+func TestTieredStorageCloseSpillsToCold(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	now := time.Now()
+
+	store := newTestTieredStorage(t, dbPath)
+	store.Save("srv", "Obj", "var", 42, now)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// После перезапуска точка должна читаться из cold
+	store = newTestTieredStorage(t, dbPath)
+	defer store.Close()
+
+	history, err := store.GetHistory("srv", "Obj", "var", now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 1 || history.Points[0].Value != float64(42) {
+		t.Errorf("expected point 42 after restart, got %v", history.Points)
+	}
+}
+
+func TestMergePoints(t *testing.T) {
+	base := time.Now()
+	at := func(i int) DataPoint { return DataPoint{Timestamp: base.Add(time.Duration(i) * time.Second), Value: i} }
+
+	merged := mergePoints([]DataPoint{at(0), at(2), at(4)}, []DataPoint{at(1), at(3)})
+	if len(merged) != 5 {
+		t.Fatalf("expected 5 points, got %d", len(merged))
+	}
+	for i, p := range merged {
+		if p.Value != i {
+			t.Errorf("point %d: expected %d, got %v", i, i, p.Value)
+		}
+	}
+}
+
+func TestMergePointsSkipsDuplicates(t *testing.T) {
+	base := time.Now()
+	at := func(i int) DataPoint { return DataPoint{Timestamp: base.Add(time.Duration(i) * time.Second), Value: i} }
+
+	// Точка, уже записанная в cold, но ещё не удалённая из памяти
+	merged := mergePoints([]DataPoint{at(0), at(1)}, []DataPoint{at(1), at(2)})
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 points, got %d: %v", len(merged), merged)
+	}
+}
+
+func TestTieredStorageListSeries(t *testing.T) {
+	store := newTestTieredStorage(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	now := time.Now()
+	store.Save("", "Obj", "var", 1, now.Add(-2*time.Hour))
+	store.Save("", "Obj", "var", 2, now)
+	store.spill(now.Add(-time.Hour))
+
+	series, err := store.ListSeries(SeriesFilter{})
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	if len(series) != 1 {
+		t.Fatalf("expected 1 merged series, got %d", len(series))
+	}
+	if s := series[0]; s.Count != 2 || !s.First.Equal(now.Add(-2*time.Hour)) || !s.Last.Equal(now) {
+		t.Errorf("unexpected merged series: %+v", s)
+	}
+}
+
+// countCold возвращает количество точек в SQLite после перезапуска
+func countCold(t *testing.T, dbPath string) int64 {
+	t.Helper()
+	cold, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer cold.Close()
+
+	series, err := cold.ListSeries(SeriesFilter{})
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	var count int64
+	for _, s := range series {
+		count += s.Count
+	}
+	return count
+}
+
+func TestTieredStorageCloseSpillsBeyondWriteQueue(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store := newTestTieredStorage(t, dbPath)
+
+	// Больше ёмкости очереди записи SQLite: перенос не должен через неё проходить
+	const total = 5 * DefaultWriteQueueSize
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < total; i++ {
+		store.Save("", "Obj", fmt.Sprintf("var%d", i%10), i, base.Add(time.Duration(i)*time.Millisecond))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if n := countCold(t, dbPath); n != total {
+		t.Errorf("expected %d points in cold, got %d", total, n)
+	}
+}
+
+func TestTieredStorageEvictedPointsGoToCold(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	cold, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	store := NewTieredStorage(cold, time.Hour, WithMaxPointsPerSeries(100), WithMemoryBudget(50*pointSize(DataPoint{Value: 1}))).(*tieredStorage)
+
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		store.Save("", "Obj", fmt.Sprintf("var%d", i%2), i, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if stats := store.hot.Stats().Memory; stats.Points > 50 {
+		t.Fatalf("expected hot tier within budget, got %d points", stats.Points)
+	}
+
+	// Вытесненные точки видны в истории после переноса
+	store.spill(now.Add(-time.Hour))
+	history, err := store.GetHistory("", "Obj", "var0", now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 500 {
+		t.Errorf("expected 500 points of var0, got %d", len(history.Points))
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := countCold(t, dbPath); n != 1000 {
+		t.Errorf("expected 1000 points in cold, got %d", n)
+	}
+}
+
+// failingStorage хранилище, отклоняющее запись при fail = true
+type failingStorage struct {
+	Storage
+	fail bool
+}
+
+func (f *failingStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
+	if f.fail {
+		return errors.New("cold unavailable")
+	}
+	return f.Storage.Save(serverID, objectName, variableName, value, timestamp)
+}
+
+func TestTieredStorageKeepsPointsWhenColdFails(t *testing.T) {
+	cold := &failingStorage{Storage: NewMemoryStorage(), fail: true}
+	store := NewTieredStorage(cold, time.Hour).(*tieredStorage)
+	defer store.Close()
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		store.Save("", "Obj", "var", i, now.Add(-2*time.Hour+time.Duration(i)*time.Minute))
+	}
+
+	boundary := store.spilledUntil
+	store.spill(now.Add(-time.Hour))
+	if n := store.hot.Stats().Memory.Points; n != 3 {
+		t.Fatalf("expected points kept in hot tier, got %d", n)
+	}
+	if !store.spilledUntil.Equal(boundary) {
+		t.Errorf("spill boundary moved after failed spill: %v -> %v", boundary, store.spilledUntil)
+	}
+
+	cold.fail = false
+	store.spill(now.Add(-time.Hour))
+	if n := store.hot.Stats().Memory.Points; n != 0 {
+		t.Fatalf("expected hot tier empty after spill, got %d", n)
+	}
+	history, _ := cold.GetHistory("", "Obj", "var", now.Add(-3*time.Hour), now)
+	if len(history.Points) != 3 {
+		t.Errorf("expected 3 points in cold, got %d", len(history.Points))
+	}
+}
+
+// blockingStorage хранилище, запись в которое ждёт закрытия release
+type blockingStorage struct {
+	Storage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStorage) Save(serverID, objectName, variableName string, value interface{}, timestamp time.Time) error {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return b.Storage.Save(serverID, objectName, variableName, value, timestamp)
+}
+
+func TestTieredStorageReadsDuringColdWrite(t *testing.T) {
+	cold := &blockingStorage{Storage: NewMemoryStorage(), started: make(chan struct{}, 1), release: make(chan struct{})}
+	store := NewTieredStorage(cold, time.Hour).(*tieredStorage)
+	defer store.Close()
+
+	now := time.Now()
+	store.Save("", "Obj", "var", 1, now.Add(-2*time.Hour))
+
+	spilled := make(chan struct{})
+	go func() {
+		store.spill(now.Add(-time.Hour))
+		close(spilled)
+	}()
+	<-cold.started
+
+	// Пока cold пишет, точка читается из памяти без ожидания записи
+	history, err := store.GetHistory("", "Obj", "var", now.Add(-3*time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history.Points) != 1 {
+		t.Errorf("expected 1 point during spill, got %d", len(history.Points))
+	}
+
+	close(cold.release)
+	<-spilled
+
+	history, _ = store.GetHistory("", "Obj", "var", now.Add(-3*time.Hour), now)
+	if len(history.Points) != 1 {
+		t.Errorf("expected 1 point after spill, got %d", len(history.Points))
+	}
+}
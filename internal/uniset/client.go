@@ -1,6 +1,7 @@
 package uniset
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	Supplier   string // supplier name for set/freeze/unfreeze operations
+	resilience *resilience
 }
 
 func NewClient(baseURL string) *Client {
@@ -26,6 +28,7 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		resilience: newResilience(DefaultResilienceConfig()),
 	}
 }
 
@@ -37,13 +40,81 @@ func NewClientWithSupplier(baseURL, supplier string) *Client {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		resilience: newResilience(DefaultResilienceConfig()),
 	}
 }
 
-func (c *Client) doGet(path string) ([]byte, error) {
+// SetHTTPClient задаёт HTTP-клиент (авторизация, TLS, таймаут)
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetResilience задаёт настройки повторов, backoff объектов и circuit breaker
+func (c *Client) SetResilience(cfg ResilienceConfig) {
+	c.resilience.configure(cfg)
+}
+
+// ResilienceStatus возвращает состояние circuit breaker и объекты в backoff
+func (c *Client) ResilienceStatus() (BreakerStatus, []ObjectBackoff) {
+	return c.resilience.status(time.Now())
+}
+
+// doGetContext выполняет запрос чтения; при ошибках связи запрос повторяется
+func (c *Client) doGetContext(ctx context.Context, path string) ([]byte, error) {
+	return c.do(ctx, path, true)
+}
+
+// doControlContext выполняет управляющий запрос (set/freeze/take...) без повторов
+func (c *Client) doControlContext(ctx context.Context, path string) ([]byte, error) {
+	return c.do(ctx, path, false)
+}
+
+func (c *Client) do(ctx context.Context, path string, retry bool) ([]byte, error) {
+	object := objectFromPath(path)
+	if err := c.resilience.allow(object, time.Now()); err != nil {
+		return nil, err
+	}
+
+	cfg := c.resilience.config()
+
+	attempts := 1
+	if retry {
+		attempts += cfg.Retries
+	}
+
+	var body []byte
+	var err error
+	delay := cfg.RetryDelay
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		body, err = c.request(ctx, path)
+		if !isConnectionError(err) {
+			break
+		}
+	}
+
+	c.resilience.done(object, err, ctx.Err(), time.Now())
+	return body, err
+}
+
+func (c *Client) request(ctx context.Context, path string) ([]byte, error) {
 	url := fmt.Sprintf("%s/api/%s/%s", c.baseURL, apiVersion, path)
 
-	resp, err := c.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request %s failed: %w", url, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request %s failed: %w", url, err)
 	}
@@ -55,7 +126,7 @@ func (c *Client) doGet(path string) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: status %d (%s)", url, resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, &statusError{url: url, code: resp.StatusCode, body: strings.TrimSpace(string(body))}
 	}
 
 	return body, nil
@@ -75,7 +146,12 @@ func ensureResult(result, errMsg string) error {
 
 // GetObjectList возвращает список доступных объектов
 func (c *Client) GetObjectList() (ObjectList, error) {
-	data, err := c.doGet("list")
+	return c.GetObjectListContext(context.Background())
+}
+
+// GetObjectListContext то же, что GetObjectList, с учётом отмены и дедлайна ctx
+func (c *Client) GetObjectListContext(ctx context.Context) (ObjectList, error) {
+	data, err := c.doGetContext(ctx, "list")
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +167,12 @@ func (c *Client) GetObjectList() (ObjectList, error) {
 // GetObjectData возвращает данные объекта
 // Гибридный подход: парсим только нужные серверу поля, остальное — raw для UI
 func (c *Client) GetObjectData(objectName string) (*ObjectData, error) {
-	data, err := c.doGet(objectName)
+	return c.GetObjectDataContext(context.Background(), objectName)
+}
+
+// GetObjectDataContext возвращает данные объекта с учётом отмены и дедлайна ctx
+func (c *Client) GetObjectDataContext(ctx context.Context, objectName string) (*ObjectData, error) {
+	data, err := c.doGetContext(ctx, objectName)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +228,12 @@ func (c *Client) GetObjectData(objectName string) (*ObjectData, error) {
 
 // GetObjectHelp возвращает справку по командам объекта
 func (c *Client) GetObjectHelp(objectName string) (*HelpResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/help", objectName))
+	return c.GetObjectHelpContext(context.Background(), objectName)
+}
+
+// GetObjectHelpContext то же, что GetObjectHelp, с учётом отмены и дедлайна ctx
+func (c *Client) GetObjectHelpContext(ctx context.Context, objectName string) (*HelpResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/help", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +265,12 @@ type SMSensorsResponse struct {
 
 // GetSMSensors возвращает список датчиков из SharedMemory
 func (c *Client) GetSMSensors() (*SMSensorsResponse, error) {
-	data, err := c.doGet("SharedMemory/sensors")
+	return c.GetSMSensorsContext(context.Background())
+}
+
+// GetSMSensorsContext то же, что GetSMSensors, с учётом отмены и дедлайна ctx
+func (c *Client) GetSMSensorsContext(ctx context.Context) (*SMSensorsResponse, error) {
+	data, err := c.doGetContext(ctx, "SharedMemory/sensors")
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +352,11 @@ type IONCLostConsumersResponse struct {
 // GetIONCSensors возвращает список датчиков из IONotifyController объекта
 // GET /{objectName}/sensors?offset=N&limit=M&search=text&iotype=AI
 func (c *Client) GetIONCSensors(objectName string, offset, limit int, search, iotype string) (*IONCSensorsResponse, error) {
+	return c.GetIONCSensorsContext(context.Background(), objectName, offset, limit, search, iotype)
+}
+
+// GetIONCSensorsContext то же, что GetIONCSensors, с учётом отмены и дедлайна ctx
+func (c *Client) GetIONCSensorsContext(ctx context.Context, objectName string, offset, limit int, search, iotype string) (*IONCSensorsResponse, error) {
 	values := url.Values{}
 	if offset > 0 {
 		values.Set("offset", strconv.Itoa(offset))
@@ -280,7 +376,7 @@ func (c *Client) GetIONCSensors(objectName string, offset, limit int, search, io
 		path += "?" + encoded
 	}
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -296,9 +392,14 @@ func (c *Client) GetIONCSensors(objectName string, offset, limit int, search, io
 // GetIONCSensorValues получает значения конкретных датчиков
 // GET /{objectName}/get?supplier={supplier}&filter=id1,name2,id3
 func (c *Client) GetIONCSensorValues(objectName string, sensors string) (*IONCSensorsResponse, error) {
+	return c.GetIONCSensorValuesContext(context.Background(), objectName, sensors)
+}
+
+// GetIONCSensorValuesContext то же, что GetIONCSensorValues, с учётом отмены и дедлайна ctx
+func (c *Client) GetIONCSensorValuesContext(ctx context.Context, objectName string, sensors string) (*IONCSensorsResponse, error) {
 	path := fmt.Sprintf("%s/get?supplier=%s&filter=%s", objectName, c.Supplier, url.QueryEscape(sensors))
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -314,36 +415,56 @@ func (c *Client) GetIONCSensorValues(objectName string, sensors string) (*IONCSe
 // SetIONCSensorValue устанавливает значение датчика
 // GET /api/v2/{objectName}/set?supplier={supplier}&id=value
 func (c *Client) SetIONCSensorValue(objectName string, sensorID int64, value int64) error {
+	return c.SetIONCSensorValueContext(context.Background(), objectName, sensorID, value)
+}
+
+// SetIONCSensorValueContext то же, что SetIONCSensorValue, с учётом отмены и дедлайна ctx
+func (c *Client) SetIONCSensorValueContext(ctx context.Context, objectName string, sensorID int64, value int64) error {
 	path := fmt.Sprintf("%s/set?supplier=%s&%d=%d", objectName, c.Supplier, sensorID, value)
 
-	_, err := c.doGet(path)
+	_, err := c.doControlContext(ctx, path)
 	return err
 }
 
 // FreezeIONCSensor замораживает датчик
 // GET /api/v2/{objectName}/freeze?supplier={supplier}&id=value
 func (c *Client) FreezeIONCSensor(objectName string, sensorID int64, value int64) error {
+	return c.FreezeIONCSensorContext(context.Background(), objectName, sensorID, value)
+}
+
+// FreezeIONCSensorContext то же, что FreezeIONCSensor, с учётом отмены и дедлайна ctx
+func (c *Client) FreezeIONCSensorContext(ctx context.Context, objectName string, sensorID int64, value int64) error {
 	path := fmt.Sprintf("%s/freeze?supplier=%s&%d=%d", objectName, c.Supplier, sensorID, value)
 
-	_, err := c.doGet(path)
+	_, err := c.doControlContext(ctx, path)
 	return err
 }
 
 // UnfreezeIONCSensor размораживает датчик
 // GET /api/v2/{objectName}/unfreeze?supplier={supplier}&id
 func (c *Client) UnfreezeIONCSensor(objectName string, sensorID int64) error {
+	return c.UnfreezeIONCSensorContext(context.Background(), objectName, sensorID)
+}
+
+// UnfreezeIONCSensorContext то же, что UnfreezeIONCSensor, с учётом отмены и дедлайна ctx
+func (c *Client) UnfreezeIONCSensorContext(ctx context.Context, objectName string, sensorID int64) error {
 	path := fmt.Sprintf("%s/unfreeze?supplier=%s&%d", objectName, c.Supplier, sensorID)
 
-	_, err := c.doGet(path)
+	_, err := c.doControlContext(ctx, path)
 	return err
 }
 
 // GetIONCConsumers возвращает список подписчиков на датчики
 // GET /{objectName}/consumers?id1,id2
 func (c *Client) GetIONCConsumers(objectName string, sensors string) (*IONCConsumersResponse, error) {
+	return c.GetIONCConsumersContext(context.Background(), objectName, sensors)
+}
+
+// GetIONCConsumersContext то же, что GetIONCConsumers, с учётом отмены и дедлайна ctx
+func (c *Client) GetIONCConsumersContext(ctx context.Context, objectName string, sensors string) (*IONCConsumersResponse, error) {
 	path := fmt.Sprintf("%s/consumers?%s", objectName, sensors)
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -359,9 +480,14 @@ func (c *Client) GetIONCConsumers(objectName string, sensors string) (*IONCConsu
 // GetIONCLostConsumers возвращает список потерянных подписчиков
 // GET /{objectName}/lost
 func (c *Client) GetIONCLostConsumers(objectName string) (*IONCLostConsumersResponse, error) {
+	return c.GetIONCLostConsumersContext(context.Background(), objectName)
+}
+
+// GetIONCLostConsumersContext то же, что GetIONCLostConsumers, с учётом отмены и дедлайна ctx
+func (c *Client) GetIONCLostConsumersContext(ctx context.Context, objectName string) (*IONCLostConsumersResponse, error) {
 	path := fmt.Sprintf("%s/lost", objectName)
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
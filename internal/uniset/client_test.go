@@ -1,10 +1,13 @@
 package uniset
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func expectAPIVersionPath(t *testing.T, got, suffix string) {
@@ -188,3 +191,24 @@ func TestClientConnectionError(t *testing.T) {
 		t.Error("expected error for connection failure")
 	}
 }
+
+func TestClientContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetIONCSensorValuesContext(ctx, "SharedMemory", "1,2")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request was not aborted by context, took %v", elapsed)
+	}
+}
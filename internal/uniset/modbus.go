@@ -1,6 +1,7 @@
 package uniset
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -79,7 +80,12 @@ type MBModeResponse struct {
 
 // GetMBStatus возвращает статус ModbusMaster
 func (c *Client) GetMBStatus(objectName string) (*MBStatusResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/status", objectName))
+	return c.GetMBStatusContext(context.Background(), objectName)
+}
+
+// GetMBStatusContext то же, что GetMBStatus, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBStatusContext(ctx context.Context, objectName string) (*MBStatusResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/status", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +102,11 @@ func (c *Client) GetMBStatus(objectName string) (*MBStatusResponse, error) {
 
 // GetMBParams читает выбранные параметры
 func (c *Client) GetMBParams(objectName string, params []string) (*MBParamsResponse, error) {
+	return c.GetMBParamsContext(context.Background(), objectName, params)
+}
+
+// GetMBParamsContext то же, что GetMBParams, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBParamsContext(ctx context.Context, objectName string, params []string) (*MBParamsResponse, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("at least one param is required")
 	}
@@ -106,7 +117,7 @@ func (c *Client) GetMBParams(objectName string, params []string) (*MBParamsRespo
 	}
 
 	path := fmt.Sprintf("%s/getparam?%s", objectName, values.Encode())
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +134,11 @@ func (c *Client) GetMBParams(objectName string, params []string) (*MBParamsRespo
 
 // SetMBParams устанавливает параметры
 func (c *Client) SetMBParams(objectName string, params map[string]interface{}) (*MBParamsResponse, error) {
+	return c.SetMBParamsContext(context.Background(), objectName, params)
+}
+
+// SetMBParamsContext то же, что SetMBParams, с учётом отмены и дедлайна ctx
+func (c *Client) SetMBParamsContext(ctx context.Context, objectName string, params map[string]interface{}) (*MBParamsResponse, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("at least one param is required")
 	}
@@ -133,7 +149,7 @@ func (c *Client) SetMBParams(objectName string, params map[string]interface{}) (
 	}
 
 	path := fmt.Sprintf("%s/setparam?%s", objectName, values.Encode())
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +167,11 @@ func (c *Client) SetMBParams(objectName string, params map[string]interface{}) (
 // GetMBRegisters возвращает список регистров (датчиков)
 // search - текстовый поиск по имени
 func (c *Client) GetMBRegisters(objectName, search, iotype string, limit, offset int) (*MBRegistersResponse, error) {
+	return c.GetMBRegistersContext(context.Background(), objectName, search, iotype, limit, offset)
+}
+
+// GetMBRegistersContext то же, что GetMBRegisters, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBRegistersContext(ctx context.Context, objectName, search, iotype string, limit, offset int) (*MBRegistersResponse, error) {
 	values := url.Values{}
 	if limit > 0 {
 		values.Set("limit", strconv.Itoa(limit))
@@ -170,7 +191,7 @@ func (c *Client) GetMBRegisters(objectName, search, iotype string, limit, offset
 		path += "?" + encoded
 	}
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -187,8 +208,13 @@ func (c *Client) GetMBRegisters(objectName, search, iotype string, limit, offset
 
 // GetMBDevices возвращает список устройств (slaves)
 func (c *Client) GetMBDevices(objectName string) (*MBDevicesResponse, error) {
+	return c.GetMBDevicesContext(context.Background(), objectName)
+}
+
+// GetMBDevicesContext то же, что GetMBDevices, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBDevicesContext(ctx context.Context, objectName string) (*MBDevicesResponse, error) {
 	path := fmt.Sprintf("%s/devices", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -205,8 +231,13 @@ func (c *Client) GetMBDevices(objectName string) (*MBDevicesResponse, error) {
 
 // GetMBMode возвращает текущий режим работы
 func (c *Client) GetMBMode(objectName string) (*MBModeResponse, error) {
+	return c.GetMBModeContext(context.Background(), objectName)
+}
+
+// GetMBModeContext то же, что GetMBMode, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBModeContext(ctx context.Context, objectName string) (*MBModeResponse, error) {
 	path := fmt.Sprintf("%s/mode?get", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -223,8 +254,13 @@ func (c *Client) GetMBMode(objectName string) (*MBModeResponse, error) {
 
 // GetMBModeSupported возвращает список поддерживаемых режимов
 func (c *Client) GetMBModeSupported(objectName string) (*MBModeResponse, error) {
+	return c.GetMBModeSupportedContext(context.Background(), objectName)
+}
+
+// GetMBModeSupportedContext то же, что GetMBModeSupported, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBModeSupportedContext(ctx context.Context, objectName string) (*MBModeResponse, error) {
 	path := fmt.Sprintf("%s/mode?supported=1", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -241,8 +277,13 @@ func (c *Client) GetMBModeSupported(objectName string) (*MBModeResponse, error)
 
 // SetMBMode устанавливает режим работы
 func (c *Client) SetMBMode(objectName, mode string) (*MBModeResponse, error) {
+	return c.SetMBModeContext(context.Background(), objectName, mode)
+}
+
+// SetMBModeContext то же, что SetMBMode, с учётом отмены и дедлайна ctx
+func (c *Client) SetMBModeContext(ctx context.Context, objectName, mode string) (*MBModeResponse, error) {
 	path := fmt.Sprintf("%s/mode?set=%s", objectName, url.QueryEscape(mode))
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -266,8 +307,13 @@ type MBControlResponse struct {
 
 // TakeMBControl перехватывает управление через HTTP
 func (c *Client) TakeMBControl(objectName string) (*MBControlResponse, error) {
+	return c.TakeMBControlContext(context.Background(), objectName)
+}
+
+// TakeMBControlContext то же, что TakeMBControl, с учётом отмены и дедлайна ctx
+func (c *Client) TakeMBControlContext(ctx context.Context, objectName string) (*MBControlResponse, error) {
 	path := fmt.Sprintf("%s/takeControl", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -282,8 +328,13 @@ func (c *Client) TakeMBControl(objectName string) (*MBControlResponse, error) {
 
 // ReleaseMBControl возвращает управление
 func (c *Client) ReleaseMBControl(objectName string) (*MBControlResponse, error) {
+	return c.ReleaseMBControlContext(context.Background(), objectName)
+}
+
+// ReleaseMBControlContext то же, что ReleaseMBControl, с учётом отмены и дедлайна ctx
+func (c *Client) ReleaseMBControlContext(ctx context.Context, objectName string) (*MBControlResponse, error) {
 	path := fmt.Sprintf("%s/releaseControl", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -301,9 +352,14 @@ func (c *Client) ReleaseMBControl(objectName string) (*MBControlResponse, error)
 // GetMBRegisterValues получает значения конкретных регистров по ID
 // GET /{objectName}/get?filter=id1,id2,id3
 func (c *Client) GetMBRegisterValues(objectName string, registerIDs string) (*MBRegistersResponse, error) {
+	return c.GetMBRegisterValuesContext(context.Background(), objectName, registerIDs)
+}
+
+// GetMBRegisterValuesContext то же, что GetMBRegisterValues, с учётом отмены и дедлайна ctx
+func (c *Client) GetMBRegisterValuesContext(ctx context.Context, objectName string, registerIDs string) (*MBRegistersResponse, error) {
 	path := fmt.Sprintf("%s/get?filter=%s", objectName, registerIDs)
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
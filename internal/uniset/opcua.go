@@ -1,6 +1,7 @@
 package uniset
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -62,7 +63,12 @@ type OPCUAControlResponse struct {
 
 // GetOPCUAStatus возвращает статус OPCUAExchange
 func (c *Client) GetOPCUAStatus(objectName string) (*OPCUAStatusResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/status", objectName))
+	return c.GetOPCUAStatusContext(context.Background(), objectName)
+}
+
+// GetOPCUAStatusContext то же, что GetOPCUAStatus, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUAStatusContext(ctx context.Context, objectName string) (*OPCUAStatusResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/status", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +85,11 @@ func (c *Client) GetOPCUAStatus(objectName string) (*OPCUAStatusResponse, error)
 
 // GetOPCUAParams читает выбранные параметры
 func (c *Client) GetOPCUAParams(objectName string, params []string) (*OPCUAParamsResponse, error) {
+	return c.GetOPCUAParamsContext(context.Background(), objectName, params)
+}
+
+// GetOPCUAParamsContext то же, что GetOPCUAParams, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUAParamsContext(ctx context.Context, objectName string, params []string) (*OPCUAParamsResponse, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("at least one param is required")
 	}
@@ -89,7 +100,7 @@ func (c *Client) GetOPCUAParams(objectName string, params []string) (*OPCUAParam
 	}
 
 	path := fmt.Sprintf("%s/getparam?%s", objectName, values.Encode())
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +117,11 @@ func (c *Client) GetOPCUAParams(objectName string, params []string) (*OPCUAParam
 
 // SetOPCUAParams устанавливает параметры
 func (c *Client) SetOPCUAParams(objectName string, params map[string]interface{}) (*OPCUAParamsResponse, error) {
+	return c.SetOPCUAParamsContext(context.Background(), objectName, params)
+}
+
+// SetOPCUAParamsContext то же, что SetOPCUAParams, с учётом отмены и дедлайна ctx
+func (c *Client) SetOPCUAParamsContext(ctx context.Context, objectName string, params map[string]interface{}) (*OPCUAParamsResponse, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("at least one param is required")
 	}
@@ -116,7 +132,7 @@ func (c *Client) SetOPCUAParams(objectName string, params map[string]interface{}
 	}
 
 	path := fmt.Sprintf("%s/setparam?%s", objectName, values.Encode())
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +151,11 @@ func (c *Client) SetOPCUAParams(objectName string, params map[string]interface{}
 // search - текстовый поиск по имени
 // iotype - фильтр по типу (AI, AO, DI, DO)
 func (c *Client) GetOPCUASensors(objectName, search, iotype string, limit, offset int) (*OPCUASensorsResponse, error) {
+	return c.GetOPCUASensorsContext(context.Background(), objectName, search, iotype, limit, offset)
+}
+
+// GetOPCUASensorsContext то же, что GetOPCUASensors, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUASensorsContext(ctx context.Context, objectName, search, iotype string, limit, offset int) (*OPCUASensorsResponse, error) {
 	values := url.Values{}
 	if limit > 0 {
 		values.Set("limit", strconv.Itoa(limit))
@@ -154,7 +175,7 @@ func (c *Client) GetOPCUASensors(objectName, search, iotype string, limit, offse
 		path += "?" + encoded
 	}
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -171,8 +192,13 @@ func (c *Client) GetOPCUASensors(objectName, search, iotype string, limit, offse
 
 // GetOPCUASensor возвращает детали конкретного сенсора
 func (c *Client) GetOPCUASensor(objectName string, sensorID int64) (*OPCUASensorResponse, error) {
+	return c.GetOPCUASensorContext(context.Background(), objectName, sensorID)
+}
+
+// GetOPCUASensorContext то же, что GetOPCUASensor, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUASensorContext(ctx context.Context, objectName string, sensorID int64) (*OPCUASensorResponse, error) {
 	path := fmt.Sprintf("%s/sensor?id=%d", objectName, sensorID)
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -189,8 +215,13 @@ func (c *Client) GetOPCUASensor(objectName string, sensorID int64) (*OPCUASensor
 
 // GetOPCUADiagnostics возвращает диагностику
 func (c *Client) GetOPCUADiagnostics(objectName string) (*OPCUADiagnosticsResponse, error) {
+	return c.GetOPCUADiagnosticsContext(context.Background(), objectName)
+}
+
+// GetOPCUADiagnosticsContext то же, что GetOPCUADiagnostics, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUADiagnosticsContext(ctx context.Context, objectName string) (*OPCUADiagnosticsResponse, error) {
 	path := fmt.Sprintf("%s/diagnostics", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +238,13 @@ func (c *Client) GetOPCUADiagnostics(objectName string) (*OPCUADiagnosticsRespon
 
 // TakeOPCUAControl включает HTTP-контроль
 func (c *Client) TakeOPCUAControl(objectName string) (*OPCUAControlResponse, error) {
+	return c.TakeOPCUAControlContext(context.Background(), objectName)
+}
+
+// TakeOPCUAControlContext то же, что TakeOPCUAControl, с учётом отмены и дедлайна ctx
+func (c *Client) TakeOPCUAControlContext(ctx context.Context, objectName string) (*OPCUAControlResponse, error) {
 	path := fmt.Sprintf("%s/takeControl", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -225,8 +261,13 @@ func (c *Client) TakeOPCUAControl(objectName string) (*OPCUAControlResponse, err
 
 // ReleaseOPCUAControl отключает HTTP-контроль
 func (c *Client) ReleaseOPCUAControl(objectName string) (*OPCUAControlResponse, error) {
+	return c.ReleaseOPCUAControlContext(context.Background(), objectName)
+}
+
+// ReleaseOPCUAControlContext то же, что ReleaseOPCUAControl, с учётом отмены и дедлайна ctx
+func (c *Client) ReleaseOPCUAControlContext(ctx context.Context, objectName string) (*OPCUAControlResponse, error) {
 	path := fmt.Sprintf("%s/releaseControl", objectName)
-	data, err := c.doGet(path)
+	data, err := c.doControlContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -245,9 +286,14 @@ func (c *Client) ReleaseOPCUAControl(objectName string) (*OPCUAControlResponse,
 // GET /{objectName}/get?filter=id1,id2,id3
 // Используется для OPCUAExchange
 func (c *Client) GetOPCUASensorValues(objectName string, sensorIDs string) (*OPCUASensorsResponse, error) {
+	return c.GetOPCUASensorValuesContext(context.Background(), objectName, sensorIDs)
+}
+
+// GetOPCUASensorValuesContext то же, что GetOPCUASensorValues, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUASensorValuesContext(ctx context.Context, objectName string, sensorIDs string) (*OPCUASensorsResponse, error) {
 	path := fmt.Sprintf("%s/get?filter=%s", objectName, sensorIDs)
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -266,9 +312,14 @@ func (c *Client) GetOPCUASensorValues(objectName string, sensorIDs string) (*OPC
 // GET /{objectName}/get?id=id1,id2,id3
 // Используется для OPCUAServer (отличается от OPCUAExchange параметром: id= вместо filter=)
 func (c *Client) GetOPCUAServerSensorValues(objectName string, sensorIDs string) (*OPCUASensorsResponse, error) {
+	return c.GetOPCUAServerSensorValuesContext(context.Background(), objectName, sensorIDs)
+}
+
+// GetOPCUAServerSensorValuesContext то же, что GetOPCUAServerSensorValues, с учётом отмены и дедлайна ctx
+func (c *Client) GetOPCUAServerSensorValuesContext(ctx context.Context, objectName string, sensorIDs string) (*OPCUASensorsResponse, error) {
 	path := fmt.Sprintf("%s/get?id=%s", objectName, sensorIDs)
 
-	data, err := c.doGet(path)
+	data, err := c.doGetContext(ctx, path)
 	if err != nil {
 		return nil, err
 	}
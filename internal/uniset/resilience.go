@@ -0,0 +1,306 @@
+package uniset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen запрос не выполнялся: сервер недоступен, выдерживается пауза
+	ErrCircuitOpen = errors.New("circuit open")
+	// ErrObjectBackoff запрос не выполнялся: объект повторно не отвечает, выдерживается пауза
+	ErrObjectBackoff = errors.New("object backing off")
+)
+
+// IsBackoff проверяет, что запрос был пропущен из-за паузы (circuit breaker или backoff объекта)
+func IsBackoff(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrObjectBackoff)
+}
+
+// Состояния circuit breaker
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ResilienceConfig настройки повторов, backoff объектов и circuit breaker
+type ResilienceConfig struct {
+	Retries    int           // дополнительные попытки для запросов чтения (0 = без повторов)
+	RetryDelay time.Duration // пауза перед первым повтором, далее удваивается
+
+	BackoffThreshold int           // ошибок подряд, после которых объект опрашивается реже (0 = выключено)
+	BackoffInitial   time.Duration // первая пауза для объекта, далее удваивается
+	BackoffMax       time.Duration
+
+	BreakerThreshold   int           // ошибок связи подряд, после которых запросы к серверу прекращаются (0 = выключено)
+	BreakerCooldown    time.Duration // первая пауза перед пробным запросом, далее удваивается
+	BreakerMaxCooldown time.Duration
+}
+
+// DefaultResilienceConfig настройки по умолчанию
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Retries:            2,
+		RetryDelay:         100 * time.Millisecond,
+		BackoffThreshold:   3,
+		BackoffInitial:     time.Second,
+		BackoffMax:         30 * time.Second,
+		BreakerThreshold:   5,
+		BreakerCooldown:    time.Second,
+		BreakerMaxCooldown: 30 * time.Second,
+	}
+}
+
+// BreakerStatus состояние circuit breaker сервера
+type BreakerStatus struct {
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`            // ошибок связи подряд
+	NextRetry time.Time `json:"nextRetry,omitempty"` // время пробного запроса (для open)
+	RetryInMs int64     `json:"retryInMs,omitempty"`
+}
+
+// ObjectBackoff объект, опрос которого приостановлен после повторных ошибок
+type ObjectBackoff struct {
+	Object    string    `json:"object"`
+	Failures  int       `json:"failures"`
+	NextRetry time.Time `json:"nextRetry"`
+	RetryInMs int64     `json:"retryInMs"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// statusError ответ сервера с кодом, отличным от 200
+type statusError struct {
+	url  string
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: status %d (%s)", e.url, e.code, e.body)
+}
+
+// objectState ошибки объекта подряд
+type objectState struct {
+	failures  int
+	nextRetry time.Time
+	lastError string
+}
+
+// resilience ограничивает запросы к неотвечающему серверу и объектам
+type resilience struct {
+	mu  sync.Mutex
+	cfg ResilienceConfig
+
+	// circuit breaker
+	state     string
+	failures  int
+	cooldown  time.Duration
+	nextRetry time.Time
+	probing   bool // в half-open уже выполняется пробный запрос
+
+	objects map[string]*objectState
+}
+
+func newResilience(cfg ResilienceConfig) *resilience {
+	return &resilience{
+		cfg:     cfg,
+		state:   BreakerClosed,
+		objects: make(map[string]*objectState),
+	}
+}
+
+func (r *resilience) configure(cfg ResilienceConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfg = cfg
+}
+
+func (r *resilience) config() ResilienceConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cfg
+}
+
+// allow проверяет, можно ли выполнять запрос к объекту (object = "" - запрос уровня сервера).
+// Пауза объекта соблюдается в любом состоянии breaker: пробным запросом в half-open
+// становится только запрос к объекту, который не выдерживает паузу.
+func (r *resilience) allow(object string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.state == BreakerOpen && now.Before(r.nextRetry):
+		return fmt.Errorf("%w, next try in %s", ErrCircuitOpen, retryIn(r.nextRetry, now))
+	case r.state == BreakerHalfOpen && r.probing:
+		return fmt.Errorf("%w, probe in progress", ErrCircuitOpen)
+	}
+
+	if st, ok := r.objects[object]; ok && now.Before(st.nextRetry) {
+		return fmt.Errorf("%w: %s, next try in %s", ErrObjectBackoff, object, retryIn(st.nextRetry, now))
+	}
+
+	switch r.state {
+	case BreakerOpen:
+		r.state = BreakerHalfOpen
+		r.probing = true
+	case BreakerHalfOpen:
+		r.probing = true
+	}
+	return nil
+}
+
+// done учитывает результат запроса. Отменённые вызывающей стороной запросы не учитываются;
+// истёкший дедлайн считается ошибкой объекта, но не ошибкой связи с сервером.
+// Ответы 4xx не считаются ошибками ни объекта, ни связи.
+func (r *resilience) done(object string, err error, ctxErr error, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if errors.Is(ctxErr, context.Canceled) {
+		r.probing = false
+		return
+	}
+
+	// circuit breaker: учитываем только ошибки связи
+	if ctxErr == nil {
+		if isConnectionError(err) {
+			r.failures++
+			if r.state == BreakerHalfOpen || (r.cfg.BreakerThreshold > 0 && r.failures >= r.cfg.BreakerThreshold) {
+				r.open(now)
+			}
+		} else {
+			r.state = BreakerClosed
+			r.failures = 0
+			r.cooldown = 0
+		}
+	}
+	r.probing = false
+
+	if object == "" {
+		return
+	}
+	// Ответ 4xx - объект отвечает, паузу не начинаем
+	if !isObjectFailure(err, ctxErr) {
+		delete(r.objects, object)
+		return
+	}
+	st, ok := r.objects[object]
+	if !ok {
+		st = &objectState{}
+		r.objects[object] = st
+	}
+	st.failures++
+	st.lastError = err.Error()
+	if r.cfg.BackoffThreshold > 0 && st.failures >= r.cfg.BackoffThreshold {
+		st.nextRetry = now.Add(backoffDelay(r.cfg.BackoffInitial, r.cfg.BackoffMax, st.failures-r.cfg.BackoffThreshold))
+	}
+}
+
+// open переводит breaker в open, удваивая паузу при повторном открытии
+func (r *resilience) open(now time.Time) {
+	if r.cooldown == 0 {
+		r.cooldown = r.cfg.BreakerCooldown
+	} else {
+		r.cooldown *= 2
+	}
+	if r.cfg.BreakerMaxCooldown > 0 && r.cooldown > r.cfg.BreakerMaxCooldown {
+		r.cooldown = r.cfg.BreakerMaxCooldown
+	}
+	r.state = BreakerOpen
+	r.nextRetry = now.Add(r.cooldown)
+}
+
+// status возвращает состояние breaker и объекты в backoff
+func (r *resilience) status(now time.Time) (BreakerStatus, []ObjectBackoff) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breaker := BreakerStatus{State: r.state, Failures: r.failures}
+	if r.state == BreakerOpen {
+		breaker.NextRetry = r.nextRetry
+		breaker.RetryInMs = r.nextRetry.Sub(now).Milliseconds()
+	}
+
+	var objects []ObjectBackoff
+	for name, st := range r.objects {
+		if !now.Before(st.nextRetry) {
+			continue
+		}
+		objects = append(objects, ObjectBackoff{
+			Object:    name,
+			Failures:  st.failures,
+			NextRetry: st.nextRetry,
+			RetryInMs: st.nextRetry.Sub(now).Milliseconds(),
+			LastError: st.lastError,
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Object < objects[j].Object })
+	return breaker, objects
+}
+
+// backoffDelay пауза initial*2^n, не больше max
+func backoffDelay(initial, max time.Duration, n int) time.Duration {
+	delay := initial
+	for i := 0; i < n && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+func retryIn(at, now time.Time) time.Duration {
+	d := at.Sub(now)
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+// isConnectionError ошибка связи с сервером: сетевая ошибка или ответ шлюза 502/503/504
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusBadGateway ||
+			se.code == http.StatusServiceUnavailable ||
+			se.code == http.StatusGatewayTimeout
+	}
+	return true
+}
+
+// isObjectFailure проверяет, что объект не ответил: ошибка связи, ответ 5xx или истёкший дедлайн
+func isObjectFailure(err, ctxErr error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		return true
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= http.StatusInternalServerError
+	}
+	return isConnectionError(err)
+}
+
+// objectFromPath извлекает имя объекта из пути запроса ("list" - запрос уровня сервера)
+func objectFromPath(path string) string {
+	if i := strings.IndexAny(path, "/?"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "list" {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,251 @@
+package uniset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Retries:            2,
+		RetryDelay:         time.Millisecond,
+		BackoffThreshold:   2,
+		BackoffInitial:     time.Hour,
+		BackoffMax:         time.Hour,
+		BreakerThreshold:   3,
+		BreakerCooldown:    50 * time.Millisecond,
+		BreakerMaxCooldown: time.Second,
+	}
+}
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode([]string{"Object1"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResilience(testResilienceConfig())
+
+	list, err := client.GetObjectList()
+	if err != nil {
+		t.Fatalf("GetObjectList failed: %v", err)
+	}
+	if len(list) != 1 || requests != 3 {
+		t.Errorf("expected success on third attempt, got %d requests", requests)
+	}
+}
+
+func TestClientDoesNotRetryControlRequests(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResilience(testResilienceConfig())
+
+	if err := client.SetIONCSensorValue("SharedMemory", 1, 10); err == nil {
+		t.Fatal("expected error")
+	}
+	if requests != 1 {
+		t.Errorf("control request must not be retried, got %d requests", requests)
+	}
+}
+
+func TestClientObjectBackoff(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/api/v2/Broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": map[string]interface{}{"id": 1}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResilience(testResilienceConfig())
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetObjectData("Broken"); err == nil || IsBackoff(err) {
+			t.Fatalf("attempt %d: expected request error, got %v", i, err)
+		}
+	}
+
+	before := atomic.LoadInt32(&requests)
+	_, err := client.GetObjectData("Broken")
+	if !errors.Is(err, ErrObjectBackoff) {
+		t.Fatalf("expected object backoff, got %v", err)
+	}
+	if atomic.LoadInt32(&requests) != before {
+		t.Error("request must not be sent while object is backing off")
+	}
+
+	// Остальные объекты сервера опрашиваются как обычно
+	if _, err := client.GetObjectData("Working"); err != nil {
+		t.Errorf("other objects should not be affected: %v", err)
+	}
+
+	_, backoff := client.ResilienceStatus()
+	if len(backoff) != 1 || backoff[0].Object != "Broken" || backoff[0].RetryInMs <= 0 {
+		t.Errorf("unexpected backoff status: %+v", backoff)
+	}
+}
+
+func TestClientObjectBackoffIgnoresClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResilience(testResilienceConfig())
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.GetObjectData("Missing"); err == nil || IsBackoff(err) {
+			t.Fatalf("attempt %d: expected 404 error, got %v", i, err)
+		}
+	}
+	if requests != 5 {
+		t.Errorf("expected every request to be sent, got %d", requests)
+	}
+
+	if _, backoff := client.ResilienceStatus(); len(backoff) != 0 {
+		t.Errorf("4xx responses must not start object backoff: %+v", backoff)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode([]string{"Object1"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	cfg := testResilienceConfig()
+	cfg.Retries = 0
+	client.SetResilience(cfg)
+
+	for i := 0; i < 3; i++ {
+		client.GetObjectList()
+	}
+
+	breaker, _ := client.ResilienceStatus()
+	if breaker.State != BreakerOpen || breaker.RetryInMs <= 0 {
+		t.Fatalf("expected open breaker, got %+v", breaker)
+	}
+
+	_, err := client.GetObjectList()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected circuit open error, got %v", err)
+	}
+
+	// После паузы пробный запрос закрывает breaker
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.GetObjectList(); err != nil {
+		t.Fatalf("probe request failed: %v", err)
+	}
+	breaker, _ = client.ResilienceStatus()
+	if breaker.State != BreakerClosed || breaker.Failures != 0 {
+		t.Errorf("expected closed breaker, got %+v", breaker)
+	}
+}
+
+func TestClientCancelledRequestDoesNotTripBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	cfg := testResilienceConfig()
+	cfg.BreakerThreshold = 1
+	client.SetResilience(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	client.GetObjectDataContext(ctx, "Object1")
+
+	breaker, backoff := client.ResilienceStatus()
+	if breaker.State != BreakerClosed || len(backoff) != 0 {
+		t.Errorf("cancelled request must not be counted: %+v %+v", breaker, backoff)
+	}
+}
+
+func TestResilienceObjectBackoffInHalfOpen(t *testing.T) {
+	r := newResilience(testResilienceConfig())
+	now := time.Now()
+	connErr := errors.New("connection refused")
+
+	// Объект уходит в паузу, затем открывается breaker
+	for i := 0; i < 3; i++ {
+		r.done("Object1", connErr, nil, now)
+	}
+	if r.state != BreakerOpen {
+		t.Fatalf("expected open breaker, got %s", r.state)
+	}
+
+	// Пауза breaker истекла, но объект в паузе: пробный запрос к нему не выполняется
+	later := now.Add(time.Minute)
+	if err := r.allow("Object1", later); !errors.Is(err, ErrObjectBackoff) {
+		t.Fatalf("expected object backoff in open->half-open transition, got %v", err)
+	}
+	if r.state != BreakerOpen || r.probing {
+		t.Fatalf("object in backoff must not start a probe: state=%s probing=%v", r.state, r.probing)
+	}
+
+	// Пробным становится запрос к другому объекту
+	if err := r.allow("Object2", later); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	if r.state != BreakerHalfOpen {
+		t.Fatalf("expected half-open breaker, got %s", r.state)
+	}
+	r.probing = false
+	if err := r.allow("Object1", later); !errors.Is(err, ErrObjectBackoff) {
+		t.Errorf("expected object backoff in half-open, got %v", err)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{10, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(time.Second, 30*time.Second, tt.n); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package uniset
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -28,7 +29,12 @@ type UNetSendersResponse struct {
 
 // GetUNetStatus возвращает статус UNetExchange
 func (c *Client) GetUNetStatus(objectName string) (*UNetStatusResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/status", objectName))
+	return c.GetUNetStatusContext(context.Background(), objectName)
+}
+
+// GetUNetStatusContext то же, что GetUNetStatus, с учётом отмены и дедлайна ctx
+func (c *Client) GetUNetStatusContext(ctx context.Context, objectName string) (*UNetStatusResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/status", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +51,12 @@ func (c *Client) GetUNetStatus(objectName string) (*UNetStatusResponse, error) {
 
 // GetUNetReceivers возвращает список receivers UNetExchange
 func (c *Client) GetUNetReceivers(objectName string) (*UNetReceiversResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/receivers", objectName))
+	return c.GetUNetReceiversContext(context.Background(), objectName)
+}
+
+// GetUNetReceiversContext то же, что GetUNetReceivers, с учётом отмены и дедлайна ctx
+func (c *Client) GetUNetReceiversContext(ctx context.Context, objectName string) (*UNetReceiversResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/receivers", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +73,12 @@ func (c *Client) GetUNetReceivers(objectName string) (*UNetReceiversResponse, er
 
 // GetUNetSenders возвращает список senders UNetExchange
 func (c *Client) GetUNetSenders(objectName string) (*UNetSendersResponse, error) {
-	data, err := c.doGet(fmt.Sprintf("%s/senders", objectName))
+	return c.GetUNetSendersContext(context.Background(), objectName)
+}
+
+// GetUNetSendersContext то же, что GetUNetSenders, с учётом отмены и дедлайна ctx
+func (c *Client) GetUNetSendersContext(ctx context.Context, objectName string) (*UNetSendersResponse, error) {
+	data, err := c.doGetContext(ctx, fmt.Sprintf("%s/senders", objectName))
 	if err != nil {
 		return nil, err
 	}
@@ -2,9 +2,11 @@ package uwsgate
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
@@ -43,6 +45,11 @@ type Client struct {
 	pendingSubscriptions []string
 	subMu sync.Mutex
 
+	// Параметры подключения (TLS, заголовки авторизации, таймаут handshake)
+	tlsConfig        *tls.Config
+	header           http.Header
+	handshakeTimeout time.Duration
+
 	logger *slog.Logger
 }
 
@@ -68,10 +75,22 @@ func NewClient(baseURL string, logger *slog.Logger) *Client {
 		maxReconnectInterval: 30 * time.Second,
 		currentReconnectInterval: time.Second,
 		pendingSubscriptions: make([]string, 0),
+		handshakeTimeout:     10 * time.Second,
 		logger:               logger.With("component", "uwsgate-client"),
 	}
 }
 
+// SetConnection задаёт TLS, заголовки (в т.ч. Authorization) и таймаут handshake
+func (c *Client) SetConnection(tlsConfig *tls.Config, header http.Header, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tlsConfig = tlsConfig
+	c.header = header
+	if timeout > 0 {
+		c.handshakeTimeout = timeout
+	}
+}
+
 // SetOnData устанавливает callback для входящих данных
 func (c *Client) SetOnData(callback DataCallback) {
 	c.mu.Lock()
@@ -116,10 +135,11 @@ func (c *Client) connect() error {
 	c.logger.Info("connecting to UWebSocketGate", "url", c.wsURL)
 
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+		HandshakeTimeout: c.handshakeTimeout,
+		TLSClientConfig:  c.tlsConfig,
 	}
 
-	conn, _, err := dialer.DialContext(c.ctx, u.String(), nil)
+	conn, _, err := dialer.DialContext(c.ctx, u.String(), c.header)
 	if err != nil {
 		return fmt.Errorf("websocket dial failed: %w", err)
 	}
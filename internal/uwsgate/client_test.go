@@ -1,9 +1,17 @@
 package uwsgate
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestNewClient(t *testing.T) {
@@ -244,3 +252,42 @@ func TestClientReconnectIntervals(t *testing.T) {
 		t.Errorf("currentReconnectInterval = %v, want 1s", client.currentReconnectInterval)
 	}
 }
+
+func TestClientConnectWithTLSAndHeaders(t *testing.T) {
+	var gotAuth string
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	client := NewClient(server.URL, nil)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	client.SetConnection(&tls.Config{RootCAs: pool}, header, 5*time.Second)
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+
+	if !client.IsConnected() {
+		t.Error("client should be connected")
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer secret")
+	}
+}
@@ -2,8 +2,10 @@ package uwsgate
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
 	"time"
 
@@ -20,8 +22,8 @@ type Poller struct {
 	serverID string
 
 	mu sync.RWMutex
-	// subscriptions: objectName -> sensorName -> struct{}
-	subscriptions map[string]map[string]struct{}
+	// subscriptions: objectName -> sensorName -> владельцы подписки (ID SSE клиентов)
+	subscriptions map[string]map[string]map[string]struct{}
 	// lastValues: sensorName -> valueHash (для change detection)
 	lastValues map[string]string
 	// currentValues: sensorName -> SensorData (текущие значения)
@@ -47,7 +49,7 @@ func NewPoller(baseURL string, callback BatchUpdateCallback, logger *slog.Logger
 	p := &Poller{
 		client:        client,
 		callback:      callback,
-		subscriptions: make(map[string]map[string]struct{}),
+		subscriptions: make(map[string]map[string]map[string]struct{}),
 		lastValues:    make(map[string]string),
 		currentValues: make(map[string]SensorData),
 		logger:        logger.With("component", "uwsgate-poller"),
@@ -59,6 +61,11 @@ func NewPoller(baseURL string, callback BatchUpdateCallback, logger *slog.Logger
 	return p
 }
 
+// SetConnection задаёт параметры подключения клиента (вызывать до Start)
+func (p *Poller) SetConnection(tlsConfig *tls.Config, header http.Header, timeout time.Duration) {
+	p.client.SetConnection(tlsConfig, header, timeout)
+}
+
 // Start запускает poller
 func (p *Poller) Start(ctx context.Context) error {
 	p.mu.Lock()
@@ -88,23 +95,32 @@ func (p *Poller) Stop() {
 	p.wg.Wait()
 }
 
-// Subscribe подписывается на датчики для объекта
+// Subscribe подписывается на датчики для объекта без владельца
 func (p *Poller) Subscribe(objectName string, sensorNames []string) error {
+	return p.SubscribeFor("", objectName, sensorNames)
+}
+
+// SubscribeFor подписывает владельца (ID SSE клиента) на датчики объекта.
+// Датчик остаётся подписанным, пока на него подписан хотя бы один владелец.
+func (p *Poller) SubscribeFor(owner, objectName string, sensorNames []string) error {
 	if len(sensorNames) == 0 {
 		return nil
 	}
 
 	p.mu.Lock()
 	if p.subscriptions[objectName] == nil {
-		p.subscriptions[objectName] = make(map[string]struct{})
+		p.subscriptions[objectName] = make(map[string]map[string]struct{})
 	}
 
 	newSensors := make([]string, 0)
 	for _, name := range sensorNames {
-		if _, exists := p.subscriptions[objectName][name]; !exists {
-			p.subscriptions[objectName][name] = struct{}{}
+		owners, exists := p.subscriptions[objectName][name]
+		if !exists {
+			owners = make(map[string]struct{})
+			p.subscriptions[objectName][name] = owners
 			newSensors = append(newSensors, name)
 		}
+		owners[owner] = struct{}{}
 	}
 	p.mu.Unlock()
 
@@ -114,77 +130,140 @@ func (p *Poller) Subscribe(objectName string, sensorNames []string) error {
 
 	p.logger.Info("subscribing to sensors",
 		"object", objectName,
+		"owner", owner,
 		"count", len(newSensors),
 		"sensors", newSensors)
 
 	return p.client.Subscribe(newSensors)
 }
 
-// Unsubscribe отписывается от датчиков
+// Unsubscribe снимает подписку без владельца с датчиков объекта
 func (p *Poller) Unsubscribe(objectName string, sensorNames []string) error {
+	return p.UnsubscribeFor("", objectName, sensorNames)
+}
+
+// UnsubscribeFor снимает подписку владельца с датчиков объекта.
+// От датчика gate отписывается, когда у него не остаётся владельцев ни в одном объекте.
+func (p *Poller) UnsubscribeFor(owner, objectName string, sensorNames []string) error {
 	if len(sensorNames) == 0 {
 		return nil
 	}
 
 	p.mu.Lock()
-	if p.subscriptions[objectName] == nil {
-		p.mu.Unlock()
-		return nil
+	toUnsubscribe := make([]string, 0)
+	for _, name := range sensorNames {
+		if p.release(owner, objectName, name) {
+			toUnsubscribe = append(toUnsubscribe, name)
+		}
 	}
+	p.mu.Unlock()
 
+	return p.unsubscribeClient(objectName, toUnsubscribe)
+}
+
+// UnsubscribeAllFor снимает все подписки владельца на датчики объекта
+func (p *Poller) UnsubscribeAllFor(owner, objectName string) error {
+	p.mu.Lock()
 	toUnsubscribe := make([]string, 0)
-	for _, name := range sensorNames {
-		if _, exists := p.subscriptions[objectName][name]; exists {
-			delete(p.subscriptions[objectName], name)
-			// Проверяем, не подписан ли этот датчик для другого объекта
-			stillSubscribed := false
-			for _, subs := range p.subscriptions {
-				if _, ok := subs[name]; ok {
-					stillSubscribed = true
-					break
-				}
+	for name := range p.subscriptions[objectName] {
+		if p.release(owner, objectName, name) {
+			toUnsubscribe = append(toUnsubscribe, name)
+		}
+	}
+	p.mu.Unlock()
+
+	return p.unsubscribeClient(objectName, toUnsubscribe)
+}
+
+// UnsubscribeAll отписывается от всех датчиков объекта независимо от владельцев
+func (p *Poller) UnsubscribeAll(objectName string) error {
+	p.mu.Lock()
+	toUnsubscribe := make([]string, 0)
+	for name := range p.subscriptions[objectName] {
+		delete(p.subscriptions[objectName], name)
+		if p.dropIfUnused(name) {
+			toUnsubscribe = append(toUnsubscribe, name)
+		}
+	}
+	delete(p.subscriptions, objectName)
+	p.mu.Unlock()
+
+	return p.unsubscribeClient(objectName, toUnsubscribe)
+}
+
+// ReleaseOwner снимает все подписки владельца, возвращает количество освобождённых датчиков
+func (p *Poller) ReleaseOwner(owner string) int {
+	released := 0
+	toUnsubscribe := make([]string, 0)
+
+	p.mu.Lock()
+	for objectName, subs := range p.subscriptions {
+		for name, owners := range subs {
+			if _, ok := owners[owner]; !ok {
+				continue
 			}
-			if !stillSubscribed {
+			released++
+			if p.release(owner, objectName, name) {
 				toUnsubscribe = append(toUnsubscribe, name)
-				delete(p.lastValues, name)
-				delete(p.currentValues, name)
 			}
 		}
 	}
+	p.mu.Unlock()
 
-	if len(p.subscriptions[objectName]) == 0 {
-		delete(p.subscriptions, objectName)
+	if err := p.unsubscribeClient("", toUnsubscribe); err != nil {
+		p.logger.Warn("failed to unsubscribe released sensors", "owner", owner, "error", err)
 	}
-	p.mu.Unlock()
+	return released
+}
 
-	if len(toUnsubscribe) == 0 {
-		return nil
+// release снимает подписку владельца с датчика объекта. Возвращает true, если
+// датчик больше никому не нужен и от него нужно отписаться. Вызывается под p.mu.
+func (p *Poller) release(owner, objectName, name string) bool {
+	owners, exists := p.subscriptions[objectName][name]
+	if !exists {
+		return false
+	}
+	if _, ok := owners[owner]; !ok {
+		return false
 	}
 
-	p.logger.Info("unsubscribing from sensors",
-		"object", objectName,
-		"count", len(toUnsubscribe),
-		"sensors", toUnsubscribe)
+	delete(owners, owner)
+	if len(owners) > 0 {
+		return false
+	}
 
-	return p.client.Unsubscribe(toUnsubscribe)
+	delete(p.subscriptions[objectName], name)
+	if len(p.subscriptions[objectName]) == 0 {
+		delete(p.subscriptions, objectName)
+	}
+	return p.dropIfUnused(name)
 }
 
-// UnsubscribeAll отписывается от всех датчиков объекта
-func (p *Poller) UnsubscribeAll(objectName string) error {
-	p.mu.RLock()
-	subs, exists := p.subscriptions[objectName]
-	if !exists {
-		p.mu.RUnlock()
-		return nil
+// dropIfUnused удаляет значения датчика, если он не подписан ни для одного объекта.
+// Возвращает true, если датчик больше не подписан. Вызывается под p.mu.
+func (p *Poller) dropIfUnused(name string) bool {
+	for _, subs := range p.subscriptions {
+		if _, ok := subs[name]; ok {
+			return false
+		}
 	}
+	delete(p.lastValues, name)
+	delete(p.currentValues, name)
+	return true
+}
 
-	sensorNames := make([]string, 0, len(subs))
-	for name := range subs {
-		sensorNames = append(sensorNames, name)
+// unsubscribeClient отписывает gate от датчиков, которые больше никому не нужны
+func (p *Poller) unsubscribeClient(objectName string, sensorNames []string) error {
+	if len(sensorNames) == 0 {
+		return nil
 	}
-	p.mu.RUnlock()
 
-	return p.Unsubscribe(objectName, sensorNames)
+	p.logger.Info("unsubscribing from sensors",
+		"object", objectName,
+		"count", len(sensorNames),
+		"sensors", sensorNames)
+
+	return p.client.Unsubscribe(sensorNames)
 }
 
 // GetSubscriptions возвращает подписки объекта
@@ -264,8 +343,8 @@ func (p *Poller) handleData(data []SensorData) {
 		// Сохраняем текущее значение
 		p.currentValues[sensor.Name] = sensor
 
-		// Change detection
-		valueHash := fmt.Sprintf("%d|%d", sensor.Value, sensor.ErrorCode())
+		// Change detection (время в SM - чтобы watchdog устаревания видел, что источник жив)
+		valueHash := fmt.Sprintf("%d|%d|%d.%d", sensor.Value, sensor.ErrorCode(), sensor.SMTVSec, sensor.SMTVNsec)
 		if p.lastValues[sensor.Name] == valueHash {
 			continue // Значение не изменилось
 		}
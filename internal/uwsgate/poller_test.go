@@ -72,6 +72,39 @@ func TestPollerSubscriptionManagement(t *testing.T) {
 	}
 }
 
+func TestPollerOwnerRefCounting(t *testing.T) {
+	p := NewPoller("http://localhost:8080", nil, nil)
+
+	_ = p.SubscribeFor("virtual", "Object1", []string{"Sensor1"})
+	_ = p.SubscribeFor("client1", "Object1", []string{"Sensor1", "Sensor2"})
+	_ = p.SubscribeFor("client2", "Object1", []string{"Sensor2"})
+
+	// Отписка клиента не снимает подписку других владельцев
+	_ = p.UnsubscribeFor("client1", "Object1", []string{"Sensor1", "Sensor2"})
+	if subs := p.GetSubscriptions("Object1"); len(subs) != 2 {
+		t.Fatalf("expected Sensor1 and Sensor2 kept, got %v", subs)
+	}
+
+	// Анонимная отписка не трогает чужие подписки
+	_ = p.Unsubscribe("Object1", []string{"Sensor1"})
+	if subs := p.GetSubscriptions("Object1"); len(subs) != 2 {
+		t.Fatalf("anonymous unsubscribe must not remove owned sensors, got %v", subs)
+	}
+
+	if n := p.ReleaseOwner("client2"); n != 1 {
+		t.Errorf("ReleaseOwner(client2) = %d, want 1", n)
+	}
+	subs := p.GetSubscriptions("Object1")
+	if len(subs) != 1 || subs[0] != "Sensor1" {
+		t.Errorf("expected only Sensor1 left, got %v", subs)
+	}
+
+	_ = p.UnsubscribeAllFor("virtual", "Object1")
+	if subs := p.GetSubscriptions("Object1"); len(subs) != 0 {
+		t.Errorf("expected no subscriptions, got %v", subs)
+	}
+}
+
 func TestPollerSubscribeEmpty(t *testing.T) {
 	p := NewPoller("http://localhost:8080", nil, nil)
 
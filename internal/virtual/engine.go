@@ -0,0 +1,300 @@
+// Package virtual вычисляет виртуальные датчики - выражения над значениями
+// датчиков IONC/Modbus/OPCUA/UWebSocketGate/SM и переменных объектов
+package virtual
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/storage"
+)
+
+// Источники входных значений (совпадают с источниками тревог)
+const (
+	SourceObject  = "object"  // переменные объекта (Variables)
+	SourceIONC    = "ionc"    // датчики IONotifyController
+	SourceModbus  = "modbus"  // регистры ModbusMaster/ModbusSlave
+	SourceOPCUA   = "opcua"   // датчики OPCUAExchange/OPCUAServer
+	SourceUWSGate = "uwsgate" // датчики UWebSocketGate
+	SourceSM      = "sm"      // внешние датчики SharedMemory
+)
+
+const (
+	// ServerID сервер, под которым виртуальные датчики публикуются и сохраняются в историю
+	ServerID = "virtual"
+	// DefaultObject объект виртуального датчика, если он не задан
+	DefaultObject = "Virtual"
+	// Owner владелец подписок на входы в опросчиках (не освобождается при отключении SSE клиентов)
+	Owner = "virtual"
+	// EventValue тип SSE события с новым значением виртуального датчика
+	EventValue = "virtual_sensor_data"
+)
+
+// Input входное значение выражения
+type Input struct {
+	Source string `json:"source"`
+	Server string `json:"server,omitempty"` // пусто = любой сервер
+	Object string `json:"object"`
+	Sensor string `json:"sensor"`       // имя датчика/регистра/переменной
+	ID     int64  `json:"id,omitempty"` // ID для подписки IONC/Modbus/OPCUA (0 = по имени из конфигурации датчиков)
+}
+
+// Definition описание виртуального датчика
+type Definition struct {
+	Name       string
+	Object     string           // default: DefaultObject
+	Expression string           // выражение над именами входов
+	Inputs     map[string]Input // имя в выражении -> вход
+}
+
+// Value вычисленное значение виртуального датчика
+type Value struct {
+	Name      string    `json:"name"`
+	Object    string    `json:"object"`
+	Value     float64   `json:"value"`
+	Error     string    `json:"error,omitempty"` // ошибка вычисления (value не действительно)
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SensorState описание и текущее состояние виртуального датчика
+type SensorState struct {
+	Name       string           `json:"name"`
+	Object     string           `json:"object"`
+	Expression string           `json:"expression"`
+	Inputs     map[string]Input `json:"inputs"`
+	Missing    []string         `json:"missing,omitempty"` // входы, значения которых ещё не получены
+	Value      *Value           `json:"value,omitempty"`   // nil, пока не получены все входы
+}
+
+// UpdateCallback вызывается при изменении значения виртуального датчика (вне блокировок движка)
+type UpdateCallback func(value Value)
+
+// sensor виртуальный датчик и последние значения его входов
+type sensor struct {
+	def    Definition
+	expr   *Expr
+	names  []string // имена входов в порядке индексов выражения
+	inputs []Input
+	values []float64
+	have   []bool
+	last   *Value
+}
+
+// inputKey значение источника, по которому ищутся зависящие от него датчики
+type inputKey struct {
+	source string
+	object string
+	sensor string
+}
+
+type inputRef struct {
+	sensor *sensor
+	index  int
+}
+
+// Engine пересчитывает виртуальные датчики при обновлении их входов
+type Engine struct {
+	mu       sync.Mutex
+	sensors  []*sensor
+	index    map[inputKey][]inputRef
+	callback UpdateCallback
+}
+
+// New создаёт движок и проверяет описания датчиков
+func New(defs []Definition, callback UpdateCallback) (*Engine, error) {
+	e := &Engine{
+		index:    make(map[inputKey][]inputRef),
+		callback: callback,
+	}
+
+	seen := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		s, err := newSensor(def)
+		if err != nil {
+			if def.Name == "" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("virtual sensor %q: %w", def.Name, err)
+		}
+		// Виджеты dashboard находят датчики по имени, поэтому имена уникальны
+		if seen[s.def.Name] {
+			return nil, fmt.Errorf("duplicate virtual sensor name %q", s.def.Name)
+		}
+		seen[s.def.Name] = true
+
+		e.sensors = append(e.sensors, s)
+		for i, in := range s.inputs {
+			key := inputKey{source: in.Source, object: in.Object, sensor: in.Sensor}
+			e.index[key] = append(e.index[key], inputRef{sensor: s, index: i})
+		}
+	}
+	return e, nil
+}
+
+func newSensor(def Definition) (*sensor, error) {
+	if def.Name == "" {
+		return nil, fmt.Errorf("virtual sensor name is required")
+	}
+	if def.Object == "" {
+		def.Object = DefaultObject
+	}
+	if strings.TrimSpace(def.Expression) == "" {
+		return nil, fmt.Errorf("expression is required")
+	}
+	if len(def.Inputs) == 0 {
+		return nil, fmt.Errorf("at least one input is required")
+	}
+
+	names := make([]string, 0, len(def.Inputs))
+	for name := range def.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inputs := make([]Input, len(names))
+	for i, name := range names {
+		in := def.Inputs[name]
+		if !isIdent(name) {
+			return nil, fmt.Errorf("invalid input name %q", name)
+		}
+		switch in.Source {
+		case SourceObject, SourceIONC, SourceModbus, SourceOPCUA, SourceUWSGate, SourceSM:
+		case "":
+			return nil, fmt.Errorf("input %q: source is required", name)
+		default:
+			return nil, fmt.Errorf("input %q: unknown source %q", name, in.Source)
+		}
+		if in.Object == "" || in.Sensor == "" {
+			return nil, fmt.Errorf("input %q: object and sensor are required", name)
+		}
+		inputs[i] = in
+	}
+
+	expr, err := Compile(def.Expression, names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	return &sensor{
+		def:    def,
+		expr:   expr,
+		names:  names,
+		inputs: inputs,
+		values: make([]float64, len(names)),
+		have:   make([]bool, len(names)),
+	}, nil
+}
+
+// Update передаёт движку новое значение источника. Зависящие от него датчики пересчитываются,
+// когда получены значения всех их входов; о смене значения сообщается через callback.
+func (e *Engine) Update(source, serverID, objectName, sensorName string, value interface{}, now time.Time) {
+	refs := e.index[inputKey{source: source, object: objectName, sensor: sensorName}]
+	if len(refs) == 0 {
+		return
+	}
+	v, ok := storage.ToFloat64(value)
+	if !ok {
+		return
+	}
+
+	var changed []Value
+	e.mu.Lock()
+	var affected []*sensor
+	for _, ref := range refs {
+		in := ref.sensor.inputs[ref.index]
+		if in.Server != "" && in.Server != serverID {
+			continue
+		}
+		ref.sensor.values[ref.index] = v
+		ref.sensor.have[ref.index] = true
+		if len(affected) == 0 || affected[len(affected)-1] != ref.sensor {
+			affected = append(affected, ref.sensor)
+		}
+	}
+	for _, s := range affected {
+		if val, ok := s.evaluate(now); ok {
+			changed = append(changed, val)
+		}
+	}
+	e.mu.Unlock()
+
+	if e.callback != nil {
+		for _, val := range changed {
+			e.callback(val)
+		}
+	}
+}
+
+// evaluate пересчитывает датчик; возвращает значение, если оно изменилось
+func (s *sensor) evaluate(now time.Time) (Value, bool) {
+	for _, have := range s.have {
+		if !have {
+			return Value{}, false
+		}
+	}
+
+	val := Value{Name: s.def.Name, Object: s.def.Object, Timestamp: now}
+	result, err := s.expr.Eval(s.values)
+	if err != nil {
+		val.Error = err.Error()
+	} else {
+		val.Value = result
+	}
+
+	if s.last != nil && s.last.Value == val.Value && s.last.Error == val.Error {
+		return Value{}, false
+	}
+	s.last = &val
+	return val, true
+}
+
+// Sensors возвращает описания и текущие значения датчиков (в порядке конфигурации)
+func (e *Engine) Sensors() []SensorState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]SensorState, 0, len(e.sensors))
+	for _, s := range e.sensors {
+		state := SensorState{
+			Name:       s.def.Name,
+			Object:     s.def.Object,
+			Expression: s.def.Expression,
+			Inputs:     make(map[string]Input, len(s.names)),
+		}
+		for i, name := range s.names {
+			state.Inputs[name] = s.inputs[i]
+			if !s.have[i] {
+				state.Missing = append(state.Missing, name)
+			}
+		}
+		if s.last != nil {
+			last := *s.last
+			state.Value = &last
+		}
+		result = append(result, state)
+	}
+	return result
+}
+
+// Inputs возвращает все входы без повторов (для подписки в опросчиках)
+func (e *Engine) Inputs() []Input {
+	seen := make(map[Input]bool)
+	var result []Input
+	for _, s := range e.sensors {
+		for _, in := range s.inputs {
+			if !seen[in] {
+				seen[in] = true
+				result = append(result, in)
+			}
+		}
+	}
+	return result
+}
+
+// Count возвращает количество виртуальных датчиков
+func (e *Engine) Count() int {
+	return len(e.sensors)
+}
@@ -0,0 +1,161 @@
+package virtual
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder собирает значения движка
+type recorder struct {
+	mu     sync.Mutex
+	values []Value
+}
+
+func (r *recorder) add(v Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values = append(r.values, v)
+}
+
+func (r *recorder) take() []Value {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := r.values
+	r.values = nil
+	return values
+}
+
+func newTestEngine(t *testing.T, defs ...Definition) (*Engine, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	engine, err := New(defs, rec.add)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return engine, rec
+}
+
+func ioncInput(sensor string) Input {
+	return Input{Source: SourceIONC, Object: "SharedMemory", Sensor: sensor}
+}
+
+func TestNewValidation(t *testing.T) {
+	in := map[string]Input{"p": ioncInput("P1")}
+	tests := []struct {
+		name string
+		defs []Definition
+		want string
+	}{
+		{"no name", []Definition{{Expression: "p", Inputs: in}}, "name is required"},
+		{"no expression", []Definition{{Name: "v", Inputs: in}}, "expression is required"},
+		{"no inputs", []Definition{{Name: "v", Expression: "1"}}, "at least one input"},
+		{"bad input name", []Definition{{Name: "v", Expression: "1", Inputs: map[string]Input{"1p": ioncInput("P1")}}}, "invalid input name"},
+		{"function input name", []Definition{{Name: "v", Expression: "1", Inputs: map[string]Input{"max": ioncInput("P1")}}}, "invalid input name"},
+		{"no source", []Definition{{Name: "v", Expression: "p", Inputs: map[string]Input{"p": {Object: "o", Sensor: "s"}}}}, "source is required"},
+		{"bad source", []Definition{{Name: "v", Expression: "p", Inputs: map[string]Input{"p": {Source: "foo", Object: "o", Sensor: "s"}}}}, "unknown source"},
+		{"no sensor", []Definition{{Name: "v", Expression: "p", Inputs: map[string]Input{"p": {Source: SourceIONC, Object: "o"}}}}, "object and sensor are required"},
+		{"bad expression", []Definition{{Name: "v", Expression: "p +", Inputs: in}}, "invalid expression"},
+		{"duplicate", []Definition{{Name: "v", Expression: "p", Inputs: in}, {Name: "v", Expression: "p", Inputs: in}}, "duplicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.defs, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEngineEvaluatesWhenAllInputsKnown(t *testing.T) {
+	engine, rec := newTestEngine(t, Definition{
+		Name:       "DeltaP",
+		Expression: "p1 - p2",
+		Inputs: map[string]Input{
+			"p1": ioncInput("P1"),
+			"p2": {Source: SourceModbus, Server: "s2", Object: "MBMaster1", Sensor: "P2"},
+		},
+	})
+	now := time.Now()
+
+	engine.Update(SourceIONC, "s1", "SharedMemory", "P1", int64(100), now)
+	if got := rec.take(); len(got) != 0 {
+		t.Fatalf("expected no value until all inputs known, got %+v", got)
+	}
+	states := engine.Sensors()
+	if len(states) != 1 || states[0].Value != nil || len(states[0].Missing) != 1 || states[0].Missing[0] != "p2" {
+		t.Fatalf("unexpected state %+v", states)
+	}
+
+	// Вход с другого сервера не подходит
+	engine.Update(SourceModbus, "s1", "MBMaster1", "P2", int64(30), now)
+	if got := rec.take(); len(got) != 0 {
+		t.Fatalf("input from other server must be ignored, got %+v", got)
+	}
+
+	engine.Update(SourceModbus, "s2", "MBMaster1", "P2", int64(30), now)
+	got := rec.take()
+	if len(got) != 1 || got[0].Name != "DeltaP" || got[0].Object != DefaultObject || got[0].Value != 70 {
+		t.Fatalf("expected DeltaP=70, got %+v", got)
+	}
+
+	// Значение не изменилось - не публикуется
+	engine.Update(SourceIONC, "s1", "SharedMemory", "P1", "100", now)
+	if got := rec.take(); len(got) != 0 {
+		t.Errorf("unchanged value must not be published, got %+v", got)
+	}
+
+	engine.Update(SourceIONC, "s1", "SharedMemory", "P1", 110.0, now)
+	if got := rec.take(); len(got) != 1 || got[0].Value != 80 {
+		t.Errorf("expected DeltaP=80, got %+v", got)
+	}
+
+	states = engine.Sensors()
+	if states[0].Value == nil || states[0].Value.Value != 80 || len(states[0].Missing) != 0 {
+		t.Errorf("unexpected state %+v", states[0])
+	}
+}
+
+func TestEngineSharedInputAndErrors(t *testing.T) {
+	engine, rec := newTestEngine(t,
+		Definition{
+			Name:       "AnyAlarm",
+			Object:     "Boiler",
+			Expression: "any(d1, d2)",
+			Inputs:     map[string]Input{"d1": ioncInput("DI1"), "d2": {Source: SourceSM, Object: "SM", Sensor: "DI2"}},
+		},
+		Definition{
+			Name:       "Ratio",
+			Expression: "x / d1",
+			Inputs:     map[string]Input{"x": {Source: SourceObject, Object: "Proc1", Sensor: "count"}, "d1": ioncInput("DI1")},
+		},
+	)
+	now := time.Now()
+
+	engine.Update(SourceSM, "sm", "SM", "DI2", 0, now)
+	engine.Update(SourceObject, "s1", "Proc1", "count", 5, now)
+	engine.Update(SourceIONC, "s1", "SharedMemory", "DI1", 0, now)
+
+	got := rec.take()
+	if len(got) != 2 {
+		t.Fatalf("expected both sensors evaluated, got %+v", got)
+	}
+	if got[0].Name != "AnyAlarm" || got[0].Object != "Boiler" || got[0].Value != 0 {
+		t.Errorf("unexpected AnyAlarm %+v", got[0])
+	}
+	if got[1].Name != "Ratio" || got[1].Error == "" {
+		t.Errorf("expected Ratio division error, got %+v", got[1])
+	}
+
+	engine.Update(SourceIONC, "s1", "SharedMemory", "DI1", true, now)
+	got = rec.take()
+	if len(got) != 2 || got[0].Value != 1 || got[1].Value != 5 || got[1].Error != "" {
+		t.Errorf("unexpected values after DI1=1: %+v", got)
+	}
+
+	if inputs := engine.Inputs(); len(inputs) != 3 {
+		t.Errorf("expected 3 distinct inputs, got %+v", inputs)
+	}
+}
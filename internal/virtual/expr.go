@@ -0,0 +1,515 @@
+package virtual
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrDivisionByZero деление или остаток от деления на ноль
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Expr разобранное выражение виртуального датчика.
+//
+// Поддерживаются числа, true/false, имена входов, скобки, операторы
+// (по убыванию приоритета) унарные - + !, * / %, + -, < <= > >=, == !=, &&, ||
+// и функции abs, min, max, sum, avg, any, all, round, floor, ceil, if(cond, a, b).
+// Логические значения - числа: ложь = 0, истина = любое другое (результат сравнения - 1).
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile разбирает выражение. vars - допустимые имена переменных;
+// индекс имени в vars - индекс значения, передаваемого в Eval.
+func Compile(src string, vars []string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, vars: make(map[string]int, len(vars))}
+	for i, name := range vars {
+		p.vars[name] = i
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("position %d: unexpected %q", tok.pos, tok.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval вычисляет выражение по значениям переменных (в порядке vars из Compile)
+func (e *Expr) Eval(values []float64) (float64, error) {
+	v, err := e.root.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return v, nil
+}
+
+// String возвращает исходный текст выражения
+func (e *Expr) String() string {
+	return e.src
+}
+
+// --- Лексер ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// operators операторы и разделители (двухсимвольные проверяются первыми)
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			// Экспонента: 1e3, 2.5E-2
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(src[j]) {
+					for j < len(src) && isDigit(src[j]) {
+						j++
+					}
+					i = j
+				}
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("position %d: invalid number %q", start, src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+		case isIdentStart(src[i]):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("position %d: unexpected character %q", i, src[i])
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isIdentPart(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+// isIdent проверяет, что имя можно использовать в выражении как переменную
+func isIdent(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentPart(s[i]) {
+			return false
+		}
+	}
+	_, isFunc := functions[s]
+	return !isFunc && s != "if" && s != "true" && s != "false"
+}
+
+// --- Парсер (рекурсивный спуск по уровням приоритета) ---
+
+type parser struct {
+	tokens []token
+	pos    int
+	vars   map[string]int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOp забирает оператор, если следующий токен - один из ops
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expectOp(op string) error {
+	if _, ok := p.acceptOp(op); !ok {
+		tok := p.peek()
+		return fmt.Errorf("position %d: expected %q, got %q", tok.pos, op, tok.text)
+	}
+	return nil
+}
+
+// parseBinary разбирает левоассоциативную цепочку операторов одного уровня
+func (p *parser) parseBinary(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, x: left, y: right}
+	}
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseEquality, "&&")
+}
+
+func (p *parser) parseEquality() (node, error) {
+	return p.parseBinary(p.parseComparison, "==", "!=")
+}
+
+func (p *parser) parseComparison() (node, error) {
+	return p.parseBinary(p.parseAdditive, "<=", ">=", "<", ">")
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.acceptOp("-", "+", "!"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return numberNode(tok.num), nil
+	case tokIdent:
+		if _, ok := p.acceptOp("("); ok {
+			return p.parseCall(tok)
+		}
+		switch tok.text {
+		case "true":
+			return numberNode(1), nil
+		case "false":
+			return numberNode(0), nil
+		}
+		idx, ok := p.vars[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("position %d: unknown input %q", tok.pos, tok.text)
+		}
+		return varNode(idx), nil
+	case tokOp:
+		if tok.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("position %d: unexpected %q", tok.pos, tok.text)
+}
+
+// parseCall разбирает аргументы функции (открывающая скобка уже прочитана)
+func (p *parser) parseCall(name token) (node, error) {
+	var args []node
+	if _, ok := p.acceptOp(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.acceptOp(","); ok {
+				continue
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if name.text == "if" {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("position %d: if expects 3 arguments, got %d", name.pos, len(args))
+		}
+		return &ifNode{cond: args[0], then: args[1], otherwise: args[2]}, nil
+	}
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("position %d: unknown function %q", name.pos, name.text)
+	}
+	if len(args) < fn.minArgs || fn.maxArgs > 0 && len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("position %d: wrong number of arguments for %s: %d", name.pos, name.text, len(args))
+	}
+	return &callNode{fn: fn.call, args: args}, nil
+}
+
+// --- Вычисление ---
+
+type node interface {
+	eval(vars []float64) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval([]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type varNode int
+
+func (n varNode) eval(vars []float64) (float64, error) {
+	return vars[n], nil
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(vars []float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "-":
+		return -x, nil
+	case "!":
+		return boolValue(x == 0), nil
+	}
+	return x, nil
+}
+
+type binaryNode struct {
+	op   string
+	x, y node
+}
+
+func (n *binaryNode) eval(vars []float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	// Логические операторы вычисляются по короткой схеме
+	switch n.op {
+	case "&&":
+		if x == 0 {
+			return 0, nil
+		}
+	case "||":
+		if x != 0 {
+			return 1, nil
+		}
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return math.Mod(x, y), nil
+	case "<":
+		return boolValue(x < y), nil
+	case "<=":
+		return boolValue(x <= y), nil
+	case ">":
+		return boolValue(x > y), nil
+	case ">=":
+		return boolValue(x >= y), nil
+	case "==":
+		return boolValue(x == y), nil
+	case "!=":
+		return boolValue(x != y), nil
+	case "&&", "||":
+		return boolValue(y != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", n.op)
+}
+
+// ifNode вычисляет только выбранную ветку
+type ifNode struct {
+	cond, then, otherwise node
+}
+
+func (n *ifNode) eval(vars []float64) (float64, error) {
+	c, err := n.cond.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if c != 0 {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+type callNode struct {
+	fn   func(args []float64) float64
+	args []node
+}
+
+func (n *callNode) eval(vars []float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return n.fn(args), nil
+}
+
+// function встроенная функция; maxArgs = 0 - без ограничения
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"abs":   {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"round": {1, 1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"floor": {1, 1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, 1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"min": {1, 0, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {1, 0, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+	"sum": {1, 0, sum},
+	"avg": {1, 0, func(a []float64) float64 { return sum(a) / float64(len(a)) }},
+	"any": {1, 0, func(a []float64) float64 {
+		for _, v := range a {
+			if v != 0 {
+				return 1
+			}
+		}
+		return 0
+	}},
+	"all": {1, 0, func(a []float64) float64 {
+		for _, v := range a {
+			if v == 0 {
+				return 0
+			}
+		}
+		return 1
+	}},
+}
+
+func sum(a []float64) float64 {
+	s := 0.0
+	for _, v := range a {
+		s += v
+	}
+	return s
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package virtual
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExprEval(t *testing.T) {
+	vars := []string{"a", "b", "di1", "di2"}
+	values := []float64{10, 4, 0, 1}
+
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"a - b", 6},
+		{"a + b * 2", 18},
+		{"(a + b) * 2", 28},
+		{"-a + +b", -6},
+		{"a / b", 2.5},
+		{"a % b", 2},
+		{"2 * 1.5e1", 30},
+		{".5 + a", 10.5},
+		{"a - b - 1", 5},
+		{"a > b", 1},
+		{"a <= b", 0},
+		{"a == 10 && b != 4", 0},
+		{"di1 || di2", 1},
+		{"!di1", 1},
+		{"!(a > b) || true", 1},
+		{"false || a < b", 0},
+		{"abs(b - a)", 6},
+		{"min(a, b, 7)", 4},
+		{"max(a, b, 7)", 10},
+		{"sum(a, b, di2)", 15},
+		{"avg(a, b)", 7},
+		{"any(di1, di2)", 1},
+		{"all(di1, di2)", 0},
+		{"round(2.5) + floor(1.9) + ceil(1.1)", 6},
+		{"if(di2, a, b)", 10},
+		{"if(di1, a, b)", 4},
+		// Невыбранная ветка и правый операнд && не вычисляются
+		{"if(di1, a / di1, 0)", 0},
+		{"di1 && a / di1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := Compile(tt.src, vars)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			got, err := expr.Eval(values)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExprEvalErrors(t *testing.T) {
+	expr, err := Compile("a / b", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if _, err := expr.Eval([]float64{1, 0}); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("expected division by zero, got %v", err)
+	}
+
+	expr, err = Compile("a % b", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if _, err := expr.Eval([]float64{1, 0}); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("expected division by zero for %%, got %v", err)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a +", "unexpected"},
+		{"a b", "unexpected"},
+		{"(a", `expected ")"`},
+		{"c + 1", `unknown input "c"`},
+		{"foo(a)", `unknown function "foo"`},
+		{"abs(a, a)", "wrong number of arguments"},
+		{"min()", "wrong number of arguments"},
+		{"if(a, 1)", "if expects 3 arguments"},
+		{"a $ 1", "unexpected character"},
+		{"1.2.3", "invalid number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src, []string{"a"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package watchdog отслеживает подписанные датчики, время источника которых
+// перестало меняться (значение "заморожено" из-за отказа устройства)
+package watchdog
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pv/uniset-panel/internal/glob"
+	"github.com/pv/uniset-panel/internal/logger"
+)
+
+// Источники значений с временем изменения
+const (
+	SourceIONC    = "ionc"    // tv_sec/tv_nsec датчиков IONotifyController
+	SourceUWSGate = "uwsgate" // sm_tv_sec/sm_tv_nsec датчиков UWebSocketGate
+)
+
+// EventStale тип SSE события смены признака устаревания (stale=true/false)
+const EventStale = "sensor_stale"
+
+// defaultTickInterval период проверки окон устаревания
+const defaultTickInterval = time.Second
+
+// Rule окно устаревания для датчиков, подходящих под glob-шаблоны.
+// Пустые шаблоны сервера и объекта равны "*".
+type Rule struct {
+	Source string // источник (пусто = любой)
+	Server string
+	Object string
+	Sensor string
+	Window time.Duration // допустимое время без изменения времени источника
+}
+
+func (r *Rule) validate() error {
+	if r.Sensor == "" {
+		return fmt.Errorf("sensor is required")
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	switch r.Source {
+	case "", SourceIONC, SourceUWSGate:
+	default:
+		return fmt.Errorf("unknown source %q", r.Source)
+	}
+	if r.Server == "" {
+		r.Server = "*"
+	}
+	if r.Object == "" {
+		r.Object = "*"
+	}
+	return glob.Validate(r.Server, r.Object, r.Sensor)
+}
+
+func (r *Rule) match(key Key) bool {
+	return (r.Source == "" || r.Source == key.Source) &&
+		glob.Match(r.Server, key.ServerID) &&
+		glob.Match(r.Object, key.Object) &&
+		glob.Match(r.Sensor, key.Sensor)
+}
+
+// Key датчик, за которым следит watchdog
+type Key struct {
+	Source   string
+	ServerID string
+	Object   string
+	Sensor   string
+}
+
+// SensorState состояние датчика для API и SSE
+type SensorState struct {
+	Source      string     `json:"source"`
+	ServerID    string     `json:"serverId"`
+	Object      string     `json:"object"`
+	Sensor      string     `json:"sensor"`
+	ID          int64      `json:"id"`
+	WindowMs    int64      `json:"windowMs"`
+	SourceTime  time.Time  `json:"sourceTime"`  // последнее время источника
+	LastAdvance time.Time  `json:"lastAdvance"` // когда время источника последний раз менялось (по часам панели)
+	Stale       bool       `json:"stale"`
+	StaleSince  *time.Time `json:"staleSince,omitempty"`
+}
+
+// Event смена признака устаревания датчика
+type Event struct {
+	Type      string      `json:"type"` // EventStale
+	Sensor    SensorState `json:"sensor"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// EventCallback вызывается при смене признака устаревания (вне блокировок)
+type EventCallback func(event Event)
+
+// SubscribedFunc проверяет, подписан ли ещё кто-нибудь на датчик.
+// Датчики без подписки перестают отслеживаться.
+type SubscribedFunc func(key Key, id int64) bool
+
+type entry struct {
+	state  SensorState
+	window time.Duration
+}
+
+// Watchdog отмечает устаревшими датчики, время источника которых не менялось дольше окна правила
+type Watchdog struct {
+	rules      []Rule
+	callback   EventCallback
+	subscribed SubscribedFunc
+
+	mu      sync.Mutex
+	entries map[Key]*entry
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New проверяет правила и создаёт watchdog. Для датчика действует первое подходящее правило.
+func New(rules []Rule, callback EventCallback) (*Watchdog, error) {
+	validated := make([]Rule, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("staleness rule %d: %w", i, err)
+		}
+		validated = append(validated, rule)
+	}
+
+	return &Watchdog{
+		rules:    validated,
+		callback: callback,
+		entries:  make(map[Key]*entry),
+	}, nil
+}
+
+// SetSubscribedFunc устанавливает проверку подписок
+func (w *Watchdog) SetSubscribedFunc(fn SubscribedFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribed = fn
+}
+
+// Start запускает периодическую проверку окон
+func (w *Watchdog) Start() {
+	w.stop = make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(defaultTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case now := <-ticker.C:
+				w.Tick(now)
+			}
+		}
+	}()
+	logger.Info("Stale-value watchdog started", "rules", len(w.rules))
+}
+
+// Stop останавливает периодическую проверку
+func (w *Watchdog) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	w.wg.Wait()
+	w.stop = nil
+}
+
+// window возвращает окно первого подходящего правила
+func (w *Watchdog) window(key Key) (time.Duration, bool) {
+	for i := range w.rules {
+		if w.rules[i].match(key) {
+			return w.rules[i].Window, true
+		}
+	}
+	return 0, false
+}
+
+// Observe передаёт время источника датчика. Нулевое время (источник его не передал) игнорируется.
+func (w *Watchdog) Observe(source, serverID, objectName, sensor string, id int64, sourceTime, now time.Time) {
+	if sourceTime.IsZero() {
+		return
+	}
+	key := Key{Source: source, ServerID: serverID, Object: objectName, Sensor: sensor}
+	window, ok := w.window(key)
+	if !ok {
+		return
+	}
+
+	var events []Event
+	w.mu.Lock()
+	e := w.entries[key]
+	if e == nil {
+		e = &entry{
+			state: SensorState{
+				Source:   source,
+				ServerID: serverID,
+				Object:   objectName,
+				Sensor:   sensor,
+				ID:       id,
+				WindowMs: window.Milliseconds(),
+			},
+			window: window,
+		}
+		w.entries[key] = e
+	}
+	if e.state.SourceTime.IsZero() || sourceTime.After(e.state.SourceTime) {
+		e.state.SourceTime = sourceTime
+		e.state.LastAdvance = now
+		if e.state.Stale {
+			e.state.Stale = false
+			e.state.StaleSince = nil
+			events = append(events, Event{Type: EventStale, Sensor: e.state, Timestamp: now})
+		}
+	}
+	w.mu.Unlock()
+
+	w.emit(events)
+}
+
+// Tick отмечает устаревшими датчики, окно которых истекло к моменту now,
+// и забывает датчики, на которые больше никто не подписан
+func (w *Watchdog) Tick(now time.Time) {
+	// Подписки проверяются вне блокировки: SubscribedFunc обращается к поллерам
+	w.mu.Lock()
+	subscribed := w.subscribed
+	ids := make(map[Key]int64, len(w.entries))
+	for key, e := range w.entries {
+		ids[key] = e.state.ID
+	}
+	w.mu.Unlock()
+
+	var unsubscribed []Key
+	if subscribed != nil {
+		for key, id := range ids {
+			if !subscribed(key, id) {
+				unsubscribed = append(unsubscribed, key)
+			}
+		}
+	}
+
+	var events []Event
+	w.mu.Lock()
+	for _, key := range unsubscribed {
+		delete(w.entries, key)
+	}
+	for _, e := range w.entries {
+		if e.state.Stale || now.Sub(e.state.LastAdvance) < e.window {
+			continue
+		}
+		since := now
+		e.state.Stale = true
+		e.state.StaleSince = &since
+		events = append(events, Event{Type: EventStale, Sensor: e.state, Timestamp: now})
+	}
+	w.mu.Unlock()
+
+	w.emit(events)
+}
+
+func (w *Watchdog) emit(events []Event) {
+	for _, ev := range events {
+		logger.Info("Sensor staleness changed", "stale", ev.Sensor.Stale, "source", ev.Sensor.Source,
+			"server", ev.Sensor.ServerID, "object", ev.Sensor.Object, "sensor", ev.Sensor.Sensor)
+		if w.callback != nil {
+			w.callback(ev)
+		}
+	}
+}
+
+// Stale возвращает устаревшие датчики (по серверу, объекту, датчику)
+func (w *Watchdog) Stale() []SensorState {
+	w.mu.Lock()
+	sensors := make([]SensorState, 0)
+	for _, e := range w.entries {
+		if e.state.Stale {
+			sensors = append(sensors, e.state)
+		}
+	}
+	w.mu.Unlock()
+
+	sort.Slice(sensors, func(i, j int) bool {
+		a, b := sensors[i], sensors[j]
+		if a.ServerID != b.ServerID {
+			return a.ServerID < b.ServerID
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		if a.Sensor != b.Sensor {
+			return a.Sensor < b.Sensor
+		}
+		return a.Source < b.Source
+	})
+	return sensors
+}
+
+// Watched возвращает число отслеживаемых датчиков
+func (w *Watchdog) Watched() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.entries)
+}
@@ -0,0 +1,123 @@
+package watchdog
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) add(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *recorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"no sensor", Rule{Window: time.Second}, "sensor is required"},
+		{"no window", Rule{Sensor: "x"}, "window must be positive"},
+		{"bad source", Rule{Sensor: "x", Window: time.Second, Source: "modbus"}, "unknown source"},
+		{"bad pattern", Rule{Sensor: "[", Window: time.Second}, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]Rule{tt.rule}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWatchdogStaleAndFresh(t *testing.T) {
+	rec := &recorder{}
+	w, err := New([]Rule{
+		{Sensor: "AI1_AS", Window: 5 * time.Second},
+		{Source: SourceIONC, Sensor: "AI*", Window: time.Minute},
+	}, rec.add)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	start := time.Now()
+	src := time.Unix(1000, 0)
+	w.Observe(SourceIONC, "s1", "SM", "AI1_AS", 1, src, start)
+	w.Observe(SourceIONC, "s1", "SM", "AI2_AS", 2, src, start)
+	w.Observe(SourceIONC, "s1", "SM", "DI1_S", 3, src, start) // нет правила
+	if n := w.Watched(); n != 2 {
+		t.Fatalf("expected 2 watched sensors, got %d", n)
+	}
+
+	// Повтор того же времени источника не продлевает окно
+	w.Observe(SourceIONC, "s1", "SM", "AI1_AS", 1, src, start.Add(3*time.Second))
+	w.Tick(start.Add(4 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("stale reported before window: %+v", events)
+	}
+
+	// AI1_AS по своему окну 5s, AI2_AS по шаблону AI* с окном 1m
+	w.Tick(start.Add(6 * time.Second))
+	events := rec.take()
+	if len(events) != 1 || !events[0].Sensor.Stale || events[0].Sensor.Sensor != "AI1_AS" || events[0].Sensor.WindowMs != 5000 {
+		t.Fatalf("expected AI1_AS stale, got %+v", events)
+	}
+	w.Tick(start.Add(7 * time.Second))
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("stale reported twice: %+v", events)
+	}
+	if stale := w.Stale(); len(stale) != 1 || stale[0].StaleSince == nil {
+		t.Fatalf("unexpected stale list: %+v", stale)
+	}
+
+	// Время источника сдвинулось - датчик снова свежий
+	w.Observe(SourceIONC, "s1", "SM", "AI1_AS", 1, src.Add(time.Second), start.Add(8*time.Second))
+	events = rec.take()
+	if len(events) != 1 || events[0].Type != EventStale || events[0].Sensor.Stale {
+		t.Fatalf("expected fresh event, got %+v", events)
+	}
+	if stale := w.Stale(); len(stale) != 0 {
+		t.Fatalf("expected no stale sensors, got %+v", stale)
+	}
+}
+
+func TestWatchdogForgetsUnsubscribed(t *testing.T) {
+	rec := &recorder{}
+	w, err := New([]Rule{{Sensor: "*", Window: time.Second}}, rec.add)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	subscribed := map[int64]bool{1: true, 2: true}
+	w.SetSubscribedFunc(func(key Key, id int64) bool { return subscribed[id] })
+
+	start := time.Now()
+	w.Observe(SourceUWSGate, "s1", "UWS", "A", 1, time.Unix(1000, 0), start)
+	w.Observe(SourceUWSGate, "s1", "UWS", "B", 2, time.Unix(1000, 0), start)
+
+	delete(subscribed, 2)
+	w.Tick(start.Add(2 * time.Second))
+	events := rec.take()
+	if len(events) != 1 || events[0].Sensor.Sensor != "A" {
+		t.Fatalf("expected only subscribed sensor A stale, got %+v", events)
+	}
+	if n := w.Watched(); n != 1 {
+		t.Errorf("expected unsubscribed sensor to be forgotten, watched %d", n)
+	}
+}
@@ -34,6 +34,8 @@ const state = window.state = {
     sse: {
         eventSource: null,
         connected: false,
+        clientId: null,     // ID SSE клиента - владелец подписок на датчики
+        lastEventId: null,  // ID последнего полученного события (для воспроизведения пропущенных)
         pollInterval: 5000, // будет обновлено с сервера
         reconnectAttempts: 0,
         maxReconnectAttempts: 10,
@@ -118,6 +120,61 @@ function updateAddSensorButtons() {
     });
 }
 
+// Интервал обновления состояния breaker/backoff серверов (оно не рассылается через SSE)
+const SERVER_RESILIENCE_REFRESH_MS = 5000;
+
+// Обновить состояние circuit breaker и объектов в паузе (backoff) сервера
+function updateServerResilience(serverId, breaker, backoff) {
+    const server = state.servers.get(serverId);
+    if (!server) return;
+
+    server.breaker = breaker || null;
+    server.backoff = backoff || [];
+
+    const el = document.querySelector(`.server-item[data-server-id="${serverId}"] .server-resilience`);
+    if (el) {
+        renderServerResilience(el, server);
+    }
+}
+
+// Показать в элементе сервера состояние breaker (кроме closed) и число объектов в паузе
+function renderServerResilience(el, server) {
+    const parts = [];
+    const titles = [];
+    const breakerState = server.breaker?.state || 'closed';
+    const retryIn = ms => `${Math.ceil(ms / 1000)}s`;
+
+    if (breakerState !== 'closed') {
+        parts.push(breakerState);
+        let title = `Circuit breaker: ${breakerState}, connection errors in a row: ${server.breaker.failures}`;
+        if (server.breaker.retryInMs > 0) {
+            title += `, next try in ${retryIn(server.breaker.retryInMs)}`;
+        }
+        titles.push(title);
+    }
+
+    const backoff = server.backoff || [];
+    if (backoff.length > 0) {
+        parts.push(`backoff ${backoff.length}`);
+        titles.push('Objects backing off:\n' + backoff
+            .map(b => `${b.object}: ${b.failures} errors, next try in ${retryIn(b.retryInMs)}`)
+            .join('\n'));
+    }
+
+    el.textContent = parts.join(' · ');
+    el.title = titles.join('\n\n');
+    el.classList.toggle('breaker-open', breakerState === 'open');
+    el.classList.toggle('breaker-half-open', breakerState === 'half-open');
+    el.hidden = parts.length === 0;
+}
+
+// Перечитать состояние breaker/backoff всех серверов
+async function refreshServersResilience() {
+    const data = await fetchServers().catch(() => null);
+    if (!data || !data.servers) return;
+    data.servers.forEach(server => updateServerResilience(server.id, server.breaker, server.backoff));
+}
+
 // Обновить статус конкретного сервера
 function updateServerStatus(serverId, connected) {
     const server = state.servers.get(serverId);
@@ -657,27 +714,56 @@ function updateRecordingUI() {
 
 
 // === 04-sse.js ===
+// Заголовки запроса подписки с ID SSE клиента:
+// сервер считает подписки по клиентам и снимает их при отключении вкладки
+function withClientId(headers = {}) {
+    if (state.sse.clientId) {
+        return { ...headers, 'X-Client-ID': state.sse.clientId };
+    }
+    return headers;
+}
+
 function initSSE() {
     if (state.sse.eventSource) {
         state.sse.eventSource.close();
     }
 
-    // Формируем URL с токеном если есть
-    let url = '/api/events';
+    // Формируем URL с токеном и прежним ID клиента (чтобы сохранить подписки при переподключении)
+    const params = new URLSearchParams();
     if (state.control.token) {
-        url += `?token=${encodeURIComponent(state.control.token)}`;
+        params.set('token', state.control.token);
+    }
+    if (state.sse.clientId) {
+        params.set('client', state.sse.clientId);
+    }
+    // ID последнего полученного события: сервер воспроизведёт пропущенные
+    if (state.sse.lastEventId) {
+        params.set('lastEventId', state.sse.lastEventId);
+    }
+    let url = '/api/events';
+    if (params.toString()) {
+        url += `?${params}`;
     }
     console.log('SSE: Подключение к', url);
 
     const eventSource = new EventSource(url);
     state.sse.eventSource = eventSource;
 
-    eventSource.addEventListener('connected', (e) => {
+    // Подписка на событие с запоминанием его ID (для Last-Event-ID при переподключении)
+    const listen = (type, handler) => eventSource.addEventListener(type, (e) => {
+        if (e.lastEventId) {
+            state.sse.lastEventId = e.lastEventId;
+        }
+        handler(e);
+    });
+
+    listen('connected', (e) => {
         try {
             const data = JSON.parse(e.data);
             state.sse.connected = true;
             state.sse.reconnectAttempts = 0;
             state.sse.pollInterval = data.data?.pollInterval || 5000;
+            state.sse.clientId = data.data?.clientId || null;
 
             // Сохраняем capabilities сервера
             state.capabilities.smEnabled = data.data?.smEnabled || false;
@@ -715,7 +801,7 @@ function initSSE() {
         }
     });
 
-    eventSource.addEventListener('object_data', (e) => {
+    listen('object_data', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId, data, timestamp } = event;
@@ -791,7 +877,7 @@ function initSSE() {
 
     // Обработка обновлений внешних датчиков из SM (SharedMemory)
     // Backend отправляет serverId="sm" для SM событий
-    eventSource.addEventListener('sensor_data', (e) => {
+    listen('sensor_data', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -842,7 +928,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений IONC датчиков (SharedMemory и подобные)
-    eventSource.addEventListener('ionc_sensor_batch', (e) => {
+    listen('ionc_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -918,7 +1004,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений Modbus регистров (ModbusMaster, ModbusSlave)
-    eventSource.addEventListener('modbus_register_batch', (e) => {
+    listen('modbus_register_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -991,7 +1077,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений OPCUA датчиков (OPCUAExchange, OPCUAServer)
-    eventSource.addEventListener('opcua_sensor_batch', (e) => {
+    listen('opcua_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -1062,7 +1148,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений UWebSocketGate датчиков
-    eventSource.addEventListener('uwsgate_sensor_batch', (e) => {
+    listen('uwsgate_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -1129,21 +1215,40 @@ function initSSE() {
         }
     });
 
+    // Обработка значений виртуальных (вычисляемых) датчиков
+    listen('virtual_sensor_data', (e) => {
+        try {
+            const event = JSON.parse(e.data);
+            const value = event.data;
+            const sensor = { name: value.name, value: value.value, error: value.error || null };
+
+            state.sensorValuesCache.set(sensor.name, {
+                value: sensor.value,
+                error: sensor.error,
+                timestamp: Date.now()
+            });
+            updateDashboardWidgets([sensor], event.timestamp);
+        } catch (err) {
+            console.warn('SSE: Error обработки virtual_sensor_data:', err);
+        }
+    });
+
     // Обработка изменений статуса серверов
-    eventSource.addEventListener('server_status', (e) => {
+    listen('server_status', (e) => {
         try {
             const event = JSON.parse(e.data);
             const serverId = event.serverId;
             const connected = event.data?.connected ?? false;
             console.log(`SSE: Сервер ${serverId} ${connected ? 'подключен' : 'отключен'}`);
             updateServerStatus(serverId, connected);
+            refreshServersResilience();
         } catch (err) {
             console.warn('SSE: Error обработки server_status:', err);
         }
     });
 
     // Обработка обновления списка объектов (при восстановлении связи)
-    eventSource.addEventListener('objects_list', (e) => {
+    listen('objects_list', (e) => {
         try {
             const event = JSON.parse(e.data);
             const serverId = event.serverId;
@@ -1162,7 +1267,7 @@ function initSSE() {
     });
 
     // Обработка изменения статуса контроля
-    eventSource.addEventListener('control_status', (e) => {
+    listen('control_status', (e) => {
         try {
             const event = JSON.parse(e.data);
             console.log('SSE: Control status changed:', event.data);
@@ -1191,7 +1296,7 @@ function initSSE() {
     });
 
     // Обработка сообщений журнала
-    eventSource.addEventListener('journal_messages', (e) => {
+    listen('journal_messages', (e) => {
         try {
             const event = JSON.parse(e.data);
             const data = event.data;
@@ -1203,6 +1308,12 @@ function initSSE() {
         }
     });
 
+    // Пропущенных событий уже нет в буфере сервера - перезагружаем состояние
+    listen('resync_required', () => {
+        console.warn('SSE: Пропущены события, перезагрузка состояния');
+        resyncState();
+    });
+
     eventSource.onerror = (e) => {
         console.warn('SSE: Error соединения');
         state.sse.connected = false;
@@ -1229,6 +1340,20 @@ function initSSE() {
     };
 }
 
+// Перезагрузить данные открытых вкладок после потери SSE событий
+function resyncState() {
+    refreshObjectsList();
+    state.tabs.forEach((tabState, tabKey) => {
+        const renderer = tabState.renderer;
+        if (typeof renderer?.reloadAll === 'function') {
+            renderer.reloadAll();
+        } else if (typeof renderer?.loadSensors === 'function') {
+            renderer.loadSensors();
+        }
+        loadObjectData(tabKey);
+    });
+}
+
 // Включить polling как fallback при недоступности SSE
 function enablePollingFallback() {
     console.log('Polling: Включение fallback режима');
@@ -1385,7 +1510,7 @@ const SSESubscriptionMixin = {
         try {
             await this.fetchJSON(`/api/objects/${encodeURIComponent(this.objectName)}${apiPath}/subscribe`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ [idField]: ids, ...extraBody })
             });
 
@@ -1404,7 +1529,7 @@ const SSESubscriptionMixin = {
             const ids = [...this.subscribedSensorIds];
             await this.fetchJSON(`/api/objects/${encodeURIComponent(this.objectName)}${apiPath}/unsubscribe`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ [idField]: ids })
             });
 
@@ -8211,7 +8336,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
                 const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/subscribe`);
                 const response = await controlledFetch(url, {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: withClientId({ 'Content-Type': 'application/json' }),
                     body: JSON.stringify({ sensors: [name] })
                 });
 
@@ -8250,7 +8375,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
             const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/unsubscribe`);
             await controlledFetch(url, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ sensors: [name] })
             });
 
@@ -8560,6 +8685,14 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
                     }
                     this.renderSensorsTable();
                     this.saveSubscriptions();
+
+                    // Становимся владельцем подписок, чтобы они не снялись без этой вкладки
+                    const subscribeUrl = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/subscribe`);
+                    controlledFetch(subscribeUrl, {
+                        method: 'POST',
+                        headers: withClientId({ 'Content-Type': 'application/json' }),
+                        body: JSON.stringify({ sensors: data.sensors.map(sensor => sensor.name) })
+                    }).catch(err => console.warn('Failed to subscribe to loaded sensors:', err));
                     return;
                 }
             }
@@ -8591,7 +8724,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
             const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/unsubscribe`);
             fetch(url, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ sensors: names })
             }).catch(err => console.warn('Failed to unsubscribe on destroy:', err));
         }
@@ -11215,6 +11348,8 @@ async function fetchObjects() {
             url: server.url,
             name: server.name || server.url,
             connected: server.connected,
+            breaker: server.breaker || null,
+            backoff: server.backoff || [],
             cachedObjects: cachedObjectsMap.get(server.id) || [] // восстанавливаем кеш
         });
     });
@@ -11617,7 +11752,7 @@ async function subscribeToExternalSensors(tabKey, sensorNames) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensors: sensorNames })
         });
         if (!response.ok) {
@@ -11642,7 +11777,7 @@ async function unsubscribeFromExternalSensor(tabKey, sensorName) {
             url += `?server=${encodeURIComponent(serverId)}`;
         }
 
-        const response = await fetch(url, { method: 'DELETE' });
+        const response = await fetch(url, { method: 'DELETE', headers: withClientId() });
         if (!response.ok) {
             const err = await response.json();
             console.warn('Error отписки от датчика:', err.error || response.statusText);
@@ -11667,7 +11802,7 @@ async function subscribeToIONCSensor(tabKey, sensorId) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensor_ids: [sensorId] })
         });
         if (!response.ok) {
@@ -11700,7 +11835,7 @@ async function unsubscribeFromIONCSensor(tabKey, sensorId) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensor_ids: [sensorId] })
         });
         if (!response.ok) {
@@ -12212,7 +12347,7 @@ function restoreExternalSensors(tabKey, displayName) {
                 }
                 fetch(url, {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: withClientId({ 'Content-Type': 'application/json' }),
                     body: JSON.stringify({ sensor_ids: restoredSensorIds })
                 }).then(response => {
                     if (response.ok) {
@@ -12465,8 +12600,10 @@ function renderServersSection() {
         li.innerHTML = `
             <span class="server-status-dot${statusClass}"></span>
             <span class="server-name" title="${escapeHtml(server.url)}">${escapeHtml(displayName)}</span>
+            <span class="server-resilience" hidden></span>
             <span class="server-stats ${statsClass}">${statsText}</span>
         `;
+        renderServerResilience(li.querySelector('.server-resilience'), server);
 
         // Клик на сервер — развернуть/свернуть его группу в списке объектов
         li.addEventListener('click', () => {
@@ -18329,8 +18466,11 @@ class DashboardManager {
         }
     }
 
-    // Fetch sensor values from IONC API
+    // Fetch sensor values from virtual sensors API, then IONC API
     async fetchSensorValues(sensorNames) {
+        sensorNames = await this.fetchVirtualSensorValues(sensorNames);
+        if (sensorNames.length === 0) return;
+
         // Find SharedMemory server
         let smServerId = null;
         for (const [id, server] of state.servers) {
@@ -18367,6 +18507,33 @@ class DashboardManager {
         }
     }
 
+    // Fetch current values of virtual sensors; returns names that are not virtual
+    async fetchVirtualSensorValues(sensorNames) {
+        try {
+            const response = await fetch('/api/sensors/virtual');
+            if (!response.ok) return sensorNames;
+            const data = await response.json();
+            if (!data.enabled) return sensorNames;
+
+            const virtualNames = new Set();
+            for (const sensor of data.sensors || []) {
+                virtualNames.add(sensor.name);
+                if (!sensor.value || !sensorNames.includes(sensor.name)) continue;
+                const error = sensor.value.error || null;
+                state.sensorValuesCache.set(sensor.name, {
+                    value: sensor.value.value,
+                    error,
+                    timestamp: Date.now()
+                });
+                this.handleSensorUpdate(sensor.name, sensor.value.value, error);
+            }
+            return sensorNames.filter(name => !virtualNames.has(name));
+        } catch (err) {
+            console.warn('Failed to fetch virtual sensors:', err);
+            return sensorNames;
+        }
+    }
+
     createWidget(widgetConfig) {
         const WidgetClass = WIDGET_TYPES[widgetConfig.type];
         if (!WidgetClass) {
@@ -20368,6 +20535,9 @@ document.addEventListener('DOMContentLoaded', () => {
                 '<li class="alert alert-error">Error loading objects</li>';
         });
 
+    // Состояние circuit breaker и backoff серверов
+    setInterval(refreshServersResilience, SERVER_RESILIENCE_REFRESH_MS);
+
     // Кнопка обновления
     document.getElementById('refresh-objects').addEventListener('click', () => {
         fetchObjects()
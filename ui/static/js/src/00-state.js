@@ -30,6 +30,8 @@ const state = window.state = {
     sse: {
         eventSource: null,
         connected: false,
+        clientId: null,     // ID SSE клиента - владелец подписок на датчики
+        lastEventId: null,  // ID последнего полученного события (для воспроизведения пропущенных)
         pollInterval: 5000, // будет обновлено с сервера
         reconnectAttempts: 0,
         maxReconnectAttempts: 10,
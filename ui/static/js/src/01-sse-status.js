@@ -64,6 +64,61 @@ function updateAddSensorButtons() {
     });
 }
 
+// Интервал обновления состояния breaker/backoff серверов (оно не рассылается через SSE)
+const SERVER_RESILIENCE_REFRESH_MS = 5000;
+
+// Обновить состояние circuit breaker и объектов в паузе (backoff) сервера
+function updateServerResilience(serverId, breaker, backoff) {
+    const server = state.servers.get(serverId);
+    if (!server) return;
+
+    server.breaker = breaker || null;
+    server.backoff = backoff || [];
+
+    const el = document.querySelector(`.server-item[data-server-id="${serverId}"] .server-resilience`);
+    if (el) {
+        renderServerResilience(el, server);
+    }
+}
+
+// Показать в элементе сервера состояние breaker (кроме closed) и число объектов в паузе
+function renderServerResilience(el, server) {
+    const parts = [];
+    const titles = [];
+    const breakerState = server.breaker?.state || 'closed';
+    const retryIn = ms => `${Math.ceil(ms / 1000)}s`;
+
+    if (breakerState !== 'closed') {
+        parts.push(breakerState);
+        let title = `Circuit breaker: ${breakerState}, connection errors in a row: ${server.breaker.failures}`;
+        if (server.breaker.retryInMs > 0) {
+            title += `, next try in ${retryIn(server.breaker.retryInMs)}`;
+        }
+        titles.push(title);
+    }
+
+    const backoff = server.backoff || [];
+    if (backoff.length > 0) {
+        parts.push(`backoff ${backoff.length}`);
+        titles.push('Objects backing off:\n' + backoff
+            .map(b => `${b.object}: ${b.failures} errors, next try in ${retryIn(b.retryInMs)}`)
+            .join('\n'));
+    }
+
+    el.textContent = parts.join(' · ');
+    el.title = titles.join('\n\n');
+    el.classList.toggle('breaker-open', breakerState === 'open');
+    el.classList.toggle('breaker-half-open', breakerState === 'half-open');
+    el.hidden = parts.length === 0;
+}
+
+// Перечитать состояние breaker/backoff всех серверов
+async function refreshServersResilience() {
+    const data = await fetchServers().catch(() => null);
+    if (!data || !data.servers) return;
+    data.servers.forEach(server => updateServerResilience(server.id, server.breaker, server.backoff));
+}
+
 // Обновить статус конкретного сервера
 function updateServerStatus(serverId, connected) {
     const server = state.servers.get(serverId);
@@ -1,24 +1,53 @@
+// Заголовки запроса подписки с ID SSE клиента:
+// сервер считает подписки по клиентам и снимает их при отключении вкладки
+function withClientId(headers = {}) {
+    if (state.sse.clientId) {
+        return { ...headers, 'X-Client-ID': state.sse.clientId };
+    }
+    return headers;
+}
+
 function initSSE() {
     if (state.sse.eventSource) {
         state.sse.eventSource.close();
     }
 
-    // Формируем URL с токеном если есть
-    let url = '/api/events';
+    // Формируем URL с токеном и прежним ID клиента (чтобы сохранить подписки при переподключении)
+    const params = new URLSearchParams();
     if (state.control.token) {
-        url += `?token=${encodeURIComponent(state.control.token)}`;
+        params.set('token', state.control.token);
+    }
+    if (state.sse.clientId) {
+        params.set('client', state.sse.clientId);
+    }
+    // ID последнего полученного события: сервер воспроизведёт пропущенные
+    if (state.sse.lastEventId) {
+        params.set('lastEventId', state.sse.lastEventId);
+    }
+    let url = '/api/events';
+    if (params.toString()) {
+        url += `?${params}`;
     }
     console.log('SSE: Подключение к', url);
 
     const eventSource = new EventSource(url);
     state.sse.eventSource = eventSource;
 
-    eventSource.addEventListener('connected', (e) => {
+    // Подписка на событие с запоминанием его ID (для Last-Event-ID при переподключении)
+    const listen = (type, handler) => eventSource.addEventListener(type, (e) => {
+        if (e.lastEventId) {
+            state.sse.lastEventId = e.lastEventId;
+        }
+        handler(e);
+    });
+
+    listen('connected', (e) => {
         try {
             const data = JSON.parse(e.data);
             state.sse.connected = true;
             state.sse.reconnectAttempts = 0;
             state.sse.pollInterval = data.data?.pollInterval || 5000;
+            state.sse.clientId = data.data?.clientId || null;
 
             // Сохраняем capabilities сервера
             state.capabilities.smEnabled = data.data?.smEnabled || false;
@@ -56,7 +85,7 @@ function initSSE() {
         }
     });
 
-    eventSource.addEventListener('object_data', (e) => {
+    listen('object_data', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId, data, timestamp } = event;
@@ -132,7 +161,7 @@ function initSSE() {
 
     // Обработка обновлений внешних датчиков из SM (SharedMemory)
     // Backend отправляет serverId="sm" для SM событий
-    eventSource.addEventListener('sensor_data', (e) => {
+    listen('sensor_data', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -183,7 +212,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений IONC датчиков (SharedMemory и подобные)
-    eventSource.addEventListener('ionc_sensor_batch', (e) => {
+    listen('ionc_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -259,7 +288,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений Modbus регистров (ModbusMaster, ModbusSlave)
-    eventSource.addEventListener('modbus_register_batch', (e) => {
+    listen('modbus_register_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -332,7 +361,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений OPCUA датчиков (OPCUAExchange, OPCUAServer)
-    eventSource.addEventListener('opcua_sensor_batch', (e) => {
+    listen('opcua_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -403,7 +432,7 @@ function initSSE() {
     });
 
     // Обработка батча обновлений UWebSocketGate датчиков
-    eventSource.addEventListener('uwsgate_sensor_batch', (e) => {
+    listen('uwsgate_sensor_batch', (e) => {
         try {
             const event = JSON.parse(e.data);
             const { objectName, serverId } = event;
@@ -470,21 +499,40 @@ function initSSE() {
         }
     });
 
+    // Обработка значений виртуальных (вычисляемых) датчиков
+    listen('virtual_sensor_data', (e) => {
+        try {
+            const event = JSON.parse(e.data);
+            const value = event.data;
+            const sensor = { name: value.name, value: value.value, error: value.error || null };
+
+            state.sensorValuesCache.set(sensor.name, {
+                value: sensor.value,
+                error: sensor.error,
+                timestamp: Date.now()
+            });
+            updateDashboardWidgets([sensor], event.timestamp);
+        } catch (err) {
+            console.warn('SSE: Error обработки virtual_sensor_data:', err);
+        }
+    });
+
     // Обработка изменений статуса серверов
-    eventSource.addEventListener('server_status', (e) => {
+    listen('server_status', (e) => {
         try {
             const event = JSON.parse(e.data);
             const serverId = event.serverId;
             const connected = event.data?.connected ?? false;
             console.log(`SSE: Сервер ${serverId} ${connected ? 'подключен' : 'отключен'}`);
             updateServerStatus(serverId, connected);
+            refreshServersResilience();
         } catch (err) {
             console.warn('SSE: Error обработки server_status:', err);
         }
     });
 
     // Обработка обновления списка объектов (при восстановлении связи)
-    eventSource.addEventListener('objects_list', (e) => {
+    listen('objects_list', (e) => {
         try {
             const event = JSON.parse(e.data);
             const serverId = event.serverId;
@@ -503,7 +551,7 @@ function initSSE() {
     });
 
     // Обработка изменения статуса контроля
-    eventSource.addEventListener('control_status', (e) => {
+    listen('control_status', (e) => {
         try {
             const event = JSON.parse(e.data);
             console.log('SSE: Control status changed:', event.data);
@@ -532,7 +580,7 @@ function initSSE() {
     });
 
     // Обработка сообщений журнала
-    eventSource.addEventListener('journal_messages', (e) => {
+    listen('journal_messages', (e) => {
         try {
             const event = JSON.parse(e.data);
             const data = event.data;
@@ -544,6 +592,12 @@ function initSSE() {
         }
     });
 
+    // Пропущенных событий уже нет в буфере сервера - перезагружаем состояние
+    listen('resync_required', () => {
+        console.warn('SSE: Пропущены события, перезагрузка состояния');
+        resyncState();
+    });
+
     eventSource.onerror = (e) => {
         console.warn('SSE: Error соединения');
         state.sse.connected = false;
@@ -570,6 +624,20 @@ function initSSE() {
     };
 }
 
+// Перезагрузить данные открытых вкладок после потери SSE событий
+function resyncState() {
+    refreshObjectsList();
+    state.tabs.forEach((tabState, tabKey) => {
+        const renderer = tabState.renderer;
+        if (typeof renderer?.reloadAll === 'function') {
+            renderer.reloadAll();
+        } else if (typeof renderer?.loadSensors === 'function') {
+            renderer.loadSensors();
+        }
+        loadObjectData(tabKey);
+    });
+}
+
 // Включить polling как fallback при недоступности SSE
 function enablePollingFallback() {
     console.log('Polling: Включение fallback режима');
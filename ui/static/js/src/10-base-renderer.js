@@ -118,7 +118,7 @@ const SSESubscriptionMixin = {
         try {
             await this.fetchJSON(`/api/objects/${encodeURIComponent(this.objectName)}${apiPath}/subscribe`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ [idField]: ids, ...extraBody })
             });
 
@@ -137,7 +137,7 @@ const SSESubscriptionMixin = {
             const ids = [...this.subscribedSensorIds];
             await this.fetchJSON(`/api/objects/${encodeURIComponent(this.objectName)}${apiPath}/unsubscribe`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ [idField]: ids })
             });
 
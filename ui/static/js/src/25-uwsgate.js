@@ -371,7 +371,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
                 const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/subscribe`);
                 const response = await controlledFetch(url, {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: withClientId({ 'Content-Type': 'application/json' }),
                     body: JSON.stringify({ sensors: [name] })
                 });
 
@@ -410,7 +410,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
             const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/unsubscribe`);
             await controlledFetch(url, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ sensors: [name] })
             });
 
@@ -720,6 +720,14 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
                     }
                     this.renderSensorsTable();
                     this.saveSubscriptions();
+
+                    // Становимся владельцем подписок, чтобы они не снялись без этой вкладки
+                    const subscribeUrl = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/subscribe`);
+                    controlledFetch(subscribeUrl, {
+                        method: 'POST',
+                        headers: withClientId({ 'Content-Type': 'application/json' }),
+                        body: JSON.stringify({ sensors: data.sensors.map(sensor => sensor.name) })
+                    }).catch(err => console.warn('Failed to subscribe to loaded sensors:', err));
                     return;
                 }
             }
@@ -751,7 +759,7 @@ class UWebSocketGateRenderer extends BaseObjectRenderer {
             const url = this.buildUrl(`/api/objects/${encodeURIComponent(this.objectName)}/uwsgate/unsubscribe`);
             fetch(url, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: withClientId({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ sensors: names })
             }).catch(err => console.warn('Failed to unsubscribe on destroy:', err));
         }
@@ -44,6 +44,8 @@ async function fetchObjects() {
             url: server.url,
             name: server.name || server.url,
             connected: server.connected,
+            breaker: server.breaker || null,
+            backoff: server.backoff || [],
             cachedObjects: cachedObjectsMap.get(server.id) || [] // восстанавливаем кеш
         });
     });
@@ -281,7 +281,7 @@ async function subscribeToExternalSensors(tabKey, sensorNames) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensors: sensorNames })
         });
         if (!response.ok) {
@@ -306,7 +306,7 @@ async function unsubscribeFromExternalSensor(tabKey, sensorName) {
             url += `?server=${encodeURIComponent(serverId)}`;
         }
 
-        const response = await fetch(url, { method: 'DELETE' });
+        const response = await fetch(url, { method: 'DELETE', headers: withClientId() });
         if (!response.ok) {
             const err = await response.json();
             console.warn('Error отписки от датчика:', err.error || response.statusText);
@@ -331,7 +331,7 @@ async function subscribeToIONCSensor(tabKey, sensorId) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensor_ids: [sensorId] })
         });
         if (!response.ok) {
@@ -364,7 +364,7 @@ async function unsubscribeFromIONCSensor(tabKey, sensorId) {
 
         const response = await fetch(url, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: withClientId({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ sensor_ids: [sensorId] })
         });
         if (!response.ok) {
@@ -876,7 +876,7 @@ function restoreExternalSensors(tabKey, displayName) {
                 }
                 fetch(url, {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: withClientId({ 'Content-Type': 'application/json' }),
                     body: JSON.stringify({ sensor_ids: restoredSensorIds })
                 }).then(response => {
                     if (response.ok) {
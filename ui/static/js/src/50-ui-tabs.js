@@ -226,8 +226,10 @@ function renderServersSection() {
         li.innerHTML = `
             <span class="server-status-dot${statusClass}"></span>
             <span class="server-name" title="${escapeHtml(server.url)}">${escapeHtml(displayName)}</span>
+            <span class="server-resilience" hidden></span>
             <span class="server-stats ${statsClass}">${statsText}</span>
         `;
+        renderServerResilience(li.querySelector('.server-resilience'), server);
 
         // Клик на сервер — развернуть/свернуть его группу в списке объектов
         li.addEventListener('click', () => {
@@ -405,8 +405,11 @@ class DashboardManager {
         }
     }
 
-    // Fetch sensor values from IONC API
+    // Fetch sensor values from virtual sensors API, then IONC API
     async fetchSensorValues(sensorNames) {
+        sensorNames = await this.fetchVirtualSensorValues(sensorNames);
+        if (sensorNames.length === 0) return;
+
         // Find SharedMemory server
         let smServerId = null;
         for (const [id, server] of state.servers) {
@@ -443,6 +446,33 @@ class DashboardManager {
         }
     }
 
+    // Fetch current values of virtual sensors; returns names that are not virtual
+    async fetchVirtualSensorValues(sensorNames) {
+        try {
+            const response = await fetch('/api/sensors/virtual');
+            if (!response.ok) return sensorNames;
+            const data = await response.json();
+            if (!data.enabled) return sensorNames;
+
+            const virtualNames = new Set();
+            for (const sensor of data.sensors || []) {
+                virtualNames.add(sensor.name);
+                if (!sensor.value || !sensorNames.includes(sensor.name)) continue;
+                const error = sensor.value.error || null;
+                state.sensorValuesCache.set(sensor.name, {
+                    value: sensor.value.value,
+                    error,
+                    timestamp: Date.now()
+                });
+                this.handleSensorUpdate(sensor.name, sensor.value.value, error);
+            }
+            return sensorNames.filter(name => !virtualNames.has(name));
+        } catch (err) {
+            console.warn('Failed to fetch virtual sensors:', err);
+            return sensorNames;
+        }
+    }
+
     createWidget(widgetConfig) {
         const WidgetClass = WIDGET_TYPES[widgetConfig.type];
         if (!WidgetClass) {
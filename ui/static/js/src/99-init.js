@@ -42,6 +42,9 @@ document.addEventListener('DOMContentLoaded', () => {
                 '<li class="alert alert-error">Error loading objects</li>';
         });
 
+    // Состояние circuit breaker и backoff серверов
+    setInterval(refreshServersResilience, SERVER_RESILIENCE_REFRESH_MS);
+
     // Кнопка обновления
     document.getElementById('refresh-objects').addEventListener('click', () => {
         fetchObjects()